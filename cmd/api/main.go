@@ -1,19 +1,36 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	// Календарные лимиты карт считаются в часовом поясе счета, база поясов
+	// встраивается, чтобы не зависеть от образа
+	_ "time/tzdata"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 
+	"bank-app/internal/cardvault"
+	"bank-app/internal/cbr"
 	"bank-app/internal/config"
 	"bank-app/internal/handler"
+	"bank-app/internal/iso8583"
+	"bank-app/internal/jwtkeys"
+	"bank-app/internal/mailer"
 	"bank-app/internal/repository"
+	"bank-app/internal/scheduler"
 	"bank-app/internal/service"
 )
 
+// shutdownTimeout - время на завершение активных запросов при остановке
+const shutdownTimeout = 15 * time.Second
+
 func main() {
 	logger := logrus.New()
 	logger.SetOutput(os.Stdout)
@@ -30,43 +47,185 @@ func main() {
 	}
 	defer db.Close()
 
+	keys, err := jwtkeys.Load(cfg.JWTKeys.ActiveKeyID, cfg.JWTKeys.Files, cfg.JWTSecret)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+
+	vault, err := cardvault.Load(cfg.CardKeys.ActiveKeyID, cfg.CardKeys.Keys, cfg.CardKeys.HMACKey)
+	if err != nil {
+		log.Fatalf("Failed to load card encryption keys (CARD_KEYS, CARD_HMAC_KEY): %v", err)
+	}
+	if len(cfg.Processing.APIKeys) == 0 {
+		logger.Warn("PROCESSING_API_KEYS is not set, card authorization API rejects all requests")
+	}
+
 	repos := repository.NewRepositories(db)
-	services := service.NewServices(repos, cfg)
-	handlers := handler.NewHandlers(services, logger)
+	services := service.NewServices(repos, keys, vault, mailer.NewSMTPMailer(cfg.SMTPConfig), cbr.New(cfg.CBRConfig), logger, cfg)
+	handlers := handler.NewHandlers(services, logger).WithTrustedProxies(cfg.TrustedProxies)
 
 	router := mux.NewRouter()
 
-	// Публичные маршруты
-	router.HandleFunc("/api/v1/register", handlers.Register).Methods(http.MethodPost)
+	// Публичные маршруты. Ответы на вход и обновление содержат токены и не сохраняются
+	// в хранилище идемпотентности.
+	router.Handle("/api/v1/register", handlers.IdempotencyMiddleware(http.HandlerFunc(handlers.Register))).Methods(http.MethodPost)
 	router.HandleFunc("/api/v1/login", handlers.Login).Methods(http.MethodPost)
-
-	// Защищенные маршруты
+	router.HandleFunc("/api/v1/login/mfa", handlers.LoginMFA).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/refresh", handlers.Refresh).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/account/unlock", handlers.UnlockAccount).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/email/verify", handlers.VerifyEmail).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/password/forgot", handlers.ForgotPassword).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/password/reset", handlers.ResetPassword).Methods(http.MethodPost)
+
+	// Открытые ключи для проверки токенов другими сервисами
+	router.HandleFunc("/.well-known/jwks.json", handlers.JWKS).Methods(http.MethodGet)
+
+	// Прием операций по картам от эквайреров по ключу API. Повторные запросы
+	// распознаются по номеру операции эквайрера.
+	processing := router.PathPrefix("/api/v1/processing").Subrouter()
+	processing.Use(handlers.APIKeyMiddleware)
+	processing.HandleFunc("/authorizations", handlers.AuthorizeCard).Methods(http.MethodPost)
+	processing.HandleFunc("/authorizations/{id:[0-9]+}", handlers.GetAuthorization).Methods(http.MethodGet)
+	processing.HandleFunc("/authorizations/{id:[0-9]+}/capture", handlers.CaptureAuthorization).Methods(http.MethodPost)
+	processing.HandleFunc("/authorizations/{id:[0-9]+}/reverse", handlers.ReverseAuthorization).Methods(http.MethodPost)
+	processing.HandleFunc("/authorizations/{id:[0-9]+}/refund", handlers.RefundAuthorization).Methods(http.MethodPost)
+
+	// Защищенные маршруты. Все изменяющие запросы поддерживают заголовок Idempotency-Key.
+	// Ответы с реквизитами карты (/cards/{id}/reveal), секретом TOTP и кодами
+	// восстановления (/mfa/enroll, /mfa/confirm) помечаются Cache-Control: no-store
+	// и не сохраняются в хранилище идемпотентности.
 	protected := router.PathPrefix("/api/v1").Subrouter()
-	protected.Use(handlers.AuthMiddleware)
+	protected.Use(handlers.AuthMiddleware, handlers.IdempotencyMiddleware)
+
+	// Маршруты с {id} проверяют, что ресурс принадлежит пользователю
+	account := protected.PathPrefix("/accounts/{id:[0-9]+}").Subrouter()
+	account.Use(handlers.AccountOwnerMiddleware)
+
+	card := protected.PathPrefix("/cards/{id:[0-9]+}").Subrouter()
+	card.Use(handlers.CardOwnerMiddleware)
+
+	credit := protected.PathPrefix("/credits/{id:[0-9]+}").Subrouter()
+	credit.Use(handlers.CreditOwnerMiddleware)
+
+	transfer := protected.PathPrefix("/transfers/{id:[0-9]+}").Subrouter()
+	transfer.Use(handlers.TransferOwnerMiddleware)
+
+	// Сессии
+	protected.HandleFunc("/logout", handlers.Logout).Methods(http.MethodPost)
+	protected.HandleFunc("/logout/all", handlers.LogoutAll).Methods(http.MethodPost)
+	protected.HandleFunc("/sessions", handlers.GetSessions).Methods(http.MethodGet)
+	protected.HandleFunc("/email/verification", handlers.ResendVerificationEmail).Methods(http.MethodPost)
+
+	// Двухфакторная аутентификация
+	protected.HandleFunc("/mfa/enroll", handlers.EnrollMFA).Methods(http.MethodPost)
+	protected.HandleFunc("/mfa/confirm", handlers.ConfirmMFA).Methods(http.MethodPost)
+	protected.HandleFunc("/mfa/disable", handlers.DisableMFA).Methods(http.MethodPost)
+	protected.HandleFunc("/mfa/verify", handlers.StepUpMFA).Methods(http.MethodPost)
 
 	// Счета
 	protected.HandleFunc("/accounts", handlers.CreateAccount).Methods(http.MethodPost)
 	protected.HandleFunc("/accounts", handlers.GetAccounts).Methods(http.MethodGet)
-	protected.HandleFunc("/accounts/{id}", handlers.GetAccount).Methods(http.MethodGet)
+	account.HandleFunc("", handlers.GetAccount).Methods(http.MethodGet)
+	account.HandleFunc("/postings", handlers.GetAccountPostings).Methods(http.MethodGet)
+	account.HandleFunc("/transactions", handlers.GetAccountTransactions).Methods(http.MethodGet)
 
 	// Карты
 	protected.HandleFunc("/cards", handlers.CreateCard).Methods(http.MethodPost)
 	protected.HandleFunc("/cards", handlers.GetCards).Methods(http.MethodGet)
-	protected.HandleFunc("/cards/{id}", handlers.GetCard).Methods(http.MethodGet)
+	card.HandleFunc("", handlers.GetCard).Methods(http.MethodGet)
+	card.Handle("/details", handlers.StepUpMiddleware(http.HandlerFunc(handlers.GetCardDetails))).Methods(http.MethodGet)
+	card.Handle("/reveal", handlers.StepUpMiddleware(http.HandlerFunc(handlers.RevealCardDetails))).Methods(http.MethodPost)
+	card.HandleFunc("/history", handlers.GetCardHistory).Methods(http.MethodGet)
+	card.HandleFunc("/block", handlers.BlockCard).Methods(http.MethodPost)
+	card.HandleFunc("/unblock", handlers.UnblockCard).Methods(http.MethodPost)
+	card.HandleFunc("/close", handlers.CloseCard).Methods(http.MethodPost)
+	card.HandleFunc("/reissue", handlers.ReissueCard).Methods(http.MethodPost)
+	card.HandleFunc("/activate", handlers.ActivateCard).Methods(http.MethodPost)
+	card.HandleFunc("/pin", handlers.SetCardPIN).Methods(http.MethodPost)
+	card.HandleFunc("/pin", handlers.ChangeCardPIN).Methods(http.MethodPut)
+	card.Handle("/pin/reset", handlers.StepUpMiddleware(http.HandlerFunc(handlers.ResetCardPIN))).Methods(http.MethodPost)
+	card.HandleFunc("/limits", handlers.GetCardLimits).Methods(http.MethodGet)
+	card.HandleFunc("/limits", handlers.CreateCardLimit).Methods(http.MethodPost)
+	card.HandleFunc("/limits/{limitId:[0-9]+}", handlers.UpdateCardLimit).Methods(http.MethodPut)
+	card.HandleFunc("/limits/{limitId:[0-9]+}", handlers.DeleteCardLimit).Methods(http.MethodDelete)
 
 	// Переводы
 	protected.HandleFunc("/transfers", handlers.CreateTransfer).Methods(http.MethodPost)
+	transfer.HandleFunc("", handlers.GetTransfer).Methods(http.MethodGet)
 
 	// Кредиты
 	protected.HandleFunc("/credits", handlers.CreateCredit).Methods(http.MethodPost)
-	protected.HandleFunc("/credits/{id}/schedule", handlers.GetCreditSchedule).Methods(http.MethodGet)
+	protected.HandleFunc("/credits/quote", handlers.QuoteCredit).Methods(http.MethodPost)
+	credit.HandleFunc("/schedule", handlers.GetCreditSchedule).Methods(http.MethodGet)
+	credit.HandleFunc("/schedule/versions", handlers.GetCreditScheduleVersions).Methods(http.MethodGet)
+	credit.HandleFunc("/prepay", handlers.PrepayCredit).Methods(http.MethodPost)
 
 	// Аналитика
 	protected.HandleFunc("/analytics", handlers.GetAnalytics).Methods(http.MethodGet)
-	protected.HandleFunc("/accounts/{id}/predict", handlers.PredictBalance).Methods(http.MethodGet)
+	account.HandleFunc("/predict", handlers.PredictBalance).Methods(http.MethodGet)
+
+	// Бэк-офис. Разрешения конкретной роли проверяются в AdminService,
+	// каждое действие записывается в журнал.
+	admin := protected.PathPrefix("/admin").Subrouter()
+	admin.Use(handlers.StaffMiddleware)
+	admin.HandleFunc("/users", handlers.AdminSearchUsers).Methods(http.MethodGet)
+	admin.HandleFunc("/users/{id:[0-9]+}/role", handlers.AdminSetRole).Methods(http.MethodPut)
+	admin.HandleFunc("/users/{id:[0-9]+}/accounts", handlers.AdminGetUserAccounts).Methods(http.MethodGet)
+	admin.HandleFunc("/users/{id:[0-9]+}/notes", handlers.AdminAddCaseNote).Methods(http.MethodPost)
+	admin.HandleFunc("/users/{id:[0-9]+}/notes", handlers.AdminGetCaseNotes).Methods(http.MethodGet)
+	admin.HandleFunc("/accounts/{id:[0-9]+}", handlers.AdminGetAccount).Methods(http.MethodGet)
+	admin.HandleFunc("/accounts/{id:[0-9]+}/freeze", handlers.AdminFreezeAccount).Methods(http.MethodPost)
+	admin.HandleFunc("/accounts/{id:[0-9]+}/unfreeze", handlers.AdminUnfreezeAccount).Methods(http.MethodPost)
+	admin.HandleFunc("/cards/{id:[0-9]+}/block", handlers.AdminBlockCard).Methods(http.MethodPost)
+	admin.HandleFunc("/cards/{id:[0-9]+}/unblock", handlers.AdminUnblockCard).Methods(http.MethodPost)
+	admin.HandleFunc("/audit", handlers.AdminGetAuditLog).Methods(http.MethodGet)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Фоновые задачи
+	jobs := scheduler.New(logger)
+	jobs.Add("card_renewal", cfg.Cards.RenewalInterval, services.Cards.RenewExpiring)
+	jobs.Add("card_settlement", cfg.Processing.SettlementInterval, services.Processing.Settle)
+	jobs.Add("credit_repayments", cfg.Credits.RepaymentInterval, services.Credits.ProcessPayments)
+	jobs.Start(ctx)
+
+	// Прием авторизаций от платежной сети по ISO 8583
+	isoDone := make(chan struct{})
+	if cfg.ISO8583.Addr != "" {
+		spec, err := iso8583.LoadSpec(cfg.ISO8583.SpecFile)
+		if err != nil {
+			log.Fatalf("Failed to load ISO 8583 spec: %v", err)
+		}
+
+		isoServer := iso8583.NewServer(spec, handlers.ISO8583(cfg.ISO8583.Acquirer, cfg.ISO8583.PINKey), logger, cfg.ISO8583.IdleTimeout)
+		go func() {
+			defer close(isoDone)
+			logger.Infof("Starting ISO 8583 listener on %s", cfg.ISO8583.Addr)
+			if err := isoServer.ListenAndServe(ctx, cfg.ISO8583.Addr); err != nil {
+				log.Fatalf("ISO 8583 listener failed: %v", err)
+			}
+		}()
+	} else {
+		close(isoDone)
+	}
+
+	server := &http.Server{Addr: cfg.ServerAddress, Handler: router}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.WithError(err).Error("Server shutdown failed")
+		}
+	}()
 
 	logger.Infof("Starting server on %s", cfg.ServerAddress)
-	if err := http.ListenAndServe(cfg.ServerAddress, router); err != nil {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Server failed: %v", err)
 	}
+
+	jobs.Wait()
+	<-isoDone
+	logger.Info("Server stopped")
 }
@@ -0,0 +1,130 @@
+// Команда merchantsim имитирует магазин, отправляющий операции по картам
+// в локально запущенный API:
+//
+//	merchantsim -card 4276... -cvv 123 -amount 1500 purchase
+//	merchantsim -amount 1500 authorize
+//	merchantsim capture <id> [сумма]
+//	merchantsim reverse <id>
+//	merchantsim refund <id> <сумма>
+//	merchantsim get <id>
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"bank-app/internal/merchantsim"
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+)
+
+func main() {
+	var (
+		baseURL   = flag.String("url", getEnv("MERCHANTSIM_URL", "http://localhost:8080"), "адрес API")
+		apiKey    = flag.String("key", os.Getenv("MERCHANTSIM_API_KEY"), "ключ API эквайрера")
+		card      = flag.String("card", "", "номер карты")
+		cvv       = flag.String("cvv", "", "CVV карты")
+		amount    = flag.String("amount", "", "сумма авторизации")
+		capture   = flag.String("capture", "", "сумма подтверждения для purchase (по умолчанию вся сумма)")
+		channel   = flag.String("channel", model.ChannelECommerce, "канал: pos, ecommerce или atm")
+		mcc       = flag.String("mcc", "5411", "код категории торговца")
+		merchant  = flag.String("merchant", "Merchant Simulator", "название торговца")
+		reference = flag.String("ref", "", "номер операции (по умолчанию генерируется)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] purchase|authorize|capture|reverse|refund|get [id] [amount]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client := merchantsim.New(*baseURL, *apiKey)
+	ctx := context.Background()
+
+	request := func() *model.AuthorizationRequest {
+		ref := *reference
+		if ref == "" {
+			ref = fmt.Sprintf("sim-%d", time.Now().UnixNano())
+		}
+		return &model.AuthorizationRequest{
+			Reference:    ref,
+			CardNumber:   *card,
+			CVV:          *cvv,
+			Amount:       parseAmount(*amount),
+			Channel:      *channel,
+			MCC:          *mcc,
+			MerchantName: *merchant,
+		}
+	}
+
+	var (
+		auth *model.CardAuthorization
+		err  error
+	)
+	switch command := flag.Arg(0); command {
+	case "purchase":
+		auth, err = client.Purchase(ctx, request(), parseAmount(*capture))
+	case "authorize":
+		auth, err = client.Authorize(ctx, request())
+	case "capture":
+		auth, err = client.Capture(ctx, argID(), parseAmount(flag.Arg(2)))
+	case "reverse":
+		auth, err = client.Reverse(ctx, argID())
+	case "refund":
+		auth, err = client.Refund(ctx, argID(), parseAmount(flag.Arg(2)))
+	case "get":
+		auth, err = client.Get(ctx, argID())
+	default:
+		fail("unknown command %q", command)
+	}
+
+	if err != nil {
+		fail("%v", err)
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	out.Encode(auth)
+}
+
+func argID() int64 {
+	id, err := strconv.ParseInt(flag.Arg(1), 10, 64)
+	if err != nil {
+		fail("invalid authorization id %q", flag.Arg(1))
+	}
+	return id
+}
+
+// parseAmount разбирает сумму, пустая строка означает ноль
+func parseAmount(value string) money.Amount {
+	if value == "" {
+		return 0
+	}
+
+	amount, err := money.Parse(value)
+	if err != nil {
+		fail("invalid amount %q: %v", value, err)
+	}
+	return amount
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "merchantsim: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return defaultValue
+}
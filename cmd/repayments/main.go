@@ -0,0 +1,50 @@
+// Команда repayments однократно проводит списания по графикам кредитов и
+// начисляет неустойку, как фоновая задача API. Используется для догоняющей
+// обработки после простоя, когда API не запущен:
+//
+//	repayments
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"bank-app/internal/cbr"
+	"bank-app/internal/config"
+	"bank-app/internal/repository"
+	"bank-app/internal/service"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := repository.NewPostgresDB(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize db: %v", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	repos := repository.NewRepositories(db)
+	ledger := service.NewLedgerService(repos.Ledger, repos.Accounts, repos.Transactor)
+	keyRates := service.NewKeyRateService(repos.KeyRates, cbr.New(cfg.CBRConfig), logrus.New(), cfg)
+	credits := service.NewCreditService(repos.Credits, repos.Accounts, repos.Transfers, ledger, keyRates, repos.Transactor, cfg)
+
+	if err := credits.ProcessPayments(ctx); err != nil {
+		log.Printf("Credit repayments finished with errors: %v", err)
+		db.Close()
+		os.Exit(1)
+	}
+
+	log.Println("Credit repayments processed")
+}
@@ -0,0 +1,237 @@
+// Пакет amortization строит графики погашения кредитов: аннуитетный и
+// дифференцированный. Проценты за каждый период начисляются на остаток долга
+// по выбранному соглашению о расчете дней.
+package amortization
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"bank-app/internal/money"
+)
+
+// Loan - условия кредита, по которым строится график
+type Loan struct {
+	Amount money.Amount
+	// Rate - годовая ставка в долях единицы: 0.12 - 12% годовых
+	Rate *big.Rat
+	// Term - число платежей в графике, по одному в месяц
+	Term     int
+	IssuedAt time.Time
+	DayCount DayCount
+	// Paid - число уже прошедших платежей: график продолжается с платежа
+	// Paid+1, даты платежей отсчитываются от IssuedAt
+	Paid int
+	// From - дата, с которой начисляются проценты по первому платежу
+	// графика, по умолчанию IssuedAt
+	From time.Time
+	// Installment - фиксированный платеж аннуитета или доля основного долга
+	// дифференцированного графика. Если не задан, рассчитывается по сроку;
+	// заданный может погасить долг раньше срока.
+	Installment money.Amount
+	// PaymentRounding округляет платеж и основной долг, InterestRounding - проценты
+	PaymentRounding  money.RoundingMode
+	InterestRounding money.RoundingMode
+}
+
+// Payment - строка графика
+type Payment struct {
+	Date      time.Time
+	Amount    money.Amount
+	Principal money.Amount
+	Interest  money.Amount
+}
+
+// Strategy определяет, какая часть долга гасится в каждом платеже
+type Strategy interface {
+	Name() string
+	Schedule(loan Loan) []Payment
+}
+
+// Стратегии погашения
+var (
+	// Annuity - равные платежи, доля процентов в которых убывает
+	Annuity Strategy = annuity{}
+	// Differentiated - равные доли основного долга и убывающие проценты
+	Differentiated Strategy = differentiated{}
+)
+
+var strategies = map[string]Strategy{
+	Annuity.Name():        Annuity,
+	Differentiated.Name(): Differentiated,
+}
+
+// ParseStrategy возвращает стратегию по названию, пустое название - аннуитет
+func ParseStrategy(name string) (Strategy, error) {
+	if name == "" {
+		return Annuity, nil
+	}
+
+	strategy, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown schedule type %q", name)
+	}
+	return strategy, nil
+}
+
+// minPrincipal - наименьший основной долг в платеже аннуитета
+const minPrincipal money.Amount = 1
+
+type annuity struct{}
+
+func (annuity) Name() string { return "annuity" }
+
+// Schedule гасит долг равными платежами. Платеж рассчитывается по долям
+// года периодов графика в соглашении о днях, последний платеж выравнивает
+// остаток долга после округлений.
+func (annuity) Schedule(loan Loan) []Payment {
+	if loan.Installment != 0 {
+		return annuitySchedule(loan, loan.Installment)
+	}
+
+	payment := installment(loan)
+	schedule := annuitySchedule(loan, payment)
+	if len(schedule) == loan.Term {
+		return schedule
+	}
+
+	// Перенесенные проценты не капитализируются, и с расчетным платежом долг
+	// гасится раньше срока. Подбирается наименьший платеж, который последний
+	// платеж графика не превышает.
+	low, high := minPrincipal, payment
+	for low < high {
+		mid := (low + high) / 2
+		if schedule := annuitySchedule(loan, mid); schedule[len(schedule)-1].Amount <= mid {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+
+	return annuitySchedule(loan, high)
+}
+
+// annuitySchedule строит график с платежом payment
+func annuitySchedule(loan Loan, payment money.Amount) []Payment {
+	return build(loan, func(balance, interest money.Amount) (money.Amount, money.Amount) {
+		// В длинных месяцах при высокой ставке проценты по фактическим дням
+		// могут превысить платеж. Их часть переносится на следующие платежи,
+		// чтобы каждый платеж гасил основной долг.
+		if interest >= payment {
+			return minPrincipal, payment - minPrincipal
+		}
+		return payment - interest, interest
+	})
+}
+
+type differentiated struct{}
+
+func (differentiated) Name() string { return "differentiated" }
+
+// Schedule гасит основной долг равными долями, проценты начисляются на
+// убывающий остаток
+func (differentiated) Schedule(loan Loan) []Payment {
+	share := loan.Installment
+	if share == 0 {
+		share = loan.Amount.Mul(big.NewRat(1, int64(loan.Term)), loan.PaymentRounding)
+	}
+
+	return build(loan, func(balance, interest money.Amount) (money.Amount, money.Amount) {
+		return share, interest
+	})
+}
+
+// installment рассчитывает аннуитетный платеж, при котором проценты по
+// соглашению о днях за регулярные периоды графика гасят долг ровно за срок:
+// amount * П(1 + f_i) / Σ_k П_{j>k}(1 + f_j), где f_i - ставка за i-й период.
+// По 30/360 все f_i равны rate/12, и платеж совпадает с AnnuityPayment.
+func installment(loan Loan) money.Amount {
+	growth := big.NewRat(1, 1)
+	sum := new(big.Rat)
+	from := PaymentDate(loan.IssuedAt, loan.Paid)
+	for month := 1; month <= loan.Term; month++ {
+		to := PaymentDate(loan.IssuedAt, loan.Paid+month)
+		factor := new(big.Rat).Mul(loan.Rate, loan.DayCount.YearFraction(from, to))
+		factor.Add(factor, big.NewRat(1, 1))
+
+		growth.Mul(growth, factor)
+		sum.Mul(sum, factor)
+		sum.Add(sum, big.NewRat(1, 1))
+		from = to
+	}
+
+	return loan.Amount.Mul(new(big.Rat).Quo(growth, sum), loan.PaymentRounding)
+}
+
+// AnnuityPayment рассчитывает аннуитетный платеж по месячной ставке rate/12
+// в точной арифметике и округляет его с политикой mode
+func AnnuityPayment(amount money.Amount, rate *big.Rat, term int, mode money.RoundingMode) money.Amount {
+	monthlyRate := new(big.Rat).Quo(rate, big.NewRat(12, 1))
+	if monthlyRate.Sign() == 0 {
+		return amount.Mul(big.NewRat(1, int64(term)), mode)
+	}
+
+	// (1 + r)^n
+	base := new(big.Rat).Add(big.NewRat(1, 1), monthlyRate)
+	growth := big.NewRat(1, 1)
+	for i := 0; i < term; i++ {
+		growth.Mul(growth, base)
+	}
+
+	// r * (1 + r)^n / ((1 + r)^n - 1)
+	factor := new(big.Rat).Mul(monthlyRate, growth)
+	factor.Quo(factor, new(big.Rat).Sub(growth, big.NewRat(1, 1)))
+
+	return amount.Mul(factor, mode)
+}
+
+// PaymentDate возвращает дату month-го платежа: тот же день месяца, что и
+// дата выдачи, или последний день месяца, если он короче
+func PaymentDate(issuedAt time.Time, month int) time.Time {
+	year, mon, day := issuedAt.UTC().Date()
+
+	// Нулевой день следующего месяца - последний день нужного
+	lastDay := time.Date(year, mon+time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return time.Date(year, mon+time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// build строит график: split делит платеж на основной долг и проценты по
+// остатку и процентам к уплате. Неуплаченные проценты переносятся на
+// следующий платеж. Последний платеж гасит остаток и проценты целиком,
+// график заканчивается, как только долг погашен.
+func build(loan Loan, split func(balance, interest money.Amount) (money.Amount, money.Amount)) []Payment {
+	payments := make([]Payment, 0, loan.Term)
+	balance := loan.Amount
+	from := loan.From
+	if from.IsZero() {
+		from = loan.IssuedAt
+	}
+	var deferred money.Amount
+	for month := 1; month <= loan.Term && balance > 0; month++ {
+		to := PaymentDate(loan.IssuedAt, loan.Paid+month)
+		factor := new(big.Rat).Mul(loan.Rate, loan.DayCount.YearFraction(from, to))
+		due := balance.Mul(factor, loan.InterestRounding) + deferred
+
+		part, interest := split(balance, due)
+		if month == loan.Term || part >= balance {
+			part, interest = balance, due
+		}
+		deferred = due - interest
+		balance -= part
+
+		payments = append(payments, Payment{
+			Date:      to,
+			Amount:    part + interest,
+			Principal: part,
+			Interest:  interest,
+		})
+		from = to
+	}
+
+	return payments
+}
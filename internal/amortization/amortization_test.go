@@ -0,0 +1,271 @@
+package amortization
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bank-app/internal/money"
+)
+
+func testLoan(amount money.Amount, term int) Loan {
+	return Loan{
+		Amount:           amount,
+		Rate:             big.NewRat(12, 100),
+		Term:             term,
+		IssuedAt:         time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC),
+		DayCount:         Thirty360,
+		PaymentRounding:  money.HalfUp,
+		InterestRounding: money.HalfEven,
+	}
+}
+
+func TestAnnuityPayment(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   money.Amount
+		term     int
+		expected money.Amount
+	}{
+		{
+			name:     "кредит 100000 на 12 месяцев под 12%",
+			amount:   money.Units(100000),
+			term:     12,
+			expected: money.MustParse("8884.88"),
+		},
+		{
+			name:     "кредит 500000 на 24 месяца под 12%",
+			amount:   money.Units(500000),
+			term:     24,
+			expected: money.MustParse("23536.74"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := AnnuityPayment(tt.amount, big.NewRat(12, 100), tt.term, money.HalfUp)
+			assert.Equal(t, tt.expected, payment)
+		})
+	}
+}
+
+func TestAnnuity(t *testing.T) {
+	amount := money.Units(100000)
+	payment := AnnuityPayment(amount, big.NewRat(12, 100), 12, money.HalfUp)
+
+	// Действие
+	schedule := Annuity.Schedule(testLoan(amount, 12))
+
+	// Проверка
+	require.Len(t, schedule, 12)
+
+	// Первый месяц: 1% от 100000 - проценты, остальное - основной долг
+	assert.Equal(t, time.Date(2026, 4, 15, 0, 0, 0, 0, time.UTC), schedule[0].Date)
+	assert.Equal(t, money.MustParse("1000.00"), schedule[0].Interest)
+	assert.Equal(t, money.MustParse("7884.88"), schedule[0].Principal)
+	assert.Equal(t, payment, schedule[0].Amount)
+
+	var principal money.Amount
+	for i, row := range schedule {
+		principal += row.Principal
+		assert.Equal(t, row.Principal+row.Interest, row.Amount)
+		if i > 0 && i < 11 {
+			assert.Equal(t, payment, row.Amount)
+			assert.Less(t, row.Interest, schedule[i-1].Interest)
+		}
+	}
+	assert.Equal(t, amount, principal)
+
+	// Последний платеж выравнивает остаток и отличается от аннуитета на копейки
+	last := schedule[11].Amount - payment
+	assert.LessOrEqual(t, last.Abs(), money.MustParse("0.10"))
+}
+
+func TestAnnuity_ActualDayCount(t *testing.T) {
+	for _, dayCount := range []DayCount{Actual365, ActualActual} {
+		for _, rate := range []int64{12, 20, 30} {
+			t.Run(fmt.Sprintf("%s под %d%% на 30 лет", dayCount, rate), func(t *testing.T) {
+				// Подготовка
+				amount := money.Units(1000000)
+				loan := testLoan(amount, 360)
+				loan.Rate = big.NewRat(rate, 100)
+				loan.DayCount = dayCount
+
+				// Действие
+				schedule := Annuity.Schedule(loan)
+
+				// Проверка: график на весь срок, каждый платеж гасит основной долг
+				require.Len(t, schedule, 360)
+				payment := schedule[0].Amount
+				var principal money.Amount
+				for i, row := range schedule {
+					principal += row.Principal
+					assert.Positive(t, int64(row.Principal), "платеж %d", i+1)
+					assert.Equal(t, row.Principal+row.Interest, row.Amount)
+					if i < 359 {
+						assert.Equal(t, payment, row.Amount, "платеж %d", i+1)
+					}
+				}
+				assert.Equal(t, amount, principal)
+
+				// Последний платеж выравнивает остаток и не становится крупным
+				last := schedule[359].Amount - payment
+				assert.LessOrEqual(t, last.Abs(), payment/10)
+			})
+		}
+	}
+}
+
+func TestDifferentiated(t *testing.T) {
+	amount := money.Units(120000)
+
+	// Действие
+	schedule := Differentiated.Schedule(testLoan(amount, 12))
+
+	// Проверка
+	require.Len(t, schedule, 12)
+
+	var principal, interest money.Amount
+	for i, row := range schedule {
+		principal += row.Principal
+		interest += row.Interest
+		assert.Equal(t, money.Units(10000), row.Principal)
+		if i > 0 {
+			assert.Less(t, row.Amount, schedule[i-1].Amount)
+		}
+	}
+	assert.Equal(t, amount, principal)
+
+	// Проценты: 1% от остатка 120000, 110000, ..., 10000
+	assert.Equal(t, money.Units(1200), schedule[0].Interest)
+	assert.Equal(t, money.Units(100), schedule[11].Interest)
+	assert.Equal(t, money.Units(7800), interest)
+
+	// Переплата меньше, чем у аннуитета на тех же условиях
+	var annuityInterest money.Amount
+	for _, row := range Annuity.Schedule(testLoan(amount, 12)) {
+		annuityInterest += row.Interest
+	}
+	assert.Less(t, interest, annuityInterest)
+}
+
+func TestDifferentiated_Remainder(t *testing.T) {
+	// 1000 на 3 месяца не делится поровну: остаток гасится последним платежом
+	schedule := Differentiated.Schedule(testLoan(money.Units(1000), 3))
+
+	require.Len(t, schedule, 3)
+	assert.Equal(t, money.MustParse("333.33"), schedule[0].Principal)
+	assert.Equal(t, money.MustParse("333.33"), schedule[1].Principal)
+	assert.Equal(t, money.MustParse("333.34"), schedule[2].Principal)
+}
+
+func TestSchedule_Continuation(t *testing.T) {
+	// Остаток 60000 после трех платежей и досрочного погашения 20 июня
+	loan := testLoan(money.Units(60000), 9)
+	loan.Paid = 3
+	loan.From = time.Date(2026, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	t.Run("пересчет платежа на оставшийся срок", func(t *testing.T) {
+		// Действие
+		schedule := Annuity.Schedule(loan)
+
+		// Проверка
+		require.Len(t, schedule, 9)
+		assert.Equal(t, time.Date(2026, 7, 15, 0, 0, 0, 0, time.UTC), schedule[0].Date)
+		// Проценты по первому платежу - с даты погашения: 25 дней по 30/360
+		assert.Equal(t, money.Units(500), schedule[0].Interest)
+		assert.Equal(t, AnnuityPayment(loan.Amount, loan.Rate, 9, money.HalfUp), schedule[1].Amount)
+
+		var principal money.Amount
+		for _, payment := range schedule {
+			principal += payment.Principal
+		}
+		assert.Equal(t, loan.Amount, principal)
+	})
+
+	t.Run("прежний платеж сокращает срок", func(t *testing.T) {
+		// Подготовка
+		loan := loan
+		loan.Installment = money.MustParse("8884.88")
+
+		// Действие
+		schedule := Annuity.Schedule(loan)
+
+		// Проверка
+		require.Len(t, schedule, 8)
+		var principal money.Amount
+		for _, payment := range schedule[:7] {
+			assert.Equal(t, loan.Installment, payment.Amount)
+			principal += payment.Principal
+		}
+		last := schedule[7]
+		assert.Equal(t, loan.Amount, principal+last.Principal)
+		assert.Less(t, last.Amount, loan.Installment)
+	})
+}
+
+func TestDayCount_YearFraction(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name     string
+		dayCount DayCount
+		from, to time.Time
+		expected *big.Rat
+	}{
+		{"30/360 за февраль", Thirty360, day(2026, 1, 31), day(2026, 2, 28), big.NewRat(1, 12)},
+		{"30/360 за 31-дневный месяц", Thirty360, day(2026, 3, 15), day(2026, 4, 15), big.NewRat(1, 12)},
+		{"30/360 за неполный месяц", Thirty360, day(2026, 3, 15), day(2026, 3, 25), big.NewRat(10, 360)},
+		{"ACT/365 за февраль", Actual365, day(2026, 1, 31), day(2026, 2, 28), big.NewRat(28, 365)},
+		{"ACT/365 в високосном году", Actual365, day(2028, 2, 1), day(2028, 3, 1), big.NewRat(29, 365)},
+		{"ACT/ACT в високосном году", ActualActual, day(2028, 2, 1), day(2028, 3, 1), big.NewRat(29, 366)},
+		{
+			name:     "ACT/ACT через границу года",
+			dayCount: ActualActual,
+			from:     day(2027, 12, 15),
+			to:       day(2028, 1, 15),
+			// 17 дней 2027 года и 14 дней високосного 2028
+			expected: new(big.Rat).Add(big.NewRat(17, 365), big.NewRat(14, 366)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected.String(), tt.dayCount.YearFraction(tt.from, tt.to).String())
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, name := range []string{"30/360", "ACT/365", "ACT/ACT"} {
+		dayCount, err := ParseDayCount(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, dayCount.String())
+	}
+
+	_, err := ParseDayCount("ACT/360")
+	assert.Error(t, err)
+
+	strategy, err := ParseStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, Annuity, strategy)
+
+	strategy, err = ParseStrategy("differentiated")
+	require.NoError(t, err)
+	assert.Equal(t, Differentiated, strategy)
+
+	_, err = ParseStrategy("balloon")
+	assert.Error(t, err)
+}
+
+func TestPaymentDate(t *testing.T) {
+	issuedAt := time.Date(2026, 1, 31, 18, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), PaymentDate(issuedAt, 1))
+	assert.Equal(t, time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC), PaymentDate(issuedAt, 2))
+	assert.Equal(t, time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC), PaymentDate(issuedAt, 12))
+}
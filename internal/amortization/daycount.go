@@ -0,0 +1,100 @@
+package amortization
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// DayCount - соглашение о расчете дней, по которому проценты за период
+// пересчитываются из годовой ставки
+type DayCount int
+
+const (
+	// Thirty360 - 30E/360 ISDA: в месяце 30 дней, последний день месяца
+	// считается 30-м, в году 360 дней. Проценты за полный месяц равны 1/12
+	// годовых независимо от длины месяца.
+	Thirty360 DayCount = iota
+	// Actual365 - фактическое число дней, в году 365 дней
+	Actual365
+	// ActualActual - ACT/ACT ISDA: дни високосного года делятся на 366,
+	// остальные на 365
+	ActualActual
+)
+
+// ParseDayCount разбирает название соглашения
+func ParseDayCount(s string) (DayCount, error) {
+	switch s {
+	case "30/360", "":
+		return Thirty360, nil
+	case "ACT/365":
+		return Actual365, nil
+	case "ACT/ACT":
+		return ActualActual, nil
+	default:
+		return 0, fmt.Errorf("unknown day count convention %q", s)
+	}
+}
+
+func (d DayCount) String() string {
+	switch d {
+	case Actual365:
+		return "ACT/365"
+	case ActualActual:
+		return "ACT/ACT"
+	default:
+		return "30/360"
+	}
+}
+
+// YearFraction возвращает долю года между датами from и to
+func (d DayCount) YearFraction(from, to time.Time) *big.Rat {
+	from, to = date(from), date(to)
+
+	switch d {
+	case Actual365:
+		return big.NewRat(days(from, to), 365)
+
+	case ActualActual:
+		fraction := new(big.Rat)
+		for from.Before(to) {
+			yearEnd := time.Date(from.Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+			if yearEnd.After(to) {
+				yearEnd = to
+			}
+			fraction.Add(fraction, big.NewRat(days(from, yearEnd), int64(daysInYear(from.Year()))))
+			from = yearEnd
+		}
+		return fraction
+
+	default:
+		y1, m1, d1 := from.Date()
+		y2, m2, d2 := to.Date()
+		if isMonthEnd(from) {
+			d1 = 30
+		}
+		if isMonthEnd(to) {
+			d2 = 30
+		}
+		n := 360*(y2-y1) + 30*(int(m2)-int(m1)) + (d2 - d1)
+		return big.NewRat(int64(n), 360)
+	}
+}
+
+// date отбрасывает время суток: проценты начисляются за календарные дни
+func date(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func days(from, to time.Time) int64 {
+	return int64(to.Sub(from).Hours() / 24)
+}
+
+func daysInYear(year int) int {
+	return time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
+
+func isMonthEnd(t time.Time) bool {
+	return t.AddDate(0, 0, 1).Day() == 1
+}
@@ -0,0 +1,212 @@
+// Package cardnumber генерирует и проверяет номера карт: префикс из диапазона
+// BIN (IIN) продукта, случайные цифры и контрольная цифра по алгоритму Луна.
+package cardnumber
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Допустимая длина номера карты (ISO/IEC 7812)
+const (
+	MinLength     = 12
+	MaxLength     = 19
+	DefaultLength = 16
+)
+
+var (
+	ErrInvalidRange  = errors.New("invalid BIN range")
+	ErrInvalidNumber = errors.New("invalid card number")
+)
+
+// Range - диапазон префиксов BIN одинаковой длины, например 427600-427699
+type Range struct {
+	Low  uint64
+	High uint64
+	// Digits - число цифр в префиксе
+	Digits int
+}
+
+func (r Range) String() string {
+	if r.Low == r.High {
+		return r.format(r.Low)
+	}
+	return r.format(r.Low) + "-" + r.format(r.High)
+}
+
+func (r Range) format(v uint64) string {
+	return fmt.Sprintf("%0*d", r.Digits, v)
+}
+
+// ParseRanges разбирает список диапазонов вида "427600-427699,220070"
+func ParseRanges(value string) ([]Range, error) {
+	var ranges []Range
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		low, high, found := strings.Cut(item, "-")
+		if !found {
+			high = low
+		}
+		low, high = strings.TrimSpace(low), strings.TrimSpace(high)
+
+		if len(low) != len(high) || len(low) < 4 || len(low) > 11 || !isDigits(low) || !isDigits(high) {
+			return nil, fmt.Errorf("%w %q", ErrInvalidRange, item)
+		}
+
+		r := Range{Digits: len(low)}
+		r.Low, _ = strconv.ParseUint(low, 10, 64)
+		r.High, _ = strconv.ParseUint(high, 10, 64)
+		if r.Low > r.High {
+			return nil, fmt.Errorf("%w %q", ErrInvalidRange, item)
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	if len(ranges) == 0 {
+		return nil, ErrInvalidRange
+	}
+
+	return ranges, nil
+}
+
+// Generate выбирает случайный префикс из диапазонов, дополняет его случайными
+// цифрами до length-1 знаков и добавляет контрольную цифру
+func Generate(ranges []Range, length int) (string, error) {
+	if len(ranges) == 0 {
+		return "", ErrInvalidRange
+	}
+	if length < MinLength || length > MaxLength {
+		return "", fmt.Errorf("card number length must be between %d and %d", MinLength, MaxLength)
+	}
+
+	// Вероятность выбора диапазона пропорциональна числу префиксов в нем
+	var total uint64
+	for _, r := range ranges {
+		if r.Digits >= length {
+			return "", fmt.Errorf("%w %s: prefix is too long", ErrInvalidRange, r)
+		}
+		total += r.High - r.Low + 1
+	}
+
+	n, err := randUint64(total)
+	if err != nil {
+		return "", err
+	}
+
+	var prefix string
+	for _, r := range ranges {
+		size := r.High - r.Low + 1
+		if n < size {
+			prefix = r.format(r.Low + n)
+			break
+		}
+		n -= size
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	for b.Len() < length-1 {
+		d, err := randUint64(10)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(byte('0' + d))
+	}
+
+	partial := b.String()
+	return partial + strconv.Itoa(CheckDigit(partial)), nil
+}
+
+// RandomDigits возвращает строку из n случайных цифр, например CVV
+func RandomDigits(n int) (string, error) {
+	b := make([]byte, n)
+	for i := range b {
+		d, err := randUint64(10)
+		if err != nil {
+			return "", err
+		}
+		b[i] = byte('0' + d)
+	}
+	return string(b), nil
+}
+
+// CheckDigit вычисляет контрольную цифру Луна для номера без нее
+func CheckDigit(partial string) int {
+	sum := luhnSum(partial, true)
+	return (10 - sum%10) % 10
+}
+
+// Valid проверяет формат номера и контрольную цифру
+func Valid(number string) bool {
+	if len(number) < MinLength || len(number) > MaxLength || !isDigits(number) {
+		return false
+	}
+	return luhnSum(number, false)%10 == 0
+}
+
+// Normalize убирает пробелы и дефисы, которыми клиенты разделяют группы цифр
+func Normalize(number string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(number)
+}
+
+// InRanges сообщает, начинается ли номер с префикса из диапазонов
+func InRanges(number string, ranges []Range) bool {
+	for _, r := range ranges {
+		if len(number) <= r.Digits {
+			continue
+		}
+		prefix, err := strconv.ParseUint(number[:r.Digits], 10, 64)
+		if err == nil && prefix >= r.Low && prefix <= r.High {
+			return true
+		}
+	}
+	return false
+}
+
+// luhnSum суммирует цифры по алгоритму Луна. Если doubleLast, удваивается
+// последняя цифра - так считается сумма номера без контрольной цифры.
+func luhnSum(number string, doubleLast bool) int {
+	sum := 0
+	double := doubleLast
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum
+}
+
+func randUint64(max uint64) (uint64, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).SetUint64(max))
+	if err != nil {
+		return 0, err
+	}
+	return n.Uint64(), nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
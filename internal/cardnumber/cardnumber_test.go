@@ -0,0 +1,89 @@
+package cardnumber
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"4111111111111111", true},
+		{"5500005555555559", true},
+		{"2200000000000053", true},
+		{"4111111111111112", false},
+		{"4111 1111 1111 1111", false},
+		{"41111111111", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Valid(tt.number), tt.number)
+	}
+}
+
+func TestCheckDigit(t *testing.T) {
+	assert.Equal(t, 1, CheckDigit("411111111111111"))
+	assert.Equal(t, 9, CheckDigit("550000555555555"))
+	assert.Equal(t, 3, CheckDigit("7992739871"))
+}
+
+func TestParseRanges(t *testing.T) {
+	t.Run("диапазоны и одиночные префиксы", func(t *testing.T) {
+		ranges, err := ParseRanges("427600-427699, 22007012")
+		require.NoError(t, err)
+		require.Len(t, ranges, 2)
+		assert.Equal(t, Range{Low: 427600, High: 427699, Digits: 6}, ranges[0])
+		assert.Equal(t, Range{Low: 22007012, High: 22007012, Digits: 8}, ranges[1])
+		assert.Equal(t, "427600-427699", ranges[0].String())
+	})
+
+	t.Run("ошибки формата", func(t *testing.T) {
+		for _, value := range []string{"", "4276-427699", "427699-427600", "42x600", "12"} {
+			_, err := ParseRanges(value)
+			assert.ErrorIs(t, err, ErrInvalidRange, value)
+		}
+	})
+}
+
+func TestGenerate(t *testing.T) {
+	ranges, err := ParseRanges("427600-427601,002200")
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		number, err := Generate(ranges, DefaultLength)
+		require.NoError(t, err)
+
+		assert.Len(t, number, DefaultLength)
+		assert.True(t, Valid(number), number)
+		assert.True(t, InRanges(number, ranges), number)
+		seen[number] = true
+	}
+
+	// Ведущие нули префикса сохраняются
+	assert.True(t, InRanges("0022000000000000", ranges))
+	assert.False(t, InRanges("4276020000000000", ranges))
+
+	// Номера не повторяются
+	assert.Greater(t, len(seen), 195)
+
+	_, err = Generate(ranges, 40)
+	assert.Error(t, err)
+}
+
+func TestRandomDigits(t *testing.T) {
+	cvv, err := RandomDigits(3)
+	require.NoError(t, err)
+	assert.Len(t, cvv, 3)
+	assert.Empty(t, strings.Trim(cvv, "0123456789"))
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "4111111111111111", Normalize("4111 1111-1111 1111"))
+}
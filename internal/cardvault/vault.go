@@ -0,0 +1,202 @@
+// Package cardvault защищает реквизиты карт конвертным шифрованием: каждая
+// запись шифруется собственным ключом данных (AES-256-GCM), а ключ данных -
+// ключом шифрования ключей (KEK) из конфигурации. Для поиска по номеру карты
+// используется HMAC, так что номер не хранится в открытом виде.
+package cardvault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize - длина ключей AES-256 и ключа HMAC в байтах
+const KeySize = 32
+
+// envelopeVersion - префикс формата "v1.<kek id>.<ключ данных>.<данные>"
+const envelopeVersion = "v1"
+
+var (
+	ErrKeysNotConfigured = errors.New("card encryption keys and hmac key must be configured")
+	ErrUnknownKey        = errors.New("unknown key encryption key")
+	ErrMalformedEnvelope = errors.New("malformed encrypted card data")
+	ErrInvalidKey        = errors.New("card encryption key must be 32 bytes")
+)
+
+// Vault шифрует и расшифровывает реквизиты карт. Новые записи шифруются
+// активным KEK, прежние ключи нужны только для расшифровки старых записей.
+type Vault struct {
+	activeID string
+	keys     map[string]cipher.AEAD
+	macKey   []byte
+}
+
+// New создает хранилище из ключей по идентификаторам и ключа HMAC
+func New(activeID string, keys map[string][]byte, macKey []byte) (*Vault, error) {
+	if len(macKey) != KeySize {
+		return nil, fmt.Errorf("hmac key: %w", ErrInvalidKey)
+	}
+
+	v := &Vault{activeID: activeID, keys: make(map[string]cipher.AEAD, len(keys)), macKey: macKey}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ".") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		v.keys[id] = aead
+	}
+
+	if _, ok := v.keys[activeID]; !ok {
+		return nil, fmt.Errorf("active key %q: %w", activeID, ErrUnknownKey)
+	}
+
+	return v, nil
+}
+
+// Load создает хранилище из ключей в base64. KEK и ключ HMAC обязательны и
+// не выводятся из других секретов приложения.
+func Load(activeID string, keys map[string]string, macKey string) (*Vault, error) {
+	if len(keys) == 0 || macKey == "" {
+		return nil, ErrKeysNotConfigured
+	}
+
+	decoded := make(map[string][]byte, len(keys))
+	for id, value := range keys {
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		decoded[id] = key
+	}
+
+	mac, err := base64.StdEncoding.DecodeString(macKey)
+	if err != nil {
+		return nil, fmt.Errorf("hmac key: %w", err)
+	}
+
+	return New(activeID, decoded, mac)
+}
+
+// Seal шифрует данные новым ключом данных и возвращает конверт в текстовом виде
+func (v *Vault) Seal(plaintext []byte) (string, error) {
+	dek := make([]byte, KeySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+
+	data, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+
+	header := envelopeVersion + "." + v.activeID
+	wrappedKey, err := seal(v.keys[v.activeID], dek, []byte(header))
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(data, plaintext, []byte(header))
+	if err != nil {
+		return "", err
+	}
+
+	return header + "." + encode(wrappedKey) + "." + encode(ciphertext), nil
+}
+
+// Open расшифровывает конверт ключом, которым он был создан
+func (v *Vault) Open(envelope string) ([]byte, error) {
+	parts := strings.Split(envelope, ".")
+	if len(parts) != 4 || parts[0] != envelopeVersion {
+		return nil, ErrMalformedEnvelope
+	}
+
+	kek, ok := v.keys[parts[1]]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	wrappedKey, err := decode(parts[2])
+	if err != nil {
+		return nil, ErrMalformedEnvelope
+	}
+	ciphertext, err := decode(parts[3])
+	if err != nil {
+		return nil, ErrMalformedEnvelope
+	}
+
+	header := []byte(parts[0] + "." + parts[1])
+	dek, err := open(kek, wrappedKey, header)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(data, ciphertext, header)
+}
+
+// MAC возвращает HMAC-SHA256 значения для поиска по равенству
+func (v *Vault) MAC(value string) string {
+	mac := hmac.New(sha256.New, v.macKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal шифрует данные, добавляя случайный nonce в начало результата
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrMalformedEnvelope
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrMalformedEnvelope
+	}
+
+	return plaintext, nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,139 @@
+package cardvault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func TestVault_SealOpen(t *testing.T) {
+	vault, err := New("k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+	require.NoError(t, err)
+
+	// Действие
+	envelope, err := vault.Seal([]byte("4276000000000000"))
+	require.NoError(t, err)
+
+	// Проверка
+	assert.True(t, strings.HasPrefix(envelope, "v1.k1."))
+	assert.NotContains(t, envelope, "4276000000000000")
+
+	plaintext, err := vault.Open(envelope)
+	require.NoError(t, err)
+	assert.Equal(t, "4276000000000000", string(plaintext))
+
+	// Каждая запись шифруется своим ключом данных
+	other, err := vault.Seal([]byte("4276000000000000"))
+	require.NoError(t, err)
+	assert.NotEqual(t, envelope, other)
+}
+
+func TestVault_Rotation(t *testing.T) {
+	// Подготовка: запись зашифрована прежним ключом
+	before, err := New("k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+	require.NoError(t, err)
+	envelope, err := before.Seal([]byte("secret"))
+	require.NoError(t, err)
+
+	// Действие: активен новый ключ, прежний оставлен для расшифровки
+	after, err := New("k2", map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, testKey(9))
+	require.NoError(t, err)
+
+	// Проверка
+	plaintext, err := after.Open(envelope)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+
+	fresh, err := after.Seal([]byte("secret"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(fresh, "v1.k2."))
+
+	// Без прежнего ключа старые записи не расшифровываются
+	retired, err := New("k2", map[string][]byte{"k2": testKey(2)}, testKey(9))
+	require.NoError(t, err)
+	_, err = retired.Open(envelope)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestVault_Tampering(t *testing.T) {
+	vault, err := New("k1", map[string][]byte{"k1": testKey(1), "k2": testKey(1)}, testKey(9))
+	require.NoError(t, err)
+	envelope, err := vault.Seal([]byte("secret"))
+	require.NoError(t, err)
+
+	t.Run("подмена идентификатора ключа", func(t *testing.T) {
+		// Ключ k2 совпадает с k1, но идентификатор входит в проверяемые данные
+		_, err := vault.Open(strings.Replace(envelope, "v1.k1.", "v1.k2.", 1))
+		assert.ErrorIs(t, err, ErrMalformedEnvelope)
+	})
+
+	t.Run("изменение шифротекста", func(t *testing.T) {
+		parts := strings.Split(envelope, ".")
+		data := []byte(parts[3])
+		if data[0] == 'A' {
+			data[0] = 'B'
+		} else {
+			data[0] = 'A'
+		}
+		parts[3] = string(data)
+
+		_, err := vault.Open(strings.Join(parts, "."))
+		assert.ErrorIs(t, err, ErrMalformedEnvelope)
+	})
+
+	t.Run("неверный формат", func(t *testing.T) {
+		_, err := vault.Open("not-an-envelope")
+		assert.ErrorIs(t, err, ErrMalformedEnvelope)
+	})
+}
+
+func TestVault_MAC(t *testing.T) {
+	vault, err := New("k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+	require.NoError(t, err)
+	other, err := New("k1", map[string][]byte{"k1": testKey(1)}, testKey(8))
+	require.NoError(t, err)
+
+	assert.Equal(t, vault.MAC("4276000000000000"), vault.MAC("4276000000000000"))
+	assert.NotEqual(t, vault.MAC("4276000000000000"), vault.MAC("4276000000000001"))
+	assert.NotEqual(t, vault.MAC("4276000000000000"), other.MAC("4276000000000000"))
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("ключи из конфигурации", func(t *testing.T) {
+		key := base64.StdEncoding.EncodeToString(testKey(1))
+		vault, err := Load("k1", map[string]string{"k1": key}, base64.StdEncoding.EncodeToString(testKey(9)))
+		require.NoError(t, err)
+
+		envelope, err := vault.Seal([]byte("secret"))
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(envelope, "v1.k1."))
+	})
+
+	t.Run("ключи не заданы", func(t *testing.T) {
+		_, err := Load("", nil, "")
+		assert.ErrorIs(t, err, ErrKeysNotConfigured)
+
+		// Без ключа HMAC хранилище тоже не создается
+		key := base64.StdEncoding.EncodeToString(testKey(1))
+		_, err = Load("k1", map[string]string{"k1": key}, "")
+		assert.ErrorIs(t, err, ErrKeysNotConfigured)
+	})
+
+	t.Run("ключ неверной длины", func(t *testing.T) {
+		_, err := Load("k1", map[string]string{"k1": "c2hvcnQ="}, "c2hvcnQ=")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+	})
+
+	t.Run("неизвестный активный ключ", func(t *testing.T) {
+		_, err := New("k3", map[string][]byte{"k1": testKey(1)}, testKey(9))
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	})
+}
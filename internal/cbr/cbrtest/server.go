@@ -0,0 +1,170 @@
+// Package cbrtest предоставляет заглушку веб-сервиса DailyInfo ЦБ РФ для
+// тестов на основе net/http/httptest. Заглушка отвечает на метод KeyRate в
+// формате настоящего сервиса.
+package cbrtest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"bank-app/internal/config"
+	"bank-app/internal/model"
+)
+
+// SOAPAction - действие метода KeyRate, которое ожидает заглушка
+const SOAPAction = "http://web.cbr.ru/KeyRate"
+
+// Request - принятый заглушкой запрос KeyRate
+type Request struct {
+	SOAPAction string
+	From, To   string
+}
+
+// Server отвечает на запросы KeyRate ставками, заданными в тесте
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	rates    []model.KeyRate
+	fault    string
+	requests []Request
+}
+
+// NewServer запускает заглушку со ставками rates
+func NewServer(rates ...model.KeyRate) *Server {
+	s := &Server{rates: rates}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Config возвращает настройки клиента для обращения к заглушке
+func (s *Server) Config() config.CBRConfig {
+	return config.CBRConfig{
+		BaseURL:    s.URL,
+		SOAPAction: SOAPAction,
+		Timeout:    5 * time.Second,
+	}
+}
+
+// SetFault заставляет заглушку отвечать SOAP Fault с текстом message.
+// Пустой message возвращает обычные ответы.
+func (s *Server) SetFault(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fault = message
+}
+
+// Requests возвращает копию принятых запросов
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+type keyRateRequest struct {
+	Body struct {
+		KeyRate *struct {
+			From string `xml:"fromDate"`
+			To   string `xml:"ToDate"`
+		} `xml:"KeyRate"`
+	} `xml:"Body"`
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	action := strings.Trim(r.Header.Get("SOAPAction"), `"`)
+	if r.Method != http.MethodPost || action != SOAPAction {
+		s.writeFault(w, "soap:Client", fmt.Sprintf("Server did not recognize the value of HTTP Header SOAPAction: %s.", action))
+		return
+	}
+
+	var req keyRateRequest
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || req.Body.KeyRate == nil {
+		s.writeFault(w, "soap:Client", "Server was unable to read request.")
+		return
+	}
+	s.requests = append(s.requests, Request{SOAPAction: action, From: req.Body.KeyRate.From, To: req.Body.KeyRate.To})
+
+	if s.fault != "" {
+		s.writeFault(w, "soap:Server", s.fault)
+		return
+	}
+
+	from, errFrom := time.Parse("2006-01-02T15:04:05", req.Body.KeyRate.From)
+	to, errTo := time.Parse("2006-01-02T15:04:05", req.Body.KeyRate.To)
+	if errFrom != nil || errTo != nil {
+		s.writeFault(w, "soap:Client", "Server was unable to read request. Input string was not in a correct format.")
+		return
+	}
+
+	// Как и ЦБ, отдаем ставки за период от новых к старым
+	var rows []keyRateRow
+	for _, rate := range s.rates {
+		if rate.EffectiveDate.Before(from) || rate.EffectiveDate.After(to) {
+			continue
+		}
+		rows = append(rows, keyRateRow{
+			Date: rate.EffectiveDate.Format("2006-01-02") + "T00:00:00+03:00",
+			Rate: strconv.FormatFloat(rate.Rate, 'f', 2, 64),
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Date > rows[j].Date })
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	keyRateResponse.Execute(w, rows)
+}
+
+func (s *Server) writeFault(w http.ResponseWriter, code, message string) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	faultResponse.Execute(w, struct{ Code, Message string }{code, message})
+}
+
+type keyRateRow struct {
+	Date string
+	Rate string
+}
+
+var keyRateResponse = template.Must(template.New("response").Parse(`<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+  <soap:Body>
+    <KeyRateResponse xmlns="http://web.cbr.ru/">
+      <KeyRateResult>
+        <xs:schema id="KeyRate" xmlns="" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:msdata="urn:schemas-microsoft-com:xml-msdata"/>
+        <diffgr:diffgram xmlns:msdata="urn:schemas-microsoft-com:xml-msdata" xmlns:diffgr="urn:schemas-microsoft-com:xml-diffgram-v1">
+          <KeyRate xmlns="">
+{{- range $i, $row := .}}
+            <KR diffgr:id="KR{{$i}}" msdata:rowOrder="{{$i}}">
+              <DT>{{$row.Date}}</DT>
+              <Rate>{{$row.Rate}}</Rate>
+            </KR>
+{{- end}}
+          </KeyRate>
+        </diffgr:diffgram>
+      </KeyRateResult>
+    </KeyRateResponse>
+  </soap:Body>
+</soap:Envelope>`))
+
+var faultResponse = template.Must(template.New("fault").Parse(`<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+  <soap:Body>
+    <soap:Fault>
+      <faultcode>{{html .Code}}</faultcode>
+      <faultstring>{{html .Message}}</faultstring>
+      <detail />
+    </soap:Fault>
+  </soap:Body>
+</soap:Envelope>`))
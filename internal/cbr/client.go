@@ -0,0 +1,142 @@
+// Package cbr получает ключевую ставку из веб-сервиса DailyInfo ЦБ РФ по
+// протоколу SOAP 1.1
+package cbr
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bank-app/internal/config"
+	"bank-app/internal/model"
+)
+
+// Source возвращает ключевые ставки, действовавшие в периоде
+type Source interface {
+	KeyRates(ctx context.Context, from, to time.Time) ([]*model.KeyRate, error)
+}
+
+// FaultError - SOAP Fault, которым веб-сервис отвечает на ошибку
+type FaultError struct {
+	Code    string
+	Message string
+}
+
+func (e *FaultError) Error() string {
+	return fmt.Sprintf("cbr soap fault %s: %s", e.Code, e.Message)
+}
+
+// Client обращается к методу KeyRate веб-сервиса DailyInfo
+type Client struct {
+	baseURL    string
+	soapAction string
+	http       *http.Client
+}
+
+func New(cfg config.CBRConfig) *Client {
+	return &Client{
+		baseURL:    cfg.BaseURL,
+		soapAction: cfg.SOAPAction,
+		http:       &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// WithHTTPClient заменяет HTTP-клиент, например на клиент httptest.Server
+func (c *Client) WithHTTPClient(client *http.Client) *Client {
+	c.http = client
+	return c
+}
+
+// dateLayout - формат параметров fromDate и ToDate (xs:dateTime без пояса)
+const dateLayout = "2006-01-02T00:00:00"
+
+const keyRateRequest = `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <KeyRate xmlns="http://web.cbr.ru/">
+      <fromDate>%s</fromDate>
+      <ToDate>%s</ToDate>
+    </KeyRate>
+  </soap:Body>
+</soap:Envelope>`
+
+// keyRateEnvelope - ответ метода KeyRate: строки KR набора данных ADO.NET
+// в diffgram с датой DT и ставкой Rate, либо SOAP Fault
+type keyRateEnvelope struct {
+	Body struct {
+		Fault *struct {
+			Code   string `xml:"faultcode"`
+			String string `xml:"faultstring"`
+		} `xml:"Fault"`
+		Rates []struct {
+			Date string `xml:"DT"`
+			Rate string `xml:"Rate"`
+		} `xml:"KeyRateResponse>KeyRateResult>diffgram>KeyRate>KR"`
+	} `xml:"Body"`
+}
+
+// KeyRates возвращает ставки по дням с from по to. ЦБ публикует ставку
+// только за рабочие дни, в выходные ответ может быть пустым.
+func (c *Client) KeyRates(ctx context.Context, from, to time.Time) ([]*model.KeyRate, error) {
+	body := fmt.Sprintf(keyRateRequest, from.Format(dateLayout), to.Format(dateLayout))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", strconv.Quote(c.soapAction))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fault приходит с кодом 500, остальные ошибки - без тела SOAP
+	var envelope keyRateEnvelope
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&envelope); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("cbr returned %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("cbr response: %w", err)
+	}
+	if fault := envelope.Body.Fault; fault != nil {
+		return nil, &FaultError{Code: fault.Code, Message: fault.String}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cbr returned %d", resp.StatusCode)
+	}
+
+	rates := make([]*model.KeyRate, 0, len(envelope.Body.Rates))
+	for _, row := range envelope.Body.Rates {
+		date, err := time.Parse(time.RFC3339, strings.TrimSpace(row.Date))
+		if err != nil {
+			return nil, fmt.Errorf("cbr key rate date %q: %w", row.Date, err)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(row.Rate), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cbr key rate %q: %w", row.Rate, err)
+		}
+
+		// Дата задана по московскому времени, сохраняем ее как календарный день
+		year, month, day := date.Date()
+		rates = append(rates, &model.KeyRate{
+			EffectiveDate: time.Date(year, month, day, 0, 0, 0, 0, time.UTC),
+			Rate:          rate,
+		})
+	}
+
+	return rates, nil
+}
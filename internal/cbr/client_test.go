@@ -0,0 +1,103 @@
+package cbr_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bank-app/internal/cbr"
+	"bank-app/internal/cbr/cbrtest"
+	"bank-app/internal/config"
+	"bank-app/internal/model"
+)
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestClient_KeyRates(t *testing.T) {
+	ctx := context.Background()
+
+	server := cbrtest.NewServer(
+		model.KeyRate{EffectiveDate: day(3, 19), Rate: 16.5},
+		model.KeyRate{EffectiveDate: day(3, 20), Rate: 16},
+		model.KeyRate{EffectiveDate: day(3, 23), Rate: 16},
+		model.KeyRate{EffectiveDate: day(4, 1), Rate: 15},
+	)
+	defer server.Close()
+
+	client := cbr.New(server.Config())
+
+	t.Run("ставки за период", func(t *testing.T) {
+		// Действие
+		rates, err := client.KeyRates(ctx, day(3, 20), day(3, 31))
+
+		// Проверка
+		require.NoError(t, err)
+		require.Len(t, rates, 2)
+		assert.Equal(t, day(3, 23), rates[0].EffectiveDate)
+		assert.Equal(t, 16.0, rates[0].Rate)
+		assert.Equal(t, day(3, 20), rates[1].EffectiveDate)
+
+		// Запрос отправлен методом KeyRate с датами периода
+		requests := server.Requests()
+		require.NotEmpty(t, requests)
+		last := requests[len(requests)-1]
+		assert.Equal(t, cbrtest.SOAPAction, last.SOAPAction)
+		assert.Equal(t, "2026-03-20T00:00:00", last.From)
+		assert.Equal(t, "2026-03-31T00:00:00", last.To)
+	})
+
+	t.Run("выходные без ставок", func(t *testing.T) {
+		rates, err := client.KeyRates(ctx, day(3, 21), day(3, 22))
+
+		require.NoError(t, err)
+		assert.Empty(t, rates)
+	})
+
+	t.Run("SOAP Fault", func(t *testing.T) {
+		// Подготовка
+		server.SetFault("Service temporarily unavailable")
+		defer server.SetFault("")
+
+		// Действие
+		_, err := client.KeyRates(ctx, day(3, 20), day(3, 31))
+
+		// Проверка
+		var fault *cbr.FaultError
+		require.ErrorAs(t, err, &fault)
+		assert.Equal(t, "soap:Server", fault.Code)
+		assert.Equal(t, "Service temporarily unavailable", fault.Message)
+	})
+
+	t.Run("неизвестное действие SOAPAction", func(t *testing.T) {
+		// Подготовка
+		cfg := server.Config()
+		cfg.SOAPAction = "http://web.cbr.ru/GetCursOnDate"
+
+		// Действие
+		_, err := cbr.New(cfg).KeyRates(ctx, day(3, 20), day(3, 31))
+
+		// Проверка
+		var fault *cbr.FaultError
+		require.ErrorAs(t, err, &fault)
+		assert.Equal(t, "soap:Client", fault.Code)
+	})
+}
+
+func TestClient_KeyRates_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := cbr.New(config.CBRConfig{BaseURL: server.URL, SOAPAction: cbrtest.SOAPAction})
+
+	_, err := client.KeyRates(context.Background(), day(3, 20), day(3, 31))
+	assert.EqualError(t, err, "cbr returned 502")
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"bank-app/internal/model"
+	"bank-app/internal/service"
+)
+
+// currentUserID возвращает ID пользователя, установленный AuthMiddleware
+func currentUserID(r *http.Request) int64 {
+	return r.Context().Value("userID").(int64)
+}
+
+// currentActor возвращает пользователя и его роль для действий бэк-офиса
+func currentActor(r *http.Request) model.Actor {
+	role, _ := r.Context().Value("role").(string)
+	return model.Actor{UserID: currentUserID(r), Role: role}
+}
+
+// StaffMiddleware пропускает в бэк-офис только сотрудников. Конкретные
+// разрешения проверяются в AdminService.
+func (h *Handler) StaffMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !service.IsStaff(currentActor(r).Role) {
+			h.error(w, r, http.StatusForbidden, service.ErrForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ownershipMiddleware пропускает запрос, только если ресурс из параметра {id}
+// принадлежит текущему пользователю. Чужой ресурс возвращает 404, а не 403,
+// чтобы не раскрывать его существование.
+func (h *Handler) ownershipMiddleware(resource string, check func(ctx context.Context, userID, id int64) error) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+			if err != nil {
+				h.error(w, r, http.StatusBadRequest, errors.New("invalid "+resource+" id"))
+				return
+			}
+
+			if err := check(r.Context(), currentUserID(r), id); err != nil {
+				h.accessError(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AccountOwnerMiddleware проверяет доступ к счету /accounts/{id}
+func (h *Handler) AccountOwnerMiddleware(next http.Handler) http.Handler {
+	return h.ownershipMiddleware("account", func(ctx context.Context, userID, id int64) error {
+		_, err := h.services.Access.Account(ctx, userID, id)
+		return err
+	})(next)
+}
+
+// CardOwnerMiddleware проверяет доступ к карте /cards/{id}
+func (h *Handler) CardOwnerMiddleware(next http.Handler) http.Handler {
+	return h.ownershipMiddleware("card", func(ctx context.Context, userID, id int64) error {
+		_, err := h.services.Access.Card(ctx, userID, id)
+		return err
+	})(next)
+}
+
+// CreditOwnerMiddleware проверяет доступ к кредиту /credits/{id}
+func (h *Handler) CreditOwnerMiddleware(next http.Handler) http.Handler {
+	return h.ownershipMiddleware("credit", func(ctx context.Context, userID, id int64) error {
+		_, err := h.services.Access.Credit(ctx, userID, id)
+		return err
+	})(next)
+}
+
+// TransferOwnerMiddleware проверяет доступ к операции /transfers/{id}
+func (h *Handler) TransferOwnerMiddleware(next http.Handler) http.Handler {
+	return h.ownershipMiddleware("transaction", func(ctx context.Context, userID, id int64) error {
+		_, err := h.services.Access.Transaction(ctx, userID, id)
+		return err
+	})(next)
+}
+
+// accessError отвечает 404 на отказ в доступе и 500 на прочие ошибки
+func (h *Handler) accessError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, service.ErrNotFound) {
+		h.error(w, r, http.StatusNotFound, err)
+		return
+	}
+	h.error(w, r, http.StatusInternalServerError, err)
+}
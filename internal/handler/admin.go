@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"bank-app/internal/model"
+	"bank-app/internal/service"
+)
+
+// adminError отвечает 403 на отсутствие разрешения и 400 на ошибки запроса.
+// Ошибки действий с картами сопоставляются как в cardError, прочие - 404 на
+// отсутствие записи и 500.
+func (h *Handler) adminError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		h.error(w, r, http.StatusForbidden, err)
+	case errors.Is(err, service.ErrSearchQueryRequired), errors.Is(err, service.ErrUnknownRole),
+		errors.Is(err, service.ErrOwnRoleChange), errors.Is(err, service.ErrNoteRequired):
+		h.error(w, r, http.StatusBadRequest, err)
+	default:
+		h.cardError(w, r, err)
+	}
+}
+
+func pathID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid id")
+	}
+	return id, nil
+}
+
+// AdminSearchUsers обработчик поиска пользователей по имени или email (?q=)
+func (h *Handler) AdminSearchUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.services.Admin.SearchUsers(r.Context(), currentActor(r), r.URL.Query().Get("q"))
+	if err != nil {
+		h.adminError(w, r, err)
+		return
+	}
+
+	if users == nil {
+		users = []*model.User{}
+	}
+
+	h.respond(w, r, http.StatusOK, users)
+}
+
+type setRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// AdminSetRole обработчик смены роли пользователя
+func (h *Handler) AdminSetRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req setRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.services.Admin.SetRole(r.Context(), currentActor(r), userID, req.Role); err != nil {
+		h.adminError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
+
+// AdminGetUserAccounts обработчик получения счетов пользователя
+func (h *Handler) AdminGetUserAccounts(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	accounts, err := h.services.Admin.GetUserAccounts(r.Context(), currentActor(r), userID)
+	if err != nil {
+		h.adminError(w, r, err)
+		return
+	}
+
+	if accounts == nil {
+		accounts = []*model.Account{}
+	}
+
+	h.respond(w, r, http.StatusOK, accounts)
+}
+
+// AdminGetAccount обработчик просмотра любого счета
+func (h *Handler) AdminGetAccount(w http.ResponseWriter, r *http.Request) {
+	accountID, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	account, err := h.services.Admin.GetAccount(r.Context(), currentActor(r), accountID)
+	if err != nil {
+		h.adminError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, account)
+}
+
+type reasonRequest struct {
+	Reason string `json:"reason"`
+}
+
+// AdminFreezeAccount обработчик заморозки счета
+func (h *Handler) AdminFreezeAccount(w http.ResponseWriter, r *http.Request) {
+	h.adminResourceAction(w, r, h.services.Admin.FreezeAccount)
+}
+
+// AdminUnfreezeAccount обработчик разморозки счета
+func (h *Handler) AdminUnfreezeAccount(w http.ResponseWriter, r *http.Request) {
+	h.adminResourceAction(w, r, h.services.Admin.UnfreezeAccount)
+}
+
+// AdminBlockCard обработчик блокировки карты
+func (h *Handler) AdminBlockCard(w http.ResponseWriter, r *http.Request) {
+	h.adminResourceAction(w, r, h.services.Admin.BlockCard)
+}
+
+// adminResourceAction выполняет действие над ресурсом {id} с указанием причины
+func (h *Handler) adminResourceAction(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, actor model.Actor, id int64, reason string) error) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req reasonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := action(r.Context(), currentActor(r), id, req.Reason); err != nil {
+		h.adminError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
+
+type caseNoteRequest struct {
+	AccountID int64  `json:"account_id"`
+	CardID    int64  `json:"card_id"`
+	Note      string `json:"note"`
+}
+
+// AdminAddCaseNote обработчик добавления заметки по обращению клиента
+func (h *Handler) AdminAddCaseNote(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req caseNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	note := &model.CaseNote{
+		UserID:    userID,
+		AccountID: req.AccountID,
+		CardID:    req.CardID,
+		Note:      req.Note,
+	}
+
+	if err := h.services.Admin.AddCaseNote(r.Context(), currentActor(r), note); err != nil {
+		h.adminError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusCreated, note)
+}
+
+// AdminGetCaseNotes обработчик получения заметок по клиенту
+func (h *Handler) AdminGetCaseNotes(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	notes, err := h.services.Admin.GetCaseNotes(r.Context(), currentActor(r), userID)
+	if err != nil {
+		h.adminError(w, r, err)
+		return
+	}
+
+	if notes == nil {
+		notes = []*model.CaseNote{}
+	}
+
+	h.respond(w, r, http.StatusOK, notes)
+}
+
+// AdminGetAuditLog обработчик просмотра журнала действий сотрудников (?limit=)
+func (h *Handler) AdminGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	records, err := h.services.Admin.GetAuditLog(r.Context(), currentActor(r), limit)
+	if err != nil {
+		h.adminError(w, r, err)
+		return
+	}
+
+	if records == nil {
+		records = []*model.AuditRecord{}
+	}
+
+	h.respond(w, r, http.StatusOK, records)
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"bank-app/internal/model"
+	"bank-app/internal/service"
+)
+
+type blockCardRequest struct {
+	Reason string `json:"reason"`
+	// Lost - карта утеряна или украдена, блокировка необратима
+	Lost bool `json:"lost"`
+}
+
+// cardError отвечает 409 на недопустимую смену статуса и истекший срок, 403 на
+// попытку снять блокировку банка и 400 на отсутствие причины
+func (h *Handler) cardError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCardTransition), errors.Is(err, service.ErrCardAlreadyReissued),
+		errors.Is(err, service.ErrVirtualCardReissue), errors.Is(err, service.ErrPINAlreadySet),
+		errors.Is(err, service.ErrCardNotActive), errors.Is(err, service.ErrCardExpired):
+		h.error(w, r, http.StatusConflict, err)
+	case errors.Is(err, service.ErrCardBlockedByBank):
+		h.error(w, r, http.StatusForbidden, err)
+	case errors.Is(err, service.ErrReasonRequired), errors.Is(err, service.ErrInvalidPINFormat):
+		h.error(w, r, http.StatusBadRequest, err)
+	default:
+		h.accessError(w, r, err)
+	}
+}
+
+// BlockCard обработчик блокировки карты клиентом
+func (h *Handler) BlockCard(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req blockCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.services.Cards.Block(r.Context(), id, currentActor(r), req.Lost, req.Reason); err != nil {
+		h.cardError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
+
+// UnblockCard обработчик снятия временной блокировки карты
+func (h *Handler) UnblockCard(w http.ResponseWriter, r *http.Request) {
+	h.cardAction(w, r, h.services.Cards.Unblock)
+}
+
+// CloseCard обработчик закрытия карты
+func (h *Handler) CloseCard(w http.ResponseWriter, r *http.Request) {
+	h.cardAction(w, r, h.services.Cards.Close)
+}
+
+// ReissueCard обработчик перевыпуска карты
+func (h *Handler) ReissueCard(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req reasonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	card, err := h.services.Cards.Reissue(r.Context(), id, currentActor(r), req.Reason)
+	if err != nil {
+		h.cardError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusCreated, card)
+}
+
+// cardAction выполняет смену статуса карты {id} с указанием причины
+func (h *Handler) cardAction(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, id int64, actor model.Actor, reason string) error) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req reasonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := action(r.Context(), id, currentActor(r), req.Reason); err != nil {
+		h.cardError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
+
+// ActivateCard обработчик активации перевыпущенной карты. В теле можно
+// передать PIN новой карты.
+func (h *Handler) ActivateCard(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req pinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.services.Cards.Activate(r.Context(), id, currentActor(r), req.PIN); err != nil {
+		h.cardError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
+
+// GetCardHistory обработчик получения истории статусов карты
+func (h *Handler) GetCardHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	history, err := h.services.Cards.GetStatusHistory(r.Context(), id)
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, history)
+}
+
+// AdminUnblockCard обработчик снятия блокировки карты сотрудником
+func (h *Handler) AdminUnblockCard(w http.ResponseWriter, r *http.Request) {
+	h.adminResourceAction(w, r, h.services.Admin.UnblockCard)
+}
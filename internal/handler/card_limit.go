@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+	"bank-app/internal/service"
+)
+
+type cardLimitRequest struct {
+	Channel        string       `json:"channel"`
+	MCC            string       `json:"mcc"`
+	PerTransaction money.Amount `json:"per_transaction"`
+	Daily          money.Amount `json:"daily"`
+	Monthly        money.Amount `json:"monthly"`
+	Disabled       bool         `json:"disabled"`
+}
+
+func (req *cardLimitRequest) limit(cardID int64) *model.CardLimit {
+	return &model.CardLimit{
+		CardID:         cardID,
+		Channel:        req.Channel,
+		MCC:            req.MCC,
+		PerTransaction: req.PerTransaction,
+		Daily:          req.Daily,
+		Monthly:        req.Monthly,
+		Disabled:       req.Disabled,
+	}
+}
+
+// cardLimitError отвечает 400 на некорректный лимит и 409 на повторный лимит
+// для того же канала и категории
+func (h *Handler) cardLimitError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnknownChannel), errors.Is(err, service.ErrInvalidMCC), errors.Is(err, service.ErrInvalidCardLimit):
+		h.error(w, r, http.StatusBadRequest, err)
+	case errors.Is(err, service.ErrCardLimitExists):
+		h.error(w, r, http.StatusConflict, err)
+	default:
+		h.accessError(w, r, err)
+	}
+}
+
+// GetCardLimits обработчик получения лимитов карты
+func (h *Handler) GetCardLimits(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	limits, err := h.services.CardLimits.GetByCardID(r.Context(), id)
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if limits == nil {
+		limits = []*model.CardLimit{}
+	}
+
+	h.respond(w, r, http.StatusOK, limits)
+}
+
+// CreateCardLimit обработчик добавления лимита карты
+func (h *Handler) CreateCardLimit(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req cardLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	limit := req.limit(id)
+	if err := h.services.CardLimits.Create(r.Context(), limit); err != nil {
+		h.cardLimitError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusCreated, limit)
+}
+
+// UpdateCardLimit обработчик изменения лимита карты
+func (h *Handler) UpdateCardLimit(w http.ResponseWriter, r *http.Request) {
+	id, limitID, err := cardLimitPathIDs(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req cardLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	limit := req.limit(id)
+	limit.ID = limitID
+	if err := h.services.CardLimits.Update(r.Context(), limit); err != nil {
+		h.cardLimitError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, limit)
+}
+
+// DeleteCardLimit обработчик удаления лимита карты
+func (h *Handler) DeleteCardLimit(w http.ResponseWriter, r *http.Request) {
+	id, limitID, err := cardLimitPathIDs(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.services.CardLimits.Delete(r.Context(), id, limitID); err != nil {
+		h.cardLimitError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
+
+func cardLimitPathIDs(r *http.Request) (int64, int64, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	limitID, err := strconv.ParseInt(mux.Vars(r)["limitId"], 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("invalid limit id")
+	}
+
+	return id, limitID, nil
+}
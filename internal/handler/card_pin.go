@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"bank-app/internal/service"
+)
+
+type pinRequest struct {
+	PIN string `json:"pin"`
+}
+
+type changePINRequest struct {
+	CurrentPIN string `json:"current_pin"`
+	NewPIN     string `json:"new_pin"`
+}
+
+// pinError отвечает 403 на неверный PIN и исчерпанные попытки, 409 на
+// недопустимую для карты операцию и 400 на некорректный PIN
+func (h *Handler) pinError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPIN), errors.Is(err, service.ErrPINTriesExceeded):
+		h.error(w, r, http.StatusForbidden, err)
+	case errors.Is(err, service.ErrPINAlreadySet), errors.Is(err, service.ErrPINNotSet),
+		errors.Is(err, service.ErrCardNotActive), errors.Is(err, service.ErrInvalidCardTransition),
+		errors.Is(err, service.ErrCardExpired):
+		h.error(w, r, http.StatusConflict, err)
+	case errors.Is(err, service.ErrInvalidPINFormat):
+		h.error(w, r, http.StatusBadRequest, err)
+	default:
+		h.accessError(w, r, err)
+	}
+}
+
+// SetCardPIN обработчик установки PIN карты, у которой его еще нет
+func (h *Handler) SetCardPIN(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req pinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.services.Cards.SetPIN(r.Context(), id, req.PIN); err != nil {
+		h.pinError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
+
+// ChangeCardPIN обработчик смены PIN по текущему
+func (h *Handler) ChangeCardPIN(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req changePINRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.services.Cards.ChangePIN(r.Context(), id, req.CurrentPIN, req.NewPIN); err != nil {
+		h.pinError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
+
+// ResetCardPIN обработчик сброса забытого PIN. Требует step-up.
+func (h *Handler) ResetCardPIN(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req pinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.services.Cards.ResetPIN(r.Context(), id, currentActor(r), req.PIN); err != nil {
+		h.pinError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"bank-app/internal/model"
+	"bank-app/internal/service"
+)
+
+type createCreditRequest struct {
+	AccountID int64 `json:"account_id"`
+	model.CreditTerms
+}
+
+// creditError отвечает 400 на некорректные параметры кредита или досрочного
+// погашения, 409 на погашение закрытого кредита или кредита с наступившим
+// платежом, 422 на превышение кредитной нагрузки или нехватку средств, 503,
+// если неизвестна ключевая ставка, и 404 на чужой или несуществующий счет
+func (h *Handler) creditError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCreditAmount), errors.Is(err, service.ErrInvalidCreditTerm),
+		errors.Is(err, service.ErrUnknownScheduleType), errors.Is(err, service.ErrUnknownDayCount),
+		errors.Is(err, service.ErrUnknownCreditProduct),
+		errors.Is(err, service.ErrInvalidPrepaymentAmount), errors.Is(err, service.ErrUnknownPrepaymentMode):
+		h.error(w, r, http.StatusBadRequest, err)
+	case errors.Is(err, service.ErrCreditClosed), errors.Is(err, service.ErrCreditPaymentDue):
+		h.error(w, r, http.StatusConflict, err)
+	case errors.Is(err, service.ErrCreditLoadLimit), errors.Is(err, service.ErrInsufficientFunds),
+		errors.Is(err, service.ErrAccountFrozen), errors.Is(err, service.ErrCreditCurrency),
+		errors.Is(err, service.ErrCurrencyMismatch):
+		h.error(w, r, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, service.ErrKeyRateUnavailable):
+		h.error(w, r, http.StatusServiceUnavailable, err)
+	default:
+		h.accessError(w, r, err)
+	}
+}
+
+// CreateCredit обработчик выдачи кредита на свой счет
+func (h *Handler) CreateCredit(w http.ResponseWriter, r *http.Request) {
+	var req createCreditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	credit, err := h.services.Credits.Create(r.Context(), currentUserID(r), req.AccountID, req.CreditTerms)
+	if err != nil {
+		h.creditError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusCreated, credit)
+}
+
+// QuoteCredit обработчик расчета графика и переплаты без оформления кредита
+func (h *Handler) QuoteCredit(w http.ResponseWriter, r *http.Request) {
+	var req model.CreditTerms
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	quote, err := h.services.Credits.Quote(r.Context(), req)
+	if err != nil {
+		h.creditError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, quote)
+}
+
+// GetCreditSchedule обработчик получения графика платежей
+func (h *Handler) GetCreditSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	schedule, err := h.services.Credits.GetSchedule(r.Context(), id)
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if schedule == nil {
+		schedule = []*model.PaymentSchedule{}
+	}
+
+	h.respond(w, r, http.StatusOK, schedule)
+}
+
+// PrepayCredit обработчик досрочного погашения кредита
+func (h *Handler) PrepayCredit(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req model.Prepayment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := h.services.Credits.Prepay(r.Context(), id, req)
+	if err != nil {
+		h.creditError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, version)
+}
+
+// GetCreditScheduleVersions обработчик получения истории редакций графика
+func (h *Handler) GetCreditScheduleVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	versions, err := h.services.Credits.GetScheduleVersions(r.Context(), id)
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if versions == nil {
+		versions = []*model.ScheduleVersion{}
+	}
+
+	h.respond(w, r, http.StatusOK, versions)
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"bank-app/internal/service"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// VerifyEmail обработчик перехода по ссылке подтверждения email
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	err := h.services.Users.VerifyEmail(r.Context(), r.URL.Query().Get("token"))
+	if errors.Is(err, service.ErrInvalidVerificationToken) {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, map[string]bool{"email_verified": true})
+}
+
+// ResendVerificationEmail обработчик повторной отправки ссылки подтверждения
+func (h *Handler) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	if err := h.services.Users.SendVerificationEmail(r.Context(), currentUserID(r)); err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusAccepted, nil)
+}
+
+// ForgotPassword обработчик запроса восстановления пароля. Ответ не зависит
+// от того, существует ли пользователь с таким email.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.services.Users.ForgotPassword(r.Context(), req.Email); err != nil {
+		h.logger.WithError(err).Error("failed to send password reset email")
+	}
+
+	h.respond(w, r, http.StatusAccepted, nil)
+}
+
+// ResetPassword обработчик смены пароля по токену из письма
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	err := h.services.Users.ResetPassword(r.Context(), req.Token, req.Password)
+	if errors.Is(err, service.ErrInvalidResetToken) || errors.Is(err, service.ErrWeakPassword) {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
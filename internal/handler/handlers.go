@@ -4,21 +4,29 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 
+	"bank-app/internal/model"
+	"bank-app/internal/money"
 	"bank-app/internal/service"
 )
 
+const maxUserAgentLength = 512
+
 type Handler struct {
-	services *service.Services
-	logger   *logrus.Logger
+	services       *service.Services
+	logger         *logrus.Logger
+	trustedProxies []netip.Prefix
 }
 
 func NewHandlers(services *service.Services, logger *logrus.Logger) *Handler {
@@ -28,6 +36,12 @@ func NewHandlers(services *service.Services, logger *logrus.Logger) *Handler {
 	}
 }
 
+// WithTrustedProxies задает прокси, от которых принимается X-Forwarded-For
+func (h *Handler) WithTrustedProxies(proxies []netip.Prefix) *Handler {
+	h.trustedProxies = proxies
+	return h
+}
+
 type response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
@@ -56,29 +70,31 @@ func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte("your-secret-key"), nil // В реальном приложении брать из конфига
-		})
+		claims, err := h.services.Users.Authenticate(r.Context(), tokenString)
+		if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrTokenRevoked) {
+			h.error(w, r, http.StatusUnauthorized, err)
+			return
+		}
+		if err != nil {
+			h.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
 
+		userID, err := claims.UserID()
 		if err != nil {
-			h.error(w, r, http.StatusUnauthorized, err)
+			h.error(w, r, http.StatusUnauthorized, errors.New("invalid user id"))
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			userID, err := strconv.ParseInt(claims["sub"].(string), 10, 64)
-			if err != nil {
-				h.error(w, r, http.StatusUnauthorized, errors.New("invalid user id"))
-				return
-			}
-			ctx := context.WithValue(r.Context(), "userID", userID)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		} else {
-			h.error(w, r, http.StatusUnauthorized, errors.New("invalid token"))
+		role := claims.Role
+		if role == "" {
+			role = model.RoleCustomer
 		}
+
+		ctx := context.WithValue(r.Context(), "userID", userID)
+		ctx = context.WithValue(ctx, "role", role)
+		ctx = context.WithValue(ctx, "sessionID", claims.SessionID)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -96,7 +112,15 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.services.Users.Register(r.Context(), req.Username, req.Email, req.Password); err != nil {
+	err := h.services.Users.Register(r.Context(), req.Username, req.Email, req.Password)
+	switch {
+	case errors.Is(err, service.ErrMailDelivery):
+		// Пользователь создан, ссылку подтверждения можно запросить повторно
+		h.logger.WithError(err).Warn("failed to send verification email")
+	case errors.Is(err, service.ErrWeakPassword):
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	case err != nil:
 		h.error(w, r, http.StatusInternalServerError, err)
 		return
 	}
@@ -109,10 +133,6 @@ type loginRequest struct {
 	Password string `json:"password"`
 }
 
-type loginResponse struct {
-	Token string `json:"token"`
-}
-
 // Login обработчик аутентификации
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req loginRequest
@@ -121,18 +141,173 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.services.Users.Login(r.Context(), req.Email, req.Password)
+	tokens, err := h.services.Users.Login(r.Context(), req.Email, req.Password, h.clientInfo(r))
 	if err != nil {
+		h.loginError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, tokens)
+}
+
+// loginError сообщает клиенту, через сколько секунд можно повторить вход
+func (h *Handler) loginError(w http.ResponseWriter, r *http.Request, err error) {
+	var retry *service.RetryAfterError
+	if errors.As(err, &retry) {
+		seconds := int64(math.Ceil(retry.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials):
 		h.error(w, r, http.StatusUnauthorized, err)
+	case errors.Is(err, service.ErrTooManyAttempts), errors.Is(err, service.ErrAccountLocked):
+		h.error(w, r, http.StatusTooManyRequests, err)
+	default:
+		h.error(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+// UnlockAccount обработчик перехода по ссылке разблокировки входа
+func (h *Handler) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	err := h.services.Users.UnlockAccount(r.Context(), r.URL.Query().Get("token"))
+	if errors.Is(err, service.ErrInvalidUnlockToken) {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, map[string]bool{"unlocked": true})
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh обработчик обмена refresh-токена на новую пару токенов
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	tokens, err := h.services.Users.Refresh(r.Context(), req.RefreshToken, h.clientInfo(r))
+	if errors.Is(err, service.ErrInvalidRefreshToken) {
+		h.error(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, tokens)
+}
+
+// Logout обработчик завершения текущей сессии
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.services.Users.Logout(r.Context(), currentUserID(r), currentSessionID(r)); err != nil {
+		h.accessError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
+
+// LogoutAll обработчик завершения всех сессий пользователя
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	if err := h.services.Users.LogoutAll(r.Context(), currentUserID(r)); err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
+
+// GetSessions обработчик получения активных сессий пользователя
+func (h *Handler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.services.Users.GetSessions(r.Context(), currentUserID(r))
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	h.respond(w, r, http.StatusOK, loginResponse{Token: token})
+	if sessions == nil {
+		sessions = []*model.Session{}
+	}
+
+	h.respond(w, r, http.StatusOK, sessions)
+}
+
+// JWKS обработчик публикации открытых ключей подписи в формате RFC 7517.
+// Ответ не оборачивается в response, чтобы его понимали стандартные библиотеки JWT.
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.services.Keys.JWKS())
+}
+
+// clientInfo собирает сведения об устройстве для сессии
+func (h *Handler) clientInfo(r *http.Request) model.ClientInfo {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	return model.ClientInfo{UserAgent: userAgent, IP: h.clientIP(r)}
+}
+
+// clientIP возвращает адрес клиента. X-Forwarded-For учитывается, только если
+// соединение пришло от доверенного прокси: адреса просматриваются справа налево
+// до первого, который не принадлежит доверенным прокси.
+func (h *Handler) clientIP(r *http.Request) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+
+	if !h.trustedProxy(ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+
+		ip = addr.Unmap().String()
+		if !h.trustedProxy(ip) {
+			break
+		}
+	}
+
+	return ip
+}
+
+func (h *Handler) trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, proxy := range h.trustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
 }
 
 // CreateAccount обработчик создания счета
 func (h *Handler) CreateAccount(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int64)
+	userID := currentUserID(r)
 	if err := h.services.Accounts.Create(r.Context(), userID); err != nil {
 		h.error(w, r, http.StatusInternalServerError, err)
 		return
@@ -143,7 +318,7 @@ func (h *Handler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 
 // GetAccounts обработчик получения списка счетов
 func (h *Handler) GetAccounts(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID").(int64)
+	userID := currentUserID(r)
 	accounts, err := h.services.Accounts.GetByUserID(r.Context(), userID)
 	if err != nil {
 		h.error(w, r, http.StatusInternalServerError, err)
@@ -171,34 +346,273 @@ func (h *Handler) GetAccount(w http.ResponseWriter, r *http.Request) {
 	h.respond(w, r, http.StatusOK, account)
 }
 
+// GetAccountPostings обработчик получения проводок, из которых складывается остаток счета
+func (h *Handler) GetAccountPostings(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, errors.New("invalid account id"))
+		return
+	}
+
+	ledger, err := h.services.Ledger.GetAccountLedger(r.Context(), accountID)
+	if err != nil {
+		h.error(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, ledger)
+}
+
+type createCardRequest struct {
+	AccountID int64  `json:"account_id"`
+	Product   string `json:"product"`
+	// Только для виртуальных карт
+	SingleUse      bool `json:"single_use"`
+	MerchantLocked bool `json:"merchant_locked"`
+}
+
 // CreateCard обработчик создания карты
 func (h *Handler) CreateCard(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("Create card handler")
+	var req createCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	// Выпуск карты возможен только к своему счету
+	if _, err := h.services.Access.Account(r.Context(), currentUserID(r), req.AccountID); err != nil {
+		h.accessError(w, r, err)
+		return
+	}
+
+	if req.Product == "" {
+		req.Product = model.CardDebit
+	}
+
+	// Виртуальная карта выпускается сразу и возвращается в ответе
+	if req.Product == model.CardVirtual {
+		card, err := h.services.Cards.CreateVirtual(r.Context(), req.AccountID, req.SingleUse, req.MerchantLocked)
+		if err != nil {
+			h.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		h.respond(w, r, http.StatusCreated, card)
+		return
+	}
+
+	if req.SingleUse || req.MerchantLocked {
+		h.error(w, r, http.StatusBadRequest, service.ErrCardNotVirtual)
+		return
+	}
+
+	err := h.services.Cards.Create(r.Context(), req.AccountID, req.Product)
+	if errors.Is(err, service.ErrUnknownCardProduct) {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusCreated, nil)
 }
 
-// GetCards обработчик получения списка карт
+// GetCards обработчик получения списка карт по всем счетам пользователя
 func (h *Handler) GetCards(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("Get cards handler")
+	accounts, err := h.services.Accounts.GetByUserID(r.Context(), currentUserID(r))
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	cards := []*model.Card{}
+	for _, account := range accounts {
+		accountCards, err := h.services.Cards.GetByAccountID(r.Context(), account.ID)
+		if err != nil {
+			h.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		cards = append(cards, accountCards...)
+	}
+
+	h.respond(w, r, http.StatusOK, cards)
 }
 
 // GetCard обработчик получения информации о карте
 func (h *Handler) GetCard(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("Get card handler")
+	vars := mux.Vars(r)
+	cardID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, errors.New("invalid card id"))
+		return
+	}
+
+	card, err := h.services.Cards.GetByID(r.Context(), cardID)
+	if err != nil {
+		h.error(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, card)
+}
+
+type transferRequest struct {
+	FromAccount int64        `json:"from_account"`
+	ToAccount   int64        `json:"to_account"`
+	Amount      money.Amount `json:"amount"`
+}
+
+func (req transferRequest) validate() error {
+	if req.FromAccount <= 0 || req.ToAccount <= 0 {
+		return errors.New("from_account and to_account are required")
+	}
+	if req.FromAccount == req.ToAccount {
+		return errors.New("cannot transfer to the same account")
+	}
+	if !req.Amount.IsPositive() {
+		return errors.New("amount must be positive")
+	}
+	return nil
 }
 
 // CreateTransfer обработчик создания перевода
 func (h *Handler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("Create transfer handler")
+	var req transferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	// Списывать можно только со своего счета
+	if _, err := h.services.Access.Account(r.Context(), currentUserID(r), req.FromAccount); err != nil {
+		h.accessError(w, r, err)
+		return
+	}
+
+	// Переводы доступны только после подтверждения email
+	err := h.services.Users.RequireVerifiedEmail(r.Context(), currentUserID(r))
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		h.error(w, r, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Крупные переводы требуют подтверждения вторым фактором
+	if err := h.services.MFA.RequireStepUpForTransfer(r.Context(), currentUserID(r), currentSessionID(r), req.Amount); err != nil {
+		h.mfaError(w, r, err)
+		return
+	}
+
+	transaction, err := h.services.Transfers.Transfer(r.Context(), req.FromAccount, req.ToAccount, req.Amount)
+	if errors.Is(err, service.ErrDestinationNotFound) {
+		h.error(w, r, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, service.ErrInsufficientFunds) || errors.Is(err, service.ErrAccountFrozen) ||
+		errors.Is(err, service.ErrCurrencyMismatch) {
+		h.error(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusCreated, transaction)
+}
+
+// GetTransfer обработчик получения операции по ID
+func (h *Handler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transactionID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, errors.New("invalid transaction id"))
+		return
+	}
+
+	transaction, err := h.services.Transfers.GetByID(r.Context(), transactionID)
+	if err != nil {
+		h.error(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, transaction)
 }
 
-// CreateCredit обработчик создания кредита
-func (h *Handler) CreateCredit(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("Create credit handler")
+// GetAccountTransactions обработчик получения истории операций по счету
+func (h *Handler) GetAccountTransactions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, errors.New("invalid account id"))
+		return
+	}
+
+	filter, err := parseTransactionFilter(r.URL.Query())
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := h.services.Transfers.GetByAccountID(r.Context(), accountID, filter)
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, page)
 }
 
-// GetCreditSchedule обработчик получения графика платежей
-func (h *Handler) GetCreditSchedule(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("Get credit schedule handler")
+// parseTransactionFilter разбирает параметры limit, cursor, from, to (RFC 3339),
+// min_amount, max_amount и status
+func parseTransactionFilter(query url.Values) (model.TransactionFilter, error) {
+	var filter model.TransactionFilter
+	var err error
+
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit <= 0 {
+			return filter, errors.New("invalid limit")
+		}
+	}
+	if v := query.Get("cursor"); v != "" {
+		if filter.Cursor, err = strconv.ParseInt(v, 10, 64); err != nil || filter.Cursor <= 0 {
+			return filter, errors.New("invalid cursor")
+		}
+	}
+	if v := query.Get("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, errors.New("invalid from date")
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, errors.New("invalid to date")
+		}
+	}
+	if v := query.Get("min_amount"); v != "" {
+		if filter.MinAmount, err = money.Parse(v); err != nil || !filter.MinAmount.IsPositive() {
+			return filter, errors.New("invalid min_amount")
+		}
+	}
+	if v := query.Get("max_amount"); v != "" {
+		if filter.MaxAmount, err = money.Parse(v); err != nil || !filter.MaxAmount.IsPositive() {
+			return filter, errors.New("invalid max_amount")
+		}
+	}
+	filter.Status = query.Get("status")
+
+	return filter, nil
 }
 
 // GetAnalytics обработчик получения аналитики
@@ -206,7 +620,34 @@ func (h *Handler) GetAnalytics(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Get analytics handler")
 }
 
+type predictBalanceResponse struct {
+	AccountID int64        `json:"account_id"`
+	Days      int          `json:"days"`
+	Balance   money.Amount `json:"balance"`
+}
+
 // PredictBalance обработчик прогноза баланса
 func (h *Handler) PredictBalance(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("Predict balance handler")
+	vars := mux.Vars(r)
+	accountID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, errors.New("invalid account id"))
+		return
+	}
+
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		if days, err = strconv.Atoi(v); err != nil || days <= 0 {
+			h.error(w, r, http.StatusBadRequest, errors.New("invalid days"))
+			return
+		}
+	}
+
+	balance, err := h.services.Analytics.PredictBalance(r.Context(), accountID, days)
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, predictBalanceResponse{AccountID: accountID, Days: days, Balance: balance})
 }
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"bank-app/internal/model"
+	"bank-app/internal/service"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayHeader   = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	maxIdempotentBodySize    = 1 << 20
+	maxIdempotencyPathLength = 255
+)
+
+// responseRecorder передает ответ клиенту и одновременно запоминает его
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	if rec.status == 0 {
+		rec.status = code
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// IdempotencyMiddleware обрабатывает заголовок Idempotency-Key для изменяющих запросов:
+// повтор с тем же ключом и телом возвращает сохраненный ответ, а тот же ключ
+// с другим телом отклоняется с кодом 422. Ответы с Cache-Control: no-store
+// содержат секреты и не сохраняются.
+func (h *Handler) IdempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" || !isMutatingMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			h.error(w, r, http.StatusBadRequest, errors.New("idempotency key is too long"))
+			return
+		}
+
+		// Усеченное тело дало бы отпечаток другого запроса, поэтому слишком
+		// большой запрос отклоняется целиком
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.error(w, r, http.StatusRequestEntityTooLarge, errors.New("request body is too large"))
+			return
+		}
+		if err != nil {
+			h.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Для публичных маршрутов пользователь не определен
+		userID, _ := r.Context().Value("userID").(int64)
+
+		record := &model.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			Method:      r.Method,
+			Path:        idempotencyPath(r),
+			RequestHash: requestFingerprint(r.Method, r.URL.RequestURI(), body),
+		}
+
+		record, isNew, err := h.services.Idempotency.Begin(r.Context(), record)
+		switch {
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			h.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		case errors.Is(err, service.ErrIdempotencyInProgress):
+			h.error(w, r, http.StatusConflict, err)
+			return
+		case err != nil:
+			h.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !isNew {
+			if record.ContentType != "" {
+				w.Header().Set("Content-Type", record.ContentType)
+			}
+			w.Header().Set(idempotentReplayHeader, "true")
+			w.WriteHeader(record.StatusCode)
+			w.Write(record.ResponseBody)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			if p := recover(); p != nil {
+				h.releaseIdempotencyKey(r, record)
+				panic(p)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		// Ошибки сервера не сохраняем, чтобы клиент мог повторить запрос.
+		// Реквизиты карт и секреты MFA не попадают в хранилище: ключ
+		// освобождается, и повтор выполняется заново.
+		if rec.status >= http.StatusInternalServerError || noStore(rec.Header()) {
+			h.releaseIdempotencyKey(r, record)
+			return
+		}
+
+		record.StatusCode = rec.status
+		record.ContentType = rec.Header().Get("Content-Type")
+		record.ResponseBody = rec.body.Bytes()
+		if err := h.services.Idempotency.Complete(r.Context(), record); err != nil {
+			h.logger.WithError(err).Error("failed to store idempotent response")
+		}
+	})
+}
+
+func (h *Handler) releaseIdempotencyKey(r *http.Request, record *model.IdempotencyRecord) {
+	if err := h.services.Idempotency.Release(r.Context(), record); err != nil {
+		h.logger.WithError(err).Error("failed to release idempotency key")
+	}
+}
+
+// noStore сообщает, запрещено ли сохранять ответ
+func noStore(header http.Header) bool {
+	return strings.Contains(header.Get("Cache-Control"), "no-store")
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// idempotencyPath - путь запроса в пределах колонки idempotency_keys.path.
+// Длинный путь усекается: полный адрес с параметрами все равно входит
+// в отпечаток запроса.
+func idempotencyPath(r *http.Request) string {
+	path := r.URL.EscapedPath()
+	if len(path) > maxIdempotencyPathLength {
+		path = path[:maxIdempotencyPathLength]
+	}
+	return path
+}
+
+// requestFingerprint - отпечаток запроса для обнаружения повторного использования ключа
+func requestFingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
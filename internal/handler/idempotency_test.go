@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+	"bank-app/internal/service"
+)
+
+type MockIdempotencyService struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyService) Begin(ctx context.Context, record *model.IdempotencyRecord) (*model.IdempotencyRecord, bool, error) {
+	args := m.Called(ctx, record)
+	return record, args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdempotencyService) Complete(ctx context.Context, record *model.IdempotencyRecord) error {
+	args := m.Called(ctx, record)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyService) Release(ctx context.Context, record *model.IdempotencyRecord) error {
+	args := m.Called(ctx, record)
+	return args.Error(0)
+}
+
+type MockMFAService struct {
+	mock.Mock
+}
+
+func (m *MockMFAService) Enroll(ctx context.Context, userID int64) (*model.MFAEnrollment, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.MFAEnrollment), args.Error(1)
+}
+
+func (m *MockMFAService) Confirm(ctx context.Context, userID int64, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockMFAService) Disable(ctx context.Context, userID int64, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) Verify(ctx context.Context, userID int64, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFAService) StepUp(ctx context.Context, userID, sessionID int64, code string) error {
+	args := m.Called(ctx, userID, sessionID, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) RequireStepUp(ctx context.Context, userID, sessionID int64) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) RequireStepUpForTransfer(ctx context.Context, userID, sessionID int64, amount money.Amount) error {
+	args := m.Called(ctx, userID, sessionID, amount)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) CreateChallenge(ctx context.Context, userID int64, client model.ClientInfo) (string, error) {
+	args := m.Called(ctx, userID, client)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockMFAService) VerifyChallenge(ctx context.Context, token, code string) (int64, error) {
+	args := m.Called(ctx, token, code)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func newTestIdempotencyHandler() (*Handler, *MockIdempotencyService) {
+	idempotency := new(MockIdempotencyService)
+	logger, _ := test.NewNullLogger()
+	return NewHandlers(&service.Services{Idempotency: idempotency}, logger), idempotency
+}
+
+func idempotentRequest(path string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader("{}"))
+	r.Header.Set(idempotencyKeyHeader, "key-1")
+	return r
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	t.Run("ответ сохраняется для повтора", func(t *testing.T) {
+		// Подготовка
+		h, idempotency := newTestIdempotencyHandler()
+		idempotency.On("Begin", mock.Anything, mock.Anything).Return(true, nil)
+		idempotency.On("Complete", mock.Anything, mock.Anything).Return(nil)
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.respond(w, r, http.StatusCreated, map[string]int64{"id": 7})
+		})
+
+		// Действие
+		w := httptest.NewRecorder()
+		h.IdempotencyMiddleware(next).ServeHTTP(w, idempotentRequest("/api/v1/accounts"))
+
+		// Проверка
+		assert.Equal(t, http.StatusCreated, w.Code)
+		idempotency.AssertCalled(t, "Complete", mock.Anything, mock.MatchedBy(func(record *model.IdempotencyRecord) bool {
+			return record.StatusCode == http.StatusCreated && strings.Contains(string(record.ResponseBody), `"id":7`)
+		}))
+	})
+
+	t.Run("реквизиты карты не сохраняются", func(t *testing.T) {
+		// Подготовка
+		h, idempotency := newTestIdempotencyHandler()
+		idempotency.On("Begin", mock.Anything, mock.Anything).Return(true, nil)
+		idempotency.On("Release", mock.Anything, mock.Anything).Return(nil)
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store")
+			h.respond(w, r, http.StatusOK, cardDetailsResponse{
+				ID:         3,
+				Number:     "4276000000000009",
+				ExpiryDate: time.Date(2029, time.March, 31, 0, 0, 0, 0, time.UTC),
+				CVV:        "123",
+			})
+		})
+
+		// Действие
+		w := httptest.NewRecorder()
+		h.IdempotencyMiddleware(next).ServeHTTP(w, idempotentRequest("/api/v1/cards/3/reveal"))
+
+		// Проверка
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "4276000000000009")
+		idempotency.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything)
+		idempotency.AssertCalled(t, "Release", mock.Anything, mock.MatchedBy(func(record *model.IdempotencyRecord) bool {
+			return record.StatusCode == 0 && len(record.ResponseBody) == 0
+		}))
+	})
+
+	t.Run("секреты MFA не сохраняются", func(t *testing.T) {
+		// Подготовка
+		h, idempotency := newTestIdempotencyHandler()
+		idempotency.On("Begin", mock.Anything, mock.Anything).Return(true, nil)
+		idempotency.On("Release", mock.Anything, mock.Anything).Return(nil)
+		mfa := new(MockMFAService)
+		mfa.On("Enroll", mock.Anything, int64(7)).Return(&model.MFAEnrollment{Secret: "JBSWY3DPEHPK3PXP", URI: "otpauth://totp/bank?secret=JBSWY3DPEHPK3PXP"}, nil)
+		h.services.MFA = mfa
+
+		// Действие
+		w := httptest.NewRecorder()
+		r := idempotentRequest("/api/v1/mfa/enroll")
+		r = r.WithContext(context.WithValue(r.Context(), "userID", int64(7)))
+		h.IdempotencyMiddleware(http.HandlerFunc(h.EnrollMFA)).ServeHTTP(w, r)
+
+		// Проверка
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "JBSWY3DPEHPK3PXP")
+		idempotency.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything)
+		idempotency.AssertCalled(t, "Release", mock.Anything, mock.Anything)
+	})
+}
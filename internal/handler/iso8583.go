@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"bank-app/internal/iso8583"
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+	"bank-app/internal/pinblock"
+	"bank-app/internal/service"
+)
+
+// Типы сообщений ISO 8583, которые принимает банк
+const (
+	mtiAuthorization = "0100"
+	mtiFinancial     = "0200"
+	mtiReversal      = "0400"
+)
+
+// isoCurrencyRUB - цифровой код рубля (поле 49)
+const isoCurrencyRUB = "643"
+
+// Коды ответа (поле 39)
+const (
+	isoApproved           = "00"
+	isoInvalidTransaction = "12"
+	isoInvalidCard        = "14"
+	isoNoOriginal         = "25"
+	isoInsufficientFunds  = "51"
+	isoInvalidPIN         = "55"
+	isoExpiredCard        = "54"
+	isoNotPermitted       = "57"
+	isoLimitExceeded      = "61"
+	isoRestrictedCard     = "62"
+	isoPINTriesExceeded   = "75"
+	isoInvalidCVV         = "82"
+	isoDuplicate          = "94"
+)
+
+// echoedFields копируются из запроса в ответ, чтобы сеть сопоставила их
+var echoedFields = []int{2, 3, 4, 7, 11, 12, 13, 32, 37, 41, 42, 49}
+
+// ISO8583 возвращает обработчик сообщений платежной сети. Все операции
+// выполняются от имени эквайрера acquirer: 0100 блокирует сумму, 0200
+// сразу подтверждает покупку, 0400 отменяет операцию по номеру из поля 37.
+// PIN-блок поля 52 расшифровывается ключом зоны pinKey.
+func (h *Handler) ISO8583(acquirer string, pinKey []byte) iso8583.HandlerFunc {
+	return func(ctx context.Context, req *iso8583.Message) *iso8583.Message {
+		mti, err := iso8583.ResponseMTI(req.MTI)
+		if err != nil {
+			return nil
+		}
+
+		resp := iso8583.NewMessage(mti)
+		for _, field := range echoedFields {
+			if req.Has(field) {
+				resp.Set(field, req.Get(field))
+			}
+		}
+
+		var auth *model.CardAuthorization
+		switch req.MTI {
+		case mtiAuthorization:
+			auth, err = h.isoAuthorize(ctx, acquirer, pinKey, req, false)
+		case mtiFinancial:
+			auth, err = h.isoAuthorize(ctx, acquirer, pinKey, req, true)
+		case mtiReversal:
+			auth, err = h.isoReverse(ctx, acquirer, req)
+		default:
+			err = fmt.Errorf("%w: unsupported MTI %s", service.ErrInvalidAuthorizationRequest, req.MTI)
+		}
+
+		code := isoResponseCode(err)
+		if code == iso8583.ResponseSystemError {
+			h.logger.WithError(err).WithField("mti", req.MTI).Error("ISO 8583 request failed")
+		}
+
+		resp.Set(39, code)
+		if err == nil && req.MTI != mtiReversal {
+			resp.Set(38, fmt.Sprintf("%06d", auth.ID%1000000))
+		}
+
+		return resp
+	}
+}
+
+func (h *Handler) isoAuthorize(ctx context.Context, acquirer string, pinKey []byte, req *iso8583.Message, capture bool) (*model.CardAuthorization, error) {
+	authReq, err := isoAuthorizationRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Has(52) {
+		if len(pinKey) == 0 {
+			return nil, errors.New("PIN block received but ISO8583_PIN_KEY is not configured")
+		}
+
+		authReq.PIN, err = pinblock.Decrypt(pinKey, []byte(req.Get(52)), authReq.CardNumber)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", service.ErrInvalidAuthorizationRequest, err)
+		}
+	}
+
+	// Покупка 0200 блокируется и подтверждается одной транзакцией, чтобы
+	// отказ в подтверждении не оставлял блокировку на счете
+	if capture {
+		return h.services.Processing.Purchase(ctx, acquirer, authReq)
+	}
+
+	return h.services.Processing.Authorize(ctx, acquirer, authReq)
+}
+
+func (h *Handler) isoReverse(ctx context.Context, acquirer string, req *iso8583.Message) (*model.CardAuthorization, error) {
+	reference := strings.TrimSpace(req.Get(37))
+	if reference == "" {
+		return nil, fmt.Errorf("%w: field 37 is required", service.ErrInvalidAuthorizationRequest)
+	}
+
+	auth, err := h.services.Processing.GetByReference(ctx, acquirer, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.services.Processing.Reverse(ctx, acquirer, auth.ID)
+}
+
+// isoAuthorizationRequest переводит поля сообщения в запрос авторизации.
+// Канал определяется по коду обработки (01 - снятие наличных) и способу ввода
+// карты (01 и 81 - без предъявления карты). CVV2 передается в поле 48.
+func isoAuthorizationRequest(req *iso8583.Message) (*model.AuthorizationRequest, error) {
+	for _, field := range []int{2, 3, 4, 37} {
+		if !req.Has(field) {
+			return nil, fmt.Errorf("%w: field %d is required", service.ErrInvalidAuthorizationRequest, field)
+		}
+	}
+
+	if currency := req.Get(49); currency != "" && currency != isoCurrencyRUB {
+		return nil, fmt.Errorf("%w: currency %s is not supported", service.ErrInvalidAuthorizationRequest, currency)
+	}
+
+	minor, err := strconv.ParseInt(req.Get(4), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid amount", service.ErrInvalidAuthorizationRequest)
+	}
+
+	channel := model.ChannelPOS
+	switch entryMode := req.Get(22); {
+	case strings.HasPrefix(req.Get(3), "01"):
+		channel = model.ChannelATM
+	case strings.HasPrefix(entryMode, "01"), strings.HasPrefix(entryMode, "81"):
+		channel = model.ChannelECommerce
+	}
+
+	return &model.AuthorizationRequest{
+		Reference:    strings.TrimSpace(req.Get(37)),
+		CardNumber:   req.Get(2),
+		CVV:          strings.TrimSpace(req.Get(48)),
+		Amount:       money.Amount(minor),
+		Channel:      channel,
+		MCC:          req.Get(18),
+		MerchantName: strings.TrimSpace(req.Get(43)),
+	}, nil
+}
+
+// isoResponseCode сопоставляет ошибку обработки коду ответа поля 39
+func isoResponseCode(err error) string {
+	switch {
+	case err == nil:
+		return isoApproved
+	case errors.Is(err, service.ErrInvalidCard):
+		return isoInvalidCard
+	case errors.Is(err, service.ErrCardExpired):
+		return isoExpiredCard
+	case errors.Is(err, service.ErrCardNotActive), errors.Is(err, service.ErrAccountFrozen):
+		return isoRestrictedCard
+	case errors.Is(err, service.ErrInvalidCVV):
+		return isoInvalidCVV
+	case errors.Is(err, service.ErrInvalidPIN), errors.Is(err, service.ErrPINNotSet):
+		return isoInvalidPIN
+	case errors.Is(err, service.ErrPINTriesExceeded):
+		return isoPINTriesExceeded
+	case errors.Is(err, service.ErrInsufficientFunds):
+		return isoInsufficientFunds
+	case errors.Is(err, service.ErrCardLimitExceeded):
+		return isoLimitExceeded
+	case errors.Is(err, service.ErrCardChannelDisabled), errors.Is(err, service.ErrCardMerchantLocked):
+		return isoNotPermitted
+	case errors.Is(err, service.ErrDuplicateReference):
+		return isoDuplicate
+	case errors.Is(err, service.ErrNotFound):
+		return isoNoOriginal
+	case errors.Is(err, service.ErrAuthorizationState), errors.Is(err, service.ErrInvalidCaptureAmount):
+		return isoInvalidTransaction
+	case errors.Is(err, service.ErrInvalidAuthorizationRequest):
+		return iso8583.ResponseFormatError
+	default:
+		return iso8583.ResponseSystemError
+	}
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"bank-app/internal/service"
+)
+
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type mfaLoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// mfaError сопоставляет ошибки двухфакторной аутентификации с кодами ответа.
+// При блокировке после серии неверных кодов сообщает, когда повторить попытку.
+func (h *Handler) mfaError(w http.ResponseWriter, r *http.Request, err error) {
+	var retry *service.RetryAfterError
+	if errors.As(err, &retry) {
+		seconds := int64(math.Ceil(retry.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+
+	switch {
+	case errors.Is(err, service.ErrInvalidMFACode), errors.Is(err, service.ErrInvalidMFAChallenge):
+		h.error(w, r, http.StatusUnauthorized, err)
+	case errors.Is(err, service.ErrMFALocked):
+		h.error(w, r, http.StatusTooManyRequests, err)
+	case errors.Is(err, service.ErrStepUpRequired), errors.Is(err, service.ErrMFAEnrollmentRequired):
+		h.error(w, r, http.StatusForbidden, err)
+	case errors.Is(err, service.ErrMFAAlreadyEnabled), errors.Is(err, service.ErrMFANotEnabled):
+		h.error(w, r, http.StatusConflict, err)
+	case errors.Is(err, service.ErrNotFound):
+		h.error(w, r, http.StatusNotFound, err)
+	default:
+		h.error(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+func currentSessionID(r *http.Request) int64 {
+	sessionID, _ := r.Context().Value("sessionID").(int64)
+	return sessionID
+}
+
+// LoginMFA обработчик второго шага входа
+func (h *Handler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req mfaLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	tokens, err := h.services.Users.CompleteMFALogin(r.Context(), req.MFAToken, req.Code, h.clientInfo(r))
+	if err != nil {
+		h.mfaError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, tokens)
+}
+
+// EnrollMFA обработчик подключения приложения-аутентификатора
+func (h *Handler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	enrollment, err := h.services.MFA.Enroll(r.Context(), currentUserID(r))
+	if err != nil {
+		h.mfaError(w, r, err)
+		return
+	}
+
+	// Секрет TOTP не должен попасть в кэши и хранилище идемпотентности
+	w.Header().Set("Cache-Control", "no-store")
+	h.respond(w, r, http.StatusOK, enrollment)
+}
+
+// ConfirmMFA обработчик включения двухфакторной аутентификации
+func (h *Handler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	codes, err := h.services.MFA.Confirm(r.Context(), currentUserID(r), req.Code)
+	if err != nil {
+		h.mfaError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.respond(w, r, http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableMFA обработчик отключения двухфакторной аутентификации
+func (h *Handler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.services.MFA.Disable(r.Context(), currentUserID(r), req.Code); err != nil {
+		h.mfaError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
+
+// StepUpMFA обработчик подтверждения второго фактора для текущей сессии
+func (h *Handler) StepUpMFA(w http.ResponseWriter, r *http.Request) {
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.services.MFA.StepUp(r.Context(), currentUserID(r), currentSessionID(r), req.Code); err != nil {
+		h.mfaError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusNoContent, nil)
+}
+
+// StepUpMiddleware требует недавнего подтверждения второго фактора в сессии
+func (h *Handler) StepUpMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h.services.MFA.RequireStepUp(r.Context(), currentUserID(r), currentSessionID(r)); err != nil {
+			h.mfaError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type cardDetailsResponse struct {
+	ID         int64     `json:"id"`
+	Number     string    `json:"number"`
+	ExpiryDate time.Time `json:"expiry_date"`
+	CVV        string    `json:"cvv,omitempty"`
+}
+
+// GetCardDetails обработчик раскрытия реквизитов карты. Требует step-up.
+func (h *Handler) GetCardDetails(w http.ResponseWriter, r *http.Request) {
+	cardID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, errors.New("invalid card id"))
+		return
+	}
+
+	card, err := h.services.Cards.GetDetails(r.Context(), cardID)
+	if err != nil {
+		h.accessError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.respond(w, r, http.StatusOK, cardDetailsResponse{
+		ID:         card.ID,
+		Number:     card.Number,
+		ExpiryDate: card.ExpiryDate,
+	})
+}
+
+// RevealCardDetails обработчик однократного раскрытия реквизитов виртуальной
+// карты вместе с CVV. Требует step-up.
+func (h *Handler) RevealCardDetails(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	card, err := h.services.Cards.RevealDetails(r.Context(), id)
+	switch {
+	case errors.Is(err, service.ErrCardNotVirtual):
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	case errors.Is(err, service.ErrCardDetailsRevealed), errors.Is(err, service.ErrCardNotActive):
+		h.error(w, r, http.StatusConflict, err)
+		return
+	case err != nil:
+		h.accessError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.respond(w, r, http.StatusOK, cardDetailsResponse{
+		ID:         card.ID,
+		Number:     card.Number,
+		ExpiryDate: card.ExpiryDate,
+		CVV:        card.CVV,
+	})
+}
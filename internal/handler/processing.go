@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+	"bank-app/internal/service"
+)
+
+type amountRequest struct {
+	Amount money.Amount `json:"amount"`
+}
+
+// APIKeyMiddleware проверяет ключ API эквайрера в заголовке X-API-Key
+func (h *Handler) APIKeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acquirer, err := h.services.Processing.AuthenticateAcquirer(r.Header.Get("X-API-Key"))
+		if err != nil {
+			h.error(w, r, http.StatusUnauthorized, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "acquirer", acquirer)))
+	})
+}
+
+// currentAcquirer возвращает эквайрера, выполнившего запрос
+func currentAcquirer(r *http.Request) string {
+	return r.Context().Value("acquirer").(string)
+}
+
+// processingError отвечает 402 на отказ в авторизации, 409 на операцию,
+// недопустимую в текущем статусе, и 400 на некорректный запрос
+func (h *Handler) processingError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCard), errors.Is(err, service.ErrCardExpired),
+		errors.Is(err, service.ErrCardNotActive), errors.Is(err, service.ErrInvalidCVV),
+		errors.Is(err, service.ErrInsufficientFunds), errors.Is(err, service.ErrAccountFrozen),
+		errors.Is(err, service.ErrCardLimitExceeded), errors.Is(err, service.ErrCardChannelDisabled),
+		errors.Is(err, service.ErrCardMerchantLocked), errors.Is(err, service.ErrInvalidPIN),
+		errors.Is(err, service.ErrPINNotSet), errors.Is(err, service.ErrPINTriesExceeded):
+		h.error(w, r, http.StatusPaymentRequired, err)
+	case errors.Is(err, service.ErrAuthorizationState), errors.Is(err, service.ErrDuplicateReference):
+		h.error(w, r, http.StatusConflict, err)
+	case errors.Is(err, service.ErrInvalidAuthorizationRequest), errors.Is(err, service.ErrInvalidCaptureAmount),
+		errors.Is(err, service.ErrInvalidRefundAmount):
+		h.error(w, r, http.StatusBadRequest, err)
+	default:
+		h.accessError(w, r, err)
+	}
+}
+
+// AuthorizeCard обработчик авторизации покупки по карте
+func (h *Handler) AuthorizeCard(w http.ResponseWriter, r *http.Request) {
+	var req model.AuthorizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	auth, err := h.services.Processing.Authorize(r.Context(), currentAcquirer(r), &req)
+	if err != nil {
+		h.processingError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusCreated, auth)
+}
+
+// GetAuthorization обработчик получения авторизации
+func (h *Handler) GetAuthorization(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	auth, err := h.services.Processing.Get(r.Context(), currentAcquirer(r), id)
+	if err != nil {
+		h.processingError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, auth)
+}
+
+// CaptureAuthorization обработчик подтверждения покупки. Без суммы
+// подтверждается вся авторизованная сумма.
+func (h *Handler) CaptureAuthorization(w http.ResponseWriter, r *http.Request) {
+	h.authorizationAction(w, r, h.services.Processing.Capture)
+}
+
+// ReverseAuthorization обработчик отмены авторизации
+func (h *Handler) ReverseAuthorization(w http.ResponseWriter, r *http.Request) {
+	h.authorizationAction(w, r, func(ctx context.Context, acquirer string, id int64, _ money.Amount) (*model.CardAuthorization, error) {
+		return h.services.Processing.Reverse(ctx, acquirer, id)
+	})
+}
+
+// RefundAuthorization обработчик возврата рассчитанной покупки
+func (h *Handler) RefundAuthorization(w http.ResponseWriter, r *http.Request) {
+	h.authorizationAction(w, r, h.services.Processing.Refund)
+}
+
+// authorizationAction выполняет действие над авторизацией {id} с необязательной суммой
+func (h *Handler) authorizationAction(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, acquirer string, id int64, amount money.Amount) (*model.CardAuthorization, error)) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var req amountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	auth, err := action(r.Context(), currentAcquirer(r), id, req.Amount)
+	if err != nil {
+		h.processingError(w, r, err)
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, auth)
+}
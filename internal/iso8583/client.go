@@ -0,0 +1,77 @@
+package iso8583
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// Client отправляет запросы по одному соединению и ждет ответа на каждый.
+// Используется в тестах и имитаторах вместо платежной сети.
+type Client struct {
+	conn net.Conn
+	spec Spec
+	mu   sync.Mutex
+}
+
+// Dial подключается к серверу ISO 8583
+func Dial(ctx context.Context, addr string, spec Spec) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{conn: conn, spec: spec}, nil
+}
+
+// Exchange отправляет запрос и возвращает ответ. Срок ожидания берется из ctx.
+func (c *Client) Exchange(ctx context.Context, req *Message) (*Message, error) {
+	expected, err := ResponseMTI(req.MTI)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.spec.Pack(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Без срока в ctx нулевое время снимает ограничение
+	deadline, _ := ctx.Deadline()
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if err := WriteFrame(c.conn, data); err != nil {
+		return nil, err
+	}
+
+	frame, err := ReadFrame(c.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.spec.Unpack(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.MTI != expected {
+		return nil, fmt.Errorf("%w: expected MTI %s, got %s", ErrInvalidMessage, expected, resp.MTI)
+	}
+
+	if req.Has(11) && resp.Has(11) && resp.Get(11) != req.Get(11) {
+		return nil, fmt.Errorf("%w: response STAN %s does not match request %s", ErrInvalidMessage, resp.Get(11), req.Get(11))
+	}
+
+	return resp, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,222 @@
+package iso8583
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidMessage возвращается (в обернутом виде) при ошибке формата сообщения
+var ErrInvalidMessage = errors.New("invalid ISO 8583 message")
+
+// Message - сообщение ISO 8583: тип (MTI) и значения полей по номерам.
+// Значения хранятся без префиксов длины, двоичные поля - как есть.
+type Message struct {
+	MTI    string
+	Fields map[int]string
+}
+
+func NewMessage(mti string) *Message {
+	return &Message{MTI: mti, Fields: make(map[int]string)}
+}
+
+func (m *Message) Get(field int) string {
+	return m.Fields[field]
+}
+
+func (m *Message) Has(field int) bool {
+	_, ok := m.Fields[field]
+	return ok
+}
+
+func (m *Message) Set(field int, value string) {
+	m.Fields[field] = value
+}
+
+// ResponseMTI возвращает тип ответа на запрос: 0100 -> 0110, 0400 -> 0410
+func ResponseMTI(mti string) (string, error) {
+	if len(mti) != 4 || !isNumeric(mti) || mti[2]%2 != 0 {
+		return "", fmt.Errorf("%w: %q is not a request MTI", ErrInvalidMessage, mti)
+	}
+	return mti[:2] + string(mti[2]+1) + mti[3:], nil
+}
+
+// Pack собирает сообщение: MTI, битовая карта (вторичная - при полях больше 64) и поля
+func (s Spec) Pack(m *Message) ([]byte, error) {
+	if len(m.MTI) != 4 || !isNumeric(m.MTI) {
+		return nil, fmt.Errorf("%w: invalid MTI %q", ErrInvalidMessage, m.MTI)
+	}
+
+	numbers := make([]int, 0, len(m.Fields))
+	for number := range m.Fields {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	bitmap := make([]byte, 8)
+	if len(numbers) > 0 && numbers[len(numbers)-1] > 64 {
+		bitmap = make([]byte, 16)
+		bitmap[0] |= 0x80
+	}
+
+	var body []byte
+	for _, number := range numbers {
+		field, ok := s[number]
+		if !ok {
+			return nil, fmt.Errorf("%w: field %d is not in the spec", ErrInvalidMessage, number)
+		}
+
+		encoded, err := field.pack(m.Fields[number])
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %d: %v", ErrInvalidMessage, number, err)
+		}
+
+		bitmap[(number-1)/8] |= 0x80 >> ((number - 1) % 8)
+		body = append(body, encoded...)
+	}
+
+	packed := append([]byte(m.MTI), bitmap...)
+	return append(packed, body...), nil
+}
+
+// Unpack разбирает сообщение. Поля, отсутствующие в спецификации, считаются ошибкой.
+func (s Spec) Unpack(data []byte) (*Message, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("%w: message is too short", ErrInvalidMessage)
+	}
+
+	m := NewMessage(string(data[:4]))
+	if !isNumeric(m.MTI) {
+		return nil, fmt.Errorf("%w: invalid MTI %q", ErrInvalidMessage, m.MTI)
+	}
+
+	bitmap := data[4:12]
+	pos := 12
+	if bitmap[0]&0x80 != 0 {
+		if len(data) < 20 {
+			return nil, fmt.Errorf("%w: secondary bitmap is truncated", ErrInvalidMessage)
+		}
+		bitmap = data[4:20]
+		pos = 20
+	}
+
+	for number := 2; number <= len(bitmap)*8; number++ {
+		if bitmap[(number-1)/8]&(0x80>>((number-1)%8)) == 0 {
+			continue
+		}
+
+		field, ok := s[number]
+		if !ok {
+			return nil, fmt.Errorf("%w: field %d is not in the spec", ErrInvalidMessage, number)
+		}
+
+		value, n, err := field.unpack(data[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %d: %v", ErrInvalidMessage, number, err)
+		}
+		m.Fields[number] = value
+		pos += n
+	}
+
+	if pos != len(data) {
+		return nil, fmt.Errorf("%w: %d unexpected trailing bytes", ErrInvalidMessage, len(data)-pos)
+	}
+
+	return m, nil
+}
+
+func (f FieldSpec) pack(value string) ([]byte, error) {
+	if err := f.checkCharset(value); err != nil {
+		return nil, err
+	}
+
+	if len(value) > f.Length {
+		return nil, fmt.Errorf("value is longer than %d", f.Length)
+	}
+
+	switch f.Kind {
+	case LLVar:
+		return []byte(fmt.Sprintf("%02d%s", len(value), value)), nil
+	case LLLVar:
+		return []byte(fmt.Sprintf("%03d%s", len(value), value)), nil
+	}
+
+	// Фиксированные числовые поля дополняются нулями слева, текстовые - пробелами справа
+	pad := f.Length - len(value)
+	switch f.Charset {
+	case Numeric:
+		value = strings.Repeat("0", pad) + value
+	case Binary:
+		if pad != 0 {
+			return nil, fmt.Errorf("binary value must be %d bytes", f.Length)
+		}
+	default:
+		value += strings.Repeat(" ", pad)
+	}
+
+	return []byte(value), nil
+}
+
+func (f FieldSpec) unpack(data []byte) (string, int, error) {
+	length, prefix := f.Length, 0
+	switch f.Kind {
+	case LLVar:
+		prefix = 2
+	case LLLVar:
+		prefix = 3
+	}
+
+	if prefix > 0 {
+		if len(data) < prefix {
+			return "", 0, errors.New("length prefix is truncated")
+		}
+
+		n, err := strconv.Atoi(string(data[:prefix]))
+		if err != nil || n > f.Length {
+			return "", 0, fmt.Errorf("invalid length %q", data[:prefix])
+		}
+		length = n
+	}
+
+	if len(data) < prefix+length {
+		return "", 0, errors.New("value is truncated")
+	}
+
+	value := string(data[prefix : prefix+length])
+	if err := f.checkCharset(value); err != nil {
+		return "", 0, err
+	}
+
+	return value, prefix + length, nil
+}
+
+func (f FieldSpec) checkCharset(value string) error {
+	for _, c := range []byte(value) {
+		var ok bool
+		switch f.Charset {
+		case Numeric:
+			ok = c >= '0' && c <= '9'
+		case Alphanumeric:
+			ok = c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == ' '
+		case AlphanumericSpecial:
+			ok = c >= 0x20 && c <= 0x7e
+		case Binary:
+			ok = true
+		}
+		if !ok {
+			return fmt.Errorf("invalid character %q for charset %s", c, f.Charset)
+		}
+	}
+	return nil
+}
+
+func isNumeric(value string) bool {
+	for _, c := range []byte(value) {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
@@ -0,0 +1,164 @@
+package iso8583
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpec_PackUnpack(t *testing.T) {
+	t.Run("сообщение с первичной битовой картой", func(t *testing.T) {
+		// Подготовка
+		msg := NewMessage("0100")
+		msg.Set(2, "4276000000000009")
+		msg.Set(3, "0")
+		msg.Set(4, "150000")
+		msg.Set(37, "ABC123")
+		msg.Set(43, "Shop")
+
+		// Действие
+		data, err := DefaultSpec.Pack(msg)
+		require.NoError(t, err)
+		got, err := DefaultSpec.Unpack(data)
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, "0100", got.MTI)
+		assert.Equal(t, []byte{0x70, 0, 0, 0, 0x08, 0x20, 0, 0}, data[4:12])
+		assert.Equal(t, "164276000000000009", string(data[12:30]))
+		assert.Equal(t, "4276000000000009", got.Get(2))
+		assert.Equal(t, "000000", got.Get(3))
+		assert.Equal(t, "000000150000", got.Get(4))
+		assert.Equal(t, "ABC123      ", got.Get(37))
+		assert.Len(t, got.Get(43), 40)
+	})
+
+	t.Run("вторичная битовая карта", func(t *testing.T) {
+		// Подготовка
+		msg := NewMessage("0400")
+		msg.Set(11, "123456")
+		msg.Set(90, "0100123456")
+
+		// Действие
+		data, err := DefaultSpec.Pack(msg)
+		require.NoError(t, err)
+		got, err := DefaultSpec.Unpack(data)
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, byte(0x80), data[4]&0x80)
+		assert.Len(t, data, 4+16+6+42)
+		assert.Equal(t, "123456", got.Get(11))
+		assert.Len(t, got.Get(90), 42)
+		assert.False(t, got.Has(1))
+	})
+
+	t.Run("двоичное поле", func(t *testing.T) {
+		msg := NewMessage("0200")
+		msg.Set(52, "\x01\x02\x03\x04\x05\x06\x07\x08")
+
+		data, err := DefaultSpec.Pack(msg)
+		require.NoError(t, err)
+		got, err := DefaultSpec.Unpack(data)
+
+		require.NoError(t, err)
+		assert.Equal(t, "\x01\x02\x03\x04\x05\x06\x07\x08", got.Get(52))
+	})
+
+	t.Run("ошибки упаковки", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			field int
+			value string
+		}{
+			{name: "буквы в числовом поле", field: 4, value: "12a"},
+			{name: "слишком длинное значение", field: 2, value: "12345678901234567890"},
+			{name: "поле вне спецификации", field: 5, value: "1"},
+			{name: "двоичное поле неполной длины", field: 52, value: "\x01"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				msg := NewMessage("0100")
+				msg.Set(tt.field, tt.value)
+
+				_, err := DefaultSpec.Pack(msg)
+
+				assert.ErrorIs(t, err, ErrInvalidMessage)
+			})
+		}
+	})
+
+	t.Run("ошибки разбора", func(t *testing.T) {
+		msg := NewMessage("0100")
+		msg.Set(2, "4276000000000009")
+		msg.Set(11, "000001")
+		data, err := DefaultSpec.Pack(msg)
+		require.NoError(t, err)
+
+		tests := []struct {
+			name string
+			data []byte
+		}{
+			{name: "короткое сообщение", data: data[:10]},
+			{name: "обрезанное поле", data: data[:len(data)-1]},
+			{name: "лишние байты", data: append(append([]byte{}, data...), '0')},
+			{name: "нечисловой MTI", data: append([]byte("01A0"), data[4:]...)},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := DefaultSpec.Unpack(tt.data)
+
+				assert.ErrorIs(t, err, ErrInvalidMessage)
+			})
+		}
+	})
+}
+
+func TestResponseMTI(t *testing.T) {
+	for request, response := range map[string]string{"0100": "0110", "0200": "0210", "0400": "0410"} {
+		got, err := ResponseMTI(request)
+		require.NoError(t, err)
+		assert.Equal(t, response, got)
+	}
+
+	_, err := ResponseMTI("0110")
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func TestLoadSpec(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("спецификация из файла", func(t *testing.T) {
+		path := filepath.Join(dir, "spec.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"2": {"length": 19, "kind": "llvar", "charset": "n"}, "39": {"length": 2, "kind": "fixed", "charset": "an"}}`), 0o600))
+
+		spec, err := LoadSpec(path)
+
+		require.NoError(t, err)
+		assert.Equal(t, Spec{
+			2:  {Length: 19, Kind: LLVar, Charset: Numeric},
+			39: {Length: 2, Kind: Fixed, Charset: Alphanumeric},
+		}, spec)
+	})
+
+	t.Run("некорректное поле", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"2": {"length": 120, "kind": "llvar", "charset": "n"}}`), 0o600))
+
+		_, err := LoadSpec(path)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("пустой путь", func(t *testing.T) {
+		spec, err := LoadSpec("")
+
+		require.NoError(t, err)
+		assert.Equal(t, DefaultSpec, spec)
+	})
+}
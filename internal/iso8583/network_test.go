@@ -0,0 +1,267 @@
+package iso8583_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bank-app/internal/handler"
+	"bank-app/internal/iso8583"
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+	"bank-app/internal/pinblock"
+	"bank-app/internal/service"
+)
+
+// testPINKey - ключ зоны 3DES, которым шифруются PIN-блоки в тестах
+var testPINKey = bytes.Repeat([]byte{0x0f, 0xe1}, 8)
+
+// fakeProcessing одобряет операции по карте 4276000000000009 с CVV 123 или
+// PIN 4821 на сумму до 1000 и хранит авторизации в памяти
+type fakeProcessing struct {
+	service.ProcessingService
+	auths    map[string]*model.CardAuthorization
+	requests []*model.AuthorizationRequest
+}
+
+func (f *fakeProcessing) Authorize(ctx context.Context, acquirer string, req *model.AuthorizationRequest) (*model.CardAuthorization, error) {
+	f.requests = append(f.requests, req)
+	switch {
+	case req.CardNumber != "4276000000000009":
+		return nil, service.ErrInvalidCard
+	case req.PIN != "" && req.PIN != "4821":
+		return nil, service.ErrInvalidPIN
+	case req.PIN == "" && req.CVV != "123":
+		return nil, service.ErrInvalidCVV
+	case req.Amount > money.Units(1000):
+		return nil, service.ErrInsufficientFunds
+	}
+
+	auth := &model.CardAuthorization{
+		ID:        int64(len(f.auths) + 1),
+		Acquirer:  acquirer,
+		Reference: req.Reference,
+		Amount:    req.Amount,
+		Status:    model.AuthorizationApproved,
+	}
+	f.auths[req.Reference] = auth
+	return auth, nil
+}
+
+func (f *fakeProcessing) Purchase(ctx context.Context, acquirer string, req *model.AuthorizationRequest) (*model.CardAuthorization, error) {
+	auth, err := f.Authorize(ctx, acquirer, req)
+	if err != nil {
+		return nil, err
+	}
+	return f.Capture(ctx, acquirer, auth.ID, 0)
+}
+
+func (f *fakeProcessing) GetByReference(ctx context.Context, acquirer, reference string) (*model.CardAuthorization, error) {
+	auth, ok := f.auths[reference]
+	if !ok || auth.Acquirer != acquirer {
+		return nil, service.ErrNotFound
+	}
+	return auth, nil
+}
+
+func (f *fakeProcessing) Capture(ctx context.Context, acquirer string, id int64, amount money.Amount) (*model.CardAuthorization, error) {
+	auth := f.byID(id)
+	auth.CapturedAmount = auth.Amount
+	auth.Status = model.AuthorizationCaptured
+	return auth, nil
+}
+
+func (f *fakeProcessing) Reverse(ctx context.Context, acquirer string, id int64) (*model.CardAuthorization, error) {
+	auth := f.byID(id)
+	auth.Status = model.AuthorizationReversed
+	return auth, nil
+}
+
+func (f *fakeProcessing) byID(id int64) *model.CardAuthorization {
+	for _, auth := range f.auths {
+		if auth.ID == id {
+			return auth
+		}
+	}
+	return nil
+}
+
+// dialNetwork запускает прием сообщений через обработчики API и подключает к нему клиента
+func dialNetwork(t *testing.T, processing *fakeProcessing) *iso8583.Client {
+	handlers := handler.NewHandlers(&service.Services{Processing: processing}, logrus.New())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		iso8583.NewServer(iso8583.DefaultSpec, handlers.ISO8583("network", testPINKey), logrus.New(), time.Minute).Serve(ctx, listener)
+	}()
+
+	client, err := iso8583.Dial(ctx, listener.Addr().String(), iso8583.DefaultSpec)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		client.Close()
+		cancel()
+		<-done
+	})
+
+	return client
+}
+
+func newPurchase(mti, rrn, amount string) *iso8583.Message {
+	msg := iso8583.NewMessage(mti)
+	msg.Set(2, "4276000000000009")
+	msg.Set(3, "000000")
+	msg.Set(4, amount)
+	msg.Set(11, "000042")
+	msg.Set(18, "5411")
+	msg.Set(22, "051")
+	msg.Set(37, rrn)
+	msg.Set(41, "TERM0001")
+	msg.Set(43, "Corner Shop")
+	msg.Set(48, "123")
+	msg.Set(49, "643")
+	return msg
+}
+
+func TestNetwork(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	processing := &fakeProcessing{auths: make(map[string]*model.CardAuthorization)}
+	client := dialNetwork(t, processing)
+
+	t.Run("авторизация 0100", func(t *testing.T) {
+		// Действие
+		resp, err := client.Exchange(ctx, newPurchase("0100", "RRN000000001", "95050"))
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, "0110", resp.MTI)
+		assert.Equal(t, "00", resp.Get(39))
+		assert.Equal(t, "000001", resp.Get(38))
+		assert.Equal(t, "000042", resp.Get(11))
+		assert.Equal(t, "RRN000000001", resp.Get(37))
+
+		req := processing.requests[len(processing.requests)-1]
+		assert.Equal(t, money.MustParse("950.50"), req.Amount)
+		assert.Equal(t, model.ChannelPOS, req.Channel)
+		assert.Equal(t, "Corner Shop", req.MerchantName)
+		assert.Equal(t, "5411", req.MCC)
+	})
+
+	t.Run("покупка 0200 в интернете", func(t *testing.T) {
+		msg := newPurchase("0200", "RRN000000002", "50000")
+		msg.Set(22, "810")
+
+		resp, err := client.Exchange(ctx, msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, "0210", resp.MTI)
+		assert.Equal(t, "00", resp.Get(39))
+		assert.Equal(t, model.AuthorizationCaptured, processing.auths["RRN000000002"].Status)
+		assert.Equal(t, model.ChannelECommerce, processing.requests[len(processing.requests)-1].Channel)
+	})
+
+	t.Run("отмена 0400", func(t *testing.T) {
+		resp, err := client.Exchange(ctx, newPurchase("0400", "RRN000000002", "50000"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "0410", resp.MTI)
+		assert.Equal(t, "00", resp.Get(39))
+		assert.Equal(t, model.AuthorizationReversed, processing.auths["RRN000000002"].Status)
+	})
+
+	t.Run("снятие наличных с PIN", func(t *testing.T) {
+		msg := newPurchase("0200", "RRN000000007", "30000")
+		msg.Set(3, "010000")
+		delete(msg.Fields, 48)
+		block, err := pinblock.EncryptFormat0(testPINKey, "4821", "4276000000000009")
+		require.NoError(t, err)
+		msg.Set(52, string(block))
+
+		resp, err := client.Exchange(ctx, msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, "00", resp.Get(39))
+		req := processing.requests[len(processing.requests)-1]
+		assert.Equal(t, "4821", req.PIN)
+		assert.Equal(t, model.ChannelATM, req.Channel)
+	})
+
+	t.Run("коды отказа", func(t *testing.T) {
+		tests := []struct {
+			name string
+			msg  func() *iso8583.Message
+			code string
+		}{
+			{
+				name: "недостаточно средств",
+				msg:  func() *iso8583.Message { return newPurchase("0100", "RRN000000003", "200000") },
+				code: "51",
+			},
+			{
+				name: "неверный CVV",
+				msg: func() *iso8583.Message {
+					msg := newPurchase("0100", "RRN000000004", "100")
+					msg.Set(48, "999")
+					return msg
+				},
+				code: "82",
+			},
+			{
+				name: "неверный PIN",
+				msg: func() *iso8583.Message {
+					msg := newPurchase("0100", "RRN000000008", "100")
+					block, _ := pinblock.EncryptFormat0(testPINKey, "1111", "4276000000000009")
+					msg.Set(52, string(block))
+					return msg
+				},
+				code: "55",
+			},
+			{
+				name: "неизвестная карта",
+				msg: func() *iso8583.Message {
+					msg := newPurchase("0100", "RRN000000005", "100")
+					msg.Set(2, "4276000000000017")
+					return msg
+				},
+				code: "14",
+			},
+			{
+				name: "отмена неизвестной операции",
+				msg:  func() *iso8583.Message { return newPurchase("0400", "RRN999999999", "100") },
+				code: "25",
+			},
+			{
+				name: "валюта не поддерживается",
+				msg: func() *iso8583.Message {
+					msg := newPurchase("0100", "RRN000000006", "100")
+					msg.Set(49, "840")
+					return msg
+				},
+				code: iso8583.ResponseFormatError,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp, err := client.Exchange(ctx, tt.msg())
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.code, resp.Get(39))
+				assert.False(t, resp.Has(38))
+			})
+		}
+	})
+}
@@ -0,0 +1,227 @@
+package iso8583
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MaxFrameSize - наибольшая длина сообщения при двухбайтовом префиксе
+const MaxFrameSize = 1<<16 - 1
+
+// Коды ответа (поле 39), которые формирует сам сервер
+const (
+	ResponseFormatError = "30"
+	ResponseSystemError = "96"
+)
+
+// ReadFrame читает сообщение с префиксом длины (2 байта, big-endian)
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var prefix [2]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint16(prefix[:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// WriteFrame записывает сообщение с префиксом длины
+func WriteFrame(w io.Writer, data []byte) error {
+	if len(data) > MaxFrameSize {
+		return fmt.Errorf("message of %d bytes exceeds frame size", len(data))
+	}
+
+	frame := make([]byte, 2+len(data))
+	binary.BigEndian.PutUint16(frame, uint16(len(data)))
+	copy(frame[2:], data)
+	_, err := w.Write(frame)
+	return err
+}
+
+// HandlerFunc обрабатывает запрос и возвращает ответ
+type HandlerFunc func(ctx context.Context, req *Message) *Message
+
+// Server принимает соединения и обрабатывает сообщения каждого соединения
+// параллельно: сеть сопоставляет ответы с запросами по полям 11 и 37.
+type Server struct {
+	spec        Spec
+	handler     HandlerFunc
+	logger      *logrus.Logger
+	idleTimeout time.Duration
+	wg          sync.WaitGroup
+}
+
+// NewServer создает сервер. Соединение без сообщений дольше idleTimeout
+// закрывается, нулевое значение отключает ограничение.
+func NewServer(spec Spec, handler HandlerFunc, logger *logrus.Logger, idleTimeout time.Duration) *Server {
+	return &Server{
+		spec:        spec,
+		handler:     handler,
+		logger:      logger,
+		idleTimeout: idleTimeout,
+	}
+}
+
+// ListenAndServe слушает адрес addr до отмены ctx
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, listener)
+}
+
+// Serve принимает соединения до отмены ctx, затем закрывает их и ждет
+// завершения начатых обработок
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	var (
+		mu    sync.Mutex
+		conns = make(map[net.Conn]bool)
+	)
+
+	stop := context.AfterFunc(ctx, func() {
+		listener.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for conn := range conns {
+			conn.Close()
+		}
+	})
+	defer stop()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.wg.Wait()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		mu.Lock()
+		conns[conn] = true
+		mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(ctx, conn)
+
+			mu.Lock()
+			delete(conns, conn)
+			mu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	logger := s.logger.WithField("remote", conn.RemoteAddr().String())
+
+	var (
+		writeMu  sync.Mutex
+		requests sync.WaitGroup
+	)
+	defer requests.Wait()
+
+	for {
+		if s.idleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.idleTimeout))
+		}
+
+		data, err := ReadFrame(conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				logger.WithError(err).Warn("ISO 8583 connection closed")
+			}
+			return
+		}
+
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+
+			resp := s.handle(ctx, data, logger)
+			if resp == nil {
+				return
+			}
+
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if err := WriteFrame(conn, resp); err != nil {
+				logger.WithError(err).Warn("failed to write ISO 8583 response")
+			}
+		}()
+	}
+}
+
+// handle разбирает запрос, вызывает обработчик и собирает ответ. На
+// неразборчивый запрос с понятным MTI отвечает кодом 30.
+func (s *Server) handle(ctx context.Context, data []byte, logger *logrus.Entry) []byte {
+	req, err := s.spec.Unpack(data)
+	if err != nil {
+		logger.WithError(err).Warn("invalid ISO 8583 request")
+		return s.reject(data, ResponseFormatError, logger)
+	}
+
+	// Начатая операция доводится до конца и при остановке сервера
+	resp := s.safeHandle(context.WithoutCancel(ctx), req, logger)
+	if resp == nil {
+		return s.reject(data, ResponseSystemError, logger)
+	}
+
+	packed, err := s.spec.Pack(resp)
+	if err != nil {
+		logger.WithError(err).Error("failed to pack ISO 8583 response")
+		return s.reject(data, ResponseSystemError, logger)
+	}
+
+	return packed
+}
+
+func (s *Server) safeHandle(ctx context.Context, req *Message, logger *logrus.Entry) (resp *Message) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.WithField("panic", r).Error("ISO 8583 handler panicked")
+			resp = nil
+		}
+	}()
+
+	return s.handler(ctx, req)
+}
+
+// reject формирует ответ только с кодом ответа, если MTI запроса удалось прочитать
+func (s *Server) reject(data []byte, code string, logger *logrus.Entry) []byte {
+	if len(data) < 4 {
+		return nil
+	}
+
+	mti, err := ResponseMTI(string(data[:4]))
+	if err != nil {
+		return nil
+	}
+
+	resp := NewMessage(mti)
+	resp.Set(39, code)
+	packed, err := s.spec.Pack(resp)
+	if err != nil {
+		logger.WithError(err).Error("failed to pack ISO 8583 rejection")
+		return nil
+	}
+
+	return packed
+}
@@ -0,0 +1,95 @@
+package iso8583
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer запускает сервер на свободном локальном порту и возвращает его адрес
+func startServer(t *testing.T, handler HandlerFunc) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer(DefaultSpec, handler, logrus.New(), time.Minute).Serve(ctx, listener)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	return listener.Addr().String()
+}
+
+func TestServer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	addr := startServer(t, func(ctx context.Context, req *Message) *Message {
+		if req.Get(4) == "000000000666" {
+			panic("unexpected amount")
+		}
+
+		mti, _ := ResponseMTI(req.MTI)
+		resp := NewMessage(mti)
+		resp.Set(11, req.Get(11))
+		resp.Set(39, "00")
+		return resp
+	})
+
+	client, err := Dial(ctx, addr, DefaultSpec)
+	require.NoError(t, err)
+	defer client.Close()
+
+	t.Run("ответ на запрос", func(t *testing.T) {
+		req := NewMessage("0100")
+		req.Set(4, "100")
+		req.Set(11, "000001")
+
+		resp, err := client.Exchange(ctx, req)
+
+		require.NoError(t, err)
+		assert.Equal(t, "0110", resp.MTI)
+		assert.Equal(t, "000001", resp.Get(11))
+		assert.Equal(t, "00", resp.Get(39))
+	})
+
+	t.Run("сбой обработчика", func(t *testing.T) {
+		req := NewMessage("0200")
+		req.Set(4, "666")
+
+		resp, err := client.Exchange(ctx, req)
+
+		require.NoError(t, err)
+		assert.Equal(t, "0210", resp.MTI)
+		assert.Equal(t, ResponseSystemError, resp.Get(39))
+	})
+
+	t.Run("неразборчивое сообщение", func(t *testing.T) {
+		// Подготовка: поле 2 заявлено в битовой карте, но не передано
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+		// Действие
+		require.NoError(t, WriteFrame(conn, append([]byte("0100"), 0x40, 0, 0, 0, 0, 0, 0, 0)))
+		frame, err := ReadFrame(conn)
+
+		// Проверка
+		require.NoError(t, err)
+		resp, err := DefaultSpec.Unpack(frame)
+		require.NoError(t, err)
+		assert.Equal(t, "0110", resp.MTI)
+		assert.Equal(t, ResponseFormatError, resp.Get(39))
+	})
+}
@@ -0,0 +1,120 @@
+// Package iso8583 разбирает и собирает сообщения ISO 8583 (версия 1987) и
+// передает их по TCP с двухбайтовым префиксом длины. Формат полей задается
+// спецификацией Spec: стандартная встроена, свою можно загрузить из JSON.
+package iso8583
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Способы кодирования длины поля
+const (
+	Fixed  = "fixed"
+	LLVar  = "llvar"
+	LLLVar = "lllvar"
+)
+
+// Наборы символов поля
+const (
+	Numeric             = "n"
+	Alphanumeric        = "an"
+	AlphanumericSpecial = "ans"
+	Binary              = "b"
+)
+
+// FieldSpec описывает поле: для Fixed Length - точная длина, для LLVar и
+// LLLVar - максимальная. Длина двоичных полей задается в байтах.
+type FieldSpec struct {
+	Length  int    `json:"length"`
+	Kind    string `json:"kind"`
+	Charset string `json:"charset"`
+}
+
+// Spec сопоставляет номер поля (2-128) и его формат
+type Spec map[int]FieldSpec
+
+// DefaultSpec - поля, используемые при авторизации покупок
+var DefaultSpec = Spec{
+	2:  {Length: 19, Kind: LLVar, Charset: Numeric},
+	3:  {Length: 6, Kind: Fixed, Charset: Numeric},
+	4:  {Length: 12, Kind: Fixed, Charset: Numeric},
+	7:  {Length: 10, Kind: Fixed, Charset: Numeric},
+	11: {Length: 6, Kind: Fixed, Charset: Numeric},
+	12: {Length: 6, Kind: Fixed, Charset: Numeric},
+	13: {Length: 4, Kind: Fixed, Charset: Numeric},
+	14: {Length: 4, Kind: Fixed, Charset: Numeric},
+	18: {Length: 4, Kind: Fixed, Charset: Numeric},
+	22: {Length: 3, Kind: Fixed, Charset: Numeric},
+	32: {Length: 11, Kind: LLVar, Charset: Numeric},
+	37: {Length: 12, Kind: Fixed, Charset: Alphanumeric},
+	38: {Length: 6, Kind: Fixed, Charset: Alphanumeric},
+	39: {Length: 2, Kind: Fixed, Charset: Alphanumeric},
+	41: {Length: 8, Kind: Fixed, Charset: AlphanumericSpecial},
+	42: {Length: 15, Kind: Fixed, Charset: AlphanumericSpecial},
+	43: {Length: 40, Kind: Fixed, Charset: AlphanumericSpecial},
+	48: {Length: 999, Kind: LLLVar, Charset: AlphanumericSpecial},
+	49: {Length: 3, Kind: Fixed, Charset: Numeric},
+	52: {Length: 8, Kind: Fixed, Charset: Binary},
+	90: {Length: 42, Kind: Fixed, Charset: Numeric},
+}
+
+// LoadSpec читает спецификацию из JSON-файла вида {"2": {"length": 19, "kind": "llvar", "charset": "n"}}.
+// Пустой путь означает DefaultSpec.
+func LoadSpec(path string) (Spec, error) {
+	if path == "" {
+		return DefaultSpec, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]FieldSpec
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse ISO 8583 spec %s: %w", path, err)
+	}
+
+	spec := make(Spec, len(raw))
+	for key, field := range raw {
+		number, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ISO 8583 field number %q", key)
+		}
+		spec[number] = field
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// Validate проверяет номера, длины и типы полей
+func (s Spec) Validate() error {
+	for number, field := range s {
+		if number < 2 || number > 128 {
+			return fmt.Errorf("field %d: number must be between 2 and 128", number)
+		}
+
+		switch field.Charset {
+		case Numeric, Alphanumeric, AlphanumericSpecial, Binary:
+		default:
+			return fmt.Errorf("field %d: unknown charset %q", number, field.Charset)
+		}
+
+		max := map[string]int{Fixed: 999, LLVar: 99, LLLVar: 999}[field.Kind]
+		if max == 0 {
+			return fmt.Errorf("field %d: unknown length kind %q", number, field.Kind)
+		}
+		if field.Length <= 0 || field.Length > max {
+			return fmt.Errorf("field %d: length must be between 1 and %d", number, max)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,278 @@
+// Package jwtkeys управляет ключами подписи JWT: одним активным ключом,
+// которым подписываются новые токены, и набором ключей для проверки,
+// что позволяет менять ключи без простоя.
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Поддерживаемые алгоритмы подписи
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// DefaultKeyID - идентификатор ключа, построенного из общего секрета HS256
+const DefaultKeyID = "default"
+
+var (
+	ErrUnknownKey      = errors.New("unknown signing key")
+	ErrUnexpectedAlg   = errors.New("unexpected signing algorithm")
+	ErrVerifyOnlyKey   = errors.New("key cannot be used for signing")
+	ErrUnsupportedType = errors.New("unsupported key type")
+)
+
+// Key - ключ подписи или проверки с идентификатором kid
+type Key struct {
+	ID        string
+	Algorithm string
+	signing   interface{}
+	verifying interface{}
+}
+
+// NewHMACKey создает симметричный ключ HS256. Такой ключ не публикуется в JWKS.
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Algorithm: HS256, signing: secret, verifying: secret}
+}
+
+// NewKey создает ключ из закрытого или открытого ключа RSA либо Ed25519.
+// Ключ с одним только открытым ключом используется лишь для проверки.
+func NewKey(id string, key interface{}) (*Key, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: id, Algorithm: RS256, signing: k, verifying: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: id, Algorithm: RS256, verifying: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Algorithm: EdDSA, signing: k, verifying: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Algorithm: EdDSA, verifying: k}, nil
+	default:
+		return nil, fmt.Errorf("key %q: %w %T", id, ErrUnsupportedType, key)
+	}
+}
+
+// ParsePEM разбирает ключ в формате PEM: PKCS#8 или PKCS#1 для закрытых ключей,
+// PKIX для открытых
+func ParsePEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q: no PEM data found", id)
+	}
+
+	var (
+		key interface{}
+		err error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %q: unsupported PEM block %q", id, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", id, err)
+	}
+
+	return NewKey(id, key)
+}
+
+// CanSign сообщает, есть ли у ключа закрытая часть
+func (k *Key) CanSign() bool {
+	return k.signing != nil
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case RS256:
+		return jwt.SigningMethodRS256
+	case EdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+// KeySet - активный ключ подписи и все ключи, принимаемые при проверке
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// NewKeySet собирает набор ключей. Ключ activeID должен уметь подписывать,
+// остальные ключи используются только для проверки ранее выданных токенов.
+func NewKeySet(activeID string, keys ...*Key) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*Key, len(keys))}
+	for _, key := range keys {
+		if _, ok := set.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+
+	active, ok := set.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q: %w", activeID, ErrUnknownKey)
+	}
+	if !active.CanSign() {
+		return nil, fmt.Errorf("active key %q: %w", activeID, ErrVerifyOnlyKey)
+	}
+	set.active = active
+
+	return set, nil
+}
+
+// Load загружает ключи из PEM-файлов (kid -> путь). Если файлы не заданы,
+// используется общий секрет HS256 с идентификатором DefaultKeyID. Рядом с
+// файлами заданный секрет остается ключом только для проверки, чтобы токены,
+// выданные до перехода на ключи из файлов, действовали до истечения срока.
+func Load(activeID string, files map[string]string, secret string) (*KeySet, error) {
+	if len(files) == 0 {
+		if secret == "" {
+			return nil, errors.New("jwt secret or signing keys must be configured")
+		}
+		return NewKeySet(DefaultKeyID, NewHMACKey(DefaultKeyID, []byte(secret)))
+	}
+
+	keys := make([]*Key, 0, len(files))
+	for id, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+
+		key, err := ParsePEM(id, data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if secret != "" {
+		keys = append(keys, &Key{ID: DefaultKeyID, Algorithm: HS256, verifying: []byte(secret)})
+	}
+
+	return NewKeySet(activeID, keys...)
+}
+
+// Sign подписывает утверждения активным ключом и указывает его kid в заголовке
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.active.method(), claims)
+	token.Header["kid"] = s.active.ID
+	return token.SignedString(s.active.signing)
+}
+
+// Parse проверяет токен ключом из заголовка kid. Алгоритм токена должен
+// совпадать с алгоритмом ключа, иначе возможна подмена алгоритма.
+func (s *KeySet) Parse(tokenString string, claims jwt.Claims, options ...jwt.ParserOption) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, s.keyFunc, options...)
+}
+
+func (s *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		// Токены, выданные до введения kid, подписаны секретом по умолчанию
+		kid = DefaultKeyID
+	}
+
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, ErrUnexpectedAlg
+	}
+
+	return key.verifying, nil
+}
+
+// ActiveKeyID возвращает kid ключа, которым подписываются новые токены
+func (s *KeySet) ActiveKeyID() string {
+	return s.active.ID
+}
+
+// JWK - открытый ключ в формате RFC 7517
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKS - набор открытых ключей для /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые части асимметричных ключей. Симметричные ключи
+// не публикуются: по ним можно не только проверить, но и выпустить токен.
+func (s *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range ids {
+		if jwk, ok := s.keys[id].jwk(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+
+	return jwks
+}
+
+func (k *Key) jwk() (JWK, bool) {
+	jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+
+	switch pub := k.verifying.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encodeSegment(pub.N.Bytes())
+		jwk.E = encodeSegment(bigEndian(pub.E))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encodeSegment(pub)
+	default:
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// bigEndian кодирует экспоненту RSA без ведущих нулей, как требует RFC 7518
+func bigEndian(n int) []byte {
+	var b []byte
+	for n > 0 {
+		b = append([]byte{byte(n)}, b...)
+		n >>= 8
+	}
+	return b
+}
@@ -0,0 +1,180 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Subject:   "1",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	oldKey, _ := NewKey("2025-07", rsaKey)
+	newKey, _ := NewKey("2026-01", edKey)
+
+	// Подготовка: токен выдан старым ключом
+	before, err := NewKeySet("2025-07", oldKey)
+	require.NoError(t, err)
+	token, err := before.Sign(testClaims())
+	require.NoError(t, err)
+
+	// Действие: новый ключ стал активным, старый остался для проверки
+	after, err := NewKeySet("2026-01", oldKey, newKey)
+	require.NoError(t, err)
+
+	// Проверка
+	var claims jwt.RegisteredClaims
+	_, err = after.Parse(token, &claims)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", claims.Subject)
+
+	fresh, err := after.Sign(testClaims())
+	require.NoError(t, err)
+	parsed, err := after.Parse(fresh, &jwt.RegisteredClaims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-01", parsed.Header["kid"])
+	assert.Equal(t, EdDSA, parsed.Method.Alg())
+
+	// После удаления старого ключа его токены отклоняются
+	retired, err := NewKeySet("2026-01", newKey)
+	require.NoError(t, err)
+	_, err = retired.Parse(token, &jwt.RegisteredClaims{})
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeySet_Parse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, _ := NewKey("rsa", rsaKey)
+	set, err := NewKeySet("rsa", key)
+	require.NoError(t, err)
+
+	t.Run("подмена алгоритма", func(t *testing.T) {
+		// Подготовка: HS256 с открытым ключом RSA в качестве секрета
+		pub := x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+		token.Header["kid"] = "rsa"
+		forged, err := token.SignedString(pub)
+		require.NoError(t, err)
+
+		// Действие
+		_, err = set.Parse(forged, &jwt.RegisteredClaims{})
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrUnexpectedAlg)
+	})
+
+	t.Run("токен без kid проверяется ключом по умолчанию", func(t *testing.T) {
+		// Подготовка
+		legacy, err := NewKeySet(DefaultKeyID, NewHMACKey(DefaultKeyID, []byte("secret")))
+		require.NoError(t, err)
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte("secret"))
+		require.NoError(t, err)
+
+		// Действие
+		_, err = legacy.Parse(token, &jwt.RegisteredClaims{})
+
+		// Проверка
+		assert.NoError(t, err)
+	})
+}
+
+func TestNewKeySet(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	verifyOnly, _ := NewKey("pub", edKey.Public())
+
+	_, err = NewKeySet("pub", verifyOnly)
+	assert.ErrorIs(t, err, ErrVerifyOnlyKey)
+
+	_, err = NewKeySet("missing", verifyOnly)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaJWK, _ := NewKey("a-rsa", rsaKey)
+	edJWK, _ := NewKey("b-ed", edPub)
+	set, err := NewKeySet("a-rsa", rsaJWK, edJWK, NewHMACKey("c-hmac", []byte("secret")))
+	require.NoError(t, err)
+
+	jwks := set.JWKS()
+
+	// Симметричный ключ не публикуется
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "RSA", jwks.Keys[0].KeyType)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+	assert.Equal(t, RS256, jwks.Keys[0].Algorithm)
+	assert.Equal(t, "OKP", jwks.Keys[1].KeyType)
+	assert.Equal(t, "Ed25519", jwks.Keys[1].Curve)
+	assert.Equal(t, encodeSegment(edPub), jwks.Keys[1].X)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "current.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	pubDer, err := x509.MarshalPKIXPublicKey(edKey.Public())
+	require.NoError(t, err)
+	pubPath := filepath.Join(dir, "previous.pub.pem")
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer}), 0o600))
+
+	set, err := Load("current", map[string]string{"current": path, "previous": pubPath}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "current", set.ActiveKeyID())
+	assert.Len(t, set.JWKS().Keys, 2)
+
+	// Токены, подписанные секретом до перехода на ключи из файлов, принимаются,
+	// но новые токены секретом не подписываются
+	legacy, err := Load("", nil, "secret")
+	require.NoError(t, err)
+	legacyToken, err := legacy.Sign(jwt.RegisteredClaims{Subject: "1"})
+	require.NoError(t, err)
+
+	withSecret, err := Load("current", map[string]string{"current": path}, "secret")
+	require.NoError(t, err)
+	_, err = withSecret.Parse(legacyToken, &jwt.RegisteredClaims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "current", withSecret.ActiveKeyID())
+	assert.Len(t, withSecret.JWKS().Keys, 1)
+
+	_, err = set.Parse(legacyToken, &jwt.RegisteredClaims{})
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	_, err = Load("default", map[string]string{"current": path}, "secret")
+	assert.ErrorIs(t, err, ErrVerifyOnlyKey)
+
+	fallback, err := Load("", nil, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultKeyID, fallback.ActiveKeyID())
+}
@@ -0,0 +1,160 @@
+// Package mailer отправляет шаблонные письма через SMTP-сервер из конфигурации
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"embed"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"text/template"
+	"time"
+
+	"bank-app/internal/config"
+)
+
+// Template - имя шаблона письма
+type Template string
+
+const (
+	VerifyEmail   Template = "verify_email"
+	PasswordReset Template = "password_reset"
+	AccountLocked Template = "account_locked"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// Mailer отправляет письмо по шаблону
+type Mailer interface {
+	Send(ctx context.Context, to string, tmpl Template, data interface{}) error
+}
+
+// Message - готовое к отправке письмо
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Render заполняет шаблон. Шаблон задает блоки "subject" и "body".
+func Render(tmpl Template, data interface{}) (*Message, error) {
+	t, err := template.ParseFS(templateFiles, "templates/"+string(tmpl)+".tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("mail template %q: %w", tmpl, err)
+	}
+
+	var subject, body bytes.Buffer
+	if err := t.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := t.ExecuteTemplate(&body, "body", data); err != nil {
+		return nil, err
+	}
+
+	return &Message{Subject: subject.String(), Body: body.String()}, nil
+}
+
+// SMTPMailer отправляет письма через SMTP. STARTTLS и аутентификация
+// используются, если сервер их поддерживает и заданы учетные данные.
+type SMTPMailer struct {
+	cfg     config.SMTPConfig
+	timeout time.Duration
+}
+
+func NewSMTPMailer(cfg config.SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{cfg: cfg, timeout: 30 * time.Second}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, to string, tmpl Template, data interface{}) error {
+	msg, err := Render(tmpl, data)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+
+	return m.deliver(ctx, msg)
+}
+
+func (m *SMTPMailer) deliver(ctx context.Context, msg *Message) error {
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return err
+		}
+	}
+
+	if m.cfg.Username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			auth := smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+			if err := client.Auth(auth); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := client.Mail(m.cfg.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(m.format(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// format собирает письмо в формате RFC 5322. Тема кодируется по RFC 2047,
+// тело - quoted-printable, чтобы кириллица проходила через любые серверы.
+func (m *SMTPMailer) format(msg *Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(msg.Body))
+	qp.Close()
+
+	return buf.Bytes()
+}
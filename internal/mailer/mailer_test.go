@@ -0,0 +1,82 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bank-app/internal/config"
+	"bank-app/internal/mailer/smtptest"
+)
+
+func TestSMTPMailer_Send(t *testing.T) {
+	server, err := smtptest.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+
+	mailer := NewSMTPMailer(config.SMTPConfig{
+		Host:     server.Host,
+		Port:     server.Port,
+		Username: "bank",
+		Password: "secret",
+		From:     "no-reply@bank-app.local",
+	})
+
+	data := struct {
+		Username  string
+		Link      string
+		ExpiresAt time.Time
+	}{
+		Username:  "Иван",
+		Link:      "http://localhost:8080/api/v1/email/verify?token=abc.def",
+		ExpiresAt: time.Date(2026, 1, 2, 15, 4, 0, 0, time.UTC),
+	}
+
+	// Действие
+	err = mailer.Send(context.Background(), "ivan@example.com", VerifyEmail, data)
+
+	// Проверка
+	require.NoError(t, err)
+
+	messages := server.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "no-reply@bank-app.local", messages[0].From)
+	assert.Equal(t, []string{"ivan@example.com"}, messages[0].To)
+	assert.Len(t, server.AuthCredentials(), 1)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(messages[0].Data))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Подтвердите адрес электронной почты", subject)
+
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "Здравствуйте, Иван!")
+	assert.Contains(t, string(body), data.Link)
+	assert.Contains(t, string(body), "02.01.2026 15:04 UTC")
+}
+
+func TestRender(t *testing.T) {
+	msg, err := Render(PasswordReset, map[string]interface{}{
+		"Username":  "anna",
+		"Link":      "http://localhost/reset",
+		"ExpiresAt": time.Now(),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Восстановление пароля", msg.Subject)
+	assert.Contains(t, msg.Body, "http://localhost/reset")
+
+	_, err = Render("missing", nil)
+	assert.Error(t, err)
+}
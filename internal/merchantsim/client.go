@@ -0,0 +1,156 @@
+// Package merchantsim имитирует эквайрера: отправляет в API приема операций
+// по картам авторизации, подтверждения, отмены и возвраты. Используется
+// командой cmd/merchantsim и тестами вместо настоящей платежной сети.
+package merchantsim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// APIError - ответ API с кодом ошибки. Отказ в авторизации приходит с кодом 402.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("processing API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Declined сообщает, что банк отказал в операции
+func (e *APIError) Declined() bool {
+	return e.StatusCode == http.StatusPaymentRequired
+}
+
+// Client обращается к API приема операций по картам от имени эквайрера
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// New создает клиента для API по адресу baseURL (например, http://localhost:8080)
+func New(baseURL, apiKey string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/") + "/api/v1/processing",
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// WithHTTPClient заменяет HTTP-клиент, например на клиент httptest.Server
+func (c *Client) WithHTTPClient(client *http.Client) *Client {
+	c.http = client
+	return c
+}
+
+func (c *Client) Authorize(ctx context.Context, req *model.AuthorizationRequest) (*model.CardAuthorization, error) {
+	return c.do(ctx, http.MethodPost, "/authorizations", req)
+}
+
+func (c *Client) Get(ctx context.Context, id int64) (*model.CardAuthorization, error) {
+	return c.do(ctx, http.MethodGet, fmt.Sprintf("/authorizations/%d", id), nil)
+}
+
+// Capture подтверждает покупку. Нулевая сумма подтверждает всю авторизацию.
+func (c *Client) Capture(ctx context.Context, id int64, amount money.Amount) (*model.CardAuthorization, error) {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/authorizations/%d/capture", id), amountBody(amount))
+}
+
+func (c *Client) Reverse(ctx context.Context, id int64) (*model.CardAuthorization, error) {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/authorizations/%d/reverse", id), nil)
+}
+
+func (c *Client) Refund(ctx context.Context, id int64, amount money.Amount) (*model.CardAuthorization, error) {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/authorizations/%d/refund", id), amountBody(amount))
+}
+
+// Purchase выполняет покупку целиком: авторизацию и подтверждение суммы capture
+// (нулевая - вся сумма)
+func (c *Client) Purchase(ctx context.Context, req *model.AuthorizationRequest, capture money.Amount) (*model.CardAuthorization, error) {
+	auth, err := c.Authorize(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Capture(ctx, auth.ID, capture)
+}
+
+func amountBody(amount money.Amount) interface{} {
+	if amount == 0 {
+		return nil
+	}
+	return map[string]money.Amount{"amount": amount}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body interface{}) (*model.CardAuthorization, error) {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, &payload)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-API-Key", c.apiKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Data  json.RawMessage `json:"data"`
+		Error string          `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("decode response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: errorMessage(envelope.Error, envelope.Data)}
+	}
+
+	var auth model.CardAuthorization
+	if len(envelope.Data) == 0 {
+		return nil, fmt.Errorf("empty response (status %d)", resp.StatusCode)
+	}
+	if err := json.Unmarshal(envelope.Data, &auth); err != nil {
+		return nil, fmt.Errorf("decode authorization: %w", err)
+	}
+
+	return &auth, nil
+}
+
+// errorMessage достает текст ошибки: API вкладывает его в поле data
+func errorMessage(message string, data json.RawMessage) string {
+	if message != "" {
+		return message
+	}
+
+	var nested struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &nested) == nil {
+		return nested.Error
+	}
+	return ""
+}
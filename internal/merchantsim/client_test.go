@@ -0,0 +1,167 @@
+package merchantsim
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bank-app/internal/handler"
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+	"bank-app/internal/service"
+)
+
+// fakeProcessing одобряет покупки до 1000 и хранит авторизации в памяти
+type fakeProcessing struct {
+	auths map[int64]*model.CardAuthorization
+}
+
+func (f *fakeProcessing) AuthenticateAcquirer(apiKey string) (string, error) {
+	if apiKey != "key" {
+		return "", service.ErrInvalidAPIKey
+	}
+	return "shop", nil
+}
+
+func (f *fakeProcessing) Authorize(ctx context.Context, acquirer string, req *model.AuthorizationRequest) (*model.CardAuthorization, error) {
+	if req.Amount > money.Units(1000) {
+		return nil, service.ErrInsufficientFunds
+	}
+	auth := &model.CardAuthorization{
+		ID:        int64(len(f.auths) + 1),
+		Acquirer:  acquirer,
+		Reference: req.Reference,
+		Amount:    req.Amount,
+		Status:    model.AuthorizationApproved,
+	}
+	f.auths[auth.ID] = auth
+	return auth, nil
+}
+
+func (f *fakeProcessing) Purchase(ctx context.Context, acquirer string, req *model.AuthorizationRequest) (*model.CardAuthorization, error) {
+	auth, err := f.Authorize(ctx, acquirer, req)
+	if err != nil {
+		return nil, err
+	}
+	return f.Capture(ctx, acquirer, auth.ID, 0)
+}
+
+func (f *fakeProcessing) Get(ctx context.Context, acquirer string, id int64) (*model.CardAuthorization, error) {
+	auth, ok := f.auths[id]
+	if !ok {
+		return nil, service.ErrNotFound
+	}
+	return auth, nil
+}
+
+func (f *fakeProcessing) GetByReference(ctx context.Context, acquirer, reference string) (*model.CardAuthorization, error) {
+	return nil, service.ErrNotFound
+}
+
+func (f *fakeProcessing) Capture(ctx context.Context, acquirer string, id int64, amount money.Amount) (*model.CardAuthorization, error) {
+	auth, err := f.Get(ctx, acquirer, id)
+	if err != nil {
+		return nil, err
+	}
+	if amount == 0 {
+		amount = auth.Amount
+	}
+	auth.CapturedAmount = amount
+	auth.Status = model.AuthorizationCaptured
+	return auth, nil
+}
+
+func (f *fakeProcessing) Reverse(ctx context.Context, acquirer string, id int64) (*model.CardAuthorization, error) {
+	auth, err := f.Get(ctx, acquirer, id)
+	if err != nil {
+		return nil, err
+	}
+	auth.Status = model.AuthorizationReversed
+	return auth, nil
+}
+
+func (f *fakeProcessing) Refund(ctx context.Context, acquirer string, id int64, amount money.Amount) (*model.CardAuthorization, error) {
+	return nil, service.ErrAuthorizationState
+}
+
+func (f *fakeProcessing) Settle(ctx context.Context) error {
+	return nil
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	handlers := handler.NewHandlers(&service.Services{
+		Processing: &fakeProcessing{auths: make(map[int64]*model.CardAuthorization)},
+	}, logrus.New())
+
+	router := mux.NewRouter()
+	processing := router.PathPrefix("/api/v1/processing").Subrouter()
+	processing.Use(handlers.APIKeyMiddleware)
+	processing.HandleFunc("/authorizations", handlers.AuthorizeCard).Methods(http.MethodPost)
+	processing.HandleFunc("/authorizations/{id:[0-9]+}", handlers.GetAuthorization).Methods(http.MethodGet)
+	processing.HandleFunc("/authorizations/{id:[0-9]+}/capture", handlers.CaptureAuthorization).Methods(http.MethodPost)
+	processing.HandleFunc("/authorizations/{id:[0-9]+}/reverse", handlers.ReverseAuthorization).Methods(http.MethodPost)
+	processing.HandleFunc("/authorizations/{id:[0-9]+}/refund", handlers.RefundAuthorization).Methods(http.MethodPost)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	client := New(server.URL, "key").WithHTTPClient(server.Client())
+
+	newRequest := func(amount int64) *model.AuthorizationRequest {
+		return &model.AuthorizationRequest{Reference: "r", CardNumber: "4276000000000009", Amount: money.Units(amount), Channel: model.ChannelPOS}
+	}
+
+	t.Run("покупка с частичным подтверждением", func(t *testing.T) {
+		auth, err := client.Purchase(ctx, newRequest(700), money.Units(500))
+
+		require.NoError(t, err)
+		assert.Equal(t, model.AuthorizationCaptured, auth.Status)
+		assert.Equal(t, money.Units(500), auth.CapturedAmount)
+	})
+
+	t.Run("отмена авторизации", func(t *testing.T) {
+		auth, err := client.Authorize(ctx, newRequest(100))
+		require.NoError(t, err)
+
+		auth, err = client.Reverse(ctx, auth.ID)
+
+		require.NoError(t, err)
+		assert.Equal(t, model.AuthorizationReversed, auth.Status)
+	})
+
+	t.Run("отказ в авторизации", func(t *testing.T) {
+		_, err := client.Authorize(ctx, newRequest(5000))
+
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.True(t, apiErr.Declined())
+		assert.Equal(t, service.ErrInsufficientFunds.Error(), apiErr.Message)
+	})
+
+	t.Run("недопустимая операция", func(t *testing.T) {
+		_, err := client.Refund(ctx, 1, money.Units(10))
+
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	})
+
+	t.Run("неверный ключ API", func(t *testing.T) {
+		_, err := New(server.URL, "wrong").WithHTTPClient(server.Client()).Get(ctx, 1)
+
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	})
+}
@@ -2,66 +2,621 @@ package model
 
 import (
 	"time"
+
+	"bank-app/internal/money"
 )
 
 type User struct {
-	ID           int64     `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              int64      `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	PasswordHash    string     `json:"-"`
+	Role            string     `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// Счетчик неудачных входов и временная блокировка
+	FailedLogins      int        `json:"-"`
+	LastFailedLoginAt *time.Time `json:"-"`
+	LockedUntil       *time.Time `json:"locked_until,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
 type Account struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	Number    string    `json:"number"`
-	Balance   float64   `json:"balance"`
-	Currency  string    `json:"currency"`
+	ID      int64        `json:"id"`
+	UserID  int64        `json:"user_id"`
+	Number  string       `json:"number"`
+	Balance money.Amount `json:"balance"`
+	// Held - средства, заблокированные под неоплаченные авторизации по картам
+	Held     money.Amount   `json:"held"`
+	Currency money.Currency `json:"currency"`
+	Status   string         `json:"status"`
+	// TimeZone - часовой пояс IANA, в котором считаются календарные лимиты карт
+	TimeZone  string    `json:"time_zone"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Available возвращает остаток, доступный для расходов
+func (a *Account) Available() money.Amount {
+	return a.Balance - a.Held
+}
+
 type Card struct {
-	ID         int64     `json:"id"`
-	AccountID  int64     `json:"account_id"`
-	Number     string    `json:"number"`
+	ID        int64 `json:"id"`
+	AccountID int64 `json:"account_id"`
+	// MaskedNumber - номер со скрытыми средними цифрами, только он попадает в ответы API
+	MaskedNumber string `json:"number"`
+	// Number и CVV в открытом виде известны только при выпуске и раскрытии реквизитов
+	Number     string    `json:"-"`
 	CVV        string    `json:"-"`
 	ExpiryDate time.Time `json:"expiry_date"`
+	Product    string    `json:"product"`
 	Status     string    `json:"status"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	// ReplacesCardID - карта, взамен которой перевыпущена эта
+	ReplacesCardID int64 `json:"replaces_card_id,omitempty"`
+	// SingleUse - виртуальная карта закрывается после первой одобренной авторизации
+	SingleUse bool `json:"single_use"`
+	// MerchantLocked - виртуальная карта принимается только торговцем
+	// LockedMerchant, за которым закрепляется при первой авторизации
+	MerchantLocked bool   `json:"merchant_locked"`
+	LockedMerchant string `json:"locked_merchant,omitempty"`
+	// DetailsRevealedAt - когда клиенту были однократно раскрыты реквизиты виртуальной карты
+	DetailsRevealedAt *time.Time `json:"details_revealed_at,omitempty"`
+	// Защищенные реквизиты в том виде, в котором они хранятся в базе
+	NumberHMAC    string    `json:"-"`
+	CVVHash       string    `json:"-"`
+	EncryptedData string    `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// CardExpiryDate возвращает последний день месяца, до которого действует карта
+func CardExpiryDate(year int, month time.Month) time.Time {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
 }
 
 type Transaction struct {
-	ID            int64     `json:"id"`
-	FromAccountID int64     `json:"from_account_id"`
-	ToAccountID   int64     `json:"to_account_id"`
-	Amount        float64   `json:"amount"`
-	Status        string    `json:"status"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            int64        `json:"id"`
+	FromAccountID int64        `json:"from_account_id"`
+	ToAccountID   int64        `json:"to_account_id"`
+	Amount        money.Amount `json:"amount"`
+	Type          string       `json:"type"`
+	Status        string       `json:"status"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
 }
 
 type Credit struct {
-	ID             int64     `json:"id"`
-	UserID         int64     `json:"user_id"`
-	AccountID      int64     `json:"account_id"`
-	Amount         float64   `json:"amount"`
-	Term           int       `json:"term"`
-	MonthlyPayment float64   `json:"monthly_payment"`
-	Status         string    `json:"status"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID        int64        `json:"id"`
+	UserID    int64        `json:"user_id"`
+	AccountID int64        `json:"account_id"`
+	Amount    money.Amount `json:"amount"`
+	// InterestRate - годовая ставка в процентах
+	InterestRate float64 `json:"interest_rate"`
+	Product      string  `json:"product"`
+	Term         int     `json:"term"`
+	ScheduleType string  `json:"schedule_type"`
+	DayCount     string  `json:"day_count"`
+	// MonthlyPayment - платеж по аннуитету или первый, наибольший платеж
+	// дифференцированного графика
+	MonthlyPayment money.Amount `json:"monthly_payment"`
+	Status         string       `json:"status"`
+	NextPaymentAt  time.Time    `json:"next_payment_at"`
+	// ScheduleVersion - номер действующей редакции графика платежей
+	ScheduleVersion int `json:"schedule_version"`
+	// PrepaidAt - дата последнего досрочного погашения: с нее начисляются
+	// проценты по следующему платежу
+	PrepaidAt *time.Time `json:"prepaid_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 }
 
+// CreditTerms - запрошенные условия кредита. Пустые тип графика и соглашение
+// о днях означают аннуитет и 30/360.
+type CreditTerms struct {
+	Product      string       `json:"product"`
+	Amount       money.Amount `json:"amount"`
+	Term         int          `json:"term"`
+	ScheduleType string       `json:"schedule_type"`
+	DayCount     string       `json:"day_count"`
+}
+
+// CreditQuote - расчет кредита без оформления: график и переплата
+type CreditQuote struct {
+	CreditTerms
+	// InterestRate - ключевая ставка KeyRate плюс надбавка продукта Margin, %
+	InterestRate   float64         `json:"interest_rate"`
+	KeyRate        float64         `json:"key_rate"`
+	Margin         float64         `json:"margin"`
+	MonthlyPayment money.Amount    `json:"monthly_payment"`
+	TotalPayment   money.Amount    `json:"total_payment"`
+	Overpayment    money.Amount    `json:"overpayment"`
+	Payments       []*QuotePayment `json:"payments"`
+}
+
+// QuotePayment - строка графика в расчете кредита и в редакции графика
+type QuotePayment struct {
+	Date      time.Time    `json:"date"`
+	Amount    money.Amount `json:"amount"`
+	Principal money.Amount `json:"principal"`
+	Interest  money.Amount `json:"interest"`
+}
+
+// PaymentSchedule - платеж по графику. Amount складывается из погашаемого
+// основного долга Principal и процентов Interest за период. PaidAmount -
+// внесенная часть платежа, Penalty - неустойка за просрочку, начисленная по
+// PenaltyAccruedTo.
 type PaymentSchedule struct {
+	ID               int64        `json:"id"`
+	CreditID         int64        `json:"credit_id"`
+	Date             time.Time    `json:"date"`
+	Amount           money.Amount `json:"amount"`
+	Principal        money.Amount `json:"principal"`
+	Interest         money.Amount `json:"interest"`
+	PaidAmount       money.Amount `json:"paid_amount"`
+	Penalty          money.Amount `json:"penalty"`
+	PenaltyPaid      money.Amount `json:"penalty_paid"`
+	PenaltyAccruedTo *time.Time   `json:"penalty_accrued_to,omitempty"`
+	Status           string       `json:"status"`
+	PaidAt           *time.Time   `json:"paid_at,omitempty"`
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+}
+
+// Outstanding возвращает непогашенную часть платежа без неустойки
+func (p *PaymentSchedule) Outstanding() money.Amount {
+	return p.Amount - p.PaidAmount
+}
+
+// PenaltyDue возвращает непогашенную неустойку
+func (p *PaymentSchedule) PenaltyDue() money.Amount {
+	return p.Penalty - p.PenaltyPaid
+}
+
+// Статусы кредита. Кредит с неоплаченным в срок платежом просрочен, после
+// погашения последнего платежа закрывается.
+const (
+	CreditActive  = "active"
+	CreditOverdue = "overdue"
+	CreditClosed  = "closed"
+)
+
+// Типы графика погашения
+const (
+	ScheduleAnnuity        = "annuity"
+	ScheduleDifferentiated = "differentiated"
+)
+
+// Статусы платежа по графику. Платеж, не погашенный в срок, становится
+// просроченным или частично оплаченным, если списана его часть.
+const (
+	PaymentPending       = "pending"
+	PaymentPartiallyPaid = "partially_paid"
+	PaymentPaid          = "paid"
+	PaymentOverdue       = "overdue"
+)
+
+// Продукты кредита. Надбавка к ключевой ставке задается для каждого продукта.
+const (
+	CreditConsumer = "consumer"
+	CreditMortgage = "mortgage"
+	CreditAuto     = "auto"
+)
+
+// KeyRate - ключевая ставка ЦБ РФ в процентах, действующая с EffectiveDate.
+// FetchedAt - время последнего запроса ставки к ЦБ.
+type KeyRate struct {
+	EffectiveDate time.Time `json:"effective_date"`
+	Rate          float64   `json:"rate"`
+	FetchedAt     time.Time `json:"fetched_at"`
+}
+
+// Prepayment - заявка на досрочное погашение кредита
+type Prepayment struct {
+	Amount money.Amount `json:"amount"`
+	Mode   string       `json:"mode"`
+}
+
+// ScheduleVersion - редакция графика платежей. Principal и Interest -
+// основной долг и начисленные на дату погашения проценты, погашенные
+// досрочным платежом Prepayment. Payments содержит весь график редакции,
+// включая платежи, внесенные до ее составления.
+type ScheduleVersion struct {
+	ID         int64           `json:"id"`
+	CreditID   int64           `json:"credit_id"`
+	Version    int             `json:"version"`
+	Mode       string          `json:"mode"`
+	Prepayment money.Amount    `json:"prepayment"`
+	Principal  money.Amount    `json:"principal"`
+	Interest   money.Amount    `json:"interest"`
+	Payments   []*QuotePayment `json:"payments"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Основания редакции графика: выдача кредита, досрочное погашение с
+// сокращением срока или платежа и полное досрочное погашение
+const (
+	ScheduleInitial         = "initial"
+	PrepaymentShortenTerm   = "shorten_term"
+	PrepaymentReducePayment = "reduce_payment"
+	PrepaymentFull          = "full"
+)
+
+// Направления проводок
+const (
+	PostingDebit  = "debit"
+	PostingCredit = "credit"
+)
+
+// Счета главной книги. Клиентские счета учитываются как пассив банка:
+// кредит увеличивает остаток, дебет уменьшает.
+const (
+	LedgerCustomer       = "customer"
+	LedgerLoans          = "loans"
+	LedgerInterestIncome = "interest_income"
+	LedgerPenaltyIncome  = "penalty_income"
+	LedgerCash           = "cash"
+	// LedgerCardSettlement - расчеты с эквайрерами по операциям с картами
+	LedgerCardSettlement = "card_settlement"
+	// LedgerOpeningBalance - капитал банка, с которого перенесены остатки
+	// счетов, открытых до ведения журнала
+	LedgerOpeningBalance = "opening_balance"
+)
+
+type LedgerEntry struct {
+	ID            int64      `json:"id"`
+	TransactionID int64      `json:"transaction_id,omitempty"`
+	Description   string     `json:"description"`
+	Postings      []*Posting `json:"postings"`
+	CreatedAt     time.Time  `json:"created_at"`
+	// Preauthorized - списание по сумме, заблокированной до заморозки счета
+	Preauthorized bool `json:"-"`
+}
+
+type Posting struct {
+	ID            int64        `json:"id"`
+	EntryID       int64        `json:"entry_id"`
+	LedgerAccount string       `json:"ledger_account"`
+	AccountID     int64        `json:"account_id,omitempty"`
+	Direction     string       `json:"direction"`
+	Amount        money.Amount `json:"amount"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// AccountLedger - остаток счета вместе с проводками, из которых он складывается
+type AccountLedger struct {
+	AccountID     int64        `json:"account_id"`
+	Balance       money.Amount `json:"balance"`
+	LedgerBalance money.Amount `json:"ledger_balance"`
+	Reconciled    bool         `json:"reconciled"`
+	Postings      []*Posting   `json:"postings"`
+}
+
+// IdempotencyRecord - сохраненный результат запроса с заголовком Idempotency-Key
+type IdempotencyRecord struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"user_id,omitempty"`
+	Key          string    `json:"key"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	RequestHash  string    `json:"request_hash"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	ContentType  string    `json:"content_type,omitempty"`
+	ResponseBody []byte    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Completed сообщает, сохранен ли уже ответ на запрос
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
+
+// TransactionFilter - фильтры и курсор для истории операций по счету.
+// Нулевые значения полей означают отсутствие фильтра.
+type TransactionFilter struct {
+	From      time.Time
+	To        time.Time
+	MinAmount money.Amount
+	MaxAmount money.Amount
+	Status    string
+	Cursor    int64
+	Limit     int
+}
+
+type TransactionPage struct {
+	Transactions []*Transaction `json:"transactions"`
+	NextCursor   string         `json:"next_cursor,omitempty"`
+}
+
+// Роли пользователей
+const (
+	RoleCustomer = "customer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// Статусы счета
+const (
+	AccountActive = "active"
+	AccountFrozen = "frozen"
+)
+
+// DefaultTimeZone - часовой пояс новых счетов
+const DefaultTimeZone = "Europe/Moscow"
+
+// Продукты карт
+const (
+	CardDebit   = "debit"
+	CardCredit  = "credit"
+	CardVirtual = "virtual"
+)
+
+// Статусы карты. Перевыпущенная карта создается в статусе issued и начинает
+// действовать после активации.
+const (
+	CardIssued             = "issued"
+	CardActive             = "active"
+	CardBlockedTemporarily = "blocked_temporarily"
+	CardBlockedLost        = "blocked_lost"
+	CardClosed             = "closed"
+	CardExpired            = "expired"
+)
+
+// CardStatusChange - запись истории статусов карты. ChangedBy равен нулю
+// для изменений, выполненных фоновыми задачами.
+type CardStatusChange struct {
+	ID         int64     `json:"id"`
+	CardID     int64     `json:"card_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Reason     string    `json:"reason"`
+	ChangedBy  int64     `json:"changed_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CardPIN - PIN карты в виде bcrypt-хеша и число неверных попыток ввода подряд
+type CardPIN struct {
+	CardID         int64
+	Hash           string
+	FailedAttempts int
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Каналы операций по карте
+const (
+	ChannelPOS       = "pos"
+	ChannelECommerce = "ecommerce"
+	ChannelATM       = "atm"
+)
+
+// CardLimit - ограничение расходов по карте. Пустые Channel и MCC означают
+// любой канал и любую категорию торговца, нулевая сумма - отсутствие лимита.
+// Disabled запрещает операции в канале и категории целиком. Дневной и месячный
+// лимиты считаются по календарю в часовом поясе счета.
+type CardLimit struct {
+	ID             int64        `json:"id"`
+	CardID         int64        `json:"card_id"`
+	Channel        string       `json:"channel,omitempty"`
+	MCC            string       `json:"mcc,omitempty"`
+	PerTransaction money.Amount `json:"per_transaction"`
+	Daily          money.Amount `json:"daily"`
+	Monthly        money.Amount `json:"monthly"`
+	Disabled       bool         `json:"disabled"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// Matches сообщает, распространяется ли лимит на операцию
+func (l *CardLimit) Matches(spend *CardSpend) bool {
+	return (l.Channel == "" || l.Channel == spend.Channel) && (l.MCC == "" || l.MCC == spend.MCC)
+}
+
+// CardSpend - расходная операция по карте, проверяемая по лимитам
+type CardSpend struct {
+	CardID  int64
+	Channel string
+	MCC     string
+	Amount  money.Amount
+	At      time.Time
+}
+
+// Статусы авторизации по карте. Средства блокируются на счете при
+// авторизации и списываются при расчете подтвержденной суммы.
+const (
+	AuthorizationApproved = "authorized"
+	AuthorizationCaptured = "captured"
+	AuthorizationSettled  = "settled"
+	AuthorizationReversed = "reversed"
+	AuthorizationExpired  = "expired"
+)
+
+// CardAuthorization - авторизация покупки по карте. Acquirer - идентификатор
+// ключа API эквайрера, Reference - уникальный у эквайрера номер операции.
+// До расчета на счете заблокирована сумма Amount, после подтверждения -
+// CapturedAmount.
+type CardAuthorization struct {
+	ID             int64        `json:"id"`
+	CardID         int64        `json:"card_id"`
+	AccountID      int64        `json:"account_id"`
+	Acquirer       string       `json:"acquirer"`
+	Reference      string       `json:"reference"`
+	MerchantName   string       `json:"merchant_name"`
+	MCC            string       `json:"mcc"`
+	Channel        string       `json:"channel"`
+	Amount         money.Amount `json:"amount"`
+	CapturedAmount money.Amount `json:"captured_amount"`
+	RefundedAmount money.Amount `json:"refunded_amount"`
+	Status         string       `json:"status"`
+	// TransactionID - операция списания, созданная при расчете
+	TransactionID int64     `json:"transaction_id,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// HeldAmount возвращает сумму, заблокированную на счете под авторизацию
+func (a *CardAuthorization) HeldAmount() money.Amount {
+	switch a.Status {
+	case AuthorizationApproved:
+		return a.Amount
+	case AuthorizationCaptured:
+		return a.CapturedAmount
+	default:
+		return 0
+	}
+}
+
+// AuthorizationRequest - запрос эквайрера на авторизацию покупки
+type AuthorizationRequest struct {
+	Reference    string       `json:"reference"`
+	CardNumber   string       `json:"card_number"`
+	CVV          string       `json:"cvv"`
+	Amount       money.Amount `json:"amount"`
+	Channel      string       `json:"channel"`
+	MCC          string       `json:"mcc"`
+	MerchantName string       `json:"merchant_name"`
+	// PIN в открытом виде, расшифрованный из PIN-блока платежной сети. Через
+	// API эквайреров не принимается.
+	PIN string `json:"-"`
+}
+
+// Actor - сотрудник, выполняющий действие в бэк-офисе
+type Actor struct {
+	UserID int64
+	Role   string
+}
+
+// CaseNote - заметка сотрудника по обращению клиента
+type CaseNote struct {
 	ID        int64     `json:"id"`
-	CreditID  int64     `json:"credit_id"`
-	Date      time.Time `json:"date"`
-	Amount    float64   `json:"amount"`
-	Status    string    `json:"status"`
+	UserID    int64     `json:"user_id"`
+	AuthorID  int64     `json:"author_id"`
+	AccountID int64     `json:"account_id,omitempty"`
+	CardID    int64     `json:"card_id,omitempty"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditRecord - запись журнала действий сотрудников
+type AuditRecord struct {
+	ID           int64     `json:"id"`
+	ActorID      int64     `json:"actor_id"`
+	ActorRole    string    `json:"actor_role"`
+	Action       string    `json:"action"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   int64     `json:"resource_id,omitempty"`
+	Allowed      bool      `json:"allowed"`
+	Details      string    `json:"details,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Session - сессия пользователя, привязанная к refresh-токену
+type Session struct {
+	ID                int64      `json:"id"`
+	UserID            int64      `json:"user_id"`
+	RefreshTokenHash  string     `json:"-"`
+	PreviousTokenHash string     `json:"-"`
+	AccessJTI         string     `json:"-"`
+	AccessExpiresAt   time.Time  `json:"-"`
+	UserAgent         string     `json:"user_agent"`
+	IP                string     `json:"ip"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	MFAVerifiedAt     *time.Time `json:"mfa_verified_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+}
+
+// Active сообщает, можно ли обновлять токены по сессии
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// ClientInfo - сведения об устройстве, с которого выполнен вход
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// TokenPair - выданные при входе или обновлении токены
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// LoginResult - результат первого шага входа. Если у пользователя включена
+// двухфакторная аутентификация, вместо токенов выдается MFAToken для второго шага.
+type LoginResult struct {
+	*TokenPair
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+// MFA - настройки TOTP пользователя
+type MFA struct {
+	UserID       int64      `json:"user_id"`
+	Secret       string     `json:"-"`
+	Enabled      bool       `json:"enabled"`
+	LastUsedStep int64      `json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	// FailedAttempts - неверные коды подряд, LockedUntil - до какого времени
+	// проверка кодов заблокирована после их серии
+	FailedAttempts int        `json:"-"`
+	LockedUntil    *time.Time `json:"-"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// MFAEnrollment - данные для подключения приложения-аутентификатора
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// MFAChallenge - незавершенный вход, ожидающий второй фактор
+type MFAChallenge struct {
+	ID         int64
+	UserID     int64
+	TokenHash  string
+	UserAgent  string
+	IP         string
+	Attempts   int
+	ExpiresAt  time.Time
+	ConsumedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// PasswordResetToken - одноразовый токен восстановления пароля
+type PasswordResetToken struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// Причины отказа во входе для журнала попыток
+const (
+	LoginUnknownEmail    = "unknown_email"
+	LoginInvalidPassword = "invalid_password"
+	LoginLocked          = "locked"
+	LoginThrottled       = "throttled"
+	LoginIPThrottled     = "ip_throttled"
+)
+
+// LoginAttempt - запись журнала попыток входа
+type LoginAttempt struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id,omitempty"`
+	Email     string    `json:"email"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
 }
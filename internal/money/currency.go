@@ -0,0 +1,42 @@
+package money
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Currency - код валюты по ISO 4217
+type Currency string
+
+const (
+	RUB Currency = "RUB"
+	USD Currency = "USD"
+	EUR Currency = "EUR"
+	CNY Currency = "CNY"
+)
+
+// minorUnits - количество знаков минимальной единицы для поддерживаемых валют
+var minorUnits = map[Currency]int{
+	RUB: 2,
+	USD: 2,
+	EUR: 2,
+	CNY: 2,
+}
+
+// ParseCurrency проверяет и нормализует код валюты
+func ParseCurrency(code string) (Currency, error) {
+	currency := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if _, ok := minorUnits[currency]; !ok {
+		return "", fmt.Errorf("unsupported currency %q", code)
+	}
+	return currency, nil
+}
+
+// MinorUnits возвращает количество знаков после запятой по ISO 4217
+func (c Currency) MinorUnits() int {
+	return minorUnits[c]
+}
+
+func (c Currency) String() string {
+	return string(c)
+}
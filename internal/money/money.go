@@ -0,0 +1,184 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Amount - денежная сумма в сотых долях единицы валюты (копейках, центах).
+// Масштаб соответствует колонкам DECIMAL(15,2) в схеме БД, поэтому
+// сложение и сравнение сумм выполняются точно, без ошибок float64.
+type Amount int64
+
+// Scale - количество минимальных единиц в одной единице валюты
+const Scale = 100
+
+// Units возвращает сумму, равную n целым единицам валюты
+func Units(n int64) Amount {
+	return Amount(n * Scale)
+}
+
+// Parse разбирает десятичную запись суммы вида "1234.56".
+// Более двух знаков после запятой считается ошибкой: округление
+// должно выполняться явно с выбранной политикой.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("empty amount")
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" || (hasFrac && frac == "") {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	if len(frac) > 2 {
+		// Незначащие нули после второго знака допустимы (значения DECIMAL из БД)
+		if strings.Trim(frac[2:], "0") != "" {
+			return 0, fmt.Errorf("amount %q has more than 2 decimal places", s)
+		}
+		frac = frac[:2]
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units < 0 {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	cents, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil || cents < 0 {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	if units > (1<<63-1-cents)/Scale {
+		return 0, fmt.Errorf("amount %q is out of range", s)
+	}
+
+	amount := Amount(units*Scale + cents)
+	if negative {
+		amount = -amount
+	}
+
+	return amount, nil
+}
+
+// MustParse аналогичен Parse, но паникует при ошибке. Предназначен для констант и тестов.
+func MustParse(s string) Amount {
+	amount, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return amount
+}
+
+func (a Amount) String() string {
+	sign := ""
+	minor := int64(a)
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, minor/Scale, minor%Scale)
+}
+
+// Minor возвращает сумму в минимальных единицах
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+func (a Amount) IsPositive() bool {
+	return a > 0
+}
+
+func (a Amount) IsNegative() bool {
+	return a < 0
+}
+
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Rat возвращает точное рациональное представление суммы в единицах валюты
+func (a Amount) Rat() *big.Rat {
+	return big.NewRat(int64(a), Scale)
+}
+
+// Mul умножает сумму на рациональный коэффициент с заданной политикой округления
+func (a Amount) Mul(factor *big.Rat, mode RoundingMode) Amount {
+	return FromRat(new(big.Rat).Mul(a.Rat(), factor), mode)
+}
+
+// FromRat округляет рациональное значение в единицах валюты до копеек
+func FromRat(r *big.Rat, mode RoundingMode) Amount {
+	scaled := new(big.Rat).Mul(r, big.NewRat(Scale, 1))
+	return Amount(mode.round(scaled).Int64())
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON принимает как число, так и строку, не проходя через float64
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		return nil
+	}
+
+	amount, err := Parse(s)
+	if err != nil {
+		return err
+	}
+
+	*a = amount
+	return nil
+}
+
+// Scan читает значение DECIMAL из БД
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case int64:
+		*a = Units(v)
+		return nil
+	case nil:
+		*a = 0
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into money.Amount", src)
+	}
+}
+
+func (a *Amount) scanString(s string) error {
+	amount, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
+
+// Value передает сумму в БД в десятичной записи
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
@@ -0,0 +1,130 @@
+package money
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Amount
+		wantErr  bool
+	}{
+		{name: "целое число", input: "100", expected: 10000},
+		{name: "копейки", input: "1234.56", expected: 123456},
+		{name: "один знак после запятой", input: "0.5", expected: 50},
+		{name: "отрицательная сумма", input: "-8884.88", expected: -888488},
+		{name: "незначащие нули", input: "15.2000", expected: 1520},
+		{name: "лишние знаки", input: "1.005", wantErr: true},
+		{name: "экспонента", input: "1e5", wantErr: true},
+		{name: "пустая строка", input: "", wantErr: true},
+		{name: "точка без дробной части", input: "10.", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := Parse(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, amount)
+		})
+	}
+}
+
+func TestAmount_String(t *testing.T) {
+	assert.Equal(t, "0.00", Amount(0).String())
+	assert.Equal(t, "0.05", Amount(5).String())
+	assert.Equal(t, "-0.50", Amount(-50).String())
+	assert.Equal(t, "1000000.00", Units(1000000).String())
+}
+
+func TestAmount_JSON(t *testing.T) {
+	var payload struct {
+		Amount Amount `json:"amount"`
+	}
+
+	// Число и строка разбираются одинаково и без потери точности
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": 0.1}`), &payload))
+	assert.Equal(t, Amount(10), payload.Amount)
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": "1000.70"}`), &payload))
+	assert.Equal(t, Amount(100070), payload.Amount)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": 0.001}`), &payload))
+
+	data, err := json.Marshal(payload)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount": 1000.70}`, string(data))
+}
+
+func TestAmount_Scan(t *testing.T) {
+	var amount Amount
+
+	assert.NoError(t, amount.Scan([]byte("8884.88")))
+	assert.Equal(t, Amount(888488), amount)
+
+	assert.NoError(t, amount.Scan(int64(3)))
+	assert.Equal(t, Units(3), amount)
+
+	assert.Error(t, amount.Scan(1.5))
+
+	value, err := Amount(-12).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "-0.12", value)
+}
+
+func TestFromRat(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    *big.Rat
+		mode     RoundingMode
+		expected Amount
+	}{
+		{name: "half up: половина вверх", value: big.NewRat(1, 200), mode: HalfUp, expected: 1},
+		{name: "half up: отрицательная половина от нуля", value: big.NewRat(-1, 200), mode: HalfUp, expected: -1},
+		{name: "half even: половина к четному вниз", value: big.NewRat(1, 200), mode: HalfEven, expected: 0},
+		{name: "half even: половина к четному вверх", value: big.NewRat(3, 200), mode: HalfEven, expected: 2},
+		{name: "half even: больше половины", value: big.NewRat(51, 10000), mode: HalfEven, expected: 1},
+		{name: "меньше половины", value: big.NewRat(1, 300), mode: HalfUp, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FromRat(tt.value, tt.mode))
+		})
+	}
+}
+
+func TestAmount_Mul(t *testing.T) {
+	// 1% от 10.50 = 0.105
+	rate := big.NewRat(1, 100)
+	assert.Equal(t, Amount(11), Amount(1050).Mul(rate, HalfUp))
+	assert.Equal(t, Amount(10), Amount(1050).Mul(rate, HalfEven))
+}
+
+func TestParseCurrency(t *testing.T) {
+	currency, err := ParseCurrency("rub")
+	assert.NoError(t, err)
+	assert.Equal(t, RUB, currency)
+	assert.Equal(t, 2, currency.MinorUnits())
+
+	_, err = ParseCurrency("XXX")
+	assert.Error(t, err)
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	mode, err := ParseRoundingMode("bankers")
+	assert.NoError(t, err)
+	assert.Equal(t, HalfEven, mode)
+
+	_, err = ParseRoundingMode("ceiling")
+	assert.Error(t, err)
+}
@@ -0,0 +1,57 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// RoundingMode - политика округления до копеек
+type RoundingMode int
+
+const (
+	// HalfUp - математическое округление: половина округляется от нуля
+	HalfUp RoundingMode = iota
+	// HalfEven - банковское округление: половина округляется к четному
+	HalfEven
+)
+
+// ParseRoundingMode разбирает название политики из конфигурации
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch s {
+	case "half_up", "":
+		return HalfUp, nil
+	case "half_even", "bankers":
+		return HalfEven, nil
+	default:
+		return 0, fmt.Errorf("unknown rounding mode %q", s)
+	}
+}
+
+func (m RoundingMode) String() string {
+	switch m {
+	case HalfEven:
+		return "half_even"
+	default:
+		return "half_up"
+	}
+}
+
+// round округляет рациональное число до целого
+func (m RoundingMode) round(r *big.Rat) *big.Int {
+	num := new(big.Int).Abs(r.Num())
+	den := r.Denom()
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+
+	// Сравниваем удвоенный остаток со знаменателем, чтобы определить половину
+	cmp := new(big.Int).Lsh(rem, 1).Cmp(den)
+	if cmp > 0 || (cmp == 0 && (m == HalfUp || quo.Bit(0) == 1)) {
+		quo.Add(quo, big.NewInt(1))
+	}
+
+	if r.Sign() < 0 {
+		quo.Neg(quo)
+	}
+
+	return quo
+}
@@ -0,0 +1,233 @@
+// Пакет pinblock реализует PIN-блоки ISO 9564: формат 0 (8 байт, шифруется
+// 3DES) и формат 4 (16 байт, шифруется AES). PIN-блоки передаются платежной
+// сетью в поле 52 сообщений ISO 8583 зашифрованными ключом зоны.
+package pinblock
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidPINBlock = errors.New("invalid PIN block")
+
+// Допустимая длина PIN по ISO 9564
+const (
+	MinPINLength = 4
+	MaxPINLength = 12
+)
+
+// Format0 собирает открытый PIN-блок формата 0: поле PIN (0, длина, цифры,
+// заполнение F) складывается по XOR с 12 правыми цифрами номера без
+// контрольной.
+func Format0(pin, pan string) ([]byte, error) {
+	if err := checkPIN(pin); err != nil {
+		return nil, err
+	}
+
+	panField, err := format0PAN(pan)
+	if err != nil {
+		return nil, err
+	}
+
+	field, err := hex.DecodeString(fmt.Sprintf("0%X%s", len(pin), pin) + strings.Repeat("F", 14-len(pin)))
+	if err != nil {
+		return nil, err
+	}
+
+	return xor(field, panField), nil
+}
+
+// ParseFormat0 извлекает PIN из открытого блока формата 0
+func ParseFormat0(block []byte, pan string) (string, error) {
+	if len(block) != 8 {
+		return "", fmt.Errorf("%w: format 0 block must be 8 bytes", ErrInvalidPINBlock)
+	}
+
+	panField, err := format0PAN(pan)
+	if err != nil {
+		return "", err
+	}
+
+	field := strings.ToUpper(hex.EncodeToString(xor(block, panField)))
+	if field[0] != '0' {
+		return "", fmt.Errorf("%w: format %c is not 0", ErrInvalidPINBlock, field[0])
+	}
+
+	return parsePINField(field[1:], 'F')
+}
+
+// EncryptFormat4 шифрует PIN ключом AES в блок формата 4
+func EncryptFormat4(key []byte, pin, pan string) ([]byte, error) {
+	if err := checkPIN(pin); err != nil {
+		return nil, err
+	}
+
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	panField, err := format4PAN(pan)
+	if err != nil {
+		return nil, err
+	}
+
+	// Поле PIN: 4, длина, цифры, заполнение A до 16 полубайт и 8 случайных байт
+	field, err := hex.DecodeString(fmt.Sprintf("4%X%s", len(pin), pin) + strings.Repeat("A", 14-len(pin)))
+	if err != nil {
+		return nil, err
+	}
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	field = append(field, random...)
+
+	block := make([]byte, aes.BlockSize)
+	c.Encrypt(block, field)
+	block = xor(block, panField)
+	c.Encrypt(block, block)
+	return block, nil
+}
+
+// EncryptFormat0 шифрует PIN ключом 3DES (16 или 24 байта) в блок формата 0
+func EncryptFormat0(key []byte, pin, pan string) ([]byte, error) {
+	c, err := tripleDES(key)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := Format0(pin, pan)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Encrypt(block, block)
+	return block, nil
+}
+
+// Decrypt расшифровывает PIN-блок ключом зоны: 8-байтовый блок считается
+// форматом 0 под 3DES, 16-байтовый - форматом 4 под AES. Ключ длиной 16 байт
+// подходит для обоих форматов.
+func Decrypt(key, block []byte, pan string) (string, error) {
+	switch len(block) {
+	case des.BlockSize:
+		c, err := tripleDES(key)
+		if err != nil {
+			return "", err
+		}
+
+		clear := make([]byte, des.BlockSize)
+		c.Decrypt(clear, block)
+		return ParseFormat0(clear, pan)
+
+	case aes.BlockSize:
+		c, err := aes.NewCipher(key)
+		if err != nil {
+			return "", err
+		}
+
+		panField, err := format4PAN(pan)
+		if err != nil {
+			return "", err
+		}
+
+		field := make([]byte, aes.BlockSize)
+		c.Decrypt(field, block)
+		field = xor(field, panField)
+		c.Decrypt(field, field)
+
+		digits := strings.ToUpper(hex.EncodeToString(field))
+		if digits[0] != '4' {
+			return "", fmt.Errorf("%w: format %c is not 4", ErrInvalidPINBlock, digits[0])
+		}
+		return parsePINField(digits[1:16], 'A')
+
+	default:
+		return "", fmt.Errorf("%w: unexpected length %d", ErrInvalidPINBlock, len(block))
+	}
+}
+
+// parsePINField разбирает длину, цифры PIN и заполнение fill
+func parsePINField(field string, fill byte) (string, error) {
+	length := int(field[0] - '0')
+	if field[0] >= 'A' {
+		length = int(field[0]-'A') + 10
+	}
+	if length < MinPINLength || length > MaxPINLength {
+		return "", fmt.Errorf("%w: PIN length %d", ErrInvalidPINBlock, length)
+	}
+
+	pin := field[1 : 1+length]
+	if !isDigits(pin) {
+		return "", fmt.Errorf("%w: PIN is not numeric", ErrInvalidPINBlock)
+	}
+
+	if strings.Trim(field[1+length:], string(fill)) != "" {
+		return "", fmt.Errorf("%w: invalid fill", ErrInvalidPINBlock)
+	}
+
+	return pin, nil
+}
+
+// format0PAN - поле номера формата 0: 0000 и 12 правых цифр без контрольной
+func format0PAN(pan string) ([]byte, error) {
+	if len(pan) < 13 || !isDigits(pan) {
+		return nil, fmt.Errorf("%w: invalid card number", ErrInvalidPINBlock)
+	}
+
+	return hex.DecodeString("0000" + pan[len(pan)-13:len(pan)-1])
+}
+
+// format4PAN - поле номера формата 4: длина сверх 12 цифр, номер и нули
+func format4PAN(pan string) ([]byte, error) {
+	if len(pan) < 12 || len(pan) > 19 || !isDigits(pan) {
+		return nil, fmt.Errorf("%w: invalid card number", ErrInvalidPINBlock)
+	}
+
+	field := fmt.Sprintf("%d%s", len(pan)-12, pan)
+	return hex.DecodeString(field + strings.Repeat("0", 32-len(field)))
+}
+
+func tripleDES(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 16:
+		// Двухключевой 3DES: K1 K2 K1
+		key = append(append([]byte{}, key...), key[:8]...)
+	case 24:
+	default:
+		return nil, fmt.Errorf("3DES key must be 16 or 24 bytes, got %d", len(key))
+	}
+
+	return des.NewTripleDESCipher(key)
+}
+
+func checkPIN(pin string) error {
+	if len(pin) < MinPINLength || len(pin) > MaxPINLength || !isDigits(pin) {
+		return fmt.Errorf("PIN must be %d to %d digits", MinPINLength, MaxPINLength)
+	}
+	return nil
+}
+
+func xor(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+func isDigits(s string) bool {
+	for _, c := range []byte(s) {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
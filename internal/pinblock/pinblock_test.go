@@ -0,0 +1,75 @@
+package pinblock
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPAN = "4111111111111111"
+
+func TestFormat0(t *testing.T) {
+	// Поле PIN 041234FFFFFFFFFF, поле номера 0000111111111111
+	block, err := Format0("1234", testPAN)
+	require.NoError(t, err)
+	assert.Equal(t, "041225eeeeeeeeee", hex.EncodeToString(block))
+
+	pin, err := ParseFormat0(block, testPAN)
+	require.NoError(t, err)
+	assert.Equal(t, "1234", pin)
+
+	_, err = Format0("12a4", testPAN)
+	assert.Error(t, err)
+	_, err = Format0("123", testPAN)
+	assert.Error(t, err)
+}
+
+func TestDecrypt(t *testing.T) {
+	tdesKey := bytes.Repeat([]byte{0x11, 0x22}, 8)
+	aesKey := bytes.Repeat([]byte{0x33}, 32)
+
+	t.Run("формат 0 под 3DES", func(t *testing.T) {
+		block, err := EncryptFormat0(tdesKey, "483920", testPAN)
+		require.NoError(t, err)
+		require.Len(t, block, 8)
+
+		pin, err := Decrypt(tdesKey, block, testPAN)
+
+		require.NoError(t, err)
+		assert.Equal(t, "483920", pin)
+	})
+
+	t.Run("формат 4 под AES", func(t *testing.T) {
+		block, err := EncryptFormat4(aesKey, "7351", testPAN)
+		require.NoError(t, err)
+		require.Len(t, block, 16)
+
+		pin, err := Decrypt(aesKey, block, testPAN)
+
+		require.NoError(t, err)
+		assert.Equal(t, "7351", pin)
+
+		// Случайное заполнение делает блоки одного PIN разными
+		other, err := EncryptFormat4(aesKey, "7351", testPAN)
+		require.NoError(t, err)
+		assert.NotEqual(t, block, other)
+	})
+
+	t.Run("блок другой карты", func(t *testing.T) {
+		block, err := EncryptFormat4(aesKey, "7351", testPAN)
+		require.NoError(t, err)
+
+		_, err = Decrypt(aesKey, block, "5500005555555559")
+
+		assert.ErrorIs(t, err, ErrInvalidPINBlock)
+	})
+
+	t.Run("неверная длина блока", func(t *testing.T) {
+		_, err := Decrypt(aesKey, []byte{1, 2, 3}, testPAN)
+
+		assert.ErrorIs(t, err, ErrInvalidPINBlock)
+	})
+}
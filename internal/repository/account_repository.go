@@ -3,9 +3,11 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"fmt"
+	"time"
 
 	"bank-app/internal/model"
+	"bank-app/internal/money"
 )
 
 type AccountRepo struct {
@@ -18,15 +20,17 @@ func NewAccountRepository(db *sql.DB) AccountRepository {
 
 func (r *AccountRepo) Create(ctx context.Context, account *model.Account) error {
 	query := `
-		INSERT INTO accounts (user_id, number, balance, currency)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO accounts (user_id, number, balance, currency, status, time_zone)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRowContext(ctx, query,
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
 		account.UserID,
 		account.Number,
 		account.Balance,
 		account.Currency,
+		account.Status,
+		account.TimeZone,
 	).Scan(&account.ID, &account.CreatedAt, &account.UpdatedAt)
 
 	if err != nil {
@@ -39,22 +43,59 @@ func (r *AccountRepo) Create(ctx context.Context, account *model.Account) error
 func (r *AccountRepo) GetByID(ctx context.Context, id int64) (*model.Account, error) {
 	account := &model.Account{}
 	query := `
-		SELECT id, user_id, number, balance, currency, created_at, updated_at
+		SELECT id, user_id, number, balance, held, currency, status, time_zone, created_at, updated_at
 		FROM accounts
 		WHERE id = $1`
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
 		&account.ID,
 		&account.UserID,
 		&account.Number,
 		&account.Balance,
+		&account.Held,
 		&account.Currency,
+		&account.Status,
+		&account.TimeZone,
 		&account.CreatedAt,
 		&account.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("account not found")
+		return nil, fmt.Errorf("account %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// GetByIDForUpdate получает счет с блокировкой строки до конца транзакции.
+// Должен вызываться внутри Transactor.WithinTransaction.
+func (r *AccountRepo) GetByIDForUpdate(ctx context.Context, id int64) (*model.Account, error) {
+	account := &model.Account{}
+	query := `
+		SELECT id, user_id, number, balance, held, currency, status, time_zone, created_at, updated_at
+		FROM accounts
+		WHERE id = $1
+		FOR UPDATE`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
+		&account.ID,
+		&account.UserID,
+		&account.Number,
+		&account.Balance,
+		&account.Held,
+		&account.Currency,
+		&account.Status,
+		&account.TimeZone,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("account %w", ErrNotFound)
 	}
 
 	if err != nil {
@@ -66,11 +107,11 @@ func (r *AccountRepo) GetByID(ctx context.Context, id int64) (*model.Account, er
 
 func (r *AccountRepo) GetByUserID(ctx context.Context, userID int64) ([]*model.Account, error) {
 	query := `
-		SELECT id, user_id, number, balance, currency, created_at, updated_at
+		SELECT id, user_id, number, balance, held, currency, status, time_zone, created_at, updated_at
 		FROM accounts
 		WHERE user_id = $1`
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +125,10 @@ func (r *AccountRepo) GetByUserID(ctx context.Context, userID int64) ([]*model.A
 			&account.UserID,
 			&account.Number,
 			&account.Balance,
+			&account.Held,
 			&account.Currency,
+			&account.Status,
+			&account.TimeZone,
 			&account.CreatedAt,
 			&account.UpdatedAt,
 		)
@@ -104,13 +148,15 @@ func (r *AccountRepo) GetByUserID(ctx context.Context, userID int64) ([]*model.A
 func (r *AccountRepo) Update(ctx context.Context, account *model.Account) error {
 	query := `
 		UPDATE accounts
-		SET balance = $1, currency = $2
-		WHERE id = $3
+		SET balance = $1, currency = $2, status = $3, time_zone = $4
+		WHERE id = $5
 		RETURNING updated_at`
 
-	err := r.db.QueryRowContext(ctx, query,
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
 		account.Balance,
 		account.Currency,
+		account.Status,
+		account.TimeZone,
 		account.ID,
 	).Scan(&account.UpdatedAt)
 
@@ -120,3 +166,21 @@ func (r *AccountRepo) Update(ctx context.Context, account *model.Account) error
 
 	return nil
 }
+
+// AdjustHeld изменяет сумму средств, заблокированных под авторизации по картам.
+// Update эту колонку не затрагивает.
+func (r *AccountRepo) AdjustHeld(ctx context.Context, id int64, delta money.Amount) error {
+	query := `
+		UPDATE accounts
+		SET held = held + $1
+		WHERE id = $2
+		RETURNING updated_at`
+
+	var updatedAt time.Time
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, delta, id).Scan(&updatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("account %w", ErrNotFound)
+	}
+
+	return err
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 
 	"bank-app/internal/model"
+	"bank-app/internal/money"
 )
 
 type AnalyticsRepo struct {
@@ -20,10 +21,10 @@ func (r *AnalyticsRepo) GetTransactionsByPeriod(ctx context.Context, userID int6
 	return nil, errors.New("not implemented")
 }
 
-func (r *AnalyticsRepo) GetCreditLoad(ctx context.Context, userID int64) (float64, error) {
+func (r *AnalyticsRepo) GetCreditLoad(ctx context.Context, userID int64) (money.Amount, error) {
 	return 0, errors.New("not implemented")
 }
 
-func (r *AnalyticsRepo) PredictBalance(ctx context.Context, accountID int64, days int) (float64, error) {
+func (r *AnalyticsRepo) PredictBalance(ctx context.Context, accountID int64, days int) (money.Amount, error) {
 	return 0, errors.New("not implemented")
 }
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"bank-app/internal/model"
+)
+
+type AuditRepo struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &AuditRepo{db: db}
+}
+
+func (r *AuditRepo) Create(ctx context.Context, record *model.AuditRecord) error {
+	query := `
+		INSERT INTO audit_log (actor_id, actor_role, action, resource_type, resource_id, allowed, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		record.ActorID,
+		record.ActorRole,
+		record.Action,
+		record.ResourceType,
+		nullInt64(record.ResourceID),
+		record.Allowed,
+		record.Details,
+	).Scan(&record.ID, &record.CreatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AuditRepo) GetRecent(ctx context.Context, limit int) ([]*model.AuditRecord, error) {
+	query := `
+		SELECT id, actor_id, actor_role, action, resource_type, resource_id, allowed, details, created_at
+		FROM audit_log
+		ORDER BY id DESC
+		LIMIT $1`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*model.AuditRecord
+	for rows.Next() {
+		record := &model.AuditRecord{}
+		var resourceID sql.NullInt64
+		var details sql.NullString
+		err := rows.Scan(
+			&record.ID,
+			&record.ActorID,
+			&record.ActorRole,
+			&record.Action,
+			&record.ResourceType,
+			&resourceID,
+			&record.Allowed,
+			&details,
+			&record.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		record.ResourceID = resourceID.Int64
+		record.Details = details.String
+		records = append(records, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bank-app/internal/model"
+)
+
+type CardAuthorizationRepo struct {
+	db *sql.DB
+}
+
+func NewCardAuthorizationRepository(db *sql.DB) CardAuthorizationRepository {
+	return &CardAuthorizationRepo{db: db}
+}
+
+const cardAuthorizationColumns = `id, card_id, account_id, acquirer, reference, merchant_name, mcc, channel,
+		amount, captured_amount, refunded_amount, status, transaction_id, expires_at, created_at, updated_at`
+
+func (r *CardAuthorizationRepo) Create(ctx context.Context, auth *model.CardAuthorization) error {
+	query := `
+		INSERT INTO card_authorizations (card_id, account_id, acquirer, reference, merchant_name, mcc, channel,
+			amount, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at`
+
+	return executor(ctx, r.db).QueryRowContext(ctx, query,
+		auth.CardID,
+		auth.AccountID,
+		auth.Acquirer,
+		auth.Reference,
+		auth.MerchantName,
+		auth.MCC,
+		auth.Channel,
+		auth.Amount,
+		auth.Status,
+		auth.ExpiresAt,
+	).Scan(&auth.ID, &auth.CreatedAt, &auth.UpdatedAt)
+}
+
+func (r *CardAuthorizationRepo) GetByID(ctx context.Context, id int64) (*model.CardAuthorization, error) {
+	query := `SELECT ` + cardAuthorizationColumns + ` FROM card_authorizations WHERE id = $1`
+	return r.getOne(ctx, query, id)
+}
+
+// GetByIDForUpdate получает авторизацию с блокировкой строки до конца транзакции
+func (r *CardAuthorizationRepo) GetByIDForUpdate(ctx context.Context, id int64) (*model.CardAuthorization, error) {
+	query := `SELECT ` + cardAuthorizationColumns + ` FROM card_authorizations WHERE id = $1 FOR UPDATE`
+	return r.getOne(ctx, query, id)
+}
+
+// GetByReference ищет авторизацию по номеру операции эквайрера
+func (r *CardAuthorizationRepo) GetByReference(ctx context.Context, acquirer, reference string) (*model.CardAuthorization, error) {
+	query := `SELECT ` + cardAuthorizationColumns + ` FROM card_authorizations WHERE acquirer = $1 AND reference = $2`
+	return r.getOne(ctx, query, acquirer, reference)
+}
+
+// GetCaptured возвращает подтвержденные, но еще не рассчитанные авторизации
+func (r *CardAuthorizationRepo) GetCaptured(ctx context.Context, limit int) ([]*model.CardAuthorization, error) {
+	query := `
+		SELECT ` + cardAuthorizationColumns + `
+		FROM card_authorizations
+		WHERE status = 'captured'
+		ORDER BY id
+		LIMIT $1`
+	return r.getMany(ctx, query, limit)
+}
+
+// GetExpired возвращает неподтвержденные авторизации с истекшим сроком блокировки
+func (r *CardAuthorizationRepo) GetExpired(ctx context.Context, now time.Time, limit int) ([]*model.CardAuthorization, error) {
+	query := `
+		SELECT ` + cardAuthorizationColumns + `
+		FROM card_authorizations
+		WHERE status = 'authorized' AND expires_at <= $1
+		ORDER BY id
+		LIMIT $2`
+	return r.getMany(ctx, query, now, limit)
+}
+
+func (r *CardAuthorizationRepo) Update(ctx context.Context, auth *model.CardAuthorization) error {
+	query := `
+		UPDATE card_authorizations
+		SET captured_amount = $1, refunded_amount = $2, status = $3, transaction_id = $4,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5
+		RETURNING updated_at`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		auth.CapturedAmount,
+		auth.RefundedAmount,
+		auth.Status,
+		nullInt64(auth.TransactionID),
+		auth.ID,
+	).Scan(&auth.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("card authorization %w", ErrNotFound)
+	}
+
+	return err
+}
+
+func (r *CardAuthorizationRepo) getOne(ctx context.Context, query string, args ...interface{}) (*model.CardAuthorization, error) {
+	auth, err := scanCardAuthorization(executor(ctx, r.db).QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("card authorization %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+func (r *CardAuthorizationRepo) getMany(ctx context.Context, query string, args ...interface{}) ([]*model.CardAuthorization, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var auths []*model.CardAuthorization
+	for rows.Next() {
+		auth, err := scanCardAuthorization(rows)
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, auth)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return auths, nil
+}
+
+func scanCardAuthorization(row rowScanner) (*model.CardAuthorization, error) {
+	auth := &model.CardAuthorization{}
+	var transactionID sql.NullInt64
+
+	err := row.Scan(
+		&auth.ID,
+		&auth.CardID,
+		&auth.AccountID,
+		&auth.Acquirer,
+		&auth.Reference,
+		&auth.MerchantName,
+		&auth.MCC,
+		&auth.Channel,
+		&auth.Amount,
+		&auth.CapturedAmount,
+		&auth.RefundedAmount,
+		&auth.Status,
+		&transactionID,
+		&auth.ExpiresAt,
+		&auth.CreatedAt,
+		&auth.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	auth.TransactionID = transactionID.Int64
+	return auth, nil
+}
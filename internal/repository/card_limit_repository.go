@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+)
+
+type CardLimitRepo struct {
+	db *sql.DB
+}
+
+func NewCardLimitRepository(db *sql.DB) CardLimitRepository {
+	return &CardLimitRepo{db: db}
+}
+
+const cardLimitColumns = `id, card_id, channel, mcc, per_transaction, daily, monthly, disabled, created_at, updated_at`
+
+// dayLayout - формат календарного дня счетчиков. Дата берется в часовом поясе
+// переданного времени, поэтому сервис передает время в поясе счета.
+const dayLayout = "2006-01-02"
+
+func (r *CardLimitRepo) Create(ctx context.Context, limit *model.CardLimit) error {
+	query := `
+		INSERT INTO card_limits (card_id, channel, mcc, per_transaction, daily, monthly, disabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`
+
+	return executor(ctx, r.db).QueryRowContext(ctx, query,
+		limit.CardID,
+		limit.Channel,
+		limit.MCC,
+		limit.PerTransaction,
+		limit.Daily,
+		limit.Monthly,
+		limit.Disabled,
+	).Scan(&limit.ID, &limit.CreatedAt, &limit.UpdatedAt)
+}
+
+func (r *CardLimitRepo) GetByID(ctx context.Context, id int64) (*model.CardLimit, error) {
+	query := `SELECT ` + cardLimitColumns + ` FROM card_limits WHERE id = $1`
+
+	limit, err := scanCardLimit(executor(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("card limit %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return limit, nil
+}
+
+func (r *CardLimitRepo) GetByCardID(ctx context.Context, cardID int64) ([]*model.CardLimit, error) {
+	query := `SELECT ` + cardLimitColumns + ` FROM card_limits WHERE card_id = $1 ORDER BY id`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, cardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var limits []*model.CardLimit
+	for rows.Next() {
+		limit, err := scanCardLimit(rows)
+		if err != nil {
+			return nil, err
+		}
+		limits = append(limits, limit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return limits, nil
+}
+
+func (r *CardLimitRepo) Update(ctx context.Context, limit *model.CardLimit) error {
+	query := `
+		UPDATE card_limits
+		SET channel = $1, mcc = $2, per_transaction = $3, daily = $4, monthly = $5, disabled = $6,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7
+		RETURNING updated_at`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		limit.Channel,
+		limit.MCC,
+		limit.PerTransaction,
+		limit.Daily,
+		limit.Monthly,
+		limit.Disabled,
+		limit.ID,
+	).Scan(&limit.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("card limit %w", ErrNotFound)
+	}
+
+	return err
+}
+
+func (r *CardLimitRepo) Delete(ctx context.Context, id int64) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `DELETE FROM card_limits WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("card limit %w", ErrNotFound)
+	}
+
+	return nil
+}
+
+// AddSpend прибавляет сумму к счетчику расходов за день. Отрицательная сумма
+// возвращает отмененную операцию.
+func (r *CardLimitRepo) AddSpend(ctx context.Context, spend *model.CardSpend, day time.Time) error {
+	query := `
+		INSERT INTO card_spend_counters (card_id, channel, mcc, day, amount)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (card_id, day, channel, mcc)
+		DO UPDATE SET amount = card_spend_counters.amount + EXCLUDED.amount`
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query,
+		spend.CardID,
+		spend.Channel,
+		spend.MCC,
+		day.Format(dayLayout),
+		spend.Amount,
+	)
+	return err
+}
+
+// SumSpend возвращает расходы по карте за дни [from, to). Пустые channel и mcc
+// означают любой канал и любую категорию.
+func (r *CardLimitRepo) SumSpend(ctx context.Context, cardID int64, channel, mcc string, from, to time.Time) (money.Amount, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM card_spend_counters
+		WHERE card_id = $1
+			AND ($2 = '' OR channel = $2)
+			AND ($3 = '' OR mcc = $3)
+			AND day >= $4::date AND day < $5::date`
+
+	var total money.Amount
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		cardID,
+		channel,
+		mcc,
+		from.Format(dayLayout),
+		to.Format(dayLayout),
+	).Scan(&total)
+	return total, err
+}
+
+func scanCardLimit(row rowScanner) (*model.CardLimit, error) {
+	limit := &model.CardLimit{}
+	err := row.Scan(
+		&limit.ID,
+		&limit.CardID,
+		&limit.Channel,
+		&limit.MCC,
+		&limit.PerTransaction,
+		&limit.Daily,
+		&limit.Monthly,
+		&limit.Disabled,
+		&limit.CreatedAt,
+		&limit.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return limit, nil
+}
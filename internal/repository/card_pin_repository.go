@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"bank-app/internal/model"
+)
+
+type CardPINRepo struct {
+	db *sql.DB
+}
+
+func NewCardPINRepository(db *sql.DB) CardPINRepository {
+	return &CardPINRepo{db: db}
+}
+
+// Get возвращает PIN карты. Сервис читает его под блокировкой строки карты.
+func (r *CardPINRepo) Get(ctx context.Context, cardID int64) (*model.CardPIN, error) {
+	query := `
+		SELECT card_id, pin_hash, failed_attempts, created_at, updated_at
+		FROM card_pins
+		WHERE card_id = $1`
+
+	pin := &model.CardPIN{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, cardID).Scan(
+		&pin.CardID,
+		&pin.Hash,
+		&pin.FailedAttempts,
+		&pin.CreatedAt,
+		&pin.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("card PIN %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pin, nil
+}
+
+// Save создает или заменяет PIN карты вместе со счетчиком неверных попыток
+func (r *CardPINRepo) Save(ctx context.Context, pin *model.CardPIN) error {
+	query := `
+		INSERT INTO card_pins (card_id, pin_hash, failed_attempts)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (card_id) DO UPDATE
+		SET pin_hash = EXCLUDED.pin_hash,
+			failed_attempts = EXCLUDED.failed_attempts,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING created_at, updated_at`
+
+	return executor(ctx, r.db).QueryRowContext(ctx, query,
+		pin.CardID,
+		pin.Hash,
+		pin.FailedAttempts,
+	).Scan(&pin.CreatedAt, &pin.UpdatedAt)
+}
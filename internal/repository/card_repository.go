@@ -3,7 +3,8 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"fmt"
+	"time"
 
 	"bank-app/internal/model"
 )
@@ -16,18 +17,253 @@ func NewCardRepository(db *sql.DB) CardRepository {
 	return &CardRepo{db: db}
 }
 
+// Колонка number хранит маскированный номер, полный номер и срок действия
+// зашифрованы в encrypted_data
+const cardColumns = `id, account_id, number, expiry_month, expiry_year, product, status,
+		replaces_card_id, single_use, merchant_locked, locked_merchant, details_revealed_at,
+		number_hmac, cvv_hash, encrypted_data, created_at, updated_at`
+
+// cardExpiryDate - последний день срока действия карты
+const cardExpiryDate = `(make_date(expiry_year, expiry_month, 1) + INTERVAL '1 month - 1 day')::date`
+
 func (r *CardRepo) Create(ctx context.Context, card *model.Card) error {
-	return errors.New("not implemented")
+	query := `
+		INSERT INTO cards (account_id, number, expiry_month, expiry_year, product, status,
+			replaces_card_id, single_use, merchant_locked, number_hmac, cvv_hash, encrypted_data)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		card.AccountID,
+		card.MaskedNumber,
+		int(card.ExpiryDate.Month()),
+		card.ExpiryDate.Year(),
+		card.Product,
+		card.Status,
+		nullInt64(card.ReplacesCardID),
+		card.SingleUse,
+		card.MerchantLocked,
+		card.NumberHMAC,
+		card.CVVHash,
+		card.EncryptedData,
+	).Scan(&card.ID, &card.CreatedAt, &card.UpdatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (r *CardRepo) GetByID(ctx context.Context, id int64) (*model.Card, error) {
-	return nil, errors.New("not implemented")
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE id = $1`
+	return r.getOne(ctx, query, id)
+}
+
+// GetByIDForUpdate получает карту с блокировкой строки до конца транзакции
+func (r *CardRepo) GetByIDForUpdate(ctx context.Context, id int64) (*model.Card, error) {
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE id = $1 FOR UPDATE`
+	return r.getOne(ctx, query, id)
+}
+
+// GetReplacement возвращает карту, перевыпущенную взамен карты id
+func (r *CardRepo) GetReplacement(ctx context.Context, id int64) (*model.Card, error) {
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE replaces_card_id = $1`
+	return r.getOne(ctx, query, id)
+}
+
+// GetByNumberHMAC ищет карту по HMAC номера
+func (r *CardRepo) GetByNumberHMAC(ctx context.Context, numberHMAC string) (*model.Card, error) {
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE number_hmac = $1`
+	return r.getOne(ctx, query, numberHMAC)
 }
 
 func (r *CardRepo) GetByAccountID(ctx context.Context, accountID int64) ([]*model.Card, error) {
-	return nil, errors.New("not implemented")
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE account_id = $1 ORDER BY id`
+	return r.getMany(ctx, query, accountID)
+}
+
+// GetRenewable возвращает действующие карты, срок которых истекает не позже
+// before и которые еще не перевыпущены. Виртуальные карты не перевыпускаются.
+func (r *CardRepo) GetRenewable(ctx context.Context, before time.Time, limit int) ([]*model.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards c
+		WHERE status IN ('active', 'blocked_temporarily')
+			AND product <> 'virtual'
+			AND ` + cardExpiryDate + ` <= $1::date
+			AND NOT EXISTS (SELECT 1 FROM cards n WHERE n.replaces_card_id = c.id)
+		ORDER BY id
+		LIMIT $2`
+	return r.getMany(ctx, query, before, limit)
+}
+
+// GetExpired возвращает карты, срок действия которых закончился до now,
+// но статус еще не изменен
+func (r *CardRepo) GetExpired(ctx context.Context, now time.Time, limit int) ([]*model.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards
+		WHERE status IN ('issued', 'active', 'blocked_temporarily')
+			AND ` + cardExpiryDate + ` < $1::date
+		ORDER BY id
+		LIMIT $2`
+	return r.getMany(ctx, query, now, limit)
+}
+
+func (r *CardRepo) getMany(ctx context.Context, query string, args ...interface{}) ([]*model.Card, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []*model.Card
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cards, nil
 }
 
+// Update сохраняет изменяемые поля карты: статус, закрепленного торговца и
+// зашифрованные реквизиты с отметкой об их раскрытии
 func (r *CardRepo) Update(ctx context.Context, card *model.Card) error {
-	return errors.New("not implemented")
+	query := `
+		UPDATE cards
+		SET status = $1, locked_merchant = $2, encrypted_data = $3, details_revealed_at = $4
+		WHERE id = $5
+		RETURNING updated_at`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		card.Status,
+		nullString(card.LockedMerchant),
+		card.EncryptedData,
+		card.DetailsRevealedAt,
+		card.ID,
+	).Scan(&card.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("card %w", ErrNotFound)
+	}
+
+	return err
+}
+
+// AddStatusChange записывает смену статуса карты в историю
+func (r *CardRepo) AddStatusChange(ctx context.Context, change *model.CardStatusChange) error {
+	query := `
+		INSERT INTO card_status_history (card_id, from_status, to_status, reason, changed_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return executor(ctx, r.db).QueryRowContext(ctx, query,
+		change.CardID,
+		change.FromStatus,
+		change.ToStatus,
+		change.Reason,
+		nullInt64(change.ChangedBy),
+	).Scan(&change.ID, &change.CreatedAt)
+}
+
+// GetStatusHistory возвращает историю статусов карты, последние изменения первыми
+func (r *CardRepo) GetStatusHistory(ctx context.Context, cardID int64) ([]*model.CardStatusChange, error) {
+	query := `
+		SELECT id, card_id, from_status, to_status, reason, changed_by, created_at
+		FROM card_status_history
+		WHERE card_id = $1
+		ORDER BY created_at DESC, id DESC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, cardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []*model.CardStatusChange
+	for rows.Next() {
+		change := &model.CardStatusChange{}
+		var changedBy sql.NullInt64
+		if err := rows.Scan(
+			&change.ID,
+			&change.CardID,
+			&change.FromStatus,
+			&change.ToStatus,
+			&change.Reason,
+			&changedBy,
+			&change.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		change.ChangedBy = changedBy.Int64
+		history = append(history, change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func (r *CardRepo) getOne(ctx context.Context, query string, arg interface{}) (*model.Card, error) {
+	card, err := scanCard(executor(ctx, r.db).QueryRowContext(ctx, query, arg))
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("card %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return card, nil
+}
+
+func scanCard(row rowScanner) (*model.Card, error) {
+	card := &model.Card{}
+	var (
+		expiryMonth, expiryYear int
+		replacesCardID          sql.NullInt64
+		lockedMerchant          sql.NullString
+		detailsRevealedAt       sql.NullTime
+	)
+
+	err := row.Scan(
+		&card.ID,
+		&card.AccountID,
+		&card.MaskedNumber,
+		&expiryMonth,
+		&expiryYear,
+		&card.Product,
+		&card.Status,
+		&replacesCardID,
+		&card.SingleUse,
+		&card.MerchantLocked,
+		&lockedMerchant,
+		&detailsRevealedAt,
+		&card.NumberHMAC,
+		&card.CVVHash,
+		&card.EncryptedData,
+		&card.CreatedAt,
+		&card.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	card.ExpiryDate = model.CardExpiryDate(expiryYear, time.Month(expiryMonth))
+	card.ReplacesCardID = replacesCardID.Int64
+	card.LockedMerchant = lockedMerchant.String
+	if detailsRevealedAt.Valid {
+		card.DetailsRevealedAt = &detailsRevealedAt.Time
+	}
+	return card, nil
 }
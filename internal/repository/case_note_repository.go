@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"bank-app/internal/model"
+)
+
+type CaseNoteRepo struct {
+	db *sql.DB
+}
+
+func NewCaseNoteRepository(db *sql.DB) CaseNoteRepository {
+	return &CaseNoteRepo{db: db}
+}
+
+func (r *CaseNoteRepo) Create(ctx context.Context, note *model.CaseNote) error {
+	query := `
+		INSERT INTO case_notes (user_id, author_id, account_id, card_id, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		note.UserID,
+		note.AuthorID,
+		nullInt64(note.AccountID),
+		nullInt64(note.CardID),
+		note.Note,
+	).Scan(&note.ID, &note.CreatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *CaseNoteRepo) GetByUserID(ctx context.Context, userID int64) ([]*model.CaseNote, error) {
+	query := `
+		SELECT id, user_id, author_id, account_id, card_id, note, created_at
+		FROM case_notes
+		WHERE user_id = $1
+		ORDER BY id DESC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []*model.CaseNote
+	for rows.Next() {
+		note := &model.CaseNote{}
+		var accountID, cardID sql.NullInt64
+		err := rows.Scan(
+			&note.ID,
+			&note.UserID,
+			&note.AuthorID,
+			&accountID,
+			&cardID,
+			&note.Note,
+			&note.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		note.AccountID = accountID.Int64
+		note.CardID = cardID.Int64
+		notes = append(notes, note)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
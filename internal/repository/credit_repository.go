@@ -3,7 +3,8 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"fmt"
+	"time"
 
 	"bank-app/internal/model"
 )
@@ -16,22 +17,370 @@ func NewCreditRepository(db *sql.DB) CreditRepository {
 	return &CreditRepo{db: db}
 }
 
+const creditColumns = `id, user_id, account_id, amount, interest_rate, product, term, schedule_type, day_count,
+	monthly_payment, status, next_payment_at, schedule_version, prepaid_at, created_at, updated_at`
+
+const paymentScheduleColumns = `id, credit_id, date, amount, principal, interest, paid_amount, penalty, penalty_paid,
+	penalty_accrued_to, status, paid_at, created_at, updated_at`
+
 func (r *CreditRepo) Create(ctx context.Context, credit *model.Credit) error {
-	return errors.New("not implemented")
+	query := `
+		INSERT INTO credits (user_id, account_id, amount, interest_rate, product, term, schedule_type, day_count,
+			monthly_payment, status, next_payment_at, schedule_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at`
+
+	return executor(ctx, r.db).QueryRowContext(ctx, query,
+		credit.UserID,
+		credit.AccountID,
+		credit.Amount,
+		credit.InterestRate,
+		credit.Product,
+		credit.Term,
+		credit.ScheduleType,
+		credit.DayCount,
+		credit.MonthlyPayment,
+		credit.Status,
+		credit.NextPaymentAt,
+		credit.ScheduleVersion,
+	).Scan(&credit.ID, &credit.CreatedAt, &credit.UpdatedAt)
 }
 
 func (r *CreditRepo) GetByID(ctx context.Context, id int64) (*model.Credit, error) {
-	return nil, errors.New("not implemented")
+	query := `SELECT ` + creditColumns + ` FROM credits WHERE id = $1`
+
+	credit, err := scanCredit(executor(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("credit %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return credit, nil
+}
+
+// GetByIDForUpdate возвращает кредит и блокирует его до конца транзакции
+func (r *CreditRepo) GetByIDForUpdate(ctx context.Context, id int64) (*model.Credit, error) {
+	query := `SELECT ` + creditColumns + ` FROM credits WHERE id = $1 FOR UPDATE`
+
+	credit, err := scanCredit(executor(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("credit %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return credit, nil
+}
+
+// GetDue возвращает незакрытые кредиты с неоплаченными платежами, срок
+// которых наступил к now, в порядке ID после afterID
+func (r *CreditRepo) GetDue(ctx context.Context, now time.Time, afterID int64, limit int) ([]*model.Credit, error) {
+	query := `
+		SELECT ` + creditColumns + `
+		FROM credits c
+		WHERE c.status <> $1 AND c.id > $2
+			AND EXISTS (
+				SELECT 1 FROM payment_schedules p
+				WHERE p.credit_id = c.id AND p.status <> $3 AND p.date <= $4
+			)
+		ORDER BY c.id
+		LIMIT $5`
+
+	return r.getMany(ctx, query, model.CreditClosed, afterID, model.PaymentPaid, now, limit)
 }
 
 func (r *CreditRepo) GetByUserID(ctx context.Context, userID int64) ([]*model.Credit, error) {
-	return nil, errors.New("not implemented")
+	query := `SELECT ` + creditColumns + ` FROM credits WHERE user_id = $1 ORDER BY id`
+
+	return r.getMany(ctx, query, userID)
+}
+
+func (r *CreditRepo) getMany(ctx context.Context, query string, args ...interface{}) ([]*model.Credit, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var credits []*model.Credit
+	for rows.Next() {
+		credit, err := scanCredit(rows)
+		if err != nil {
+			return nil, err
+		}
+		credits = append(credits, credit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
 }
 
 func (r *CreditRepo) Update(ctx context.Context, credit *model.Credit) error {
-	return errors.New("not implemented")
+	query := `
+		UPDATE credits
+		SET term = $1, monthly_payment = $2, status = $3, next_payment_at = $4, schedule_version = $5, prepaid_at = $6,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7
+		RETURNING updated_at`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		credit.Term,
+		credit.MonthlyPayment,
+		credit.Status,
+		credit.NextPaymentAt,
+		credit.ScheduleVersion,
+		credit.PrepaidAt,
+		credit.ID,
+	).Scan(&credit.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("credit %w", ErrNotFound)
+	}
+
+	return err
 }
 
+// GetSchedule возвращает график платежей по кредиту в порядке дат
 func (r *CreditRepo) GetSchedule(ctx context.Context, creditID int64) ([]*model.PaymentSchedule, error) {
-	return nil, errors.New("not implemented")
+	query := `SELECT ` + paymentScheduleColumns + ` FROM payment_schedules WHERE credit_id = $1 ORDER BY date`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, creditID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*model.PaymentSchedule
+	for rows.Next() {
+		payment, err := scanPaymentSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// CreateSchedule сохраняет строки графика. Вызывается в транзакции создания
+// кредита, чтобы кредит не остался без графика.
+func (r *CreditRepo) CreateSchedule(ctx context.Context, payments []*model.PaymentSchedule) error {
+	query := `
+		INSERT INTO payment_schedules (credit_id, date, amount, principal, interest, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`
+
+	for _, payment := range payments {
+		err := executor(ctx, r.db).QueryRowContext(ctx, query,
+			payment.CreditID,
+			payment.Date,
+			payment.Amount,
+			payment.Principal,
+			payment.Interest,
+			payment.Status,
+		).Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeletePendingSchedule удаляет платежи, к которым еще не приступали: перед
+// сохранением новой редакции графика или при досрочном закрытии кредита
+func (r *CreditRepo) DeletePendingSchedule(ctx context.Context, creditID int64) error {
+	query := `DELETE FROM payment_schedules WHERE credit_id = $1 AND status = $2`
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, creditID, model.PaymentPending)
+	return err
+}
+
+// CreateScheduleVersion сохраняет редакцию графика вместе с ее платежами
+func (r *CreditRepo) CreateScheduleVersion(ctx context.Context, version *model.ScheduleVersion) error {
+	query := `
+		INSERT INTO credit_schedule_versions (credit_id, version, mode, prepayment, principal, interest)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		version.CreditID,
+		version.Version,
+		version.Mode,
+		version.Prepayment,
+		version.Principal,
+		version.Interest,
+	).Scan(&version.ID, &version.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	paymentQuery := `
+		INSERT INTO credit_schedule_version_payments (version_id, date, amount, principal, interest)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	for _, payment := range version.Payments {
+		_, err := executor(ctx, r.db).ExecContext(ctx, paymentQuery,
+			version.ID,
+			payment.Date,
+			payment.Amount,
+			payment.Principal,
+			payment.Interest,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetScheduleVersions возвращает редакции графика кредита от первой к
+// последней вместе с их платежами
+func (r *CreditRepo) GetScheduleVersions(ctx context.Context, creditID int64) ([]*model.ScheduleVersion, error) {
+	query := `
+		SELECT v.id, v.credit_id, v.version, v.mode, v.prepayment, v.principal, v.interest, v.created_at,
+			p.date, p.amount, p.principal, p.interest
+		FROM credit_schedule_versions v
+		LEFT JOIN credit_schedule_version_payments p ON p.version_id = v.id
+		WHERE v.credit_id = $1
+		ORDER BY v.version, p.date`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, creditID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Редакция без платежей (кредит закрыт до первого платежа) приходит одной
+	// строкой с пустыми полями платежа
+	var versions []*model.ScheduleVersion
+	for rows.Next() {
+		var (
+			version model.ScheduleVersion
+			payment model.QuotePayment
+			date    sql.NullTime
+		)
+		err := rows.Scan(
+			&version.ID,
+			&version.CreditID,
+			&version.Version,
+			&version.Mode,
+			&version.Prepayment,
+			&version.Principal,
+			&version.Interest,
+			&version.CreatedAt,
+			&date,
+			&payment.Amount,
+			&payment.Principal,
+			&payment.Interest,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(versions) == 0 || versions[len(versions)-1].ID != version.ID {
+			version.Payments = []*model.QuotePayment{}
+			versions = append(versions, &version)
+		}
+		if date.Valid {
+			payment.Date = date.Time
+			current := versions[len(versions)-1]
+			current.Payments = append(current.Payments, &payment)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// UpdatePayment сохраняет погашение платежа и начисленную неустойку
+func (r *CreditRepo) UpdatePayment(ctx context.Context, payment *model.PaymentSchedule) error {
+	query := `
+		UPDATE payment_schedules
+		SET paid_amount = $1, penalty = $2, penalty_paid = $3, penalty_accrued_to = $4, status = $5, paid_at = $6,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7
+		RETURNING updated_at`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		payment.PaidAmount,
+		payment.Penalty,
+		payment.PenaltyPaid,
+		payment.PenaltyAccruedTo,
+		payment.Status,
+		payment.PaidAt,
+		payment.ID,
+	).Scan(&payment.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("payment schedule %w", ErrNotFound)
+	}
+
+	return err
+}
+
+func scanCredit(row rowScanner) (*model.Credit, error) {
+	credit := &model.Credit{}
+	err := row.Scan(
+		&credit.ID,
+		&credit.UserID,
+		&credit.AccountID,
+		&credit.Amount,
+		&credit.InterestRate,
+		&credit.Product,
+		&credit.Term,
+		&credit.ScheduleType,
+		&credit.DayCount,
+		&credit.MonthlyPayment,
+		&credit.Status,
+		&credit.NextPaymentAt,
+		&credit.ScheduleVersion,
+		&credit.PrepaidAt,
+		&credit.CreatedAt,
+		&credit.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return credit, nil
+}
+
+func scanPaymentSchedule(row rowScanner) (*model.PaymentSchedule, error) {
+	payment := &model.PaymentSchedule{}
+	err := row.Scan(
+		&payment.ID,
+		&payment.CreditID,
+		&payment.Date,
+		&payment.Amount,
+		&payment.Principal,
+		&payment.Interest,
+		&payment.PaidAmount,
+		&payment.Penalty,
+		&payment.PenaltyPaid,
+		&payment.PenaltyAccruedTo,
+		&payment.Status,
+		&payment.PaidAt,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
 }
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"bank-app/internal/model"
+)
+
+type IdempotencyRepo struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &IdempotencyRepo{db: db}
+}
+
+// Create резервирует ключ. Возвращает false, если ключ уже занят.
+func (r *IdempotencyRepo) Create(ctx context.Context, record *model.IdempotencyRecord) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (user_id, key, method, path, request_hash)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at, updated_at`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		nullInt64(record.UserID),
+		record.Key,
+		record.Method,
+		record.Path,
+		record.RequestHash,
+	).Scan(&record.ID, &record.CreatedAt, &record.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *IdempotencyRepo) Get(ctx context.Context, userID int64, key string) (*model.IdempotencyRecord, error) {
+	record := &model.IdempotencyRecord{}
+	query := `
+		SELECT id, user_id, key, method, path, request_hash, status_code, content_type, response_body, created_at, updated_at
+		FROM idempotency_keys
+		WHERE COALESCE(user_id, 0) = $1 AND key = $2`
+
+	var (
+		user        sql.NullInt64
+		statusCode  sql.NullInt64
+		contentType sql.NullString
+	)
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, userID, key).Scan(
+		&record.ID,
+		&user,
+		&record.Key,
+		&record.Method,
+		&record.Path,
+		&record.RequestHash,
+		&statusCode,
+		&contentType,
+		&record.ResponseBody,
+		&record.CreatedAt,
+		&record.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("idempotency key %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	record.UserID = user.Int64
+	record.StatusCode = int(statusCode.Int64)
+	record.ContentType = contentType.String
+
+	return record, nil
+}
+
+func (r *IdempotencyRepo) Complete(ctx context.Context, record *model.IdempotencyRecord) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $1, content_type = $2, response_body = $3
+		WHERE id = $4
+		RETURNING updated_at`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		record.StatusCode,
+		record.ContentType,
+		record.ResponseBody,
+		record.ID,
+	).Scan(&record.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("idempotency key %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepo) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM idempotency_keys WHERE id = $1`
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, id)
+	return err
+}
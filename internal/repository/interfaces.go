@@ -2,7 +2,9 @@ package repository
 
 import (
 	"bank-app/internal/model"
+	"bank-app/internal/money"
 	"context"
+	"time"
 )
 
 type UserRepository interface {
@@ -11,26 +13,65 @@ type UserRepository interface {
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
 	GetByUsername(ctx context.Context, username string) (*model.User, error)
 	Update(ctx context.Context, user *model.User) error
+	Search(ctx context.Context, query string, limit int) ([]*model.User, error)
+	IncrementFailedLogins(ctx context.Context, id int64, window time.Duration) (int, error)
+	ResetFailedLogins(ctx context.Context, id int64) error
+	Lock(ctx context.Context, id int64, until time.Time) error
 }
 
 type AccountRepository interface {
 	Create(ctx context.Context, account *model.Account) error
 	GetByID(ctx context.Context, id int64) (*model.Account, error)
+	GetByIDForUpdate(ctx context.Context, id int64) (*model.Account, error)
 	GetByUserID(ctx context.Context, userID int64) ([]*model.Account, error)
 	Update(ctx context.Context, account *model.Account) error
+	AdjustHeld(ctx context.Context, id int64, delta money.Amount) error
 }
 
 type CardRepository interface {
 	Create(ctx context.Context, card *model.Card) error
 	GetByID(ctx context.Context, id int64) (*model.Card, error)
 	GetByAccountID(ctx context.Context, accountID int64) ([]*model.Card, error)
+	GetByIDForUpdate(ctx context.Context, id int64) (*model.Card, error)
+	GetReplacement(ctx context.Context, id int64) (*model.Card, error)
+	GetByNumberHMAC(ctx context.Context, numberHMAC string) (*model.Card, error)
+	GetRenewable(ctx context.Context, before time.Time, limit int) ([]*model.Card, error)
+	GetExpired(ctx context.Context, now time.Time, limit int) ([]*model.Card, error)
 	Update(ctx context.Context, card *model.Card) error
+	AddStatusChange(ctx context.Context, change *model.CardStatusChange) error
+	GetStatusHistory(ctx context.Context, cardID int64) ([]*model.CardStatusChange, error)
+}
+
+type CardPINRepository interface {
+	Get(ctx context.Context, cardID int64) (*model.CardPIN, error)
+	Save(ctx context.Context, pin *model.CardPIN) error
+}
+
+type CardLimitRepository interface {
+	Create(ctx context.Context, limit *model.CardLimit) error
+	GetByID(ctx context.Context, id int64) (*model.CardLimit, error)
+	GetByCardID(ctx context.Context, cardID int64) ([]*model.CardLimit, error)
+	Update(ctx context.Context, limit *model.CardLimit) error
+	Delete(ctx context.Context, id int64) error
+	AddSpend(ctx context.Context, spend *model.CardSpend, day time.Time) error
+	// SumSpend возвращает расходы по карте за календарные дни [from, to)
+	SumSpend(ctx context.Context, cardID int64, channel, mcc string, from, to time.Time) (money.Amount, error)
+}
+
+type CardAuthorizationRepository interface {
+	Create(ctx context.Context, auth *model.CardAuthorization) error
+	GetByID(ctx context.Context, id int64) (*model.CardAuthorization, error)
+	GetByIDForUpdate(ctx context.Context, id int64) (*model.CardAuthorization, error)
+	GetByReference(ctx context.Context, acquirer, reference string) (*model.CardAuthorization, error)
+	GetCaptured(ctx context.Context, limit int) ([]*model.CardAuthorization, error)
+	GetExpired(ctx context.Context, now time.Time, limit int) ([]*model.CardAuthorization, error)
+	Update(ctx context.Context, auth *model.CardAuthorization) error
 }
 
 type TransferRepository interface {
 	Create(ctx context.Context, transaction *model.Transaction) error
 	GetByID(ctx context.Context, id int64) (*model.Transaction, error)
-	GetByAccountID(ctx context.Context, accountID int64) ([]*model.Transaction, error)
+	GetByAccountID(ctx context.Context, accountID int64, filter model.TransactionFilter) ([]*model.Transaction, error)
 	Update(ctx context.Context, transaction *model.Transaction) error
 }
 
@@ -40,10 +81,83 @@ type CreditRepository interface {
 	GetByUserID(ctx context.Context, userID int64) ([]*model.Credit, error)
 	Update(ctx context.Context, credit *model.Credit) error
 	GetSchedule(ctx context.Context, creditID int64) ([]*model.PaymentSchedule, error)
+	CreateSchedule(ctx context.Context, payments []*model.PaymentSchedule) error
+	GetByIDForUpdate(ctx context.Context, id int64) (*model.Credit, error)
+	GetDue(ctx context.Context, now time.Time, afterID int64, limit int) ([]*model.Credit, error)
+	UpdatePayment(ctx context.Context, payment *model.PaymentSchedule) error
+	DeletePendingSchedule(ctx context.Context, creditID int64) error
+	CreateScheduleVersion(ctx context.Context, version *model.ScheduleVersion) error
+	GetScheduleVersions(ctx context.Context, creditID int64) ([]*model.ScheduleVersion, error)
+}
+
+type KeyRateRepository interface {
+	GetLatest(ctx context.Context, at time.Time) (*model.KeyRate, error)
+	Save(ctx context.Context, rates []*model.KeyRate) error
 }
 
 type AnalyticsRepository interface {
 	GetTransactionsByPeriod(ctx context.Context, userID int64, from, to string) ([]*model.Transaction, error)
-	GetCreditLoad(ctx context.Context, userID int64) (float64, error)
-	PredictBalance(ctx context.Context, accountID int64, days int) (float64, error)
+	GetCreditLoad(ctx context.Context, userID int64) (money.Amount, error)
+	PredictBalance(ctx context.Context, accountID int64, days int) (money.Amount, error)
+}
+
+type LedgerRepository interface {
+	CreateEntry(ctx context.Context, entry *model.LedgerEntry) error
+	GetPostingsByAccountID(ctx context.Context, accountID int64) ([]*model.Posting, error)
+	GetAccountBalance(ctx context.Context, accountID int64) (money.Amount, error)
+}
+
+type IdempotencyRepository interface {
+	Create(ctx context.Context, record *model.IdempotencyRecord) (bool, error)
+	Get(ctx context.Context, userID int64, key string) (*model.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *model.IdempotencyRecord) error
+	Delete(ctx context.Context, id int64) error
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, record *model.AuditRecord) error
+	GetRecent(ctx context.Context, limit int) ([]*model.AuditRecord, error)
+}
+
+type CaseNoteRepository interface {
+	Create(ctx context.Context, note *model.CaseNote) error
+	GetByUserID(ctx context.Context, userID int64) ([]*model.CaseNote, error)
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *model.Session) error
+	GetByID(ctx context.Context, id int64) (*model.Session, error)
+	GetByRefreshTokenHash(ctx context.Context, hash string) (*model.Session, error)
+	GetActiveByUserID(ctx context.Context, userID int64) ([]*model.Session, error)
+	Update(ctx context.Context, session *model.Session) error
+}
+
+type RevokedTokenRepository interface {
+	Create(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type MFARepository interface {
+	Get(ctx context.Context, userID int64) (*model.MFA, error)
+	Save(ctx context.Context, mfa *model.MFA) error
+	Delete(ctx context.Context, userID int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error)
+	CreateChallenge(ctx context.Context, challenge *model.MFAChallenge) error
+	GetChallengeByTokenHash(ctx context.Context, hash string) (*model.MFAChallenge, error)
+	UpdateChallenge(ctx context.Context, challenge *model.MFAChallenge) error
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, token *model.PasswordResetToken) error
+	GetByTokenHash(ctx context.Context, hash string) (*model.PasswordResetToken, error)
+	// InvalidateByUserID погашает все неиспользованные токены пользователя
+	InvalidateByUserID(ctx context.Context, userID int64) error
+}
+
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, attempt *model.LoginAttempt) error
+	// CountFailuresByIP возвращает число неудачных попыток с IP после since
+	// и время самой ранней из них
+	CountFailuresByIP(ctx context.Context, ip string, since time.Time) (int, time.Time, error)
 }
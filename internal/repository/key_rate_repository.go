@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bank-app/internal/model"
+)
+
+type KeyRateRepo struct {
+	db *sql.DB
+}
+
+func NewKeyRateRepository(db *sql.DB) KeyRateRepository {
+	return &KeyRateRepo{db: db}
+}
+
+// GetLatest возвращает ставку, действующую на дату at: последнюю с датой
+// начала действия не позже at
+func (r *KeyRateRepo) GetLatest(ctx context.Context, at time.Time) (*model.KeyRate, error) {
+	query := `
+		SELECT effective_date, rate, fetched_at
+		FROM key_rates
+		WHERE effective_date <= $1
+		ORDER BY effective_date DESC
+		LIMIT 1`
+
+	rate := &model.KeyRate{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, at).Scan(
+		&rate.EffectiveDate,
+		&rate.Rate,
+		&rate.FetchedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("key rate %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rate, nil
+}
+
+// Save сохраняет полученные от ЦБ ставки, обновляя уже известные даты
+func (r *KeyRateRepo) Save(ctx context.Context, rates []*model.KeyRate) error {
+	query := `
+		INSERT INTO key_rates (effective_date, rate, fetched_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (effective_date) DO UPDATE
+		SET rate = EXCLUDED.rate,
+			fetched_at = EXCLUDED.fetched_at`
+
+	for _, rate := range rates {
+		_, err := executor(ctx, r.db).ExecContext(ctx, query, rate.EffectiveDate, rate.Rate, rate.FetchedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
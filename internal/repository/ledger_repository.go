@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+)
+
+type LedgerRepo struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) LedgerRepository {
+	return &LedgerRepo{db: db}
+}
+
+func (r *LedgerRepo) CreateEntry(ctx context.Context, entry *model.LedgerEntry) error {
+	conn := executor(ctx, r.db)
+
+	query := `
+		INSERT INTO ledger_entries (transaction_id, description)
+		VALUES ($1, $2)
+		RETURNING id, created_at`
+
+	err := conn.QueryRowContext(ctx, query,
+		nullInt64(entry.TransactionID),
+		entry.Description,
+	).Scan(&entry.ID, &entry.CreatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	postingQuery := `
+		INSERT INTO ledger_postings (entry_id, ledger_account, account_id, direction, amount)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	for _, posting := range entry.Postings {
+		posting.EntryID = entry.ID
+		err := conn.QueryRowContext(ctx, postingQuery,
+			posting.EntryID,
+			posting.LedgerAccount,
+			nullInt64(posting.AccountID),
+			posting.Direction,
+			posting.Amount,
+		).Scan(&posting.ID, &posting.CreatedAt)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *LedgerRepo) GetPostingsByAccountID(ctx context.Context, accountID int64) ([]*model.Posting, error) {
+	query := `
+		SELECT id, entry_id, ledger_account, account_id, direction, amount, created_at
+		FROM ledger_postings
+		WHERE account_id = $1
+		ORDER BY id`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var postings []*model.Posting
+	for rows.Next() {
+		posting := &model.Posting{}
+		var account sql.NullInt64
+		err := rows.Scan(
+			&posting.ID,
+			&posting.EntryID,
+			&posting.LedgerAccount,
+			&account,
+			&posting.Direction,
+			&posting.Amount,
+			&posting.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		posting.AccountID = account.Int64
+		postings = append(postings, posting)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return postings, nil
+}
+
+func (r *LedgerRepo) GetAccountBalance(ctx context.Context, accountID int64) (money.Amount, error) {
+	query := `
+		SELECT COALESCE(SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END), 0)
+		FROM ledger_postings
+		WHERE account_id = $1`
+
+	var balance money.Amount
+	if err := executor(ctx, r.db).QueryRowContext(ctx, query, accountID).Scan(&balance); err != nil {
+		return 0, err
+	}
+
+	return balance, nil
+}
+
+// nullInt64 сохраняет нулевой идентификатор как NULL
+func nullInt64(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: v != 0}
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"bank-app/internal/model"
+)
+
+type LoginAttemptRepo struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository(db *sql.DB) LoginAttemptRepository {
+	return &LoginAttemptRepo{db: db}
+}
+
+func (r *LoginAttemptRepo) Create(ctx context.Context, attempt *model.LoginAttempt) error {
+	query := `
+		INSERT INTO login_attempts (user_id, email, ip, user_agent, success, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return executor(ctx, r.db).QueryRowContext(ctx, query,
+		nullInt64(attempt.UserID),
+		attempt.Email,
+		attempt.IP,
+		attempt.UserAgent,
+		attempt.Success,
+		attempt.Reason,
+	).Scan(&attempt.ID, &attempt.CreatedAt)
+}
+
+// CountFailuresByIP считает неудачные проверки учетных данных с ip после since.
+// Отказы без проверки пароля (задержка, блокировка) не учитываются, иначе
+// повторные попытки продлевали бы ограничение бесконечно.
+func (r *LoginAttemptRepo) CountFailuresByIP(ctx context.Context, ip string, since time.Time) (int, time.Time, error) {
+	query := `
+		SELECT count(*), min(created_at)
+		FROM login_attempts
+		WHERE ip = $1 AND NOT success AND created_at > $2 AND reason IN ($3, $4)`
+
+	var (
+		count int
+		first sql.NullTime
+	)
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, ip, since, model.LoginUnknownEmail, model.LoginInvalidPassword).Scan(&count, &first)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return count, first.Time, nil
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"bank-app/internal/model"
+)
+
+type MFARepo struct {
+	db *sql.DB
+}
+
+func NewMFARepository(db *sql.DB) MFARepository {
+	return &MFARepo{db: db}
+}
+
+// Get возвращает настройки TOTP и блокирует их до конца транзакции,
+// чтобы параллельные проверки не приняли один и тот же код дважды
+func (r *MFARepo) Get(ctx context.Context, userID int64) (*model.MFA, error) {
+	mfa := &model.MFA{}
+	query := `
+		SELECT user_id, secret, enabled, last_used_step, confirmed_at, failed_attempts, locked_until,
+			created_at, updated_at
+		FROM user_mfa
+		WHERE user_id = $1
+		FOR UPDATE`
+
+	var confirmedAt, lockedUntil sql.NullTime
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(
+		&mfa.UserID,
+		&mfa.Secret,
+		&mfa.Enabled,
+		&mfa.LastUsedStep,
+		&confirmedAt,
+		&mfa.FailedAttempts,
+		&lockedUntil,
+		&mfa.CreatedAt,
+		&mfa.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("mfa settings %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if confirmedAt.Valid {
+		mfa.ConfirmedAt = &confirmedAt.Time
+	}
+
+	if lockedUntil.Valid {
+		mfa.LockedUntil = &lockedUntil.Time
+	}
+
+	return mfa, nil
+}
+
+func (r *MFARepo) Save(ctx context.Context, mfa *model.MFA) error {
+	query := `
+		INSERT INTO user_mfa (user_id, secret, enabled, last_used_step, confirmed_at, failed_attempts, locked_until)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, enabled = EXCLUDED.enabled,
+			last_used_step = EXCLUDED.last_used_step, confirmed_at = EXCLUDED.confirmed_at,
+			failed_attempts = EXCLUDED.failed_attempts, locked_until = EXCLUDED.locked_until
+		RETURNING created_at, updated_at`
+
+	return executor(ctx, r.db).QueryRowContext(ctx, query,
+		mfa.UserID,
+		mfa.Secret,
+		mfa.Enabled,
+		mfa.LastUsedStep,
+		mfa.ConfirmedAt,
+		mfa.FailedAttempts,
+		mfa.LockedUntil,
+	).Scan(&mfa.CreatedAt, &mfa.UpdatedAt)
+}
+
+func (r *MFARepo) Delete(ctx context.Context, userID int64) error {
+	db := executor(ctx, r.db)
+
+	if _, err := db.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	_, err := db.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+	return err
+}
+
+// ReplaceRecoveryCodes заменяет все коды восстановления пользователя новыми
+func (r *MFARepo) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	db := executor(ctx, r.db)
+
+	if _, err := db.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		query := `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+		if _, err := db.ExecContext(ctx, query, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode погашает код восстановления. Возвращает false, если код
+// не найден или уже использован.
+func (r *MFARepo) UseRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error) {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, userID, hash)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *MFARepo) CreateChallenge(ctx context.Context, challenge *model.MFAChallenge) error {
+	query := `
+		INSERT INTO mfa_challenges (user_id, token_hash, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return executor(ctx, r.db).QueryRowContext(ctx, query,
+		challenge.UserID,
+		challenge.TokenHash,
+		challenge.UserAgent,
+		challenge.IP,
+		challenge.ExpiresAt,
+	).Scan(&challenge.ID, &challenge.CreatedAt)
+}
+
+func (r *MFARepo) GetChallengeByTokenHash(ctx context.Context, hash string) (*model.MFAChallenge, error) {
+	challenge := &model.MFAChallenge{}
+	query := `
+		SELECT id, user_id, token_hash, user_agent, ip, attempts, expires_at, consumed_at, created_at
+		FROM mfa_challenges
+		WHERE token_hash = $1
+		FOR UPDATE`
+
+	var consumedAt sql.NullTime
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, hash).Scan(
+		&challenge.ID,
+		&challenge.UserID,
+		&challenge.TokenHash,
+		&challenge.UserAgent,
+		&challenge.IP,
+		&challenge.Attempts,
+		&challenge.ExpiresAt,
+		&consumedAt,
+		&challenge.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("mfa challenge %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if consumedAt.Valid {
+		challenge.ConsumedAt = &consumedAt.Time
+	}
+
+	return challenge, nil
+}
+
+func (r *MFARepo) UpdateChallenge(ctx context.Context, challenge *model.MFAChallenge) error {
+	query := `
+		UPDATE mfa_challenges
+		SET attempts = $1, consumed_at = $2
+		WHERE id = $3`
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query,
+		challenge.Attempts,
+		challenge.ConsumedAt,
+		challenge.ID,
+	)
+	return err
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"bank-app/internal/model"
+)
+
+type PasswordResetRepo struct {
+	db *sql.DB
+}
+
+func NewPasswordResetRepository(db *sql.DB) PasswordResetRepository {
+	return &PasswordResetRepo{db: db}
+}
+
+func (r *PasswordResetRepo) Create(ctx context.Context, token *model.PasswordResetToken) error {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	return executor(ctx, r.db).QueryRowContext(ctx, query,
+		token.UserID,
+		token.TokenHash,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+// GetByTokenHash возвращает токен и блокирует его до конца транзакции,
+// чтобы его нельзя было использовать дважды
+func (r *PasswordResetRepo) GetByTokenHash(ctx context.Context, hash string) (*model.PasswordResetToken, error) {
+	token := &model.PasswordResetToken{}
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1
+		FOR UPDATE`
+
+	var usedAt sql.NullTime
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, hash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&usedAt,
+		&token.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("password reset token %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+
+	return token, nil
+}
+
+func (r *PasswordResetRepo) InvalidateByUserID(ctx context.Context, userID int64) error {
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = now()
+		WHERE user_id = $1 AND used_at IS NULL`
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, userID)
+	return err
+}
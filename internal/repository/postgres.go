@@ -2,11 +2,15 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
 	_ "github.com/lib/pq"
 )
 
+// ErrNotFound возвращается (в обернутом виде), если запись не найдена
+var ErrNotFound = errors.New("not found")
+
 // NewPostgresDB creates a new connection to PostgreSQL
 func NewPostgresDB(databaseURL string) (*sql.DB, error) {
 	db, err := sql.Open("postgres", databaseURL)
@@ -22,21 +26,49 @@ func NewPostgresDB(databaseURL string) (*sql.DB, error) {
 }
 
 type Repositories struct {
-	Users     UserRepository
-	Accounts  AccountRepository
-	Cards     CardRepository
-	Credits   CreditRepository
-	Transfers TransferRepository
-	Analytics AnalyticsRepository
+	Transactor  Transactor
+	Users       UserRepository
+	Accounts    AccountRepository
+	Cards       CardRepository
+	CardPINs    CardPINRepository
+	CardLimits  CardLimitRepository
+	CardAuths   CardAuthorizationRepository
+	Credits     CreditRepository
+	KeyRates    KeyRateRepository
+	Transfers   TransferRepository
+	Analytics   AnalyticsRepository
+	Ledger      LedgerRepository
+	Idempotency IdempotencyRepository
+	Audit       AuditRepository
+	CaseNotes   CaseNoteRepository
+	Sessions    SessionRepository
+	Revoked     RevokedTokenRepository
+	MFA         MFARepository
+	Resets      PasswordResetRepository
+	Logins      LoginAttemptRepository
 }
 
 func NewRepositories(db *sql.DB) *Repositories {
 	return &Repositories{
-		Users:     NewUserRepository(db),
-		Accounts:  NewAccountRepository(db),
-		Cards:     NewCardRepository(db),
-		Credits:   NewCreditRepository(db),
-		Transfers: NewTransferRepository(db),
-		Analytics: NewAnalyticsRepository(db),
+		Transactor:  NewTransactor(db),
+		Users:       NewUserRepository(db),
+		Accounts:    NewAccountRepository(db),
+		Cards:       NewCardRepository(db),
+		CardPINs:    NewCardPINRepository(db),
+		CardLimits:  NewCardLimitRepository(db),
+		CardAuths:   NewCardAuthorizationRepository(db),
+		Credits:     NewCreditRepository(db),
+		KeyRates:    NewKeyRateRepository(db),
+		Transfers:   NewTransferRepository(db),
+		Analytics:   NewAnalyticsRepository(db),
+		Ledger:      NewLedgerRepository(db),
+		Idempotency: NewIdempotencyRepository(db),
+		Audit:       NewAuditRepository(db),
+		CaseNotes:   NewCaseNoteRepository(db),
+		Sessions:    NewSessionRepository(db),
+		Revoked:     NewRevokedTokenRepository(db),
+		MFA:         NewMFARepository(db),
+		Resets:      NewPasswordResetRepository(db),
+		Logins:      NewLoginAttemptRepository(db),
 	}
 }
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type RevokedTokenRepo struct {
+	db *sql.DB
+}
+
+func NewRevokedTokenRepository(db *sql.DB) RevokedTokenRepository {
+	return &RevokedTokenRepo{db: db}
+}
+
+// Create добавляет jti в список отзыва. Заодно удаляются истекшие записи,
+// чтобы список не рос бесконечно.
+func (r *RevokedTokenRepo) Create(ctx context.Context, jti string, expiresAt time.Time) error {
+	db := executor(ctx, r.db)
+
+	if _, err := db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < now()`); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`
+
+	_, err := db.ExecContext(ctx, query, jti, expiresAt)
+	return err
+}
+
+func (r *RevokedTokenRepo) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, jti).Scan(&revoked)
+	return revoked, err
+}
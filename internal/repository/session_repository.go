@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"bank-app/internal/model"
+)
+
+type SessionRepo struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) SessionRepository {
+	return &SessionRepo{db: db}
+}
+
+const sessionColumns = `id, user_id, refresh_token_hash, previous_token_hash, access_jti, access_expires_at,
+		user_agent, ip, expires_at, revoked_at, mfa_verified_at, created_at, last_used_at`
+
+func (r *SessionRepo) Create(ctx context.Context, session *model.Session) error {
+	query := `
+		INSERT INTO sessions (user_id, refresh_token_hash, access_jti, access_expires_at, user_agent, ip, expires_at, mfa_verified_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, last_used_at`
+
+	return executor(ctx, r.db).QueryRowContext(ctx, query,
+		session.UserID,
+		session.RefreshTokenHash,
+		session.AccessJTI,
+		session.AccessExpiresAt,
+		session.UserAgent,
+		session.IP,
+		session.ExpiresAt,
+		session.MFAVerifiedAt,
+	).Scan(&session.ID, &session.CreatedAt, &session.LastUsedAt)
+}
+
+func (r *SessionRepo) GetByID(ctx context.Context, id int64) (*model.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1 FOR UPDATE`
+
+	session, err := scanSession(executor(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session %w", ErrNotFound)
+	}
+
+	return session, err
+}
+
+// GetByRefreshTokenHash ищет сессию по текущему или предыдущему refresh-токену
+// и блокирует ее до конца транзакции
+func (r *SessionRepo) GetByRefreshTokenHash(ctx context.Context, hash string) (*model.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE refresh_token_hash = $1 OR previous_token_hash = $1
+		FOR UPDATE`
+
+	session, err := scanSession(executor(ctx, r.db).QueryRowContext(ctx, query, hash))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session %w", ErrNotFound)
+	}
+
+	return session, err
+}
+
+func (r *SessionRepo) GetActiveByUserID(ctx context.Context, userID int64) ([]*model.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_used_at DESC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*model.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (r *SessionRepo) Update(ctx context.Context, session *model.Session) error {
+	query := `
+		UPDATE sessions
+		SET refresh_token_hash = $1, previous_token_hash = $2, access_jti = $3, access_expires_at = $4,
+			user_agent = $5, ip = $6, revoked_at = $7, mfa_verified_at = $8, last_used_at = $9
+		WHERE id = $10`
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query,
+		session.RefreshTokenHash,
+		nullString(session.PreviousTokenHash),
+		session.AccessJTI,
+		session.AccessExpiresAt,
+		session.UserAgent,
+		session.IP,
+		session.RevokedAt,
+		session.MFAVerifiedAt,
+		session.LastUsedAt,
+		session.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("session %w", ErrNotFound)
+	}
+
+	return nil
+}
+
+func scanSession(row rowScanner) (*model.Session, error) {
+	session := &model.Session{}
+	var (
+		previous      sql.NullString
+		revokedAt     sql.NullTime
+		mfaVerifiedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.RefreshTokenHash,
+		&previous,
+		&session.AccessJTI,
+		&session.AccessExpiresAt,
+		&session.UserAgent,
+		&session.IP,
+		&session.ExpiresAt,
+		&revokedAt,
+		&mfaVerifiedAt,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	session.PreviousTokenHash = previous.String
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	if mfaVerifiedAt.Valid {
+		session.MFAVerifiedAt = &mfaVerifiedAt.Time
+	}
+
+	return session, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -3,7 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"fmt"
 
 	"bank-app/internal/model"
 )
@@ -17,17 +17,152 @@ func NewTransferRepository(db *sql.DB) TransferRepository {
 }
 
 func (r *TransferRepo) Create(ctx context.Context, transaction *model.Transaction) error {
-	return errors.New("not implemented")
+	query := `
+		INSERT INTO transactions (from_account_id, to_account_id, amount, type, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		nullInt64(transaction.FromAccountID),
+		nullInt64(transaction.ToAccountID),
+		transaction.Amount,
+		transaction.Type,
+		transaction.Status,
+	).Scan(&transaction.ID, &transaction.CreatedAt, &transaction.UpdatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (r *TransferRepo) GetByID(ctx context.Context, id int64) (*model.Transaction, error) {
-	return nil, errors.New("not implemented")
+	query := `
+		SELECT id, from_account_id, to_account_id, amount, type, status, created_at, updated_at
+		FROM transactions
+		WHERE id = $1`
+
+	transaction, err := scanTransaction(executor(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("transaction %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
 }
 
-func (r *TransferRepo) GetByAccountID(ctx context.Context, accountID int64) ([]*model.Transaction, error) {
-	return nil, errors.New("not implemented")
+// GetByAccountID возвращает операции по счету от новых к старым.
+// Курсор - ID последней полученной операции.
+func (r *TransferRepo) GetByAccountID(ctx context.Context, accountID int64, filter model.TransactionFilter) ([]*model.Transaction, error) {
+	query := `
+		SELECT id, from_account_id, to_account_id, amount, type, status, created_at, updated_at
+		FROM transactions
+		WHERE (from_account_id = $1 OR to_account_id = $1)`
+	args := []interface{}{accountID}
+
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		query += fmt.Sprintf(" AND "+condition, len(args))
+	}
+
+	if filter.Cursor > 0 {
+		addCondition("id < $%d", filter.Cursor)
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < $%d", filter.To)
+	}
+	if filter.MinAmount > 0 {
+		addCondition("amount >= $%d", filter.MinAmount)
+	}
+	if filter.MaxAmount > 0 {
+		addCondition("amount <= $%d", filter.MaxAmount)
+	}
+	if filter.Status != "" {
+		addCondition("status = $%d", filter.Status)
+	}
+
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*model.Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
 }
 
 func (r *TransferRepo) Update(ctx context.Context, transaction *model.Transaction) error {
-	return errors.New("not implemented")
+	query := `
+		UPDATE transactions
+		SET status = $1
+		WHERE id = $2
+		RETURNING updated_at`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		transaction.Status,
+		transaction.ID,
+	).Scan(&transaction.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("transaction %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransaction(row rowScanner) (*model.Transaction, error) {
+	transaction := &model.Transaction{}
+	var from, to sql.NullInt64
+
+	err := row.Scan(
+		&transaction.ID,
+		&from,
+		&to,
+		&transaction.Amount,
+		&transaction.Type,
+		&transaction.Status,
+		&transaction.CreatedAt,
+		&transaction.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction.FromAccountID = from.Int64
+	transaction.ToAccountID = to.Int64
+
+	return transaction, nil
 }
@@ -4,7 +4,9 @@ import (
 	"bank-app/internal/model"
 	"context"
 	"database/sql"
-	"errors"
+	"fmt"
+	"strings"
+	"time"
 )
 
 type UserRepo struct {
@@ -15,16 +17,20 @@ func NewUserRepository(db *sql.DB) UserRepository {
 	return &UserRepo{db: db}
 }
 
+const userColumns = `id, username, email, password_hash, role, email_verified_at,
+		failed_logins, last_failed_login_at, locked_until, created_at, updated_at`
+
 func (r *UserRepo) Create(ctx context.Context, user *model.User) error {
 	query := `
-		INSERT INTO users (username, email, password_hash)
-		VALUES ($1, $2, $3)
+		INSERT INTO users (username, email, password_hash, role)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRowContext(ctx, query,
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
 		user.Username,
 		user.Email,
 		user.PasswordHash,
+		user.Role,
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
@@ -35,23 +41,25 @@ func (r *UserRepo) Create(ctx context.Context, user *model.User) error {
 }
 
 func (r *UserRepo) GetByID(ctx context.Context, id int64) (*model.User, error) {
-	user := &model.User{}
-	query := `
-		SELECT id, username, email, password_hash, created_at, updated_at
-		FROM users
-		WHERE id = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	return r.getOne(ctx, query, id)
+}
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+	return r.getOne(ctx, query, email)
+}
+
+func (r *UserRepo) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = $1`
+	return r.getOne(ctx, query, username)
+}
+
+func (r *UserRepo) getOne(ctx context.Context, query string, arg interface{}) (*model.User, error) {
+	user, err := scanUser(executor(ctx, r.db).QueryRowContext(ctx, query, arg))
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+		return nil, fmt.Errorf("user %w", ErrNotFound)
 	}
 
 	if err != nil {
@@ -61,77 +69,142 @@ func (r *UserRepo) GetByID(ctx context.Context, id int64) (*model.User, error) {
 	return user, nil
 }
 
-func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
-	user := &model.User{}
+// Update сохраняет профиль пользователя. Счетчики неудачных входов
+// меняются только атомарными методами ниже.
+func (r *UserRepo) Update(ctx context.Context, user *model.User) error {
 	query := `
-		SELECT id, username, email, password_hash, created_at, updated_at
-		FROM users
-		WHERE email = $1`
+		UPDATE users
+		SET username = $1, email = $2, password_hash = $3, role = $4, email_verified_at = $5
+		WHERE id = $6
+		RETURNING updated_at`
 
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err := executor(ctx, r.db).QueryRowContext(ctx, query,
+		user.Username,
+		user.Email,
+		user.PasswordHash,
+		user.Role,
+		user.EmailVerifiedAt,
+		user.ID,
+	).Scan(&user.UpdatedAt)
 
-	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+	if err != nil {
+		return err
 	}
 
+	return nil
+}
+
+// Search ищет пользователей по подстроке имени или email
+func (r *UserRepo) Search(ctx context.Context, query string, limit int) ([]*model.User, error) {
+	sqlQuery := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE username ILIKE $1 OR email ILIKE $1
+		ORDER BY id
+		LIMIT $2`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, sqlQuery, "%"+escapeLike(query)+"%", limit)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
 
-	return user, nil
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
 }
 
-func (r *UserRepo) GetByUsername(ctx context.Context, username string) (*model.User, error) {
-	user := &model.User{}
+// IncrementFailedLogins атомарно увеличивает счетчик неудачных входов.
+// Если предыдущая неудача была раньше window, счет начинается заново.
+func (r *UserRepo) IncrementFailedLogins(ctx context.Context, id int64, window time.Duration) (int, error) {
 	query := `
-		SELECT id, username, email, password_hash, created_at, updated_at
-		FROM users
-		WHERE username = $1`
+		UPDATE users
+		SET failed_logins = CASE
+				WHEN last_failed_login_at > now() - make_interval(secs => $2) THEN failed_logins + 1
+				ELSE 1
+			END,
+			last_failed_login_at = now()
+		WHERE id = $1
+		RETURNING failed_logins`
+
+	var failures int
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, id, window.Seconds()).Scan(&failures)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("user %w", ErrNotFound)
+	}
+
+	return failures, err
+}
+
+// ResetFailedLogins сбрасывает счетчик неудачных входов и блокировку
+func (r *UserRepo) ResetFailedLogins(ctx context.Context, id int64) error {
+	query := `
+		UPDATE users
+		SET failed_logins = 0, last_failed_login_at = NULL, locked_until = NULL
+		WHERE id = $1`
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, id)
+	return err
+}
+
+// Lock блокирует вход до указанного момента
+func (r *UserRepo) Lock(ctx context.Context, id int64, until time.Time) error {
+	query := `UPDATE users SET locked_until = $1 WHERE id = $2`
 
-	err := r.db.QueryRowContext(ctx, query, username).Scan(
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, until, id)
+	return err
+}
+
+func scanUser(row rowScanner) (*model.User, error) {
+	user := &model.User{}
+	var (
+		emailVerifiedAt   sql.NullTime
+		lastFailedLoginAt sql.NullTime
+		lockedUntil       sql.NullTime
+	)
+
+	err := row.Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
+		&user.Role,
+		&emailVerifiedAt,
+		&user.FailedLogins,
+		&lastFailedLoginAt,
+		&lockedUntil,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
-
-	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
-	}
-
 	if err != nil {
 		return nil, err
 	}
 
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	if lastFailedLoginAt.Valid {
+		user.LastFailedLoginAt = &lastFailedLoginAt.Time
+	}
+	if lockedUntil.Valid {
+		user.LockedUntil = &lockedUntil.Time
+	}
+
 	return user, nil
 }
 
-func (r *UserRepo) Update(ctx context.Context, user *model.User) error {
-	query := `
-		UPDATE users
-		SET username = $1, email = $2, password_hash = $3
-		WHERE id = $4
-		RETURNING updated_at`
-
-	err := r.db.QueryRowContext(ctx, query,
-		user.Username,
-		user.Email,
-		user.PasswordHash,
-		user.ID,
-	).Scan(&user.UpdatedAt)
-
-	if err != nil {
-		return err
-	}
-
-	return nil
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
@@ -0,0 +1,88 @@
+// Package scheduler периодически запускает фоновые задачи приложения
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Job - задача, которая выполняется сразу после запуска и затем раз в Interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler запускает задачи в отдельных горутинах. Следующий запуск задачи
+// начинается не раньше, чем закончится предыдущий.
+type Scheduler struct {
+	jobs   []Job
+	logger *logrus.Logger
+	wg     sync.WaitGroup
+}
+
+func New(logger *logrus.Logger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+// Add регистрирует задачу. Задачи с неположительным интервалом не запускаются.
+func (s *Scheduler) Add(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Start запускает все задачи до отмены ctx
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		if job.Interval <= 0 {
+			s.logger.WithField("job", job.Name).Warn("job is disabled")
+			continue
+		}
+
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+}
+
+// Wait ждет завершения задач после отмены контекста
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, job Job) {
+	logger := s.logger.WithField("job", job.Name)
+
+	// Паника в задаче не должна останавливать остальные задачи и сервер
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	started := time.Now()
+	if err := job.Run(ctx); err != nil {
+		logger.WithError(err).Error("job failed")
+		return
+	}
+	logger.WithField("duration", time.Since(started)).Debug("job finished")
+}
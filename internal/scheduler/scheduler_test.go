@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestScheduler_RunsJobsUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := New(testLogger())
+
+	var runs, failures, panics atomic.Int32
+	s.Add("counter", 5*time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+	s.Add("failing", 5*time.Millisecond, func(ctx context.Context) error {
+		failures.Add(1)
+		return errors.New("boom")
+	})
+	s.Add("panicking", 5*time.Millisecond, func(ctx context.Context) error {
+		panics.Add(1)
+		panic("boom")
+	})
+	s.Add("disabled", 0, func(ctx context.Context) error {
+		t.Error("disabled job must not run")
+		return nil
+	})
+
+	// Действие
+	s.Start(ctx)
+	assert.Eventually(t, func() bool {
+		return runs.Load() >= 3 && failures.Load() >= 3 && panics.Load() >= 3
+	}, time.Second, time.Millisecond)
+	cancel()
+	s.Wait()
+
+	// Проверка: после остановки задачи больше не запускаются
+	stopped := runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, stopped, runs.Load())
+}
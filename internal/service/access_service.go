@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"bank-app/internal/model"
+	"bank-app/internal/repository"
+)
+
+// ErrNotFound возвращается, если ресурс не существует или не принадлежит пользователю.
+// Чужие ресурсы намеренно неотличимы от несуществующих.
+var ErrNotFound = repository.ErrNotFound
+
+// AccessSvc - единая политика доступа клиента к ресурсам
+type AccessSvc struct {
+	accounts  repository.AccountRepository
+	cards     repository.CardRepository
+	credits   repository.CreditRepository
+	transfers repository.TransferRepository
+}
+
+func NewAccessService(accounts repository.AccountRepository, cards repository.CardRepository, credits repository.CreditRepository, transfers repository.TransferRepository) AccessService {
+	return &AccessSvc{
+		accounts:  accounts,
+		cards:     cards,
+		credits:   credits,
+		transfers: transfers,
+	}
+}
+
+func (s *AccessSvc) Account(ctx context.Context, userID, accountID int64) (*model.Account, error) {
+	account, err := s.accounts.GetByID(ctx, accountID)
+	if err != nil {
+		return nil, notFound("account", err)
+	}
+
+	if account.UserID != userID {
+		return nil, fmt.Errorf("account %w", ErrNotFound)
+	}
+
+	return account, nil
+}
+
+func (s *AccessSvc) Card(ctx context.Context, userID, cardID int64) (*model.Card, error) {
+	card, err := s.cards.GetByID(ctx, cardID)
+	if err != nil {
+		return nil, notFound("card", err)
+	}
+
+	if _, err := s.Account(ctx, userID, card.AccountID); err != nil {
+		return nil, notFound("card", err)
+	}
+
+	return card, nil
+}
+
+func (s *AccessSvc) Credit(ctx context.Context, userID, creditID int64) (*model.Credit, error) {
+	credit, err := s.credits.GetByID(ctx, creditID)
+	if err != nil {
+		return nil, notFound("credit", err)
+	}
+
+	if credit.UserID != userID {
+		return nil, fmt.Errorf("credit %w", ErrNotFound)
+	}
+
+	return credit, nil
+}
+
+// Transaction доступна владельцу любого из участвующих в ней счетов
+func (s *AccessSvc) Transaction(ctx context.Context, userID, transactionID int64) (*model.Transaction, error) {
+	transaction, err := s.transfers.GetByID(ctx, transactionID)
+	if err != nil {
+		return nil, notFound("transaction", err)
+	}
+
+	for _, accountID := range []int64{transaction.FromAccountID, transaction.ToAccountID} {
+		if accountID == 0 {
+			continue
+		}
+
+		_, err := s.Account(ctx, userID, accountID)
+		if err == nil {
+			return transaction, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("transaction %w", ErrNotFound)
+}
+
+// notFound приводит отсутствие связанной записи к ошибке доступа к ресурсу,
+// сохраняя прочие ошибки (например, сбои БД) как есть
+func notFound(resource string, err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%s %w", resource, ErrNotFound)
+	}
+	return err
+}
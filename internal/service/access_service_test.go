@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"bank-app/internal/model"
+	"bank-app/internal/repository"
+)
+
+type MockCardRepository struct {
+	mock.Mock
+}
+
+func (m *MockCardRepository) Create(ctx context.Context, card *model.Card) error {
+	args := m.Called(ctx, card)
+	return args.Error(0)
+}
+
+func (m *MockCardRepository) GetByID(ctx context.Context, id int64) (*model.Card, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Card), args.Error(1)
+}
+
+func (m *MockCardRepository) GetByAccountID(ctx context.Context, accountID int64) ([]*model.Card, error) {
+	args := m.Called(ctx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Card), args.Error(1)
+}
+
+func (m *MockCardRepository) GetByNumberHMAC(ctx context.Context, numberHMAC string) (*model.Card, error) {
+	args := m.Called(ctx, numberHMAC)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Card), args.Error(1)
+}
+
+func (m *MockCardRepository) GetByIDForUpdate(ctx context.Context, id int64) (*model.Card, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Card), args.Error(1)
+}
+
+func (m *MockCardRepository) GetReplacement(ctx context.Context, id int64) (*model.Card, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Card), args.Error(1)
+}
+
+func (m *MockCardRepository) GetRenewable(ctx context.Context, before time.Time, limit int) ([]*model.Card, error) {
+	args := m.Called(ctx, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Card), args.Error(1)
+}
+
+func (m *MockCardRepository) GetExpired(ctx context.Context, now time.Time, limit int) ([]*model.Card, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Card), args.Error(1)
+}
+
+func (m *MockCardRepository) Update(ctx context.Context, card *model.Card) error {
+	args := m.Called(ctx, card)
+	return args.Error(0)
+}
+
+func (m *MockCardRepository) AddStatusChange(ctx context.Context, change *model.CardStatusChange) error {
+	args := m.Called(ctx, change)
+	return args.Error(0)
+}
+
+func (m *MockCardRepository) GetStatusHistory(ctx context.Context, cardID int64) ([]*model.CardStatusChange, error) {
+	args := m.Called(ctx, cardID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.CardStatusChange), args.Error(1)
+}
+
+func TestAccessService(t *testing.T) {
+	ctx := context.Background()
+	notFoundErr := fmt.Errorf("account %w", repository.ErrNotFound)
+
+	newService := func() (*AccessSvc, *MockAccountRepository, *MockCardRepository, *MockCreditRepository, *MockTransferRepository) {
+		accounts := new(MockAccountRepository)
+		cards := new(MockCardRepository)
+		credits := new(MockCreditRepository)
+		transfers := new(MockTransferRepository)
+		return NewAccessService(accounts, cards, credits, transfers).(*AccessSvc), accounts, cards, credits, transfers
+	}
+
+	t.Run("свой счет", func(t *testing.T) {
+		// Подготовка
+		service, accounts, _, _, _ := newService()
+		accounts.On("GetByID", ctx, int64(1)).Return(&model.Account{ID: 1, UserID: 10}, nil)
+
+		// Действие
+		account, err := service.Account(ctx, 10, 1)
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), account.ID)
+	})
+
+	t.Run("чужой счет неотличим от несуществующего", func(t *testing.T) {
+		// Подготовка
+		service, accounts, _, _, _ := newService()
+		accounts.On("GetByID", ctx, int64(1)).Return(&model.Account{ID: 1, UserID: 20}, nil)
+		accounts.On("GetByID", ctx, int64(2)).Return(nil, notFoundErr)
+
+		// Действие
+		_, foreignErr := service.Account(ctx, 10, 1)
+		_, missingErr := service.Account(ctx, 10, 2)
+
+		// Проверка
+		assert.ErrorIs(t, foreignErr, ErrNotFound)
+		assert.ErrorIs(t, missingErr, ErrNotFound)
+		assert.Equal(t, missingErr.Error(), foreignErr.Error())
+	})
+
+	t.Run("ошибка БД не маскируется", func(t *testing.T) {
+		// Подготовка
+		service, accounts, _, _, _ := newService()
+		accounts.On("GetByID", ctx, int64(1)).Return(nil, errors.New("connection refused"))
+
+		// Действие
+		_, err := service.Account(ctx, 10, 1)
+
+		// Проверка
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("карта на чужом счете", func(t *testing.T) {
+		// Подготовка
+		service, accounts, cards, _, _ := newService()
+		cards.On("GetByID", ctx, int64(5)).Return(&model.Card{ID: 5, AccountID: 1}, nil)
+		accounts.On("GetByID", ctx, int64(1)).Return(&model.Account{ID: 1, UserID: 20}, nil)
+
+		// Действие
+		_, err := service.Card(ctx, 10, 5)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Equal(t, "card not found", err.Error())
+	})
+
+	t.Run("чужой кредит", func(t *testing.T) {
+		// Подготовка
+		service, _, _, credits, _ := newService()
+		credits.On("GetByID", ctx, int64(3)).Return(&model.Credit{ID: 3, UserID: 20}, nil)
+
+		// Действие
+		_, err := service.Credit(ctx, 10, 3)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("входящий перевод виден получателю", func(t *testing.T) {
+		// Подготовка
+		service, accounts, _, _, transfers := newService()
+		transfers.On("GetByID", ctx, int64(7)).Return(&model.Transaction{ID: 7, FromAccountID: 1, ToAccountID: 2}, nil)
+		accounts.On("GetByID", ctx, int64(1)).Return(&model.Account{ID: 1, UserID: 20}, nil)
+		accounts.On("GetByID", ctx, int64(2)).Return(&model.Account{ID: 2, UserID: 10}, nil)
+
+		// Действие
+		transaction, err := service.Transaction(ctx, 10, 7)
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), transaction.ID)
+	})
+
+	t.Run("чужой перевод", func(t *testing.T) {
+		// Подготовка
+		service, accounts, _, _, transfers := newService()
+		transfers.On("GetByID", ctx, int64(7)).Return(&model.Transaction{ID: 7, FromAccountID: 1, ToAccountID: 2}, nil)
+		accounts.On("GetByID", ctx, mock.Anything).Return(&model.Account{UserID: 20}, nil)
+
+		// Действие
+		_, err := service.Transaction(ctx, 10, 7)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Equal(t, "transaction not found", err.Error())
+	})
+}
@@ -8,15 +8,24 @@ import (
 	"time"
 
 	"bank-app/internal/model"
+	"bank-app/internal/money"
 	"bank-app/internal/repository"
 )
 
 type AccountSvc struct {
-	repo repository.AccountRepository
+	repo      repository.AccountRepository
+	transfers repository.TransferRepository
+	ledger    LedgerService
+	tx        repository.Transactor
 }
 
-func NewAccountService(repo repository.AccountRepository) AccountService {
-	return &AccountSvc{repo: repo}
+func NewAccountService(repo repository.AccountRepository, transfers repository.TransferRepository, ledger LedgerService, tx repository.Transactor) AccountService {
+	return &AccountSvc{
+		repo:      repo,
+		transfers: transfers,
+		ledger:    ledger,
+		tx:        tx,
+	}
 }
 
 func (s *AccountSvc) Create(ctx context.Context, userID int64) error {
@@ -28,7 +37,9 @@ func (s *AccountSvc) Create(ctx context.Context, userID int64) error {
 		UserID:   userID,
 		Number:   accountNumber,
 		Balance:  0,
-		Currency: "RUB",
+		Currency: money.RUB,
+		Status:   model.AccountActive,
+		TimeZone: model.DefaultTimeZone,
 	}
 
 	return s.repo.Create(ctx, account)
@@ -42,17 +53,29 @@ func (s *AccountSvc) GetByUserID(ctx context.Context, userID int64) ([]*model.Ac
 	return s.repo.GetByUserID(ctx, userID)
 }
 
-func (s *AccountSvc) UpdateBalance(ctx context.Context, id int64, amount float64) error {
-	account, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		return err
+// UpdateBalance вносит (amount > 0) или снимает (amount < 0) наличные через главную книгу
+func (s *AccountSvc) UpdateBalance(ctx context.Context, id int64, amount money.Amount) error {
+	if amount == 0 {
+		return errors.New("amount must not be zero")
 	}
 
-	newBalance := account.Balance + amount
-	if newBalance < 0 {
-		return errors.New("insufficient funds")
-	}
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		transaction := &model.Transaction{
+			ToAccountID: id,
+			Amount:      amount,
+			Type:        "deposit",
+			Status:      "completed",
+		}
+		if amount.IsNegative() {
+			transaction.FromAccountID, transaction.ToAccountID = id, 0
+			transaction.Amount = amount.Abs()
+			transaction.Type = "withdrawal"
+		}
+
+		if err := s.transfers.Create(ctx, transaction); err != nil {
+			return err
+		}
 
-	account.Balance = newBalance
-	return s.repo.Update(ctx, account)
+		return s.ledger.Post(ctx, cashEntry(transaction.ID, id, amount))
+	})
 }
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bank-app/internal/model"
+	"bank-app/internal/repository"
+)
+
+// Разрешения бэк-офиса
+const (
+	PermSearchUsers    = "users:search"
+	PermManageRoles    = "users:manage_roles"
+	PermViewAccounts   = "accounts:view"
+	PermFreezeAccounts = "accounts:freeze"
+	PermBlockCards     = "cards:block"
+	PermAnnotateCases  = "cases:annotate"
+	PermViewAudit      = "audit:view"
+)
+
+// rolePermissions - разрешения сотрудников по ролям. Клиенты не имеют доступа к бэк-офису.
+var rolePermissions = map[string]map[string]bool{
+	model.RoleOperator: {
+		PermSearchUsers:    true,
+		PermViewAccounts:   true,
+		PermFreezeAccounts: true,
+		PermBlockCards:     true,
+		PermAnnotateCases:  true,
+	},
+	model.RoleAdmin: {
+		PermSearchUsers:    true,
+		PermManageRoles:    true,
+		PermViewAccounts:   true,
+		PermFreezeAccounts: true,
+		PermBlockCards:     true,
+		PermAnnotateCases:  true,
+		PermViewAudit:      true,
+	},
+}
+
+const (
+	maxUserSearchResults = 50
+	maxAuditLogRecords   = 500
+)
+
+var (
+	ErrForbidden           = errors.New("forbidden")
+	ErrSearchQueryRequired = errors.New("search query is required")
+	ErrUnknownRole         = errors.New("unknown role")
+	ErrOwnRoleChange       = errors.New("cannot change own role")
+	ErrNoteRequired        = errors.New("note is required")
+)
+
+// HasPermission сообщает, есть ли у роли разрешение
+func HasPermission(role, permission string) bool {
+	return rolePermissions[role][permission]
+}
+
+// IsStaff сообщает, имеет ли роль доступ к бэк-офису
+func IsStaff(role string) bool {
+	return len(rolePermissions[role]) > 0
+}
+
+type AdminSvc struct {
+	users     repository.UserRepository
+	sessions  repository.SessionRepository
+	revoked   repository.RevokedTokenRepository
+	accounts  repository.AccountRepository
+	caseNotes repository.CaseNoteRepository
+	audit     repository.AuditRepository
+	cards     CardService
+	tx        repository.Transactor
+	now       func() time.Time
+}
+
+func NewAdminService(users repository.UserRepository, sessions repository.SessionRepository, revoked repository.RevokedTokenRepository, accounts repository.AccountRepository, caseNotes repository.CaseNoteRepository, audit repository.AuditRepository, cards CardService, tx repository.Transactor) AdminService {
+	return &AdminSvc{
+		users:     users,
+		sessions:  sessions,
+		revoked:   revoked,
+		accounts:  accounts,
+		caseNotes: caseNotes,
+		audit:     audit,
+		cards:     cards,
+		tx:        tx,
+		now:       time.Now,
+	}
+}
+
+func (s *AdminSvc) SearchUsers(ctx context.Context, actor model.Actor, query string) ([]*model.User, error) {
+	query = strings.TrimSpace(query)
+	if err := s.authorize(ctx, actor, PermSearchUsers, "search_users", "user", 0, query); err != nil {
+		return nil, err
+	}
+
+	if query == "" {
+		return nil, ErrSearchQueryRequired
+	}
+
+	return s.users.Search(ctx, query, maxUserSearchResults)
+}
+
+// SetRole меняет роль пользователя и завершает все его сессии: роль
+// передается в access-токене, поэтому прежние токены не должны действовать
+func (s *AdminSvc) SetRole(ctx context.Context, actor model.Actor, userID int64, role string) error {
+	if err := s.authorize(ctx, actor, PermManageRoles, "set_role", "user", userID, role); err != nil {
+		return err
+	}
+
+	if role != model.RoleCustomer && !IsStaff(role) {
+		return fmt.Errorf("%w %q", ErrUnknownRole, role)
+	}
+
+	// Администратор не может понизить сам себя и потерять доступ
+	if userID == actor.UserID {
+		return ErrOwnRoleChange
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := s.users.GetByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		user.Role = role
+		if err := s.users.Update(ctx, user); err != nil {
+			return err
+		}
+
+		if err := revokeUserSessions(ctx, s.sessions, s.revoked, userID, s.now()); err != nil {
+			return err
+		}
+
+		return s.record(ctx, actor, "set_role", "user", userID, true, role)
+	})
+}
+
+func (s *AdminSvc) GetUserAccounts(ctx context.Context, actor model.Actor, userID int64) ([]*model.Account, error) {
+	if err := s.authorize(ctx, actor, PermViewAccounts, "view_user_accounts", "user", userID, ""); err != nil {
+		return nil, err
+	}
+
+	return s.accounts.GetByUserID(ctx, userID)
+}
+
+func (s *AdminSvc) GetAccount(ctx context.Context, actor model.Actor, accountID int64) (*model.Account, error) {
+	if err := s.authorize(ctx, actor, PermViewAccounts, "view_account", "account", accountID, ""); err != nil {
+		return nil, err
+	}
+
+	return s.accounts.GetByID(ctx, accountID)
+}
+
+func (s *AdminSvc) FreezeAccount(ctx context.Context, actor model.Actor, accountID int64, reason string) error {
+	return s.setAccountStatus(ctx, actor, accountID, model.AccountFrozen, "freeze_account", reason)
+}
+
+func (s *AdminSvc) UnfreezeAccount(ctx context.Context, actor model.Actor, accountID int64, reason string) error {
+	return s.setAccountStatus(ctx, actor, accountID, model.AccountActive, "unfreeze_account", reason)
+}
+
+func (s *AdminSvc) setAccountStatus(ctx context.Context, actor model.Actor, accountID int64, status, action, reason string) error {
+	if err := s.authorize(ctx, actor, PermFreezeAccounts, action, "account", accountID, reason); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(reason) == "" {
+		return ErrReasonRequired
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		account, err := s.accounts.GetByIDForUpdate(ctx, accountID)
+		if err != nil {
+			return err
+		}
+
+		account.Status = status
+		if err := s.accounts.Update(ctx, account); err != nil {
+			return err
+		}
+
+		return s.record(ctx, actor, action, "account", accountID, true, reason)
+	})
+}
+
+func (s *AdminSvc) BlockCard(ctx context.Context, actor model.Actor, cardID int64, reason string) error {
+	return s.cardAction(ctx, actor, cardID, "block_card", reason, func(ctx context.Context) error {
+		return s.cards.Block(ctx, cardID, actor, false, reason)
+	})
+}
+
+// UnblockCard снимает временную блокировку, в том числе установленную банком
+func (s *AdminSvc) UnblockCard(ctx context.Context, actor model.Actor, cardID int64, reason string) error {
+	return s.cardAction(ctx, actor, cardID, "unblock_card", reason, func(ctx context.Context) error {
+		return s.cards.Unblock(ctx, cardID, actor, reason)
+	})
+}
+
+func (s *AdminSvc) cardAction(ctx context.Context, actor model.Actor, cardID int64, action, reason string, apply func(ctx context.Context) error) error {
+	if err := s.authorize(ctx, actor, PermBlockCards, action, "card", cardID, reason); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(reason) == "" {
+		return ErrReasonRequired
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := apply(ctx); err != nil {
+			return err
+		}
+
+		return s.record(ctx, actor, action, "card", cardID, true, reason)
+	})
+}
+
+func (s *AdminSvc) AddCaseNote(ctx context.Context, actor model.Actor, note *model.CaseNote) error {
+	if err := s.authorize(ctx, actor, PermAnnotateCases, "add_case_note", "user", note.UserID, ""); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(note.Note) == "" {
+		return ErrNoteRequired
+	}
+
+	note.AuthorID = actor.UserID
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.caseNotes.Create(ctx, note); err != nil {
+			return err
+		}
+
+		return s.record(ctx, actor, "add_case_note", "user", note.UserID, true, fmt.Sprintf("note_id=%d", note.ID))
+	})
+}
+
+func (s *AdminSvc) GetCaseNotes(ctx context.Context, actor model.Actor, userID int64) ([]*model.CaseNote, error) {
+	if err := s.authorize(ctx, actor, PermAnnotateCases, "view_case_notes", "user", userID, ""); err != nil {
+		return nil, err
+	}
+
+	return s.caseNotes.GetByUserID(ctx, userID)
+}
+
+func (s *AdminSvc) GetAuditLog(ctx context.Context, actor model.Actor, limit int) ([]*model.AuditRecord, error) {
+	if err := s.authorize(ctx, actor, PermViewAudit, "view_audit_log", "audit_log", 0, ""); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > maxAuditLogRecords {
+		limit = maxAuditLogRecords
+	}
+
+	return s.audit.GetRecent(ctx, limit)
+}
+
+// authorize проверяет разрешение сотрудника. Отказ записывается в журнал,
+// чтения записываются сразу, а изменения - в транзакции вместе с самим действием.
+func (s *AdminSvc) authorize(ctx context.Context, actor model.Actor, permission, action, resourceType string, resourceID int64, details string) error {
+	if !HasPermission(actor.Role, permission) {
+		if err := s.record(ctx, actor, action, resourceType, resourceID, false, details); err != nil {
+			return err
+		}
+		return ErrForbidden
+	}
+
+	if isReadAction(action) {
+		return s.record(ctx, actor, action, resourceType, resourceID, true, details)
+	}
+
+	return nil
+}
+
+func (s *AdminSvc) record(ctx context.Context, actor model.Actor, action, resourceType string, resourceID int64, allowed bool, details string) error {
+	return s.audit.Create(ctx, &model.AuditRecord{
+		ActorID:      actor.UserID,
+		ActorRole:    actor.Role,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Allowed:      allowed,
+		Details:      details,
+	})
+}
+
+func isReadAction(action string) bool {
+	return strings.HasPrefix(action, "view_") || strings.HasPrefix(action, "search_")
+}
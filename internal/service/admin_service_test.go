@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"bank-app/internal/model"
+)
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) Create(ctx context.Context, record *model.AuditRecord) error {
+	args := m.Called(ctx, record)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) GetRecent(ctx context.Context, limit int) ([]*model.AuditRecord, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.AuditRecord), args.Error(1)
+}
+
+type MockCaseNoteRepository struct {
+	mock.Mock
+}
+
+func (m *MockCaseNoteRepository) Create(ctx context.Context, note *model.CaseNote) error {
+	args := m.Called(ctx, note)
+	return args.Error(0)
+}
+
+func (m *MockCaseNoteRepository) GetByUserID(ctx context.Context, userID int64) ([]*model.CaseNote, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.CaseNote), args.Error(1)
+}
+
+// auditedAction возвращает matcher записи журнала с заданным действием и результатом
+func auditedAction(action string, allowed bool) interface{} {
+	return mock.MatchedBy(func(record *model.AuditRecord) bool {
+		return record.Action == action && record.Allowed == allowed
+	})
+}
+
+func TestAdminService_FreezeAccount(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("оператор замораживает счет", func(t *testing.T) {
+		// Подготовка
+		mockAccountRepo := new(MockAccountRepository)
+		mockAudit := new(MockAuditRepository)
+		tx := &MockTransactor{}
+		service := NewAdminService(new(MockUserRepository), new(MockSessionRepository), new(MockRevokedTokenRepository), mockAccountRepo, new(MockCaseNoteRepository), mockAudit, nil, tx)
+
+		account := &model.Account{ID: 1, UserID: 5, Status: model.AccountActive}
+		mockAccountRepo.On("GetByIDForUpdate", ctx, int64(1)).Return(account, nil)
+		mockAccountRepo.On("Update", ctx, account).Return(nil)
+		mockAudit.On("Create", ctx, auditedAction("freeze_account", true)).Return(nil).Once()
+
+		// Действие
+		err := service.FreezeAccount(ctx, model.Actor{UserID: 2, Role: model.RoleOperator}, 1, "подозрительные операции")
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.Equal(t, model.AccountFrozen, account.Status)
+		assert.True(t, tx.committed)
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("клиенту запрещено", func(t *testing.T) {
+		// Подготовка
+		mockAccountRepo := new(MockAccountRepository)
+		mockAudit := new(MockAuditRepository)
+		service := NewAdminService(new(MockUserRepository), new(MockSessionRepository), new(MockRevokedTokenRepository), mockAccountRepo, new(MockCaseNoteRepository), mockAudit, nil, &MockTransactor{})
+
+		mockAudit.On("Create", ctx, auditedAction("freeze_account", false)).Return(nil).Once()
+
+		// Действие
+		err := service.FreezeAccount(ctx, model.Actor{UserID: 5, Role: model.RoleCustomer}, 1, "причина")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockAudit.AssertExpectations(t)
+		mockAccountRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("без причины", func(t *testing.T) {
+		// Подготовка
+		mockAccountRepo := new(MockAccountRepository)
+		service := NewAdminService(new(MockUserRepository), new(MockSessionRepository), new(MockRevokedTokenRepository), mockAccountRepo, new(MockCaseNoteRepository), new(MockAuditRepository), nil, &MockTransactor{})
+
+		// Действие
+		err := service.FreezeAccount(ctx, model.Actor{UserID: 2, Role: model.RoleOperator}, 1, " ")
+
+		// Проверка
+		assert.Error(t, err)
+		assert.Equal(t, "reason is required", err.Error())
+		mockAccountRepo.AssertNotCalled(t, "GetByIDForUpdate", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAdminService_SetRole(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("администратор назначает оператора", func(t *testing.T) {
+		// Подготовка
+		mockUserRepo := new(MockUserRepository)
+		mockSessions := new(MockSessionRepository)
+		mockRevoked := new(MockRevokedTokenRepository)
+		mockAudit := new(MockAuditRepository)
+		service := NewAdminService(mockUserRepo, mockSessions, mockRevoked, new(MockAccountRepository), new(MockCaseNoteRepository), mockAudit, nil, &MockTransactor{}).(*AdminSvc)
+		now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+		service.now = func() time.Time { return now }
+
+		user := &model.User{ID: 7, Role: model.RoleCustomer}
+		session := &model.Session{ID: 3, UserID: 7, AccessJTI: "jti-3", AccessExpiresAt: now.Add(10 * time.Minute)}
+		mockUserRepo.On("GetByID", ctx, int64(7)).Return(user, nil)
+		mockUserRepo.On("Update", ctx, user).Return(nil)
+		mockSessions.On("GetActiveByUserID", ctx, int64(7)).Return([]*model.Session{session}, nil)
+		mockSessions.On("Update", ctx, session).Return(nil)
+		mockRevoked.On("Create", ctx, "jti-3", session.AccessExpiresAt).Return(nil)
+		mockAudit.On("Create", ctx, auditedAction("set_role", true)).Return(nil).Once()
+
+		// Действие
+		err := service.SetRole(ctx, model.Actor{UserID: 1, Role: model.RoleAdmin}, 7, model.RoleOperator)
+
+		// Проверка: токены с прежней ролью больше не действуют
+		assert.NoError(t, err)
+		assert.Equal(t, model.RoleOperator, user.Role)
+		assert.Equal(t, &now, session.RevokedAt)
+		mockRevoked.AssertExpectations(t)
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("оператор не может менять роли", func(t *testing.T) {
+		// Подготовка
+		mockUserRepo := new(MockUserRepository)
+		mockAudit := new(MockAuditRepository)
+		service := NewAdminService(mockUserRepo, new(MockSessionRepository), new(MockRevokedTokenRepository), new(MockAccountRepository), new(MockCaseNoteRepository), mockAudit, nil, &MockTransactor{})
+
+		mockAudit.On("Create", ctx, auditedAction("set_role", false)).Return(nil).Once()
+
+		// Действие
+		err := service.SetRole(ctx, model.Actor{UserID: 2, Role: model.RoleOperator}, 2, model.RoleAdmin)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockUserRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		mockAudit.AssertExpectations(t)
+	})
+}
+
+func TestAdminService_GetAuditLog(t *testing.T) {
+	ctx := context.Background()
+
+	mockAudit := new(MockAuditRepository)
+	service := NewAdminService(new(MockUserRepository), new(MockSessionRepository), new(MockRevokedTokenRepository), new(MockAccountRepository), new(MockCaseNoteRepository), mockAudit, nil, &MockTransactor{})
+
+	mockAudit.On("Create", ctx, auditedAction("view_audit_log", true)).Return(nil).Once()
+	mockAudit.On("GetRecent", ctx, maxAuditLogRecords).Return([]*model.AuditRecord{{ID: 1}}, nil)
+
+	records, err := service.GetAuditLog(ctx, model.Actor{UserID: 1, Role: model.RoleAdmin}, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	mockAudit.AssertExpectations(t)
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 
+	"bank-app/internal/money"
 	"bank-app/internal/repository"
 )
 
@@ -14,9 +15,9 @@ func NewAnalyticsService(repo repository.AnalyticsRepository) AnalyticsService {
 	return &AnalyticsSvc{repo: repo}
 }
 
-func (s *AnalyticsSvc) GetTransactionAnalytics(ctx context.Context, userID int64, period string) (map[string]float64, error) {
+func (s *AnalyticsSvc) GetTransactionAnalytics(ctx context.Context, userID int64, period string) (map[string]money.Amount, error) {
 	// В реальном приложении здесь должен быть анализ транзакций по категориям
-	return map[string]float64{
+	return map[string]money.Amount{
 		"income":      0,
 		"expenses":    0,
 		"transfers":   0,
@@ -25,10 +26,10 @@ func (s *AnalyticsSvc) GetTransactionAnalytics(ctx context.Context, userID int64
 	}, nil
 }
 
-func (s *AnalyticsSvc) GetCreditLoad(ctx context.Context, userID int64) (float64, error) {
+func (s *AnalyticsSvc) GetCreditLoad(ctx context.Context, userID int64) (money.Amount, error) {
 	return s.repo.GetCreditLoad(ctx, userID)
 }
 
-func (s *AnalyticsSvc) PredictBalance(ctx context.Context, accountID int64, days int) (float64, error) {
+func (s *AnalyticsSvc) PredictBalance(ctx context.Context, accountID int64, days int) (money.Amount, error) {
 	return s.repo.PredictBalance(ctx, accountID, days)
 }
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"bank-app/internal/model"
+	"bank-app/internal/repository"
+)
+
+var (
+	ErrInvalidCardTransition = errors.New("card status transition is not allowed")
+	ErrCardBlockedByBank     = errors.New("card was blocked by the bank, contact support to unblock it")
+	ErrCardAlreadyReissued   = errors.New("card has already been reissued")
+	ErrReasonRequired        = errors.New("reason is required")
+	ErrVirtualCardReissue    = errors.New("virtual cards are not reissued, issue a new virtual card instead")
+)
+
+// cardTransitions - допустимые переходы между статусами карты
+var cardTransitions = map[string]map[string]bool{
+	model.CardIssued: {
+		model.CardActive:  true,
+		model.CardClosed:  true,
+		model.CardExpired: true,
+	},
+	model.CardActive: {
+		model.CardBlockedTemporarily: true,
+		model.CardBlockedLost:        true,
+		model.CardClosed:             true,
+		model.CardExpired:            true,
+	},
+	model.CardBlockedTemporarily: {
+		model.CardActive:      true,
+		model.CardBlockedLost: true,
+		model.CardClosed:      true,
+		model.CardExpired:     true,
+	},
+	model.CardBlockedLost: {
+		model.CardClosed: true,
+	},
+	model.CardExpired: {
+		model.CardClosed: true,
+	},
+}
+
+// reissuable - статусы, из которых карту можно перевыпустить
+var reissuable = map[string]bool{
+	model.CardActive:             true,
+	model.CardBlockedTemporarily: true,
+	model.CardBlockedLost:        true,
+	model.CardExpired:            true,
+}
+
+// cardJobBatchSize ограничивает число карт, обрабатываемых за один запуск задачи
+const cardJobBatchSize = 500
+
+// Block блокирует карту: временно или окончательно при утере. Клиент не
+// может объявить утерянной карту, заблокированную банком: утерянную карту
+// перевыпускают, и блокировка банка была бы обойдена.
+func (s *CardSvc) Block(ctx context.Context, id int64, actor model.Actor, lost bool, reason string) error {
+	if !lost {
+		return s.changeStatus(ctx, id, actor, model.CardBlockedTemporarily, reason)
+	}
+
+	if strings.TrimSpace(reason) == "" {
+		return ErrReasonRequired
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		card, err := s.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.checkBlockedBy(ctx, card, actor); err != nil {
+			return err
+		}
+
+		return s.transition(ctx, card, model.CardBlockedLost, reason, actor)
+	})
+}
+
+// Unblock снимает временную блокировку. Клиент может снять только
+// блокировку, которую установил сам.
+func (s *CardSvc) Unblock(ctx context.Context, id int64, actor model.Actor, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return ErrReasonRequired
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		card, err := s.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if card.Status != model.CardBlockedTemporarily {
+			return ErrInvalidCardTransition
+		}
+
+		if err := s.checkBlockedBy(ctx, card, actor); err != nil {
+			return err
+		}
+
+		return s.transition(ctx, card, model.CardActive, reason, actor)
+	})
+}
+
+// Close закрывает карту без возможности восстановления
+func (s *CardSvc) Close(ctx context.Context, id int64, actor model.Actor, reason string) error {
+	return s.changeStatus(ctx, id, actor, model.CardClosed, reason)
+}
+
+// Reissue выпускает карту взамен текущей с новым номером и сроком действия.
+// Утерянная или просроченная карта сразу закрывается, действующая работает
+// до активации новой. Вместо виртуальной карты выпускается новая. Карту,
+// заблокированную банком, клиент перевыпустить не может.
+func (s *CardSvc) Reissue(ctx context.Context, id int64, actor model.Actor, reason string) (*model.Card, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrReasonRequired
+	}
+
+	var replacement *model.Card
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		card, err := s.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if card.Product == model.CardVirtual {
+			return ErrVirtualCardReissue
+		}
+
+		if !reissuable[card.Status] {
+			return ErrInvalidCardTransition
+		}
+
+		if err := s.checkBlockedBy(ctx, card, actor); err != nil {
+			return err
+		}
+
+		_, err = s.repo.GetReplacement(ctx, id)
+		if err == nil {
+			return ErrCardAlreadyReissued
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		replacement = &model.Card{
+			AccountID:      card.AccountID,
+			Product:        card.Product,
+			Status:         model.CardIssued,
+			ReplacesCardID: card.ID,
+		}
+		if err := s.issue(ctx, replacement, actor, fmt.Sprintf("reissue of card %d: %s", card.ID, reason)); err != nil {
+			return err
+		}
+
+		if card.Status == model.CardBlockedLost || card.Status == model.CardExpired {
+			return s.transition(ctx, card, model.CardClosed, fmt.Sprintf("reissued as card %d", replacement.ID), actor)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return replacement, nil
+}
+
+// Activate активирует выпущенную карту и, если передан pin, устанавливает
+// PIN. Карта, взамен которой она выпущена, закрывается. Замену карты,
+// заблокированной банком, клиент активировать не может.
+func (s *CardSvc) Activate(ctx context.Context, id int64, actor model.Actor, pin string) error {
+	if pin != "" && !isPIN(pin) {
+		return ErrInvalidPINFormat
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		card, err := s.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if card.Status != model.CardIssued {
+			return ErrInvalidCardTransition
+		}
+
+		// Карта могла быть перевыпущена по сроку, пока ее блокировал банк
+		var previous *model.Card
+		if card.ReplacesCardID != 0 {
+			previous, err = s.repo.GetByIDForUpdate(ctx, card.ReplacesCardID)
+			if err != nil {
+				return err
+			}
+			if err := s.checkBlockedBy(ctx, previous, actor); err != nil {
+				return err
+			}
+		}
+
+		if err := s.transition(ctx, card, model.CardActive, "activated", actor); err != nil {
+			return err
+		}
+
+		if pin != "" {
+			if err := s.setPIN(ctx, card.ID, pin); err != nil {
+				return err
+			}
+		}
+
+		if previous == nil || previous.Status == model.CardClosed {
+			return nil
+		}
+
+		return s.transition(ctx, previous, model.CardClosed, fmt.Sprintf("replaced by card %d", card.ID), actor)
+	})
+}
+
+// checkBlockedBy проверяет, что блокировку карты установил сам клиент.
+// Блокировка сотрудником или системой (по лимиту попыток PIN) снимается и
+// обходится перевыпуском только через банк. Сотрудники и фоновые задачи
+// не ограничены.
+func (s *CardSvc) checkBlockedBy(ctx context.Context, card *model.Card, actor model.Actor) error {
+	if card.Status != model.CardBlockedTemporarily && card.Status != model.CardBlockedLost {
+		return nil
+	}
+	if IsStaff(actor.Role) || actor == (model.Actor{}) {
+		return nil
+	}
+
+	history, err := s.repo.GetStatusHistory(ctx, card.ID)
+	if err != nil {
+		return err
+	}
+
+	// История отсортирована от новых записей к старым
+	for _, change := range history {
+		if change.ToStatus != model.CardBlockedTemporarily && change.ToStatus != model.CardBlockedLost {
+			continue
+		}
+		if change.ChangedBy != actor.UserID {
+			return ErrCardBlockedByBank
+		}
+		if change.FromStatus != model.CardBlockedTemporarily {
+			break
+		}
+	}
+
+	return nil
+}
+
+// GetStatusHistory возвращает историю статусов карты
+func (s *CardSvc) GetStatusHistory(ctx context.Context, id int64) ([]*model.CardStatusChange, error) {
+	return s.repo.GetStatusHistory(ctx, id)
+}
+
+// RenewExpiring переводит карты с истекшим сроком в статус expired и
+// перевыпускает карты, срок которых истекает в ближайшие renewalLead.
+// Ошибка по одной карте не останавливает обработку остальных.
+func (s *CardSvc) RenewExpiring(ctx context.Context) error {
+	now := s.now()
+	var errs []error
+
+	expired, err := s.repo.GetExpired(ctx, now, cardJobBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, card := range expired {
+		if err := s.changeStatus(ctx, card.ID, model.Actor{}, model.CardExpired, "card expired"); err != nil {
+			errs = append(errs, fmt.Errorf("expire card %d: %w", card.ID, err))
+		}
+	}
+
+	renewable, err := s.repo.GetRenewable(ctx, now.Add(s.renewalLead), cardJobBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, card := range renewable {
+		if _, err := s.Reissue(ctx, card.ID, model.Actor{}, "expiry renewal"); err != nil {
+			errs = append(errs, fmt.Errorf("renew card %d: %w", card.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// changeStatus переводит карту в статус to в отдельной транзакции
+func (s *CardSvc) changeStatus(ctx context.Context, id int64, actor model.Actor, to, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return ErrReasonRequired
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		card, err := s.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return s.transition(ctx, card, to, reason, actor)
+	})
+}
+
+// transition проверяет допустимость перехода, сохраняет статус и запись истории
+func (s *CardSvc) transition(ctx context.Context, card *model.Card, to, reason string, actor model.Actor) error {
+	if !cardTransitions[card.Status][to] {
+		return ErrInvalidCardTransition
+	}
+
+	// Карту с истекшим сроком нельзя активировать или разблокировать
+	if to == model.CardActive && s.expired(card) {
+		return ErrCardExpired
+	}
+
+	change := &model.CardStatusChange{
+		CardID:     card.ID,
+		FromStatus: card.Status,
+		ToStatus:   to,
+		Reason:     reason,
+		ChangedBy:  actor.UserID,
+	}
+
+	card.Status = to
+	if err := s.repo.Update(ctx, card); err != nil {
+		return err
+	}
+
+	return s.repo.AddStatusChange(ctx, change)
+}
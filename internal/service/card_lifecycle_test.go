@@ -0,0 +1,450 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"bank-app/internal/model"
+)
+
+// statusChange проверяет запись истории перехода между статусами
+func statusChange(cardID int64, from, to string) interface{} {
+	return mock.MatchedBy(func(c *model.CardStatusChange) bool {
+		return c.CardID == cardID && c.FromStatus == from && c.ToStatus == to
+	})
+}
+
+func TestCardService_Transitions(t *testing.T) {
+	ctx := context.Background()
+	customer := model.Actor{UserID: 10, Role: model.RoleCustomer}
+
+	tests := []struct {
+		name    string
+		from    string
+		action  func(s *CardSvc) error
+		to      string
+		wantErr error
+	}{
+		{
+			name:   "временная блокировка",
+			from:   model.CardActive,
+			action: func(s *CardSvc) error { return s.Block(ctx, 1, customer, false, "не помню, где карта") },
+			to:     model.CardBlockedTemporarily,
+		},
+		{
+			name:   "блокировка при утере",
+			from:   model.CardBlockedTemporarily,
+			action: func(s *CardSvc) error { return s.Block(ctx, 1, customer, true, "карта украдена") },
+			to:     model.CardBlockedLost,
+		},
+		{
+			name:   "закрытие",
+			from:   model.CardActive,
+			action: func(s *CardSvc) error { return s.Close(ctx, 1, customer, "больше не нужна") },
+			to:     model.CardClosed,
+		},
+		{
+			name:    "утерянную карту нельзя разблокировать",
+			from:    model.CardBlockedLost,
+			action:  func(s *CardSvc) error { return s.Unblock(ctx, 1, customer, "нашлась") },
+			wantErr: ErrInvalidCardTransition,
+		},
+		{
+			name:    "закрытую карту нельзя заблокировать",
+			from:    model.CardClosed,
+			action:  func(s *CardSvc) error { return s.Block(ctx, 1, customer, false, "на всякий случай") },
+			wantErr: ErrInvalidCardTransition,
+		},
+		{
+			name:    "причина обязательна",
+			from:    model.CardActive,
+			action:  func(s *CardSvc) error { return s.Close(ctx, 1, customer, " ") },
+			wantErr: ErrReasonRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Подготовка
+			cards := new(MockCardRepository)
+			service := newTestCardService(t, cards)
+			card := &model.Card{ID: 1, Status: tt.from}
+			cards.On("GetByIDForUpdate", ctx, int64(1)).Return(card, nil).Maybe()
+			cards.On("GetStatusHistory", ctx, int64(1)).Return(nil, nil).Maybe()
+			if tt.wantErr == nil {
+				cards.On("Update", ctx, card).Return(nil)
+				cards.On("AddStatusChange", ctx, statusChange(1, tt.from, tt.to)).Return(nil)
+			}
+
+			// Действие
+			err := tt.action(service)
+
+			// Проверка
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				cards.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.to, card.Status)
+			cards.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCardService_Unblock(t *testing.T) {
+	ctx := context.Background()
+	customer := model.Actor{UserID: 10, Role: model.RoleCustomer}
+	operator := model.Actor{UserID: 2, Role: model.RoleOperator}
+
+	newService := func(blockedBy int64) (*CardSvc, *MockCardRepository, *model.Card) {
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		card := &model.Card{ID: 1, Status: model.CardBlockedTemporarily, ExpiryDate: model.CardExpiryDate(2099, time.December)}
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(card, nil)
+		cards.On("GetStatusHistory", ctx, int64(1)).Return([]*model.CardStatusChange{
+			{CardID: 1, FromStatus: model.CardActive, ToStatus: model.CardBlockedTemporarily, ChangedBy: blockedBy},
+			{CardID: 1, FromStatus: model.CardBlockedTemporarily, ToStatus: model.CardActive, ChangedBy: 10},
+			{CardID: 1, FromStatus: model.CardActive, ToStatus: model.CardBlockedTemporarily, ChangedBy: 10},
+		}, nil).Maybe()
+		return service, cards, card
+	}
+
+	t.Run("клиент снимает свою блокировку", func(t *testing.T) {
+		// Подготовка
+		service, cards, card := newService(10)
+		cards.On("Update", ctx, card).Return(nil)
+		cards.On("AddStatusChange", ctx, statusChange(1, model.CardBlockedTemporarily, model.CardActive)).Return(nil)
+
+		// Действие
+		err := service.Unblock(ctx, 1, customer, "карта нашлась")
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.Equal(t, model.CardActive, card.Status)
+	})
+
+	t.Run("клиент не может снять блокировку банка", func(t *testing.T) {
+		// Подготовка
+		service, cards, _ := newService(2)
+
+		// Действие
+		err := service.Unblock(ctx, 1, customer, "хочу платить")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardBlockedByBank)
+		cards.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("сотрудник снимает блокировку банка", func(t *testing.T) {
+		// Подготовка
+		service, cards, card := newService(2)
+		cards.On("Update", ctx, card).Return(nil)
+		cards.On("AddStatusChange", ctx, mock.MatchedBy(func(c *model.CardStatusChange) bool {
+			return c.ToStatus == model.CardActive && c.ChangedBy == 2
+		})).Return(nil)
+
+		// Действие
+		err := service.Unblock(ctx, 1, operator, "проверка завершена")
+
+		// Проверка
+		assert.NoError(t, err)
+		cards.AssertNotCalled(t, "GetStatusHistory", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCardService_Reissue(t *testing.T) {
+	ctx := context.Background()
+	customer := model.Actor{UserID: 10, Role: model.RoleCustomer}
+
+	t.Run("утерянная карта закрывается при перевыпуске", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		card := &model.Card{ID: 1, AccountID: 5, Product: model.CardDebit, Status: model.CardBlockedLost}
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(card, nil)
+		cards.On("GetStatusHistory", ctx, int64(1)).Return([]*model.CardStatusChange{
+			{CardID: 1, FromStatus: model.CardActive, ToStatus: model.CardBlockedLost, ChangedBy: 10},
+		}, nil)
+		cards.On("GetReplacement", ctx, int64(1)).Return(nil, ErrNotFound)
+		cards.On("GetByNumberHMAC", ctx, mock.Anything).Return(nil, ErrNotFound)
+		cards.On("Create", ctx, mock.AnythingOfType("*model.Card")).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.Card).ID = 2
+		})
+		cards.On("AddStatusChange", ctx, statusChange(2, "", model.CardIssued)).Return(nil)
+		cards.On("Update", ctx, card).Return(nil)
+		cards.On("AddStatusChange", ctx, statusChange(1, model.CardBlockedLost, model.CardClosed)).Return(nil)
+
+		// Действие
+		replacement, err := service.Reissue(ctx, 1, customer, "карта украдена")
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), replacement.ID)
+		assert.Equal(t, int64(1), replacement.ReplacesCardID)
+		assert.Equal(t, int64(5), replacement.AccountID)
+		assert.Equal(t, model.CardIssued, replacement.Status)
+		assert.Equal(t, model.CardClosed, card.Status)
+		cards.AssertExpectations(t)
+	})
+
+	t.Run("действующая карта работает до активации новой", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		card := &model.Card{ID: 1, AccountID: 5, Product: model.CardDebit, Status: model.CardActive}
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(card, nil)
+		cards.On("GetReplacement", ctx, int64(1)).Return(nil, ErrNotFound)
+		cards.On("GetByNumberHMAC", ctx, mock.Anything).Return(nil, ErrNotFound)
+		cards.On("Create", ctx, mock.Anything).Return(nil)
+		cards.On("AddStatusChange", ctx, mock.Anything).Return(nil)
+
+		// Действие
+		_, err := service.Reissue(ctx, 1, customer, "карта повреждена")
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, model.CardActive, card.Status)
+		cards.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("клиент не может перевыпустить карту, заблокированную банком", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1, Product: model.CardDebit, Status: model.CardBlockedTemporarily}, nil)
+		cards.On("GetStatusHistory", ctx, int64(1)).Return([]*model.CardStatusChange{
+			{CardID: 1, FromStatus: model.CardActive, ToStatus: model.CardBlockedTemporarily, ChangedBy: 2},
+		}, nil)
+
+		// Действие
+		_, err := service.Reissue(ctx, 1, customer, "карта повреждена")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardBlockedByBank)
+		cards.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("утерянную после блокировки банка карту клиент не перевыпускает", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1, Product: model.CardDebit, Status: model.CardBlockedLost}, nil)
+		cards.On("GetStatusHistory", ctx, int64(1)).Return([]*model.CardStatusChange{
+			{CardID: 1, FromStatus: model.CardBlockedTemporarily, ToStatus: model.CardBlockedLost, ChangedBy: 10},
+			{CardID: 1, FromStatus: model.CardActive, ToStatus: model.CardBlockedTemporarily, ChangedBy: 2},
+		}, nil)
+
+		// Действие
+		_, err := service.Reissue(ctx, 1, customer, "карта украдена")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardBlockedByBank)
+		cards.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("повторный перевыпуск", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1, Status: model.CardActive}, nil)
+		cards.On("GetReplacement", ctx, int64(1)).Return(&model.Card{ID: 2}, nil)
+
+		// Действие
+		_, err := service.Reissue(ctx, 1, customer, "карта повреждена")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardAlreadyReissued)
+		cards.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("закрытую карту нельзя перевыпустить", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1, Status: model.CardClosed}, nil)
+
+		// Действие
+		_, err := service.Reissue(ctx, 1, customer, "карта повреждена")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidCardTransition)
+	})
+
+	t.Run("виртуальная карта не перевыпускается", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1, Product: model.CardVirtual, Status: model.CardActive}, nil)
+
+		// Действие
+		_, err := service.Reissue(ctx, 1, customer, "срок истекает")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrVirtualCardReissue)
+		cards.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCardService_BlockLostAfterBankBlock(t *testing.T) {
+	ctx := context.Background()
+	customer := model.Actor{UserID: 10, Role: model.RoleCustomer}
+	operator := model.Actor{UserID: 2, Role: model.RoleOperator}
+
+	newService := func() (*CardSvc, *MockCardRepository, *model.Card) {
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		card := &model.Card{ID: 1, Status: model.CardBlockedTemporarily}
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(card, nil)
+		cards.On("GetStatusHistory", ctx, int64(1)).Return([]*model.CardStatusChange{
+			{CardID: 1, FromStatus: model.CardActive, ToStatus: model.CardBlockedTemporarily, ChangedBy: 2},
+		}, nil).Maybe()
+		return service, cards, card
+	}
+
+	t.Run("клиент не может объявить утерянной карту, заблокированную банком", func(t *testing.T) {
+		// Подготовка
+		service, cards, card := newService()
+
+		// Действие
+		err := service.Block(ctx, 1, customer, true, "карта украдена")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardBlockedByBank)
+		assert.Equal(t, model.CardBlockedTemporarily, card.Status)
+		cards.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("сотрудник объявляет карту утерянной", func(t *testing.T) {
+		// Подготовка
+		service, cards, card := newService()
+		cards.On("Update", ctx, card).Return(nil)
+		cards.On("AddStatusChange", ctx, statusChange(1, model.CardBlockedTemporarily, model.CardBlockedLost)).Return(nil)
+
+		// Действие
+		err := service.Block(ctx, 1, operator, true, "обращение клиента")
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.Equal(t, model.CardBlockedLost, card.Status)
+	})
+}
+
+func TestCardService_ActivateReplacementOfBankBlockedCard(t *testing.T) {
+	ctx := context.Background()
+	customer := model.Actor{UserID: 10, Role: model.RoleCustomer}
+
+	// Подготовка: карта перевыпущена по сроку, пока ее блокировал банк
+	cards := new(MockCardRepository)
+	service := newTestCardService(t, cards)
+	replacement := &model.Card{ID: 2, Status: model.CardIssued, ReplacesCardID: 1, ExpiryDate: model.CardExpiryDate(2099, time.December)}
+	previous := &model.Card{ID: 1, Status: model.CardBlockedTemporarily}
+	cards.On("GetByIDForUpdate", ctx, int64(2)).Return(replacement, nil)
+	cards.On("GetByIDForUpdate", ctx, int64(1)).Return(previous, nil)
+	cards.On("GetStatusHistory", ctx, int64(1)).Return([]*model.CardStatusChange{
+		{CardID: 1, FromStatus: model.CardActive, ToStatus: model.CardBlockedTemporarily, ChangedBy: 2},
+	}, nil)
+
+	// Действие
+	err := service.Activate(ctx, 2, customer, "")
+
+	// Проверка
+	assert.ErrorIs(t, err, ErrCardBlockedByBank)
+	assert.Equal(t, model.CardIssued, replacement.Status)
+	cards.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestCardService_Activate(t *testing.T) {
+	ctx := context.Background()
+	customer := model.Actor{UserID: 10, Role: model.RoleCustomer}
+
+	// Подготовка
+	cards := new(MockCardRepository)
+	service := newTestCardService(t, cards)
+	replacement := &model.Card{ID: 2, Status: model.CardIssued, ReplacesCardID: 1, ExpiryDate: model.CardExpiryDate(2099, time.December)}
+	previous := &model.Card{ID: 1, Status: model.CardActive}
+	cards.On("GetByIDForUpdate", ctx, int64(2)).Return(replacement, nil)
+	cards.On("GetByIDForUpdate", ctx, int64(1)).Return(previous, nil)
+	cards.On("Update", ctx, mock.Anything).Return(nil)
+	cards.On("AddStatusChange", ctx, statusChange(2, model.CardIssued, model.CardActive)).Return(nil)
+	cards.On("AddStatusChange", ctx, statusChange(1, model.CardActive, model.CardClosed)).Return(nil)
+
+	// Действие
+	err := service.Activate(ctx, 2, customer, "")
+
+	// Проверка
+	require.NoError(t, err)
+	assert.Equal(t, model.CardActive, replacement.Status)
+	assert.Equal(t, model.CardClosed, previous.Status)
+	cards.AssertExpectations(t)
+
+	// Повторная активация недопустима
+	assert.ErrorIs(t, service.Activate(ctx, 2, customer, ""), ErrInvalidCardTransition)
+}
+
+func TestCardService_ActivateExpired(t *testing.T) {
+	ctx := context.Background()
+	customer := model.Actor{UserID: 10, Role: model.RoleCustomer}
+
+	// Подготовка: карта выпущена, но не активирована до конца срока
+	cards := new(MockCardRepository)
+	service := newTestCardService(t, cards)
+	service.now = func() time.Time { return time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC) }
+	card := &model.Card{ID: 2, Status: model.CardIssued, ExpiryDate: model.CardExpiryDate(2026, time.December)}
+	cards.On("GetByIDForUpdate", ctx, int64(2)).Return(card, nil)
+
+	// Действие
+	err := service.Activate(ctx, 2, customer, "")
+
+	// Проверка
+	assert.ErrorIs(t, err, ErrCardExpired)
+	assert.Equal(t, model.CardIssued, card.Status)
+	cards.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestCardService_RenewExpiring(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 15, 3, 0, 0, 0, time.UTC)
+
+	// Подготовка
+	cards := new(MockCardRepository)
+	service := newTestCardService(t, cards)
+	service.now = func() time.Time { return now }
+
+	expired := &model.Card{ID: 1, Status: model.CardActive}
+	expiring := &model.Card{ID: 2, AccountID: 5, Product: model.CardDebit, Status: model.CardActive}
+	broken := &model.Card{ID: 3, Status: model.CardActive}
+
+	cards.On("GetExpired", ctx, now, cardJobBatchSize).Return([]*model.Card{expired}, nil)
+	cards.On("GetByIDForUpdate", ctx, int64(1)).Return(expired, nil)
+	cards.On("Update", ctx, expired).Return(nil)
+	cards.On("AddStatusChange", ctx, mock.MatchedBy(func(c *model.CardStatusChange) bool {
+		return c.CardID == 1 && c.ToStatus == model.CardExpired && c.ChangedBy == 0
+	})).Return(nil)
+
+	cards.On("GetRenewable", ctx, now.Add(30*24*time.Hour), cardJobBatchSize).Return([]*model.Card{expiring, broken}, nil)
+	cards.On("GetByIDForUpdate", ctx, int64(2)).Return(expiring, nil)
+	cards.On("GetReplacement", ctx, int64(2)).Return(nil, ErrNotFound)
+	cards.On("GetByNumberHMAC", ctx, mock.Anything).Return(nil, ErrNotFound)
+	cards.On("Create", ctx, mock.MatchedBy(func(c *model.Card) bool {
+		return c.ReplacesCardID == 2 && c.Status == model.CardIssued
+	})).Return(nil)
+	cards.On("AddStatusChange", ctx, mock.MatchedBy(func(c *model.CardStatusChange) bool {
+		return c.ToStatus == model.CardIssued
+	})).Return(nil)
+	cards.On("GetByIDForUpdate", ctx, int64(3)).Return(nil, errors.New("connection reset"))
+
+	// Действие
+	err := service.RenewExpiring(ctx)
+
+	// Проверка: ошибка по одной карте не мешает обработке остальных
+	assert.ErrorContains(t, err, "renew card 3")
+	assert.Equal(t, model.CardExpired, expired.Status)
+	cards.AssertExpectations(t)
+}
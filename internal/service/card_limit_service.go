@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+	"bank-app/internal/repository"
+)
+
+var (
+	ErrUnknownChannel      = errors.New("unknown card channel")
+	ErrInvalidMCC          = errors.New("merchant category code must be 4 digits")
+	ErrInvalidCardLimit    = errors.New("card limit amounts must not be negative")
+	ErrCardLimitExists     = errors.New("card limit for this channel and merchant category already exists")
+	ErrCardChannelDisabled = errors.New("card operations are disabled for this channel")
+	ErrCardLimitExceeded   = errors.New("card spending limit exceeded")
+)
+
+var cardChannels = map[string]bool{
+	model.ChannelPOS:       true,
+	model.ChannelECommerce: true,
+	model.ChannelATM:       true,
+}
+
+type CardLimitSvc struct {
+	limits   repository.CardLimitRepository
+	cards    repository.CardRepository
+	accounts repository.AccountRepository
+	tx       repository.Transactor
+	now      func() time.Time
+}
+
+func NewCardLimitService(limits repository.CardLimitRepository, cards repository.CardRepository, accounts repository.AccountRepository, tx repository.Transactor) CardLimitService {
+	return &CardLimitSvc{
+		limits:   limits,
+		cards:    cards,
+		accounts: accounts,
+		tx:       tx,
+		now:      time.Now,
+	}
+}
+
+func (s *CardLimitSvc) GetByCardID(ctx context.Context, cardID int64) ([]*model.CardLimit, error) {
+	return s.limits.GetByCardID(ctx, cardID)
+}
+
+func (s *CardLimitSvc) Create(ctx context.Context, limit *model.CardLimit) error {
+	if err := validateCardLimit(limit); err != nil {
+		return err
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Блокировка карты не дает параллельно создать одинаковые лимиты
+		if _, err := s.cards.GetByIDForUpdate(ctx, limit.CardID); err != nil {
+			return err
+		}
+
+		if err := s.checkDuplicate(ctx, limit); err != nil {
+			return err
+		}
+
+		return s.limits.Create(ctx, limit)
+	})
+}
+
+// Update изменяет лимит карты limit.CardID. Лимит другой карты считается ненайденным.
+func (s *CardLimitSvc) Update(ctx context.Context, limit *model.CardLimit) error {
+	if err := validateCardLimit(limit); err != nil {
+		return err
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.cards.GetByIDForUpdate(ctx, limit.CardID); err != nil {
+			return err
+		}
+
+		if _, err := s.get(ctx, limit.CardID, limit.ID); err != nil {
+			return err
+		}
+
+		if err := s.checkDuplicate(ctx, limit); err != nil {
+			return err
+		}
+
+		return s.limits.Update(ctx, limit)
+	})
+}
+
+func (s *CardLimitSvc) Delete(ctx context.Context, cardID, id int64) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.get(ctx, cardID, id); err != nil {
+			return err
+		}
+
+		return s.limits.Delete(ctx, id)
+	})
+}
+
+// Authorize проверяет расход по всем лимитам карты, под которые он подпадает,
+// и учитывает его в счетчиках. Дневные и месячные суммы считаются по календарю
+// в часовом поясе счета карты. Строка карты блокируется, поэтому параллельные
+// операции по одной карте не могут вместе превысить лимит.
+func (s *CardLimitSvc) Authorize(ctx context.Context, spend *model.CardSpend) error {
+	if !cardChannels[spend.Channel] {
+		return ErrUnknownChannel
+	}
+
+	if !spend.Amount.IsPositive() {
+		return errors.New("amount must be positive")
+	}
+
+	if spend.At.IsZero() {
+		spend.At = s.now()
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		day, err := s.spendDay(ctx, spend)
+		if err != nil {
+			return err
+		}
+
+		limits, err := s.limits.GetByCardID(ctx, spend.CardID)
+		if err != nil {
+			return err
+		}
+
+		month := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+		for _, limit := range limits {
+			if !limit.Matches(spend) {
+				continue
+			}
+
+			if limit.Disabled {
+				return ErrCardChannelDisabled
+			}
+
+			if limit.PerTransaction > 0 && spend.Amount > limit.PerTransaction {
+				return fmt.Errorf("%w: per transaction limit is %s", ErrCardLimitExceeded, limit.PerTransaction)
+			}
+
+			if err := s.checkPeriod(ctx, limit, spend, "daily", limit.Daily, day, day.AddDate(0, 0, 1)); err != nil {
+				return err
+			}
+
+			if err := s.checkPeriod(ctx, limit, spend, "monthly", limit.Monthly, month, month.AddDate(0, 1, 0)); err != nil {
+				return err
+			}
+		}
+
+		return s.limits.AddSpend(ctx, spend, day)
+	})
+}
+
+// Release возвращает в лимиты отмененный расход. Сумма вычитается из счетчика
+// того дня, в который расход был учтен.
+func (s *CardLimitSvc) Release(ctx context.Context, spend *model.CardSpend) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		day, err := s.spendDay(ctx, spend)
+		if err != nil {
+			return err
+		}
+
+		reversal := *spend
+		reversal.Amount = -spend.Amount.Abs()
+		return s.limits.AddSpend(ctx, &reversal, day)
+	})
+}
+
+// spendDay блокирует карту и возвращает начало календарного дня расхода
+// в часовом поясе ее счета
+func (s *CardLimitSvc) spendDay(ctx context.Context, spend *model.CardSpend) (time.Time, error) {
+	card, err := s.cards.GetByIDForUpdate(ctx, spend.CardID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	account, err := s.accounts.GetByID(ctx, card.AccountID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	location, err := time.LoadLocation(account.TimeZone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("account %d time zone: %w", account.ID, err)
+	}
+
+	local := spend.At.In(location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location), nil
+}
+
+// checkPeriod проверяет, что расход вместе с уже учтенными за [from, to)
+// не превысит лимит max. Нулевой лимит не ограничивает расходы.
+func (s *CardLimitSvc) checkPeriod(ctx context.Context, limit *model.CardLimit, spend *model.CardSpend, period string, max money.Amount, from, to time.Time) error {
+	if max == 0 {
+		return nil
+	}
+
+	spent, err := s.limits.SumSpend(ctx, spend.CardID, limit.Channel, limit.MCC, from, to)
+	if err != nil {
+		return err
+	}
+
+	if spent+spend.Amount > max {
+		return fmt.Errorf("%w: %s limit is %s, already spent %s", ErrCardLimitExceeded, period, max, spent)
+	}
+
+	return nil
+}
+
+func (s *CardLimitSvc) get(ctx context.Context, cardID, id int64) (*model.CardLimit, error) {
+	limit, err := s.limits.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit.CardID != cardID {
+		return nil, fmt.Errorf("card limit %w", ErrNotFound)
+	}
+
+	return limit, nil
+}
+
+// checkDuplicate не допускает двух лимитов с одинаковыми каналом и категорией
+func (s *CardLimitSvc) checkDuplicate(ctx context.Context, limit *model.CardLimit) error {
+	limits, err := s.limits.GetByCardID(ctx, limit.CardID)
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range limits {
+		if existing.ID != limit.ID && existing.Channel == limit.Channel && existing.MCC == limit.MCC {
+			return ErrCardLimitExists
+		}
+	}
+
+	return nil
+}
+
+func validateCardLimit(limit *model.CardLimit) error {
+	if limit.Channel != "" && !cardChannels[limit.Channel] {
+		return ErrUnknownChannel
+	}
+
+	if limit.MCC != "" && !isMCC(limit.MCC) {
+		return ErrInvalidMCC
+	}
+
+	if limit.PerTransaction.IsNegative() || limit.Daily.IsNegative() || limit.Monthly.IsNegative() {
+		return ErrInvalidCardLimit
+	}
+
+	return nil
+}
+
+func isMCC(code string) bool {
+	if len(code) != 4 {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+)
+
+type MockCardLimitRepository struct {
+	mock.Mock
+}
+
+func (m *MockCardLimitRepository) Create(ctx context.Context, limit *model.CardLimit) error {
+	args := m.Called(ctx, limit)
+	return args.Error(0)
+}
+
+func (m *MockCardLimitRepository) GetByID(ctx context.Context, id int64) (*model.CardLimit, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CardLimit), args.Error(1)
+}
+
+func (m *MockCardLimitRepository) GetByCardID(ctx context.Context, cardID int64) ([]*model.CardLimit, error) {
+	args := m.Called(ctx, cardID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.CardLimit), args.Error(1)
+}
+
+func (m *MockCardLimitRepository) Update(ctx context.Context, limit *model.CardLimit) error {
+	args := m.Called(ctx, limit)
+	return args.Error(0)
+}
+
+func (m *MockCardLimitRepository) Delete(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockCardLimitRepository) AddSpend(ctx context.Context, spend *model.CardSpend, day time.Time) error {
+	args := m.Called(ctx, spend, day)
+	return args.Error(0)
+}
+
+func (m *MockCardLimitRepository) SumSpend(ctx context.Context, cardID int64, channel, mcc string, from, to time.Time) (money.Amount, error) {
+	args := m.Called(ctx, cardID, channel, mcc, from, to)
+	return args.Get(0).(money.Amount), args.Error(1)
+}
+
+func newTestCardLimitService(limits *MockCardLimitRepository, cards *MockCardRepository, accounts *MockAccountRepository) *CardLimitSvc {
+	return NewCardLimitService(limits, cards, accounts, &MockTransactor{}).(*CardLimitSvc)
+}
+
+// expectCardAccount настраивает карту 1 на счете 10 с часовым поясом Москвы
+func expectCardAccount(ctx context.Context, cards *MockCardRepository, accounts *MockAccountRepository) {
+	cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1, AccountID: 10, Status: model.CardActive}, nil)
+	accounts.On("GetByID", ctx, int64(10)).Return(&model.Account{ID: 10, TimeZone: "Europe/Moscow"}, nil)
+}
+
+func TestCardLimitService_Authorize(t *testing.T) {
+	ctx := context.Background()
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	// 21:30 UTC 31 марта - уже 1 апреля по Москве
+	at := time.Date(2026, time.March, 31, 21, 30, 0, 0, time.UTC)
+	day := time.Date(2026, time.April, 1, 0, 0, 0, 0, moscow)
+	month := time.Date(2026, time.April, 1, 0, 0, 0, 0, moscow)
+
+	newSpend := func(channel string, amount string) *model.CardSpend {
+		return &model.CardSpend{CardID: 1, Channel: channel, MCC: "5411", Amount: money.MustParse(amount), At: at}
+	}
+
+	t.Run("расход в пределах лимитов учитывается за день счета", func(t *testing.T) {
+		// Подготовка
+		limits, cards, accounts := new(MockCardLimitRepository), new(MockCardRepository), new(MockAccountRepository)
+		service := newTestCardLimitService(limits, cards, accounts)
+		expectCardAccount(ctx, cards, accounts)
+
+		spend := newSpend(model.ChannelPOS, "400")
+		limits.On("GetByCardID", ctx, int64(1)).Return([]*model.CardLimit{
+			{ID: 1, CardID: 1, Daily: money.MustParse("1000"), Monthly: money.MustParse("5000")},
+			// Лимит банкоматов не распространяется на покупки
+			{ID: 2, CardID: 1, Channel: model.ChannelATM, Disabled: true},
+		}, nil)
+		limits.On("SumSpend", ctx, int64(1), "", "", day, day.AddDate(0, 0, 1)).Return(money.MustParse("600"), nil)
+		limits.On("SumSpend", ctx, int64(1), "", "", month, month.AddDate(0, 1, 0)).Return(money.MustParse("4000"), nil)
+		limits.On("AddSpend", ctx, spend, day).Return(nil)
+
+		// Действие
+		err := service.Authorize(ctx, spend)
+
+		// Проверка
+		require.NoError(t, err)
+		limits.AssertExpectations(t)
+	})
+
+	t.Run("превышение дневного лимита", func(t *testing.T) {
+		// Подготовка
+		limits, cards, accounts := new(MockCardLimitRepository), new(MockCardRepository), new(MockAccountRepository)
+		service := newTestCardLimitService(limits, cards, accounts)
+		expectCardAccount(ctx, cards, accounts)
+
+		limits.On("GetByCardID", ctx, int64(1)).Return([]*model.CardLimit{
+			{ID: 1, CardID: 1, Channel: model.ChannelPOS, Daily: money.MustParse("1000")},
+		}, nil)
+		limits.On("SumSpend", ctx, int64(1), model.ChannelPOS, "", day, day.AddDate(0, 0, 1)).Return(money.MustParse("600"), nil)
+
+		// Действие
+		err := service.Authorize(ctx, newSpend(model.ChannelPOS, "400.01"))
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardLimitExceeded)
+		limits.AssertNotCalled(t, "AddSpend", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("превышение лимита на операцию", func(t *testing.T) {
+		// Подготовка
+		limits, cards, accounts := new(MockCardLimitRepository), new(MockCardRepository), new(MockAccountRepository)
+		service := newTestCardLimitService(limits, cards, accounts)
+		expectCardAccount(ctx, cards, accounts)
+
+		limits.On("GetByCardID", ctx, int64(1)).Return([]*model.CardLimit{
+			{ID: 1, CardID: 1, MCC: "5411", PerTransaction: money.MustParse("100")},
+		}, nil)
+
+		// Действие
+		err := service.Authorize(ctx, newSpend(model.ChannelECommerce, "150"))
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardLimitExceeded)
+	})
+
+	t.Run("канал отключен", func(t *testing.T) {
+		// Подготовка
+		limits, cards, accounts := new(MockCardLimitRepository), new(MockCardRepository), new(MockAccountRepository)
+		service := newTestCardLimitService(limits, cards, accounts)
+		expectCardAccount(ctx, cards, accounts)
+
+		limits.On("GetByCardID", ctx, int64(1)).Return([]*model.CardLimit{
+			{ID: 1, CardID: 1, Channel: model.ChannelECommerce, Disabled: true},
+		}, nil)
+
+		// Действие
+		err := service.Authorize(ctx, newSpend(model.ChannelECommerce, "1"))
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardChannelDisabled)
+	})
+
+	t.Run("неизвестный канал", func(t *testing.T) {
+		// Подготовка
+		service := newTestCardLimitService(new(MockCardLimitRepository), new(MockCardRepository), new(MockAccountRepository))
+
+		// Действие
+		err := service.Authorize(ctx, newSpend("phone", "1"))
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrUnknownChannel)
+	})
+}
+
+func TestCardLimitService_Release(t *testing.T) {
+	ctx := context.Background()
+
+	// Подготовка
+	limits, cards, accounts := new(MockCardLimitRepository), new(MockCardRepository), new(MockAccountRepository)
+	service := newTestCardLimitService(limits, cards, accounts)
+	expectCardAccount(ctx, cards, accounts)
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+	spend := &model.CardSpend{
+		CardID:  1,
+		Channel: model.ChannelATM,
+		Amount:  money.MustParse("300"),
+		At:      time.Date(2026, time.May, 10, 12, 0, 0, 0, time.UTC),
+	}
+	limits.On("AddSpend", ctx, mock.MatchedBy(func(s *model.CardSpend) bool {
+		return s.Amount == money.MustParse("-300") && s.Channel == model.ChannelATM
+	}), time.Date(2026, time.May, 10, 0, 0, 0, 0, moscow)).Return(nil)
+
+	// Действие
+	err = service.Release(ctx, spend)
+
+	// Проверка
+	require.NoError(t, err)
+	assert.Equal(t, money.MustParse("300"), spend.Amount)
+	limits.AssertExpectations(t)
+}
+
+func TestCardLimitService_Create(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("некорректный лимит", func(t *testing.T) {
+		service := newTestCardLimitService(new(MockCardLimitRepository), new(MockCardRepository), new(MockAccountRepository))
+
+		assert.ErrorIs(t, service.Create(ctx, &model.CardLimit{CardID: 1, Channel: "phone"}), ErrUnknownChannel)
+		assert.ErrorIs(t, service.Create(ctx, &model.CardLimit{CardID: 1, MCC: "54a1"}), ErrInvalidMCC)
+		assert.ErrorIs(t, service.Create(ctx, &model.CardLimit{CardID: 1, Daily: money.MustParse("-1")}), ErrInvalidCardLimit)
+	})
+
+	t.Run("повторный лимит для канала", func(t *testing.T) {
+		// Подготовка
+		limits, cards := new(MockCardLimitRepository), new(MockCardRepository)
+		service := newTestCardLimitService(limits, cards, new(MockAccountRepository))
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1}, nil)
+		limits.On("GetByCardID", ctx, int64(1)).Return([]*model.CardLimit{
+			{ID: 5, CardID: 1, Channel: model.ChannelATM},
+		}, nil)
+
+		// Действие
+		err := service.Create(ctx, &model.CardLimit{CardID: 1, Channel: model.ChannelATM, Daily: money.MustParse("100")})
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardLimitExists)
+		limits.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCardLimitService_Update(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("лимит другой карты не найден", func(t *testing.T) {
+		// Подготовка
+		limits, cards := new(MockCardLimitRepository), new(MockCardRepository)
+		service := newTestCardLimitService(limits, cards, new(MockAccountRepository))
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1}, nil)
+		limits.On("GetByID", ctx, int64(5)).Return(&model.CardLimit{ID: 5, CardID: 2}, nil)
+
+		// Действие
+		err := service.Update(ctx, &model.CardLimit{ID: 5, CardID: 1, Daily: money.MustParse("100")})
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrNotFound)
+		limits.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("успешное изменение", func(t *testing.T) {
+		// Подготовка
+		limits, cards := new(MockCardLimitRepository), new(MockCardRepository)
+		service := newTestCardLimitService(limits, cards, new(MockAccountRepository))
+		limit := &model.CardLimit{ID: 5, CardID: 1, Channel: model.ChannelECommerce, Monthly: money.MustParse("20000")}
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1}, nil)
+		limits.On("GetByID", ctx, int64(5)).Return(&model.CardLimit{ID: 5, CardID: 1}, nil)
+		limits.On("GetByCardID", ctx, int64(1)).Return([]*model.CardLimit{{ID: 5, CardID: 1}}, nil)
+		limits.On("Update", ctx, limit).Return(nil)
+
+		// Действие
+		err := service.Update(ctx, limit)
+
+		// Проверка
+		require.NoError(t, err)
+		limits.AssertExpectations(t)
+	})
+}
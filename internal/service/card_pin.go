@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"bank-app/internal/model"
+	"bank-app/internal/repository"
+)
+
+var (
+	ErrInvalidPINFormat = errors.New("PIN must be 4 to 6 digits")
+	ErrPINAlreadySet    = errors.New("card PIN is already set, use change or reset")
+	ErrPINNotSet        = errors.New("card PIN is not set")
+	ErrInvalidPIN       = errors.New("invalid PIN")
+	ErrPINTriesExceeded = errors.New("PIN try limit exceeded, card is blocked")
+)
+
+const (
+	minPINLength = 4
+	maxPINLength = 6
+	// maxPINAttempts - число неверных PIN подряд, после которого карта блокируется
+	maxPINAttempts = 3
+)
+
+// SetPIN устанавливает PIN выпущенной или действующей карты, у которой его еще нет
+func (s *CardSvc) SetPIN(ctx context.Context, id int64, pin string) error {
+	if !isPIN(pin) {
+		return ErrInvalidPINFormat
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		card, err := s.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if card.Status != model.CardIssued && card.Status != model.CardActive {
+			return ErrCardNotActive
+		}
+
+		return s.setPIN(ctx, card.ID, pin)
+	})
+}
+
+// ChangePIN меняет PIN по текущему. Неверный текущий PIN учитывается в
+// счетчике попыток так же, как при оплате.
+func (s *CardSvc) ChangePIN(ctx context.Context, id int64, current, pin string) error {
+	if !isPIN(pin) {
+		return ErrInvalidPINFormat
+	}
+
+	if err := s.VerifyPIN(ctx, id, current); err != nil {
+		return err
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.repo.GetByIDForUpdate(ctx, id); err != nil {
+			return err
+		}
+
+		return s.savePIN(ctx, id, pin)
+	})
+}
+
+// ResetPIN задает новый PIN без знания текущего (после step-up) и сбрасывает
+// счетчик попыток. Карта, заблокированная из-за неверных PIN, разблокируется.
+func (s *CardSvc) ResetPIN(ctx context.Context, id int64, actor model.Actor, pin string) error {
+	if !isPIN(pin) {
+		return ErrInvalidPINFormat
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		card, err := s.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		stored, err := s.pins.Get(ctx, id)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		blockedByPIN := stored != nil && stored.FailedAttempts >= maxPINAttempts
+
+		switch {
+		case card.Status == model.CardBlockedTemporarily && blockedByPIN:
+			if err := s.transition(ctx, card, model.CardActive, "PIN reset", actor); err != nil {
+				return err
+			}
+		case card.Status != model.CardIssued && card.Status != model.CardActive:
+			return ErrCardNotActive
+		}
+
+		return s.savePIN(ctx, id, pin)
+	})
+}
+
+// VerifyPIN проверяет PIN, предъявленный в банкомате или терминале. Неверный
+// PIN увеличивает счетчик попыток, после maxPINAttempts подряд карта
+// блокируется. Счетчик сохраняется в отдельной транзакции, поэтому метод
+// нельзя вызывать внутри транзакции, которая откатится при отказе.
+func (s *CardSvc) VerifyPIN(ctx context.Context, id int64, pin string) error {
+	var (
+		mismatch bool
+		left     int
+	)
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		card, err := s.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		stored, err := s.pins.Get(ctx, id)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrPINNotSet
+		}
+		if err != nil {
+			return err
+		}
+
+		if stored.FailedAttempts >= maxPINAttempts {
+			return ErrPINTriesExceeded
+		}
+
+		if card.Status != model.CardActive {
+			return ErrCardNotActive
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(stored.Hash), []byte(pin)) == nil {
+			if stored.FailedAttempts == 0 {
+				return nil
+			}
+			stored.FailedAttempts = 0
+			return s.pins.Save(ctx, stored)
+		}
+
+		mismatch = true
+		stored.FailedAttempts++
+		left = maxPINAttempts - stored.FailedAttempts
+		if err := s.pins.Save(ctx, stored); err != nil {
+			return err
+		}
+
+		if left == 0 {
+			return s.transition(ctx, card, model.CardBlockedTemporarily, "PIN try limit exceeded", model.Actor{})
+		}
+
+		return nil
+	})
+
+	switch {
+	case err != nil:
+		return err
+	case mismatch && left == 0:
+		return ErrPINTriesExceeded
+	case mismatch:
+		return fmt.Errorf("%w: %d attempts left", ErrInvalidPIN, left)
+	}
+
+	return nil
+}
+
+// ValidateCardPIN проверяет карту, предъявленную с PIN: номер, срок
+// действия, статус и PIN
+func (s *CardSvc) ValidateCardPIN(ctx context.Context, number, pin string) (*model.Card, error) {
+	card, err := s.presentedCard(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.VerifyPIN(ctx, card.ID, pin); err != nil {
+		return nil, err
+	}
+
+	return card, nil
+}
+
+// setPIN сохраняет первый PIN карты
+func (s *CardSvc) setPIN(ctx context.Context, id int64, pin string) error {
+	_, err := s.pins.Get(ctx, id)
+	if err == nil {
+		return ErrPINAlreadySet
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	return s.savePIN(ctx, id, pin)
+}
+
+func (s *CardSvc) savePIN(ctx context.Context, id int64, pin string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("hash PIN: %w", err)
+	}
+
+	return s.pins.Save(ctx, &model.CardPIN{CardID: id, Hash: string(hash)})
+}
+
+func isPIN(pin string) bool {
+	if len(pin) < minPINLength || len(pin) > maxPINLength {
+		return false
+	}
+	for _, c := range pin {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
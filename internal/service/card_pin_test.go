@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"bank-app/internal/model"
+)
+
+type MockCardPINRepository struct {
+	mock.Mock
+}
+
+func (m *MockCardPINRepository) Get(ctx context.Context, cardID int64) (*model.CardPIN, error) {
+	args := m.Called(ctx, cardID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CardPIN), args.Error(1)
+}
+
+func (m *MockCardPINRepository) Save(ctx context.Context, pin *model.CardPIN) error {
+	args := m.Called(ctx, pin)
+	return args.Error(0)
+}
+
+func newTestCardPIN(t *testing.T, cardID int64, pin string, failed int) *model.CardPIN {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.MinCost)
+	require.NoError(t, err)
+	return &model.CardPIN{CardID: cardID, Hash: string(hash), FailedAttempts: failed}
+}
+
+func TestCardService_SetPIN(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("первый PIN", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		pins := new(MockCardPINRepository)
+		service := newTestCardService(t, cards)
+		service.pins = pins
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1, Status: model.CardActive}, nil)
+		pins.On("Get", ctx, int64(1)).Return(nil, ErrNotFound)
+
+		var saved *model.CardPIN
+		pins.On("Save", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			saved = args.Get(1).(*model.CardPIN)
+		})
+
+		// Действие
+		err := service.SetPIN(ctx, 1, "4821")
+
+		// Проверка
+		require.NoError(t, err)
+		require.NotNil(t, saved)
+		assert.NotEqual(t, "4821", saved.Hash)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(saved.Hash), []byte("4821")))
+		assert.Zero(t, saved.FailedAttempts)
+	})
+
+	t.Run("PIN уже установлен", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		pins := new(MockCardPINRepository)
+		service := newTestCardService(t, cards)
+		service.pins = pins
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1, Status: model.CardActive}, nil)
+		pins.On("Get", ctx, int64(1)).Return(newTestCardPIN(t, 1, "4821", 0), nil)
+
+		// Действие
+		err := service.SetPIN(ctx, 1, "1111")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrPINAlreadySet)
+		pins.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
+
+	t.Run("некорректный PIN", func(t *testing.T) {
+		service := newTestCardService(t, new(MockCardRepository))
+
+		for _, pin := range []string{"", "123", "1234567", "12a4"} {
+			assert.ErrorIs(t, service.SetPIN(ctx, 1, pin), ErrInvalidPINFormat, pin)
+		}
+	})
+}
+
+func TestCardService_VerifyPIN(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("верный PIN сбрасывает счетчик", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		pins := new(MockCardPINRepository)
+		service := newTestCardService(t, cards)
+		service.pins = pins
+		stored := newTestCardPIN(t, 1, "4821", 2)
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1, Status: model.CardActive}, nil)
+		pins.On("Get", ctx, int64(1)).Return(stored, nil)
+		pins.On("Save", ctx, stored).Return(nil)
+
+		// Действие
+		err := service.VerifyPIN(ctx, 1, "4821")
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Zero(t, stored.FailedAttempts)
+		pins.AssertExpectations(t)
+	})
+
+	t.Run("третий неверный PIN блокирует карту", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		pins := new(MockCardPINRepository)
+		service := newTestCardService(t, cards)
+		service.pins = pins
+		card := &model.Card{ID: 1, Status: model.CardActive}
+		stored := newTestCardPIN(t, 1, "4821", 0)
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(card, nil)
+		cards.On("Update", ctx, card).Return(nil)
+		cards.On("AddStatusChange", ctx, statusChange(1, model.CardActive, model.CardBlockedTemporarily)).Return(nil).Once()
+		pins.On("Get", ctx, int64(1)).Return(stored, nil)
+		pins.On("Save", ctx, stored).Return(nil)
+
+		// Действие
+		first := service.VerifyPIN(ctx, 1, "0000")
+		second := service.VerifyPIN(ctx, 1, "1111")
+		third := service.VerifyPIN(ctx, 1, "2222")
+		correct := service.VerifyPIN(ctx, 1, "4821")
+
+		// Проверка
+		assert.ErrorIs(t, first, ErrInvalidPIN)
+		assert.Contains(t, first.Error(), "2 attempts left")
+		assert.ErrorIs(t, second, ErrInvalidPIN)
+		assert.ErrorIs(t, third, ErrPINTriesExceeded)
+		assert.ErrorIs(t, correct, ErrPINTriesExceeded)
+		assert.Equal(t, maxPINAttempts, stored.FailedAttempts)
+		assert.Equal(t, model.CardBlockedTemporarily, card.Status)
+		cards.AssertExpectations(t)
+	})
+
+	t.Run("PIN не установлен", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		pins := new(MockCardPINRepository)
+		service := newTestCardService(t, cards)
+		service.pins = pins
+		cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1, Status: model.CardActive}, nil)
+		pins.On("Get", ctx, int64(1)).Return(nil, ErrNotFound)
+
+		// Действие
+		err := service.VerifyPIN(ctx, 1, "4821")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrPINNotSet)
+	})
+}
+
+func TestCardService_ChangePIN(t *testing.T) {
+	ctx := context.Background()
+
+	// Подготовка
+	cards := new(MockCardRepository)
+	pins := new(MockCardPINRepository)
+	service := newTestCardService(t, cards)
+	service.pins = pins
+	stored := newTestCardPIN(t, 1, "4821", 0)
+	cards.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Card{ID: 1, Status: model.CardActive}, nil)
+	pins.On("Get", ctx, int64(1)).Return(stored, nil)
+	pins.On("Save", ctx, stored).Return(nil)
+
+	var changed *model.CardPIN
+	pins.On("Save", ctx, mock.MatchedBy(func(p *model.CardPIN) bool { return p != stored })).Return(nil).Run(func(args mock.Arguments) {
+		changed = args.Get(1).(*model.CardPIN)
+	})
+
+	// Действие
+	wrong := service.ChangePIN(ctx, 1, "0000", "7350")
+	err := service.ChangePIN(ctx, 1, "4821", "7350")
+
+	// Проверка
+	assert.ErrorIs(t, wrong, ErrInvalidPIN)
+	require.NoError(t, err)
+	require.NotNil(t, changed)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(changed.Hash), []byte("7350")))
+}
+
+func TestCardService_ResetPIN(t *testing.T) {
+	ctx := context.Background()
+	operator := model.Actor{UserID: 7, Role: model.RoleOperator}
+
+	// Подготовка
+	cards := new(MockCardRepository)
+	pins := new(MockCardPINRepository)
+	service := newTestCardService(t, cards)
+	service.pins = pins
+	card := &model.Card{ID: 1, Status: model.CardBlockedTemporarily, ExpiryDate: model.CardExpiryDate(2099, time.December)}
+	cards.On("GetByIDForUpdate", ctx, int64(1)).Return(card, nil)
+	cards.On("Update", ctx, card).Return(nil)
+	cards.On("AddStatusChange", ctx, statusChange(1, model.CardBlockedTemporarily, model.CardActive)).Return(nil)
+	pins.On("Get", ctx, int64(1)).Return(newTestCardPIN(t, 1, "4821", maxPINAttempts), nil)
+
+	var saved *model.CardPIN
+	pins.On("Save", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*model.CardPIN)
+	})
+
+	// Действие
+	err := service.ResetPIN(ctx, 1, operator, "9034")
+
+	// Проверка
+	require.NoError(t, err)
+	assert.Equal(t, model.CardActive, card.Status)
+	require.NotNil(t, saved)
+	assert.Zero(t, saved.FailedAttempts)
+	cards.AssertExpectations(t)
+}
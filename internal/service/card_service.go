@@ -2,65 +2,404 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand"
+	"strings"
 	"time"
 
+	"golang.org/x/crypto/bcrypt"
+
+	"bank-app/internal/cardnumber"
+	"bank-app/internal/cardvault"
+	"bank-app/internal/config"
 	"bank-app/internal/model"
 	"bank-app/internal/repository"
 )
 
-type CardSvc struct {
-	repo repository.CardRepository
+// cardSecret - реквизиты карты, которые хранятся только в зашифрованном виде.
+// CVV сохраняется только у виртуальных карт до раскрытия реквизитов клиенту.
+type cardSecret struct {
+	Number      string `json:"pan"`
+	ExpiryMonth int    `json:"exp_month"`
+	ExpiryYear  int    `json:"exp_year"`
+	CVV         string `json:"cvv,omitempty"`
 }
 
-func NewCardService(repo repository.CardRepository) CardService {
-	return &CardSvc{repo: repo}
+var (
+	ErrUnknownCardProduct  = errors.New("unknown card product")
+	ErrInvalidCard         = errors.New("invalid card number")
+	ErrCardExpired         = errors.New("card has expired")
+	ErrCardNotActive       = errors.New("card is not active")
+	ErrInvalidCVV          = errors.New("invalid card verification code")
+	ErrCardNotVirtual      = errors.New("operation is only available for virtual cards")
+	ErrCardDetailsRevealed = errors.New("card details have already been revealed")
+	ErrCardMerchantLocked  = errors.New("card is locked to another merchant")
+)
+
+const (
+	cardValidityYears = 4
+	cvvLength         = 3
+	// maxCardNumberAttempts ограничивает повторную генерацию при совпадении номера
+	maxCardNumberAttempts = 10
+)
+
+type CardSvc struct {
+	repo        repository.CardRepository
+	pins        repository.CardPINRepository
+	tx          repository.Transactor
+	vault       *cardvault.Vault
+	products    map[string][]cardnumber.Range
+	length      int
+	renewalLead time.Duration
+	now         func() time.Time
 }
 
-func (s *CardSvc) Create(ctx context.Context, accountID int64) error {
-	// Генерируем номер карты (в реальном приложении использовать более надежный алгоритм)
-	rand.Seed(time.Now().UnixNano())
-	cardNumber := fmt.Sprintf("4276%012d", rand.Int63n(1000000000000))
+func NewCardService(repo repository.CardRepository, pins repository.CardPINRepository, tx repository.Transactor, vault *cardvault.Vault, cfg *config.Config) CardService {
+	return &CardSvc{
+		repo:        repo,
+		pins:        pins,
+		tx:          tx,
+		vault:       vault,
+		products:    cfg.Cards.Products,
+		length:      cfg.Cards.NumberLength,
+		renewalLead: cfg.Cards.RenewalLead,
+		now:         time.Now,
+	}
+}
 
-	// Генерируем CVV
-	cvv := fmt.Sprintf("%03d", rand.Intn(1000))
+// Create выпускает карту продукта product к счету
+func (s *CardSvc) Create(ctx context.Context, accountID int64, product string) error {
+	card := &model.Card{AccountID: accountID, Product: product, Status: model.CardActive}
 
-	// Срок действия карты (4 года от текущей даты)
-	expiryDate := time.Now().AddDate(4, 0, 0)
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		return s.issue(ctx, card, model.Actor{}, "issued")
+	})
+}
 
+// CreateVirtual сразу выпускает действующую виртуальную карту. Одноразовая
+// карта закрывается после первой одобренной авторизации, закрепляемая
+// принимается только торговцем, у которого ею впервые расплатились.
+func (s *CardSvc) CreateVirtual(ctx context.Context, accountID int64, singleUse, merchantLocked bool) (*model.Card, error) {
 	card := &model.Card{
-		AccountID:  accountID,
-		Number:     cardNumber,
-		CVV:        cvv,
-		ExpiryDate: expiryDate,
-		Status:     "active",
+		AccountID:      accountID,
+		Product:        model.CardVirtual,
+		Status:         model.CardActive,
+		SingleUse:      singleUse,
+		MerchantLocked: merchantLocked,
 	}
 
-	return s.repo.Create(ctx, card)
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		return s.issue(ctx, card, model.Actor{}, "issued")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return card, nil
+}
+
+// issue генерирует реквизиты и сохраняет новую карту вместе с первой записью истории
+func (s *CardSvc) issue(ctx context.Context, card *model.Card, actor model.Actor, reason string) error {
+	ranges, ok := s.products[card.Product]
+	if !ok {
+		return ErrUnknownCardProduct
+	}
+
+	number, err := s.newCardNumber(ctx, ranges)
+	if err != nil {
+		return err
+	}
+
+	cvv, err := cardnumber.RandomDigits(cvvLength)
+	if err != nil {
+		return err
+	}
+
+	// Карта действует до конца месяца, в котором истекает срок
+	expiry := s.now().AddDate(cardValidityYears, 0, 0)
+
+	card.Number = number
+	card.CVV = cvv
+	card.ExpiryDate = model.CardExpiryDate(expiry.Year(), expiry.Month())
+	if s.expired(card) {
+		return ErrCardExpired
+	}
+
+	if err := s.protect(card); err != nil {
+		return err
+	}
+
+	if err := s.repo.Create(ctx, card); err != nil {
+		return err
+	}
+
+	return s.repo.AddStatusChange(ctx, &model.CardStatusChange{
+		CardID:    card.ID,
+		ToStatus:  card.Status,
+		Reason:    reason,
+		ChangedBy: actor.UserID,
+	})
+}
+
+// newCardNumber генерирует номер, которого еще нет среди выпущенных карт.
+// Уникальный индекс по number_hmac защищает от гонки между проверкой и вставкой.
+func (s *CardSvc) newCardNumber(ctx context.Context, ranges []cardnumber.Range) (string, error) {
+	for i := 0; i < maxCardNumberAttempts; i++ {
+		number, err := cardnumber.Generate(ranges, s.length)
+		if err != nil {
+			return "", err
+		}
+
+		_, err = s.repo.GetByNumberHMAC(ctx, s.vault.MAC(number))
+		if errors.Is(err, repository.ErrNotFound) {
+			return number, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return "", errors.New("failed to generate unique card number")
 }
 
 func (s *CardSvc) GetByID(ctx context.Context, id int64) (*model.Card, error) {
 	return s.repo.GetByID(ctx, id)
 }
 
+// GetDetails возвращает карту с расшифрованным номером. CVV не раскрывается.
+func (s *CardSvc) GetDetails(ctx context.Context, id int64) (*model.Card, error) {
+	card, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := s.open(card)
+	if err != nil {
+		return nil, err
+	}
+
+	card.Number = secret.Number
+	card.ExpiryDate = model.CardExpiryDate(secret.ExpiryYear, time.Month(secret.ExpiryMonth))
+	return card, nil
+}
+
+// RevealDetails однократно раскрывает номер, срок действия и CVV виртуальной
+// карты. После раскрытия CVV удаляется из хранимых реквизитов.
+func (s *CardSvc) RevealDetails(ctx context.Context, id int64) (*model.Card, error) {
+	var revealed *model.Card
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		card, err := s.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if card.Product != model.CardVirtual {
+			return ErrCardNotVirtual
+		}
+
+		if card.DetailsRevealedAt != nil {
+			return ErrCardDetailsRevealed
+		}
+
+		if card.Status != model.CardActive {
+			return ErrCardNotActive
+		}
+
+		secret, err := s.open(card)
+		if err != nil {
+			return err
+		}
+
+		if secret.CVV == "" {
+			return ErrCardDetailsRevealed
+		}
+
+		card.Number = secret.Number
+		card.CVV = secret.CVV
+		card.ExpiryDate = model.CardExpiryDate(secret.ExpiryYear, time.Month(secret.ExpiryMonth))
+
+		secret.CVV = ""
+		if card.EncryptedData, err = s.seal(secret); err != nil {
+			return err
+		}
+
+		now := s.now()
+		card.DetailsRevealedAt = &now
+		if err := s.repo.Update(ctx, card); err != nil {
+			return err
+		}
+
+		revealed = card
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revealed, nil
+}
+
 func (s *CardSvc) GetByAccountID(ctx context.Context, accountID int64) ([]*model.Card, error) {
 	return s.repo.GetByAccountID(ctx, accountID)
 }
 
-func (s *CardSvc) Block(ctx context.Context, id int64) error {
-	card, err := s.repo.GetByID(ctx, id)
+// ValidateCard проверяет реквизиты, предъявленные при оплате: формат и
+// контрольную цифру номера, срок действия, статус карты и CVV - и возвращает карту
+func (s *CardSvc) ValidateCard(ctx context.Context, number, cvv string) (*model.Card, error) {
+	card, err := s.presentedCard(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(card.CVVHash), []byte(cvv)) != nil {
+		return nil, ErrInvalidCVV
+	}
+
+	return card, nil
+}
+
+// FindByNumber возвращает карту по номеру без проверки реквизитов, срока
+// и статуса
+func (s *CardSvc) FindByNumber(ctx context.Context, number string) (*model.Card, error) {
+	number = cardnumber.Normalize(number)
+	if !cardnumber.Valid(number) {
+		return nil, ErrInvalidCard
+	}
+
+	card, err := s.repo.GetByNumberHMAC(ctx, s.vault.MAC(number))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidCard
+	}
+	return card, err
+}
+
+// expired сообщает, истек ли срок действия карты. Срок включает последний
+// день месяца.
+func (s *CardSvc) expired(card *model.Card) bool {
+	return !s.now().Before(card.ExpiryDate.AddDate(0, 0, 1))
+}
+
+// presentedCard находит карту по предъявленному номеру и проверяет формат
+// номера, срок действия и статус
+func (s *CardSvc) presentedCard(ctx context.Context, number string) (*model.Card, error) {
+	card, err := s.FindByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.expired(card) {
+		return nil, ErrCardExpired
+	}
+
+	if card.Status != model.CardActive {
+		return nil, ErrCardNotActive
+	}
+
+	return card, nil
+}
+
+// RecordAuthorization применяет к виртуальной карте ограничения первой
+// авторизации: закрепляет карту за торговцем merchant или отклоняет операцию
+// другого торговца и закрывает одноразовую карту. Вызывается в транзакции
+// авторизации, поэтому при ошибке она откатывается целиком.
+func (s *CardSvc) RecordAuthorization(ctx context.Context, card *model.Card, merchant string) error {
+	if !card.SingleUse && !card.MerchantLocked {
+		return nil
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Повторное чтение под блокировкой: параллельная авторизация могла
+		// закрыть карту или закрепить ее за другим торговцем
+		card, err := s.repo.GetByIDForUpdate(ctx, card.ID)
+		if err != nil {
+			return err
+		}
+
+		if card.Status != model.CardActive {
+			return ErrCardNotActive
+		}
+
+		if card.MerchantLocked {
+			merchant = strings.TrimSpace(merchant)
+			switch {
+			case merchant == "":
+				return ErrCardMerchantLocked
+			case card.LockedMerchant == "":
+				card.LockedMerchant = merchant
+				if err := s.repo.Update(ctx, card); err != nil {
+					return err
+				}
+			case !strings.EqualFold(card.LockedMerchant, merchant):
+				return ErrCardMerchantLocked
+			}
+		}
+
+		if card.SingleUse {
+			return s.transition(ctx, card, model.CardClosed, "single-use card used", model.Actor{})
+		}
+
+		return nil
+	})
+}
+
+// protect заполняет хранимые поля карты: маскированный номер, HMAC номера,
+// хеш CVV и зашифрованные номер и срок действия (у виртуальной карты - и CVV)
+func (s *CardSvc) protect(card *model.Card) error {
+	cvvHash, err := bcrypt.GenerateFromPassword([]byte(card.CVV), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	secret := &cardSecret{
+		Number:      card.Number,
+		ExpiryMonth: int(card.ExpiryDate.Month()),
+		ExpiryYear:  card.ExpiryDate.Year(),
+	}
+	if card.Product == model.CardVirtual {
+		secret.CVV = card.CVV
+	}
+
+	encrypted, err := s.seal(secret)
 	if err != nil {
 		return err
 	}
 
-	card.Status = "blocked"
-	return s.repo.Update(ctx, card)
+	card.MaskedNumber = maskCardNumber(card.Number)
+	card.NumberHMAC = s.vault.MAC(card.Number)
+	card.CVVHash = string(cvvHash)
+	card.EncryptedData = encrypted
+	return nil
+}
+
+// open расшифровывает хранимые реквизиты карты
+func (s *CardSvc) open(card *model.Card) (*cardSecret, error) {
+	plaintext, err := s.vault.Open(card.EncryptedData)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt card %d: %w", card.ID, err)
+	}
+
+	var secret cardSecret
+	if err := json.Unmarshal(plaintext, &secret); err != nil {
+		return nil, fmt.Errorf("decrypt card %d: %w", card.ID, err)
+	}
+
+	return &secret, nil
 }
 
-func (s *CardSvc) ValidateCard(ctx context.Context, number, cvv string) error {
-	// В реальном приложении здесь должна быть проверка по алгоритму Луна
-	// и проверка CVV через безопасное хранилище
-	return errors.New("not implemented")
+func (s *CardSvc) seal(secret *cardSecret) (string, error) {
+	plaintext, err := json.Marshal(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return s.vault.Seal(plaintext)
+}
+
+// maskCardNumber оставляет открытыми первые шесть и последние четыре цифры
+func maskCardNumber(number string) string {
+	if len(number) < 13 {
+		return strings.Repeat("*", len(number))
+	}
+	return number[:6] + strings.Repeat("*", len(number)-10) + number[len(number)-4:]
 }
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"bank-app/internal/cardnumber"
+	"bank-app/internal/cardvault"
+	"bank-app/internal/config"
+	"bank-app/internal/model"
+)
+
+func newTestVault(t *testing.T) *cardvault.Vault {
+	vault, err := cardvault.New("test", map[string][]byte{"test": bytes.Repeat([]byte{1}, cardvault.KeySize)}, bytes.Repeat([]byte{2}, cardvault.KeySize))
+	require.NoError(t, err)
+	return vault
+}
+
+func newTestCardService(t *testing.T, cards *MockCardRepository) *CardSvc {
+	debit, _ := cardnumber.ParseRanges("427600-427699")
+	virtual, _ := cardnumber.ParseRanges("22007012")
+	cfg := &config.Config{
+		Cards: config.CardIssuanceConfig{
+			Products:     map[string][]cardnumber.Range{model.CardDebit: debit, model.CardVirtual: virtual},
+			NumberLength: cardnumber.DefaultLength,
+			RenewalLead:  30 * 24 * time.Hour,
+		},
+	}
+	return NewCardService(cards, new(MockCardPINRepository), &MockTransactor{}, newTestVault(t), cfg).(*CardSvc)
+}
+
+func TestCardService_Create(t *testing.T) {
+	ctx := context.Background()
+	cards := new(MockCardRepository)
+	service := newTestCardService(t, cards)
+	vault := service.vault
+
+	var stored *model.Card
+	cards.On("GetByNumberHMAC", ctx, mock.Anything).Return(nil, ErrNotFound)
+	cards.On("Create", ctx, mock.AnythingOfType("*model.Card")).Return(nil).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*model.Card)
+	})
+	cards.On("AddStatusChange", ctx, mock.MatchedBy(func(c *model.CardStatusChange) bool {
+		return c.FromStatus == "" && c.ToStatus == model.CardActive
+	})).Return(nil)
+
+	// Действие
+	err := service.Create(ctx, 7, model.CardVirtual)
+
+	// Проверка
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, int64(7), stored.AccountID)
+	assert.Equal(t, model.CardVirtual, stored.Product)
+	assert.Equal(t, model.CardActive, stored.Status)
+	assert.True(t, cardnumber.Valid(stored.Number))
+	assert.True(t, strings.HasPrefix(stored.Number, "22007012"))
+	assert.Len(t, stored.CVV, 3)
+
+	// В базу попадают только маскированный номер, HMAC, хеш CVV и шифротекст
+	assert.Regexp(t, `^\d{6}\*{6}\d{4}$`, stored.MaskedNumber)
+	assert.Equal(t, vault.MAC(stored.Number), stored.NumberHMAC)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(stored.CVVHash), []byte(stored.CVV)))
+	assert.NotContains(t, stored.EncryptedData, stored.Number)
+
+	plaintext, err := vault.Open(stored.EncryptedData)
+	require.NoError(t, err)
+	assert.Contains(t, string(plaintext), stored.Number)
+
+	// Срок действия - последний день месяца
+	assert.Equal(t, 1, stored.ExpiryDate.AddDate(0, 0, 1).Day())
+
+	// В JSON нет полного номера и CVV
+	body, err := json.Marshal(stored)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), stored.Number)
+	assert.NotContains(t, string(body), stored.CVVHash)
+	assert.Contains(t, string(body), stored.MaskedNumber)
+}
+
+func TestCardService_CreateUniqueNumber(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("повторная генерация при совпадении номера", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		cards.On("GetByNumberHMAC", ctx, mock.Anything).Return(&model.Card{ID: 1}, nil).Once()
+		cards.On("GetByNumberHMAC", ctx, mock.Anything).Return(nil, ErrNotFound).Once()
+		cards.On("Create", ctx, mock.Anything).Return(nil)
+		cards.On("AddStatusChange", ctx, mock.Anything).Return(nil)
+
+		// Действие
+		err := service.Create(ctx, 7, model.CardDebit)
+
+		// Проверка
+		assert.NoError(t, err)
+		cards.AssertNumberOfCalls(t, "GetByNumberHMAC", 2)
+	})
+
+	t.Run("неизвестный продукт", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+
+		// Действие
+		err := service.Create(ctx, 7, "platinum")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrUnknownCardProduct)
+		cards.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCardService_ValidateCard(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	number := "4276000000000009"
+	cvvHash, _ := bcrypt.GenerateFromPassword([]byte("123"), bcrypt.MinCost)
+
+	newService := func(card *model.Card) (*CardSvc, *MockCardRepository) {
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		service.now = func() time.Time { return now }
+		if card != nil {
+			cards.On("GetByNumberHMAC", ctx, service.vault.MAC(number)).Return(card, nil)
+		}
+		return service, cards
+	}
+
+	activeCard := func() *model.Card {
+		return &model.Card{
+			ID:         1,
+			Status:     model.CardActive,
+			ExpiryDate: model.CardExpiryDate(2026, time.March),
+			CVVHash:    string(cvvHash),
+		}
+	}
+
+	t.Run("действующая карта", func(t *testing.T) {
+		service, _ := newService(activeCard())
+		card, err := service.ValidateCard(ctx, "4276 0000 0000 0009", "123")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), card.ID)
+	})
+
+	t.Run("неверная контрольная цифра", func(t *testing.T) {
+		service, cards := newService(nil)
+		_, err := service.ValidateCard(ctx, "4276000000000008", "123")
+		assert.ErrorIs(t, err, ErrInvalidCard)
+		cards.AssertNotCalled(t, "GetByNumberHMAC", mock.Anything, mock.Anything)
+	})
+
+	t.Run("карта не выпускалась", func(t *testing.T) {
+		service, cards := newService(nil)
+		cards.On("GetByNumberHMAC", ctx, mock.Anything).Return(nil, ErrNotFound)
+		_, err := service.ValidateCard(ctx, number, "123")
+		assert.ErrorIs(t, err, ErrInvalidCard)
+	})
+
+	t.Run("истек срок действия", func(t *testing.T) {
+		card := activeCard()
+		card.ExpiryDate = model.CardExpiryDate(2026, time.February)
+		service, _ := newService(card)
+		_, err := service.ValidateCard(ctx, number, "123")
+		assert.ErrorIs(t, err, ErrCardExpired)
+	})
+
+	t.Run("заблокированная карта", func(t *testing.T) {
+		card := activeCard()
+		card.Status = model.CardBlockedTemporarily
+		service, _ := newService(card)
+		_, err := service.ValidateCard(ctx, number, "123")
+		assert.ErrorIs(t, err, ErrCardNotActive)
+	})
+
+	t.Run("неверный CVV", func(t *testing.T) {
+		service, _ := newService(activeCard())
+		_, err := service.ValidateCard(ctx, number, "321")
+		assert.ErrorIs(t, err, ErrInvalidCVV)
+	})
+}
+
+func TestCardService_GetDetails(t *testing.T) {
+	ctx := context.Background()
+	vault := newTestVault(t)
+
+	t.Run("расшифровка реквизитов", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		service.vault = vault
+		encrypted, err := vault.Seal([]byte(`{"pan":"4276000011112222","exp_month":3,"exp_year":2029}`))
+		require.NoError(t, err)
+		cards.On("GetByID", ctx, int64(5)).Return(&model.Card{
+			ID:            5,
+			MaskedNumber:  "427600******2222",
+			EncryptedData: encrypted,
+		}, nil)
+
+		// Действие
+		card, err := service.GetDetails(ctx, 5)
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, "4276000011112222", card.Number)
+		assert.Equal(t, time.Date(2029, 3, 31, 0, 0, 0, 0, time.UTC), card.ExpiryDate)
+	})
+
+	t.Run("поврежденные данные", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		cards.On("GetByID", ctx, int64(6)).Return(&model.Card{ID: 6, EncryptedData: "v1.test.broken.data"}, nil)
+
+		// Действие
+		_, err := service.GetDetails(ctx, 6)
+
+		// Проверка
+		assert.ErrorIs(t, err, cardvault.ErrMalformedEnvelope)
+	})
+}
+
+func TestCardService_CreateVirtual(t *testing.T) {
+	ctx := context.Background()
+
+	// Подготовка
+	cards := new(MockCardRepository)
+	service := newTestCardService(t, cards)
+	cards.On("GetByNumberHMAC", ctx, mock.Anything).Return(nil, ErrNotFound)
+	cards.On("Create", ctx, mock.AnythingOfType("*model.Card")).Return(nil)
+	cards.On("AddStatusChange", ctx, mock.Anything).Return(nil)
+
+	// Действие
+	card, err := service.CreateVirtual(ctx, 7, true, false)
+
+	// Проверка
+	require.NoError(t, err)
+	assert.Equal(t, model.CardVirtual, card.Product)
+	assert.Equal(t, model.CardActive, card.Status)
+	assert.True(t, card.SingleUse)
+	assert.False(t, card.MerchantLocked)
+
+	// CVV виртуальной карты хранится зашифрованным для раскрытия
+	secret, err := service.open(card)
+	require.NoError(t, err)
+	assert.Equal(t, card.CVV, secret.CVV)
+}
+
+func TestCardService_RevealDetails(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	newVirtualCard := func(t *testing.T, service *CardSvc) *model.Card {
+		encrypted, err := service.seal(&cardSecret{Number: "2200701200001111", ExpiryMonth: 3, ExpiryYear: 2030, CVV: "456"})
+		require.NoError(t, err)
+		return &model.Card{ID: 5, Product: model.CardVirtual, Status: model.CardActive, EncryptedData: encrypted}
+	}
+
+	t.Run("однократное раскрытие с CVV", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		service.now = func() time.Time { return now }
+		cards.On("GetByIDForUpdate", ctx, int64(5)).Return(newVirtualCard(t, service), nil)
+		cards.On("Update", ctx, mock.AnythingOfType("*model.Card")).Return(nil)
+
+		// Действие
+		card, err := service.RevealDetails(ctx, 5)
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, "2200701200001111", card.Number)
+		assert.Equal(t, "456", card.CVV)
+		assert.Equal(t, time.Date(2030, 3, 31, 0, 0, 0, 0, time.UTC), card.ExpiryDate)
+		require.NotNil(t, card.DetailsRevealedAt)
+		assert.Equal(t, now, *card.DetailsRevealedAt)
+
+		// После раскрытия CVV больше не хранится
+		secret, err := service.open(card)
+		require.NoError(t, err)
+		assert.Empty(t, secret.CVV)
+		assert.Equal(t, "2200701200001111", secret.Number)
+	})
+
+	t.Run("повторное раскрытие", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		card := newVirtualCard(t, service)
+		card.DetailsRevealedAt = &now
+		cards.On("GetByIDForUpdate", ctx, int64(5)).Return(card, nil)
+
+		// Действие
+		_, err := service.RevealDetails(ctx, 5)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardDetailsRevealed)
+		cards.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("пластиковая карта", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		cards.On("GetByIDForUpdate", ctx, int64(5)).Return(&model.Card{ID: 5, Product: model.CardDebit, Status: model.CardActive}, nil)
+
+		// Действие
+		_, err := service.RevealDetails(ctx, 5)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardNotVirtual)
+	})
+}
+
+func TestCardService_RecordAuthorization(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("карта без ограничений не блокируется", func(t *testing.T) {
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+
+		err := service.RecordAuthorization(ctx, &model.Card{ID: 5, Product: model.CardDebit}, "Shop")
+
+		require.NoError(t, err)
+		cards.AssertNotCalled(t, "GetByIDForUpdate", mock.Anything, mock.Anything)
+	})
+
+	t.Run("карта закрепляется за первым торговцем", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		card := &model.Card{ID: 5, Product: model.CardVirtual, Status: model.CardActive, MerchantLocked: true}
+		cards.On("GetByIDForUpdate", ctx, int64(5)).Return(card, nil)
+		cards.On("Update", ctx, card).Return(nil)
+
+		// Действие
+		err := service.RecordAuthorization(ctx, &model.Card{ID: 5, MerchantLocked: true}, " Online Cinema ")
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, "Online Cinema", card.LockedMerchant)
+		assert.Equal(t, model.CardActive, card.Status)
+	})
+
+	t.Run("операция другого торговца", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		cards.On("GetByIDForUpdate", ctx, int64(5)).Return(&model.Card{
+			ID: 5, Product: model.CardVirtual, Status: model.CardActive, MerchantLocked: true, LockedMerchant: "Online Cinema",
+		}, nil)
+
+		// Действие
+		err := service.RecordAuthorization(ctx, &model.Card{ID: 5, MerchantLocked: true}, "Other Shop")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardMerchantLocked)
+		assert.NoError(t, service.RecordAuthorization(ctx, &model.Card{ID: 5, MerchantLocked: true}, "online cinema"))
+		cards.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("одноразовая карта закрывается", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		card := &model.Card{ID: 5, Product: model.CardVirtual, Status: model.CardActive, SingleUse: true}
+		cards.On("GetByIDForUpdate", ctx, int64(5)).Return(card, nil)
+		cards.On("Update", ctx, card).Return(nil)
+		cards.On("AddStatusChange", ctx, mock.MatchedBy(func(c *model.CardStatusChange) bool {
+			return c.CardID == 5 && c.FromStatus == model.CardActive && c.ToStatus == model.CardClosed && c.ChangedBy == 0
+		})).Return(nil)
+
+		// Действие
+		err := service.RecordAuthorization(ctx, &model.Card{ID: 5, SingleUse: true}, "Shop")
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, model.CardClosed, card.Status)
+		cards.AssertExpectations(t)
+	})
+
+	t.Run("одноразовая карта уже использована", func(t *testing.T) {
+		// Подготовка
+		cards := new(MockCardRepository)
+		service := newTestCardService(t, cards)
+		cards.On("GetByIDForUpdate", ctx, int64(5)).Return(&model.Card{ID: 5, Product: model.CardVirtual, Status: model.CardClosed, SingleUse: true}, nil)
+
+		// Действие
+		err := service.RecordAuthorization(ctx, &model.Card{ID: 5, SingleUse: true}, "Shop")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardNotActive)
+	})
+}
+
+func TestMaskCardNumber(t *testing.T) {
+	assert.Equal(t, "427600******2222", maskCardNumber("4276000011112222"))
+	assert.Equal(t, "220000*********1234", maskCardNumber("2200000000000001234"))
+	assert.Equal(t, "****", maskCardNumber("1234"))
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"bank-app/internal/amortization"
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+)
+
+var (
+	ErrCreditClosed            = errors.New("credit is closed")
+	ErrCreditPaymentDue        = errors.New("credit has unpaid due payments")
+	ErrInvalidPrepaymentAmount = errors.New("prepayment must exceed interest accrued to date")
+	ErrUnknownPrepaymentMode   = errors.New("prepayment mode must be shorten_term, reduce_payment or full")
+)
+
+// transactionCreditPrepayment - тип операции досрочного погашения кредита
+const transactionCreditPrepayment = "credit_prepayment"
+
+// Prepay досрочно погашает кредит со счета, на который он выдан. Платеж
+// сначала гасит проценты, начисленные с последнего платежа по сегодняшний
+// день, остаток идет в основной долг. Оставшиеся платежи пересчитываются:
+// при shorten_term сохраняется размер платежа и сокращается срок, при
+// reduce_payment сохраняется срок и уменьшается платеж. Если суммы хватает
+// на весь долг или выбран режим full, кредит закрывается. Каждый пересчет
+// сохраняется новой редакцией графика.
+func (s *CreditSvc) Prepay(ctx context.Context, creditID int64, prepayment model.Prepayment) (*model.ScheduleVersion, error) {
+	switch prepayment.Mode {
+	case model.PrepaymentShortenTerm, model.PrepaymentReducePayment:
+		if !prepayment.Amount.IsPositive() {
+			return nil, ErrInvalidPrepaymentAmount
+		}
+	case model.PrepaymentFull:
+	default:
+		return nil, ErrUnknownPrepaymentMode
+	}
+
+	today := paymentDay(s.now())
+
+	var version *model.ScheduleVersion
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		credit, err := s.repo.GetByIDForUpdate(ctx, creditID)
+		if err != nil {
+			return err
+		}
+		if credit.Status == model.CreditClosed {
+			return ErrCreditClosed
+		}
+
+		schedule, err := s.repo.GetSchedule(ctx, creditID)
+		if err != nil {
+			return err
+		}
+
+		// Досрочно гасится долг по будущим платежам. Наступившие платежи
+		// сначала списывает обработка по графику.
+		var paid, pending []*model.PaymentSchedule
+		accruedFrom := paymentDay(credit.CreatedAt)
+		if credit.PrepaidAt != nil && credit.PrepaidAt.After(accruedFrom) {
+			accruedFrom = *credit.PrepaidAt
+		}
+		for _, payment := range schedule {
+			switch {
+			case payment.Status == model.PaymentPaid:
+				paid = append(paid, payment)
+				if payment.Date.After(accruedFrom) {
+					accruedFrom = payment.Date
+				}
+			case payment.Status == model.PaymentPending && payment.Date.After(today):
+				pending = append(pending, payment)
+			default:
+				return ErrCreditPaymentDue
+			}
+		}
+
+		dayCount, err := amortization.ParseDayCount(credit.DayCount)
+		if err != nil {
+			return err
+		}
+		rate := annualRate(credit.InterestRate)
+
+		var balance money.Amount
+		for _, payment := range pending {
+			balance += payment.Principal
+		}
+		factor := new(big.Rat).Mul(rate, dayCount.YearFraction(accruedFrom, today))
+		interest := balance.Mul(factor, s.cfg.Rounding.Interest)
+
+		mode, amount := prepayment.Mode, prepayment.Amount
+		if payoff := balance + interest; mode == model.PrepaymentFull || amount >= payoff {
+			mode, amount = model.PrepaymentFull, payoff
+		}
+		if amount <= interest {
+			return ErrInvalidPrepaymentAmount
+		}
+		principal := amount - interest
+
+		transaction := &model.Transaction{
+			FromAccountID: credit.AccountID,
+			Amount:        amount,
+			Type:          transactionCreditPrepayment,
+			Status:        "completed",
+		}
+		if err := s.transfers.Create(ctx, transaction); err != nil {
+			return err
+		}
+		if err := s.ledger.Post(ctx, repaymentEntry(transaction.ID, credit.AccountID, principal, interest, 0)); err != nil {
+			return err
+		}
+
+		if err := s.repo.DeletePendingSchedule(ctx, creditID); err != nil {
+			return err
+		}
+
+		var payments []amortization.Payment
+		if mode != model.PrepaymentFull {
+			payments, err = s.reschedule(credit, dayCount, balance-principal, len(paid), pending, mode, today)
+			if err != nil {
+				return err
+			}
+
+			rows := make([]*model.PaymentSchedule, 0, len(payments))
+			for _, payment := range payments {
+				rows = append(rows, &model.PaymentSchedule{
+					CreditID:  creditID,
+					Date:      payment.Date,
+					Amount:    payment.Amount,
+					Principal: payment.Principal,
+					Interest:  payment.Interest,
+					Status:    model.PaymentPending,
+				})
+			}
+			if err := s.repo.CreateSchedule(ctx, rows); err != nil {
+				return err
+			}
+
+			credit.Term = len(paid) + len(payments)
+			credit.MonthlyPayment = payments[0].Amount
+			credit.NextPaymentAt = payments[0].Date
+		} else {
+			credit.Status = model.CreditClosed
+		}
+
+		credit.ScheduleVersion++
+		credit.PrepaidAt = &today
+		if err := s.repo.Update(ctx, credit); err != nil {
+			return err
+		}
+
+		version = &model.ScheduleVersion{
+			CreditID:   creditID,
+			Version:    credit.ScheduleVersion,
+			Mode:       mode,
+			Prepayment: amount,
+			Principal:  principal,
+			Interest:   interest,
+			Payments:   make([]*model.QuotePayment, 0, len(paid)+len(payments)),
+		}
+		for _, payment := range paid {
+			version.Payments = append(version.Payments, &model.QuotePayment{
+				Date:      payment.Date,
+				Amount:    payment.Amount,
+				Principal: payment.Principal,
+				Interest:  payment.Interest,
+			})
+		}
+		for _, payment := range payments {
+			version.Payments = append(version.Payments, &model.QuotePayment{
+				Date:      payment.Date,
+				Amount:    payment.Amount,
+				Principal: payment.Principal,
+				Interest:  payment.Interest,
+			})
+		}
+
+		return s.repo.CreateScheduleVersion(ctx, version)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// GetScheduleVersions возвращает историю редакций графика кредита
+func (s *CreditSvc) GetScheduleVersions(ctx context.Context, creditID int64) ([]*model.ScheduleVersion, error) {
+	return s.repo.GetScheduleVersions(ctx, creditID)
+}
+
+// reschedule строит оставшиеся платежи по остатку долга balance после
+// досрочного погашения today. Первые paid платежей графика уже внесены,
+// pending - платежи прежней редакции, которые заменяются.
+func (s *CreditSvc) reschedule(credit *model.Credit, dayCount amortization.DayCount, balance money.Amount, paid int,
+	pending []*model.PaymentSchedule, mode string, today time.Time) ([]amortization.Payment, error) {
+	strategy, err := amortization.ParseStrategy(credit.ScheduleType)
+	if err != nil {
+		return nil, err
+	}
+
+	loan := amortization.Loan{
+		Amount:           balance,
+		Rate:             annualRate(credit.InterestRate),
+		Term:             len(pending),
+		IssuedAt:         credit.CreatedAt,
+		DayCount:         dayCount,
+		Paid:             paid,
+		From:             today,
+		PaymentRounding:  s.cfg.Rounding.Payment,
+		InterestRounding: s.cfg.Rounding.Interest,
+	}
+
+	// Сокращение срока сохраняет прежний платеж аннуитета или прежнюю долю
+	// основного долга дифференцированного графика
+	if mode == model.PrepaymentShortenTerm {
+		loan.Installment = credit.MonthlyPayment
+		if strategy == amortization.Differentiated {
+			loan.Installment = pending[0].Principal
+		}
+	}
+
+	return strategy.Schedule(loan), nil
+}
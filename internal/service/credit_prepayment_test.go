@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"bank-app/internal/amortization"
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+)
+
+// prepaymentCredit возвращает кредит 100000 на 12 месяцев, выданный 15 марта,
+// и его график с внесенным первым платежом
+func prepaymentCredit() (*model.Credit, []*model.PaymentSchedule) {
+	issuedAt := time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC)
+	credit := &model.Credit{
+		ID:              1,
+		AccountID:       5,
+		Amount:          money.Units(100000),
+		InterestRate:    12,
+		Term:            12,
+		ScheduleType:    model.ScheduleAnnuity,
+		DayCount:        "30/360",
+		MonthlyPayment:  money.MustParse("8884.88"),
+		Status:          model.CreditActive,
+		NextPaymentAt:   day(5, 15),
+		ScheduleVersion: 1,
+		CreatedAt:       issuedAt,
+	}
+
+	payments := amortization.Annuity.Schedule(amortization.Loan{
+		Amount:   credit.Amount,
+		Rate:     big.NewRat(12, 100),
+		Term:     credit.Term,
+		IssuedAt: issuedAt,
+	})
+
+	schedule := make([]*model.PaymentSchedule, 0, len(payments))
+	for i, payment := range payments {
+		schedule = append(schedule, &model.PaymentSchedule{
+			ID:        int64(11 + i),
+			CreditID:  credit.ID,
+			Date:      payment.Date,
+			Amount:    payment.Amount,
+			Principal: payment.Principal,
+			Interest:  payment.Interest,
+			Status:    model.PaymentPending,
+		})
+	}
+	schedule[0].Status = model.PaymentPaid
+	schedule[0].PaidAmount = schedule[0].Amount
+
+	return credit, schedule
+}
+
+// prepay настраивает кредит и график для досрочного погашения
+func (rt *repaymentTest) prepay(ctx context.Context, credit *model.Credit, schedule []*model.PaymentSchedule) {
+	rt.credits.On("GetByIDForUpdate", ctx, credit.ID).Return(credit, nil)
+	rt.credits.On("GetSchedule", ctx, credit.ID).Return(schedule, nil)
+}
+
+// posted настраивает успешное списание и возвращает проводку через entry
+func (rt *repaymentTest) posted(ctx context.Context, amount money.Amount, entry **model.LedgerEntry) {
+	rt.transfers.On("Create", ctx, mock.MatchedBy(func(tr *model.Transaction) bool {
+		return tr.FromAccountID == 5 && tr.Amount == amount && tr.Type == transactionCreditPrepayment
+	})).Return(nil)
+	rt.ledger.On("Post", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		*entry = args.Get(1).(*model.LedgerEntry)
+	})
+}
+
+func TestCreditService_Prepay(t *testing.T) {
+	ctx := context.Background()
+	// Досрочное погашение через 10 дней после первого платежа: проценты на
+	// остаток 92115.12 за 10 дней по 30/360 составляют 307.05
+	now := time.Date(2026, 4, 25, 12, 0, 0, 0, time.UTC)
+	interest := money.MustParse("307.05")
+
+	t.Run("уменьшение платежа", func(t *testing.T) {
+		// Подготовка
+		rt := newRepaymentTest(now)
+		credit, schedule := prepaymentCredit()
+		rt.prepay(ctx, credit, schedule)
+
+		var entry *model.LedgerEntry
+		rt.posted(ctx, money.Units(20000), &entry)
+		rt.credits.On("DeletePendingSchedule", ctx, credit.ID).Return(nil)
+
+		var rows []*model.PaymentSchedule
+		rt.credits.On("CreateSchedule", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			rows = args.Get(1).([]*model.PaymentSchedule)
+		})
+		rt.credits.On("Update", ctx, credit).Return(nil)
+		rt.credits.On("CreateScheduleVersion", ctx, mock.Anything).Return(nil)
+
+		// Действие
+		version, err := rt.service.Prepay(ctx, credit.ID, model.Prepayment{
+			Amount: money.Units(20000),
+			Mode:   model.PrepaymentReducePayment,
+		})
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, 2, version.Version)
+		assert.Equal(t, model.PrepaymentReducePayment, version.Mode)
+		assert.Equal(t, interest, version.Interest)
+		assert.Equal(t, money.Units(20000)-interest, version.Principal)
+
+		// Срок сохраняется, платеж уменьшается, долг по новому графику равен
+		// остатку после погашения
+		require.Len(t, rows, 11)
+		assert.Equal(t, day(5, 15), rows[0].Date)
+		var principal money.Amount
+		for _, row := range rows {
+			principal += row.Principal
+			assert.Equal(t, model.PaymentPending, row.Status)
+		}
+		assert.Equal(t, money.MustParse("92115.12")-version.Principal, principal)
+		assert.Less(t, credit.MonthlyPayment, money.MustParse("8884.88"))
+		assert.Equal(t, 12, credit.Term)
+
+		// Редакция содержит и внесенный платеж
+		require.Len(t, version.Payments, 12)
+		assert.Equal(t, schedule[0].Date, version.Payments[0].Date)
+
+		assert.Equal(t, 2, credit.ScheduleVersion)
+		require.NotNil(t, credit.PrepaidAt)
+		assert.Equal(t, day(4, 25), *credit.PrepaidAt)
+
+		// Проценты и основной долг разносятся по главной книге
+		assert.Equal(t, money.Units(20000), entry.Postings[0].Amount)
+		rt.credits.AssertExpectations(t)
+		rt.ledger.AssertExpectations(t)
+	})
+
+	t.Run("сокращение срока", func(t *testing.T) {
+		// Подготовка
+		rt := newRepaymentTest(now)
+		credit, schedule := prepaymentCredit()
+		rt.prepay(ctx, credit, schedule)
+
+		var entry *model.LedgerEntry
+		rt.posted(ctx, money.Units(20000), &entry)
+		rt.credits.On("DeletePendingSchedule", ctx, credit.ID).Return(nil)
+
+		var rows []*model.PaymentSchedule
+		rt.credits.On("CreateSchedule", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			rows = args.Get(1).([]*model.PaymentSchedule)
+		})
+		rt.credits.On("Update", ctx, credit).Return(nil)
+		rt.credits.On("CreateScheduleVersion", ctx, mock.Anything).Return(nil)
+
+		// Действие
+		version, err := rt.service.Prepay(ctx, credit.ID, model.Prepayment{
+			Amount: money.Units(20000),
+			Mode:   model.PrepaymentShortenTerm,
+		})
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, model.PrepaymentShortenTerm, version.Mode)
+
+		// Платеж сохраняется, срок сокращается
+		require.Less(t, len(rows), 11)
+		for _, row := range rows[:len(rows)-1] {
+			assert.Equal(t, money.MustParse("8884.88"), row.Amount)
+		}
+		assert.Equal(t, money.MustParse("8884.88"), credit.MonthlyPayment)
+		assert.Equal(t, 1+len(rows), credit.Term)
+		assert.Len(t, version.Payments, 1+len(rows))
+	})
+
+	t.Run("полное погашение закрывает кредит", func(t *testing.T) {
+		// Подготовка
+		rt := newRepaymentTest(now)
+		credit, schedule := prepaymentCredit()
+		rt.prepay(ctx, credit, schedule)
+
+		payoff := money.MustParse("92115.12") + interest
+		var entry *model.LedgerEntry
+		rt.posted(ctx, payoff, &entry)
+		rt.credits.On("DeletePendingSchedule", ctx, credit.ID).Return(nil)
+		rt.credits.On("Update", ctx, credit).Return(nil)
+		rt.credits.On("CreateScheduleVersion", ctx, mock.Anything).Return(nil)
+
+		// Действие: сумма больше долга, списывается только долг
+		version, err := rt.service.Prepay(ctx, credit.ID, model.Prepayment{
+			Amount: money.Units(100000),
+			Mode:   model.PrepaymentReducePayment,
+		})
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, model.PrepaymentFull, version.Mode)
+		assert.Equal(t, payoff, version.Prepayment)
+		assert.Len(t, version.Payments, 1)
+		assert.Equal(t, model.CreditClosed, credit.Status)
+		rt.credits.AssertNotCalled(t, "CreateSchedule", mock.Anything, mock.Anything)
+	})
+
+	t.Run("наступивший платеж не погашен", func(t *testing.T) {
+		// Подготовка
+		rt := newRepaymentTest(time.Date(2026, 5, 15, 8, 0, 0, 0, time.UTC))
+		credit, schedule := prepaymentCredit()
+		rt.prepay(ctx, credit, schedule)
+
+		// Действие
+		_, err := rt.service.Prepay(ctx, credit.ID, model.Prepayment{Mode: model.PrepaymentFull})
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCreditPaymentDue)
+		rt.ledger.AssertNotCalled(t, "Post", mock.Anything, mock.Anything)
+	})
+
+	t.Run("сумма не покрывает начисленные проценты", func(t *testing.T) {
+		// Подготовка
+		rt := newRepaymentTest(now)
+		credit, schedule := prepaymentCredit()
+		rt.prepay(ctx, credit, schedule)
+
+		// Действие
+		_, err := rt.service.Prepay(ctx, credit.ID, model.Prepayment{
+			Amount: money.Units(300),
+			Mode:   model.PrepaymentShortenTerm,
+		})
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidPrepaymentAmount)
+	})
+
+	t.Run("закрытый кредит", func(t *testing.T) {
+		// Подготовка
+		rt := newRepaymentTest(now)
+		credit, _ := prepaymentCredit()
+		credit.Status = model.CreditClosed
+		rt.credits.On("GetByIDForUpdate", ctx, credit.ID).Return(credit, nil)
+
+		// Действие
+		_, err := rt.service.Prepay(ctx, credit.ID, model.Prepayment{Mode: model.PrepaymentFull})
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCreditClosed)
+	})
+
+	t.Run("неизвестный режим", func(t *testing.T) {
+		rt := newRepaymentTest(now)
+
+		_, err := rt.service.Prepay(ctx, 1, model.Prepayment{Amount: money.Units(1000), Mode: "skip_payment"})
+		assert.ErrorIs(t, err, ErrUnknownPrepaymentMode)
+
+		_, err = rt.service.Prepay(ctx, 1, model.Prepayment{Mode: model.PrepaymentReducePayment})
+		assert.ErrorIs(t, err, ErrInvalidPrepaymentAmount)
+	})
+}
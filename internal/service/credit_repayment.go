@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+)
+
+// creditJobBatchSize ограничивает число кредитов, читаемых за один запрос
+const creditJobBatchSize = 500
+
+// transactionCreditRepayment - тип операции списания платежа по кредиту
+const transactionCreditRepayment = "credit_repayment"
+
+// ProcessPayments списывает со счетов платежи по графику, срок которых
+// наступил, и начисляет неустойку на просроченные. Пропущенные сроки
+// (например, после простоя) обрабатываются при следующем запуске. Ошибка по
+// одному кредиту не останавливает обработку остальных.
+func (s *CreditSvc) ProcessPayments(ctx context.Context) error {
+	now := s.now()
+
+	var (
+		errs    []error
+		afterID int64
+	)
+	for {
+		credits, err := s.repo.GetDue(ctx, now, afterID, creditJobBatchSize)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+
+		for _, credit := range credits {
+			if err := s.repay(ctx, credit.ID, now); err != nil {
+				errs = append(errs, fmt.Errorf("repay credit %d: %w", credit.ID, err))
+			}
+			afterID = credit.ID
+		}
+
+		if len(credits) < creditJobBatchSize {
+			return errors.Join(errs...)
+		}
+	}
+}
+
+// repay списывает наступившие платежи по кредиту в пределах доступного
+// остатка счета. Платежи гасятся от старых к новым, в каждом сначала
+// проценты, затем основной долг и неустойка.
+func (s *CreditSvc) repay(ctx context.Context, id int64, now time.Time) error {
+	today := paymentDay(now)
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		credit, err := s.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if credit.Status == model.CreditClosed {
+			return nil
+		}
+
+		schedule, err := s.repo.GetSchedule(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		account, err := s.accounts.GetByIDForUpdate(ctx, credit.AccountID)
+		if err != nil {
+			return err
+		}
+
+		// С замороженного счета списать ничего нельзя, долг становится просроченным
+		available := account.Available()
+		if account.Status == model.AccountFrozen || available.IsNegative() {
+			available = 0
+		}
+
+		var principal, interest, penalty money.Amount
+		for _, payment := range schedule {
+			if payment.Status == model.PaymentPaid || payment.Date.After(today) {
+				continue
+			}
+
+			paid := minAmount(available, payment.Outstanding())
+			paidInterest, paidPrincipal := splitPayment(payment, paid)
+			interest += paidInterest
+			principal += paidPrincipal
+			payment.PaidAmount += paid
+			available -= paid
+
+			// Неустойка начисляется только на то, что не покрыли средства счета
+			s.accruePenalty(payment, today)
+
+			paidPenalty := minAmount(available, payment.PenaltyDue())
+			payment.PenaltyPaid += paidPenalty
+			penalty += paidPenalty
+			available -= paidPenalty
+
+			switch {
+			case payment.Outstanding() == 0 && payment.PenaltyDue() == 0:
+				payment.Status = model.PaymentPaid
+				payment.PaidAt = &now
+			case payment.PaidAmount > 0:
+				payment.Status = model.PaymentPartiallyPaid
+			default:
+				payment.Status = model.PaymentOverdue
+			}
+
+			if err := s.repo.UpdatePayment(ctx, payment); err != nil {
+				return err
+			}
+		}
+
+		if total := principal + interest + penalty; total > 0 {
+			transaction := &model.Transaction{
+				FromAccountID: credit.AccountID,
+				Amount:        total,
+				Type:          transactionCreditRepayment,
+				Status:        "completed",
+			}
+			if err := s.transfers.Create(ctx, transaction); err != nil {
+				return err
+			}
+
+			if err := s.ledger.Post(ctx, repaymentEntry(transaction.ID, credit.AccountID, principal, interest, penalty)); err != nil {
+				return err
+			}
+		}
+
+		updateCreditStatus(credit, schedule, today)
+		return s.repo.Update(ctx, credit)
+	})
+}
+
+// accruePenalty начисляет неустойку на часть платежа, оставшуюся
+// непогашенной после списания, за дни нехватки средств. Начало нехватки
+// фиксируется в PenaltyAccruedTo при первом ее обнаружении: платеж, впервые
+// обработанный с опозданием (например, после простоя), не штрафуется за дни,
+// когда нехватку никто не наблюдал.
+func (s *CreditSvc) accruePenalty(payment *model.PaymentSchedule, today time.Time) {
+	if payment.Outstanding() <= 0 {
+		return
+	}
+
+	from := today
+	switch {
+	case payment.PenaltyAccruedTo != nil:
+		from = *payment.PenaltyAccruedTo
+	case payment.Status == model.PaymentOverdue || payment.Status == model.PaymentPartiallyPaid:
+		// Нехватка обнаружена раньше, но дата ее начала не сохранена
+		from = payment.Date
+	}
+	if from.Before(payment.Date) {
+		from = payment.Date
+	}
+	if from.After(today) {
+		return
+	}
+
+	if days := int64(today.Sub(from) / (24 * time.Hour)); days > 0 {
+		factor := new(big.Rat).Mul(annualRate(s.cfg.Credits.PenaltyRate), big.NewRat(days, 365))
+		payment.Penalty += payment.Outstanding().Mul(factor, s.cfg.Rounding.Interest)
+	}
+	payment.PenaltyAccruedTo = &today
+}
+
+// splitPayment делит сумму paid, вносимую в платеж, на проценты и основной
+// долг: сначала гасятся проценты
+func splitPayment(payment *model.PaymentSchedule, paid money.Amount) (interest, principal money.Amount) {
+	interestDue := payment.Interest - payment.PaidAmount
+	if interestDue.IsNegative() {
+		interestDue = 0
+	}
+
+	interest = minAmount(paid, interestDue)
+	return interest, paid - interest
+}
+
+// updateCreditStatus переносит дату следующего платежа на первый
+// неоплаченный, отмечает просрочку и закрывает погашенный кредит
+func updateCreditStatus(credit *model.Credit, schedule []*model.PaymentSchedule, today time.Time) {
+	for _, payment := range schedule {
+		if payment.Status == model.PaymentPaid {
+			continue
+		}
+
+		credit.NextPaymentAt = payment.Date
+		credit.Status = model.CreditActive
+		if !payment.Date.After(today) {
+			credit.Status = model.CreditOverdue
+		}
+		return
+	}
+
+	credit.Status = model.CreditClosed
+}
+
+// paymentDay возвращает начало календарного дня UTC: в этом поясе заданы
+// даты графика платежей
+func paymentDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func minAmount(a, b money.Amount) money.Amount {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"bank-app/internal/config"
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+)
+
+type repaymentTest struct {
+	service   *CreditSvc
+	credits   *MockCreditRepository
+	accounts  *MockAccountRepository
+	transfers *MockTransferRepository
+	ledger    *MockLedgerService
+}
+
+func newRepaymentTest(now time.Time) *repaymentTest {
+	rt := &repaymentTest{
+		credits:   new(MockCreditRepository),
+		accounts:  new(MockAccountRepository),
+		transfers: new(MockTransferRepository),
+		ledger:    new(MockLedgerService),
+	}
+	cfg := &config.Config{Credits: config.CreditConfig{PenaltyRate: 20}}
+	rt.service = NewCreditService(rt.credits, rt.accounts, rt.transfers, rt.ledger, nil, &MockTransactor{}, cfg).(*CreditSvc)
+	rt.service.now = func() time.Time { return now }
+	return rt
+}
+
+// due настраивает один кредит со сроком платежа, графиком и счетом
+func (rt *repaymentTest) due(ctx context.Context, now time.Time, credit *model.Credit, schedule []*model.PaymentSchedule, account *model.Account) {
+	rt.credits.On("GetDue", ctx, now, int64(0), creditJobBatchSize).Return([]*model.Credit{credit}, nil)
+	rt.credits.On("GetByIDForUpdate", ctx, credit.ID).Return(credit, nil)
+	rt.credits.On("GetSchedule", ctx, credit.ID).Return(schedule, nil)
+	rt.credits.On("Update", ctx, credit).Return(nil)
+	rt.accounts.On("GetByIDForUpdate", ctx, account.ID).Return(account, nil)
+}
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func testSchedule() []*model.PaymentSchedule {
+	return []*model.PaymentSchedule{
+		{ID: 11, CreditID: 1, Date: day(4, 15), Amount: money.MustParse("8884.88"), Principal: money.MustParse("7884.88"),
+			Interest: money.Units(1000), Status: model.PaymentPending},
+		{ID: 12, CreditID: 1, Date: day(5, 15), Amount: money.MustParse("8884.88"), Principal: money.MustParse("7963.73"),
+			Interest: money.MustParse("921.15"), Status: model.PaymentPending},
+	}
+}
+
+func TestCreditService_ProcessPayments(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("платеж списывается в срок", func(t *testing.T) {
+		// Подготовка
+		now := time.Date(2026, 4, 15, 9, 0, 0, 0, time.UTC)
+		rt := newRepaymentTest(now)
+		credit := &model.Credit{ID: 1, AccountID: 5, Status: model.CreditActive, NextPaymentAt: day(4, 15)}
+		schedule := testSchedule()
+		rt.due(ctx, now, credit, schedule, &model.Account{ID: 5, Balance: money.Units(10000)})
+		rt.credits.On("UpdatePayment", ctx, schedule[0]).Return(nil)
+		rt.transfers.On("Create", ctx, mock.MatchedBy(func(tr *model.Transaction) bool {
+			return tr.FromAccountID == 5 && tr.Amount == money.MustParse("8884.88") && tr.Type == transactionCreditRepayment
+		})).Return(nil)
+
+		var entry *model.LedgerEntry
+		rt.ledger.On("Post", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			entry = args.Get(1).(*model.LedgerEntry)
+		})
+
+		// Действие
+		err := rt.service.ProcessPayments(ctx)
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, model.PaymentPaid, schedule[0].Status)
+		assert.Equal(t, schedule[0].Amount, schedule[0].PaidAmount)
+		require.NotNil(t, schedule[0].PaidAt)
+		assert.Equal(t, model.PaymentPending, schedule[1].Status)
+		assert.Equal(t, model.CreditActive, credit.Status)
+		assert.Equal(t, day(5, 15), credit.NextPaymentAt)
+
+		// Основной долг гасит ссудную задолженность, проценты - в доход
+		require.NotNil(t, entry)
+		require.Len(t, entry.Postings, 3)
+		assert.Equal(t, money.MustParse("7884.88"), entry.Postings[1].Amount)
+		assert.Equal(t, model.LedgerLoans, entry.Postings[1].LedgerAccount)
+		assert.Equal(t, money.Units(1000), entry.Postings[2].Amount)
+		assert.Equal(t, model.LedgerInterestIncome, entry.Postings[2].LedgerAccount)
+		rt.credits.AssertExpectations(t)
+	})
+
+	t.Run("недостаточно средств", func(t *testing.T) {
+		// Подготовка
+		now := time.Date(2026, 4, 15, 9, 0, 0, 0, time.UTC)
+		rt := newRepaymentTest(now)
+		credit := &model.Credit{ID: 1, AccountID: 5, Status: model.CreditActive}
+		schedule := testSchedule()
+		rt.due(ctx, now, credit, schedule, &model.Account{ID: 5, Balance: money.Units(700), Held: money.Units(200)})
+		rt.credits.On("UpdatePayment", ctx, schedule[0]).Return(nil)
+		rt.transfers.On("Create", ctx, mock.Anything).Return(nil)
+
+		var entry *model.LedgerEntry
+		rt.ledger.On("Post", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			entry = args.Get(1).(*model.LedgerEntry)
+		})
+
+		// Действие
+		err := rt.service.ProcessPayments(ctx)
+
+		// Проверка: списан доступный остаток без заблокированной суммы, он
+		// целиком ушел на проценты
+		require.NoError(t, err)
+		assert.Equal(t, model.PaymentPartiallyPaid, schedule[0].Status)
+		assert.Equal(t, money.Units(500), schedule[0].PaidAmount)
+		assert.Equal(t, model.CreditOverdue, credit.Status)
+		assert.Equal(t, day(4, 15), credit.NextPaymentAt)
+
+		require.Len(t, entry.Postings, 2)
+		assert.Equal(t, model.LedgerInterestIncome, entry.Postings[1].LedgerAccount)
+		assert.Equal(t, money.Units(500), entry.Postings[1].Amount)
+	})
+
+	t.Run("неустойка за дни просрочки", func(t *testing.T) {
+		// Подготовка: платеж просрочен на 20 дней, средств нет
+		now := time.Date(2026, 5, 5, 9, 0, 0, 0, time.UTC)
+		rt := newRepaymentTest(now)
+		credit := &model.Credit{ID: 1, AccountID: 5, Status: model.CreditOverdue}
+		schedule := testSchedule()
+		schedule[0].Status = model.PaymentOverdue
+		rt.due(ctx, now, credit, schedule, &model.Account{ID: 5})
+		rt.credits.On("UpdatePayment", ctx, schedule[0]).Return(nil)
+
+		// Действие
+		err := rt.service.ProcessPayments(ctx)
+
+		// Проверка: 8884.88 * 20% * 20 / 365
+		require.NoError(t, err)
+		assert.Equal(t, money.MustParse("97.37"), schedule[0].Penalty)
+		require.NotNil(t, schedule[0].PenaltyAccruedTo)
+		assert.Equal(t, day(5, 5), *schedule[0].PenaltyAccruedTo)
+		assert.Equal(t, model.PaymentOverdue, schedule[0].Status)
+		assert.Equal(t, model.CreditOverdue, credit.Status)
+		rt.transfers.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+		// Повторный запуск в тот же день неустойку не начисляет
+		require.NoError(t, rt.service.ProcessPayments(ctx))
+		assert.Equal(t, money.MustParse("97.37"), schedule[0].Penalty)
+	})
+
+	t.Run("погашение просрочки с неустойкой закрывает кредит", func(t *testing.T) {
+		// Подготовка: просроченный последний платеж, неустойка начислена по 1 мая
+		now := time.Date(2026, 5, 20, 9, 0, 0, 0, time.UTC)
+		rt := newRepaymentTest(now)
+		credit := &model.Credit{ID: 1, AccountID: 5, Status: model.CreditOverdue}
+		accruedTo := day(5, 1)
+		schedule := testSchedule()
+		schedule[0].Status = model.PaymentPaid
+		schedule[0].PaidAmount = schedule[0].Amount
+		schedule[1].Date = day(4, 30)
+		schedule[1].Status = model.PaymentPartiallyPaid
+		schedule[1].PaidAmount = money.MustParse("921.15")
+		schedule[1].Penalty = money.MustParse("8.73")
+		schedule[1].PenaltyAccruedTo = &accruedTo
+		rt.due(ctx, now, credit, schedule, &model.Account{ID: 5, Balance: money.Units(50000)})
+		rt.credits.On("UpdatePayment", ctx, schedule[1]).Return(nil)
+		rt.transfers.On("Create", ctx, mock.Anything).Return(nil)
+
+		var entry *model.LedgerEntry
+		rt.ledger.On("Post", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			entry = args.Get(1).(*model.LedgerEntry)
+		})
+
+		// Действие
+		err := rt.service.ProcessPayments(ctx)
+
+		// Проверка: остаток долга покрыт средствами счета, поэтому новых дней
+		// нехватки нет и списывается только ранее начисленная неустойка
+		require.NoError(t, err)
+		assert.Equal(t, money.MustParse("8.73"), schedule[1].Penalty)
+		assert.Equal(t, schedule[1].Penalty, schedule[1].PenaltyPaid)
+		assert.Equal(t, model.PaymentPaid, schedule[1].Status)
+		assert.Equal(t, model.CreditClosed, credit.Status)
+
+		require.Len(t, entry.Postings, 3)
+		assert.Equal(t, money.MustParse("7972.46"), entry.Postings[0].Amount)
+		assert.Equal(t, model.LedgerLoans, entry.Postings[1].LedgerAccount)
+		assert.Equal(t, money.MustParse("7963.73"), entry.Postings[1].Amount)
+		assert.Equal(t, model.LedgerPenaltyIncome, entry.Postings[2].LedgerAccount)
+		assert.Equal(t, money.MustParse("8.73"), entry.Postings[2].Amount)
+		rt.credits.AssertNotCalled(t, "UpdatePayment", ctx, schedule[0])
+	})
+
+	t.Run("догоняющее списание после простоя без неустойки", func(t *testing.T) {
+		// Подготовка: задача не запускалась 10 дней, средств на счете достаточно
+		now := time.Date(2026, 4, 25, 9, 0, 0, 0, time.UTC)
+		rt := newRepaymentTest(now)
+		credit := &model.Credit{ID: 1, AccountID: 5, Status: model.CreditActive, NextPaymentAt: day(4, 15)}
+		schedule := testSchedule()
+		rt.due(ctx, now, credit, schedule, &model.Account{ID: 5, Balance: money.Units(10000)})
+		rt.credits.On("UpdatePayment", ctx, schedule[0]).Return(nil)
+		rt.transfers.On("Create", ctx, mock.MatchedBy(func(tr *model.Transaction) bool {
+			return tr.Amount == money.MustParse("8884.88")
+		})).Return(nil)
+		rt.ledger.On("Post", ctx, mock.Anything).Return(nil)
+
+		// Действие
+		err := rt.service.ProcessPayments(ctx)
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Zero(t, schedule[0].Penalty)
+		assert.Nil(t, schedule[0].PenaltyAccruedTo)
+		assert.Equal(t, model.PaymentPaid, schedule[0].Status)
+		assert.Equal(t, model.CreditActive, credit.Status)
+		rt.transfers.AssertExpectations(t)
+	})
+
+	t.Run("при догоняющем списании неустойка считается с обнаружения нехватки", func(t *testing.T) {
+		// Подготовка: задача не запускалась 10 дней, средств хватает на часть платежа
+		now := time.Date(2026, 4, 25, 9, 0, 0, 0, time.UTC)
+		rt := newRepaymentTest(now)
+		credit := &model.Credit{ID: 1, AccountID: 5, Status: model.CreditActive}
+		schedule := testSchedule()
+		account := &model.Account{ID: 5, Balance: money.Units(700)}
+		rt.due(ctx, now, credit, schedule, account)
+		rt.credits.On("UpdatePayment", ctx, schedule[0]).Return(nil)
+		rt.transfers.On("Create", ctx, mock.Anything).Return(nil)
+		rt.ledger.On("Post", ctx, mock.Anything).Return(nil)
+
+		// Действие
+		err := rt.service.ProcessPayments(ctx)
+
+		// Проверка: за дни простоя неустойка не начислена, начало нехватки записано
+		require.NoError(t, err)
+		assert.Equal(t, money.Units(700), schedule[0].PaidAmount)
+		assert.Zero(t, schedule[0].Penalty)
+		require.NotNil(t, schedule[0].PenaltyAccruedTo)
+		assert.Equal(t, day(4, 25), *schedule[0].PenaltyAccruedTo)
+		assert.Equal(t, model.PaymentPartiallyPaid, schedule[0].Status)
+
+		// Действие: на следующий день средств нет
+		next := now.Add(24 * time.Hour)
+		rt.service.now = func() time.Time { return next }
+		rt.credits.On("GetDue", ctx, next, int64(0), creditJobBatchSize).Return([]*model.Credit{credit}, nil)
+		account.Balance = 0
+		err = rt.service.ProcessPayments(ctx)
+
+		// Проверка: 8184.88 * 20% * 1 / 365 на непокрытый остаток
+		require.NoError(t, err)
+		assert.Equal(t, money.MustParse("4.48"), schedule[0].Penalty)
+		assert.Equal(t, day(4, 26), *schedule[0].PenaltyAccruedTo)
+	})
+
+	t.Run("ошибка по кредиту не останавливает остальные", func(t *testing.T) {
+		// Подготовка
+		now := time.Date(2026, 4, 15, 9, 0, 0, 0, time.UTC)
+		rt := newRepaymentTest(now)
+		broken := &model.Credit{ID: 1, AccountID: 5}
+		closed := &model.Credit{ID: 2, AccountID: 6, Status: model.CreditClosed}
+		rt.credits.On("GetDue", ctx, now, int64(0), creditJobBatchSize).Return([]*model.Credit{broken, closed}, nil)
+		rt.credits.On("GetByIDForUpdate", ctx, int64(1)).Return(nil, errors.New("connection reset"))
+		rt.credits.On("GetByIDForUpdate", ctx, int64(2)).Return(closed, nil)
+
+		// Действие
+		err := rt.service.ProcessPayments(ctx)
+
+		// Проверка
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "repay credit 1")
+		rt.credits.AssertExpectations(t)
+	})
+}
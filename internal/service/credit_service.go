@@ -3,72 +3,171 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
+	"math/big"
+	"strconv"
+	"time"
 
+	"bank-app/internal/amortization"
 	"bank-app/internal/config"
 	"bank-app/internal/model"
+	"bank-app/internal/money"
 	"bank-app/internal/repository"
 )
 
+var (
+	ErrCreditLoadLimit      = errors.New("credit load limit exceeded")
+	ErrInvalidCreditAmount  = errors.New("credit amount must be positive")
+	ErrInvalidCreditTerm    = errors.New("credit term must be 1 to 360 months")
+	ErrUnknownScheduleType  = errors.New("schedule type must be annuity or differentiated")
+	ErrUnknownDayCount      = errors.New("day count must be 30/360, ACT/365 or ACT/ACT")
+	ErrUnknownCreditProduct = errors.New("credit product must be consumer, mortgage or auto")
+	ErrCreditCurrency       = errors.New("credits are issued only to RUB accounts")
+)
+
+const (
+	// creditLoadLimit - предельная сумма активных кредитов одного клиента,
+	// включительно
+	creditLoadLimit money.Amount = 1000000 * money.Scale
+	// maxCreditTerm - наибольший срок кредита в месяцах
+	maxCreditTerm = 360
+	// creditCurrency - валюта кредитов и счетов, на которые они выдаются
+	creditCurrency = money.RUB
+)
+
 type CreditSvc struct {
-	repo     repository.CreditRepository
-	accounts repository.AccountRepository
-	cfg      *config.Config
+	repo      repository.CreditRepository
+	accounts  repository.AccountRepository
+	transfers repository.TransferRepository
+	ledger    LedgerService
+	keyRates  KeyRateService
+	tx        repository.Transactor
+	cfg       *config.Config
+	now       func() time.Time
 }
 
-func NewCreditService(repo repository.CreditRepository, accounts repository.AccountRepository, cfg *config.Config) CreditService {
+func NewCreditService(repo repository.CreditRepository, accounts repository.AccountRepository, transfers repository.TransferRepository, ledger LedgerService, keyRates KeyRateService, tx repository.Transactor, cfg *config.Config) CreditService {
 	return &CreditSvc{
-		repo:     repo,
-		accounts: accounts,
-		cfg:      cfg,
+		repo:      repo,
+		accounts:  accounts,
+		transfers: transfers,
+		ledger:    ledger,
+		keyRates:  keyRates,
+		tx:        tx,
+		cfg:       cfg,
+		now:       time.Now,
 	}
 }
 
-func (s *CreditSvc) Create(ctx context.Context, userID, accountID int64, amount float64, term int) error {
-	// Проверяем существование счета
-	account, err := s.accounts.GetByID(ctx, accountID)
+// Create выдает кредит на счет клиента: кредит, график платежей и
+// зачисление суммы проводятся в одной транзакции. Ставка фиксируется на
+// весь срок по ключевой ставке на дату выдачи.
+func (s *CreditSvc) Create(ctx context.Context, userID, accountID int64, terms model.CreditTerms) (*model.Credit, error) {
+	issuedAt := s.now()
+	quote, err := s.quote(ctx, terms, issuedAt)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Получаем существующие кредиты
-	credits, err := s.repo.GetByUserID(ctx, userID)
-	if err != nil {
-		return err
-	}
+	var credit *model.Credit
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Блокируем счет зачисления: параллельные заявки по нему проверяют
+		// кредитную нагрузку последовательно
+		account, err := s.accounts.GetByIDForUpdate(ctx, accountID)
+		if err != nil {
+			return err
+		}
+		if account.UserID != userID {
+			return fmt.Errorf("account %w", ErrNotFound)
+		}
+		if account.Currency != creditCurrency {
+			return ErrCreditCurrency
+		}
 
-	// Проверяем кредитную нагрузку с учетом нового кредита
-	var totalAmount float64
-	for _, credit := range credits {
-		if credit.Status == "active" {
-			totalAmount += credit.Amount
+		// Проверяем кредитную нагрузку с учетом нового кредита
+		credits, err := s.repo.GetByUserID(ctx, userID)
+		if err != nil {
+			return err
 		}
-	}
 
-	// Проверяем общую сумму с учетом нового кредита
-	if totalAmount+amount >= 1000000 {
-		return errors.New("credit load limit exceeded")
-	}
+		var totalAmount money.Amount
+		for _, existing := range credits {
+			if existing.Status != model.CreditClosed {
+				totalAmount += existing.Amount
+			}
+		}
+		if totalAmount+quote.Amount > creditLoadLimit {
+			return ErrCreditLoadLimit
+		}
 
-	// Рассчитываем ежемесячный платеж (12% годовых)
-	monthlyPayment := s.calculateMonthlyPayment(amount, term, 12.0)
+		credit = &model.Credit{
+			UserID:         userID,
+			AccountID:      accountID,
+			Amount:         quote.Amount,
+			InterestRate:   quote.InterestRate,
+			Product:        quote.Product,
+			Term:           quote.Term,
+			ScheduleType:   quote.ScheduleType,
+			DayCount:       quote.DayCount,
+			MonthlyPayment: quote.MonthlyPayment,
+			Status:         model.CreditActive,
+			NextPaymentAt:  quote.Payments[0].Date,
+			// Первая редакция графика - составленная при выдаче
+			ScheduleVersion: 1,
+		}
+		if err := s.repo.Create(ctx, credit); err != nil {
+			return err
+		}
 
-	credit := &model.Credit{
-		UserID:         userID,
-		AccountID:      accountID,
-		Amount:         amount,
-		Term:           term,
-		Status:         "active",
-		MonthlyPayment: monthlyPayment,
-	}
+		schedule := make([]*model.PaymentSchedule, 0, len(quote.Payments))
+		for _, payment := range quote.Payments {
+			schedule = append(schedule, &model.PaymentSchedule{
+				CreditID:  credit.ID,
+				Date:      payment.Date,
+				Amount:    payment.Amount,
+				Principal: payment.Principal,
+				Interest:  payment.Interest,
+				Status:    model.PaymentPending,
+			})
+		}
+		if err := s.repo.CreateSchedule(ctx, schedule); err != nil {
+			return err
+		}
 
-	if err := s.repo.Create(ctx, credit); err != nil {
-		return err
+		err = s.repo.CreateScheduleVersion(ctx, &model.ScheduleVersion{
+			CreditID: credit.ID,
+			Version:  credit.ScheduleVersion,
+			Mode:     model.ScheduleInitial,
+			Payments: quote.Payments,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Зачисляем сумму кредита на счет через главную книгу
+		transaction := &model.Transaction{
+			ToAccountID: accountID,
+			Amount:      quote.Amount,
+			Type:        "credit_disbursement",
+			Status:      "completed",
+		}
+		if err := s.transfers.Create(ctx, transaction); err != nil {
+			return err
+		}
+
+		return s.ledger.Post(ctx, disbursementEntry(transaction.ID, accountID, quote.Amount))
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Зачисляем сумму кредита на счет
-	account.Balance += amount
-	return s.accounts.Update(ctx, account)
+	return credit, nil
+}
+
+// Quote рассчитывает график и переплату по условиям кредита без его оформления
+func (s *CreditSvc) Quote(ctx context.Context, terms model.CreditTerms) (*model.CreditQuote, error) {
+	return s.quote(ctx, terms, s.now())
 }
 
 func (s *CreditSvc) GetByID(ctx context.Context, id int64) (*model.Credit, error) {
@@ -83,15 +182,85 @@ func (s *CreditSvc) GetSchedule(ctx context.Context, creditID int64) ([]*model.P
 	return s.repo.GetSchedule(ctx, creditID)
 }
 
-func (s *CreditSvc) ProcessPayments(ctx context.Context) error {
-	// В реальном приложении здесь должна быть обработка платежей по кредитам
-	// с учетом графика платежей и баланса счетов
-	return errors.New("not implemented")
+// quote проверяет условия и строит график кредита, выданного в issuedAt
+func (s *CreditSvc) quote(ctx context.Context, terms model.CreditTerms, issuedAt time.Time) (*model.CreditQuote, error) {
+	if !terms.Amount.IsPositive() {
+		return nil, ErrInvalidCreditAmount
+	}
+	if terms.Term <= 0 || terms.Term > maxCreditTerm {
+		return nil, ErrInvalidCreditTerm
+	}
+
+	strategy, err := amortization.ParseStrategy(terms.ScheduleType)
+	if err != nil {
+		return nil, ErrUnknownScheduleType
+	}
+	dayCount, err := amortization.ParseDayCount(terms.DayCount)
+	if err != nil {
+		return nil, ErrUnknownDayCount
+	}
+
+	if terms.Product == "" {
+		terms.Product = model.CreditConsumer
+	}
+	margin, ok := s.cfg.Credits.Margins[terms.Product]
+	if !ok {
+		return nil, ErrUnknownCreditProduct
+	}
+
+	keyRate, err := s.keyRates.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	interestRate := creditRate(keyRate.Rate, margin)
+
+	payments := strategy.Schedule(amortization.Loan{
+		Amount:           terms.Amount,
+		Rate:             annualRate(interestRate),
+		Term:             terms.Term,
+		IssuedAt:         issuedAt,
+		DayCount:         dayCount,
+		PaymentRounding:  s.cfg.Rounding.Payment,
+		InterestRounding: s.cfg.Rounding.Interest,
+	})
+
+	terms.ScheduleType = strategy.Name()
+	terms.DayCount = dayCount.String()
+	quote := &model.CreditQuote{
+		CreditTerms:  terms,
+		InterestRate: interestRate,
+		KeyRate:      keyRate.Rate,
+		Margin:       margin,
+		Payments:     make([]*model.QuotePayment, 0, len(payments)),
+	}
+	for _, payment := range payments {
+		quote.TotalPayment += payment.Amount
+		quote.Overpayment += payment.Interest
+		quote.Payments = append(quote.Payments, &model.QuotePayment{
+			Date:      payment.Date,
+			Amount:    payment.Amount,
+			Principal: payment.Principal,
+			Interest:  payment.Interest,
+		})
+	}
+	quote.MonthlyPayment = quote.Payments[0].Amount
+
+	return quote, nil
+}
+
+// creditRate возвращает ставку кредита: ключевую ставку плюс надбавку
+// продукта, округленную до сотых процента, как в столбце interest_rate
+func creditRate(keyRate, margin float64) float64 {
+	return math.Round((keyRate+margin)*100) / 100
+}
+
+// annualRate переводит годовую ставку в процентах в долю единицы
+func annualRate(percent float64) *big.Rat {
+	return new(big.Rat).Quo(ratFromFloat(percent), big.NewRat(100, 1))
 }
 
-func (s *CreditSvc) calculateMonthlyPayment(amount float64, term int, rate float64) float64 {
-	monthlyRate := rate / 12 / 100
-	payment := amount * monthlyRate * math.Pow(1+monthlyRate, float64(term)) /
-		(math.Pow(1+monthlyRate, float64(term)) - 1)
-	return math.Round(payment*100) / 100
+// ratFromFloat переводит ставку в точное десятичное значение без двоичной погрешности float64
+func ratFromFloat(v float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(v, 'f', -1, 64))
+	return r
 }
@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"bank-app/internal/config"
 	"bank-app/internal/model"
+	"bank-app/internal/money"
 )
 
 type MockCreditRepository struct {
@@ -50,6 +53,76 @@ func (m *MockCreditRepository) Update(ctx context.Context, credit *model.Credit)
 	return args.Error(0)
 }
 
+func (m *MockCreditRepository) CreateSchedule(ctx context.Context, payments []*model.PaymentSchedule) error {
+	args := m.Called(ctx, payments)
+	return args.Error(0)
+}
+
+func (m *MockCreditRepository) GetByIDForUpdate(ctx context.Context, id int64) (*model.Credit, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Credit), args.Error(1)
+}
+
+func (m *MockCreditRepository) GetDue(ctx context.Context, now time.Time, afterID int64, limit int) ([]*model.Credit, error) {
+	args := m.Called(ctx, now, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Credit), args.Error(1)
+}
+
+func (m *MockCreditRepository) UpdatePayment(ctx context.Context, payment *model.PaymentSchedule) error {
+	args := m.Called(ctx, payment)
+	return args.Error(0)
+}
+
+func (m *MockCreditRepository) DeletePendingSchedule(ctx context.Context, creditID int64) error {
+	args := m.Called(ctx, creditID)
+	return args.Error(0)
+}
+
+func (m *MockCreditRepository) CreateScheduleVersion(ctx context.Context, version *model.ScheduleVersion) error {
+	args := m.Called(ctx, version)
+	return args.Error(0)
+}
+
+func (m *MockCreditRepository) GetScheduleVersions(ctx context.Context, creditID int64) ([]*model.ScheduleVersion, error) {
+	args := m.Called(ctx, creditID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ScheduleVersion), args.Error(1)
+}
+
+type MockKeyRateService struct {
+	mock.Mock
+}
+
+func (m *MockKeyRateService) Current(ctx context.Context) (*model.KeyRate, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.KeyRate), args.Error(1)
+}
+
+// fixedKeyRate возвращает ключевую ставку 7%: с надбавкой потребительского
+// кредита 5% ставка кредита составляет 12%
+func fixedKeyRate() *MockKeyRateService {
+	keyRates := new(MockKeyRateService)
+	keyRates.On("Current", mock.Anything).Return(&model.KeyRate{EffectiveDate: time.Date(2025, 12, 19, 0, 0, 0, 0, time.UTC), Rate: 7}, nil)
+	return keyRates
+}
+
+func creditTestConfig() *config.Config {
+	return &config.Config{Credits: config.CreditConfig{
+		Margins: map[string]float64{model.CreditConsumer: 5, model.CreditMortgage: 2.25},
+	}}
+}
+
 type MockAccountRepository struct {
 	mock.Mock
 }
@@ -67,6 +140,14 @@ func (m *MockAccountRepository) GetByID(ctx context.Context, id int64) (*model.A
 	return args.Get(0).(*model.Account), args.Error(1)
 }
 
+func (m *MockAccountRepository) GetByIDForUpdate(ctx context.Context, id int64) (*model.Account, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Account), args.Error(1)
+}
+
 func (m *MockAccountRepository) GetByUserID(ctx context.Context, userID int64) ([]*model.Account, error) {
 	args := m.Called(ctx, userID)
 	if args.Get(0) == nil {
@@ -80,158 +161,307 @@ func (m *MockAccountRepository) Update(ctx context.Context, account *model.Accou
 	return args.Error(0)
 }
 
+func (m *MockAccountRepository) AdjustHeld(ctx context.Context, id int64, delta money.Amount) error {
+	args := m.Called(ctx, id, delta)
+	return args.Error(0)
+}
+
 func TestCreditService_Create(t *testing.T) {
 	ctx := context.Background()
+	issuedAt := time.Date(2026, 1, 31, 10, 0, 0, 0, time.UTC)
 
-	t.Run("успешное создание кредита", func(t *testing.T) {
-		// Подготовка
+	newService := func() (*CreditSvc, *MockCreditRepository, *MockAccountRepository, *MockTransferRepository, *MockLedgerService) {
 		mockCreditRepo := new(MockCreditRepository)
 		mockAccountRepo := new(MockAccountRepository)
-		cfg := &config.Config{}
-		service := NewCreditService(mockCreditRepo, mockAccountRepo, cfg)
+		mockTransferRepo := new(MockTransferRepository)
+		mockLedger := new(MockLedgerService)
+		service := NewCreditService(mockCreditRepo, mockAccountRepo, mockTransferRepo, mockLedger, fixedKeyRate(), &MockTransactor{}, creditTestConfig()).(*CreditSvc)
+		service.now = func() time.Time { return issuedAt }
+		return service, mockCreditRepo, mockAccountRepo, mockTransferRepo, mockLedger
+	}
+
+	t.Run("успешное создание кредита", func(t *testing.T) {
+		// Подготовка
+		service, mockCreditRepo, mockAccountRepo, mockTransferRepo, mockLedger := newService()
 
 		userID := int64(1)
 		accountID := int64(1)
-		amount := 100000.0
+		amount := money.Units(100000)
 		term := 12
 
 		account := &model.Account{
-			ID:      accountID,
-			UserID:  userID,
-			Balance: 0,
+			ID:       accountID,
+			UserID:   userID,
+			Balance:  0,
+			Currency: money.RUB,
 		}
 
 		// Проверяем, что нет активных кредитов
 		mockCreditRepo.On("GetByUserID", ctx, userID).Return([]*model.Credit{}, nil)
-		mockAccountRepo.On("GetByID", ctx, accountID).Return(account, nil)
-		mockCreditRepo.On("Create", ctx, mock.AnythingOfType("*model.Credit")).Return(nil)
-		mockAccountRepo.On("Update", ctx, mock.AnythingOfType("*model.Account")).Return(nil)
+		mockAccountRepo.On("GetByIDForUpdate", ctx, accountID).Return(account, nil)
+		mockCreditRepo.On("Create", ctx, mock.AnythingOfType("*model.Credit")).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.Credit).ID = 7
+		})
+
+		var schedule []*model.PaymentSchedule
+		mockCreditRepo.On("CreateSchedule", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			schedule = args.Get(1).([]*model.PaymentSchedule)
+		})
+
+		var version *model.ScheduleVersion
+		mockCreditRepo.On("CreateScheduleVersion", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			version = args.Get(1).(*model.ScheduleVersion)
+		})
+		mockTransferRepo.On("Create", ctx, mock.AnythingOfType("*model.Transaction")).Return(nil)
+		mockLedger.On("Post", ctx, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
 
 		// Действие
-		err := service.Create(ctx, userID, accountID, amount, term)
+		credit, err := service.Create(ctx, userID, accountID, model.CreditTerms{Amount: amount, Term: term})
 
 		// Проверка
-		assert.NoError(t, err)
+		require.NoError(t, err)
 		mockCreditRepo.AssertExpectations(t)
 		mockAccountRepo.AssertExpectations(t)
-
-		// Проверяем, что был вызван Create с правильными параметрами
-		if calls := mockCreditRepo.Calls; len(calls) > 0 {
-			for _, call := range calls {
-				if call.Method == "Create" {
-					credit := call.Arguments[1].(*model.Credit)
-					assert.Equal(t, userID, credit.UserID)
-					assert.Equal(t, accountID, credit.AccountID)
-					assert.Equal(t, amount, credit.Amount)
-					assert.Equal(t, term, credit.Term)
-					assert.Equal(t, "active", credit.Status)
-					assert.Greater(t, credit.MonthlyPayment, 0.0)
-				}
-			}
+		mockTransferRepo.AssertExpectations(t)
+		mockLedger.AssertExpectations(t)
+
+		assert.Equal(t, userID, credit.UserID)
+		assert.Equal(t, accountID, credit.AccountID)
+		assert.Equal(t, amount, credit.Amount)
+		assert.Equal(t, term, credit.Term)
+		assert.Equal(t, model.CreditActive, credit.Status)
+		assert.Equal(t, 12.0, credit.InterestRate)
+		assert.Equal(t, model.CreditConsumer, credit.Product)
+		assert.Equal(t, money.MustParse("8884.88"), credit.MonthlyPayment)
+
+		// График сохраняется вместе с кредитом, первый платеж - через месяц,
+		// в коротком месяце - в последний день
+		require.Len(t, schedule, term)
+		assert.Equal(t, time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), schedule[0].Date)
+		assert.Equal(t, time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC), schedule[1].Date)
+		assert.Equal(t, schedule[0].Date, credit.NextPaymentAt)
+		for _, payment := range schedule {
+			assert.Equal(t, int64(7), payment.CreditID)
+			assert.Equal(t, model.PaymentPending, payment.Status)
 		}
+
+		// График сохраняется первой редакцией
+		assert.Equal(t, 1, credit.ScheduleVersion)
+		assert.Equal(t, int64(7), version.CreditID)
+		assert.Equal(t, 1, version.Version)
+		assert.Equal(t, model.ScheduleInitial, version.Mode)
+		assert.Len(t, version.Payments, term)
+
+		// Сумма кредита зачисляется проводкой по главной книге
+		entry := mockLedger.Calls[0].Arguments[1].(*model.LedgerEntry)
+		assert.Equal(t, model.LedgerLoans, entry.Postings[0].LedgerAccount)
+		assert.Equal(t, accountID, entry.Postings[1].AccountID)
+		assert.Equal(t, amount, entry.Postings[1].Amount)
 	})
 
 	t.Run("превышен лимит кредитной нагрузки", func(t *testing.T) {
 		// Подготовка
-		mockCreditRepo := new(MockCreditRepository)
-		mockAccountRepo := new(MockAccountRepository)
-		cfg := &config.Config{}
-		service := NewCreditService(mockCreditRepo, mockAccountRepo, cfg)
+		service, mockCreditRepo, mockAccountRepo, _, _ := newService()
 
 		userID := int64(1)
 		accountID := int64(1)
-		amount := 100000.0
-		term := 12
 
 		account := &model.Account{
-			ID:      accountID,
-			UserID:  userID,
-			Balance: 0,
+			ID:       accountID,
+			UserID:   userID,
+			Balance:  0,
+			Currency: money.RUB,
 		}
 
 		existingCredits := []*model.Credit{
 			{
 				UserID: userID,
-				Amount: 1000000,
-				Status: "active",
+				Amount: money.Units(1000000),
+				Status: model.CreditActive,
 			},
 		}
 
-		// Сначала должна быть проверка существования счета
-		mockAccountRepo.On("GetByID", ctx, accountID).Return(account, nil).Once()
-		// Затем проверка кредитной нагрузки
+		mockAccountRepo.On("GetByIDForUpdate", ctx, accountID).Return(account, nil).Once()
 		mockCreditRepo.On("GetByUserID", ctx, userID).Return(existingCredits, nil).Once()
 
 		// Действие
-		err := service.Create(ctx, userID, accountID, amount, term)
+		_, err := service.Create(ctx, userID, accountID, model.CreditTerms{Amount: money.Units(100000), Term: 12})
 
 		// Проверка
-		assert.Error(t, err)
-		assert.Equal(t, "credit load limit exceeded", err.Error())
+		assert.ErrorIs(t, err, ErrCreditLoadLimit)
 		mockCreditRepo.AssertExpectations(t)
 		mockAccountRepo.AssertExpectations(t)
+		mockCreditRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
 	})
 
-	t.Run("счет не найден", func(t *testing.T) {
+	t.Run("нагрузка ровно на пределе", func(t *testing.T) {
 		// Подготовка
-		mockCreditRepo := new(MockCreditRepository)
-		mockAccountRepo := new(MockAccountRepository)
-		cfg := &config.Config{}
-		service := NewCreditService(mockCreditRepo, mockAccountRepo, cfg)
+		service, mockCreditRepo, mockAccountRepo, mockTransferRepo, mockLedger := newService()
+		existingCredits := []*model.Credit{
+			{UserID: 1, Amount: money.Units(900000), Status: model.CreditActive},
+		}
+		mockAccountRepo.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Account{ID: 1, UserID: 1, Currency: money.RUB}, nil)
+		mockCreditRepo.On("GetByUserID", ctx, int64(1)).Return(existingCredits, nil)
+		mockCreditRepo.On("Create", ctx, mock.Anything).Return(nil)
+		mockCreditRepo.On("CreateSchedule", ctx, mock.Anything).Return(nil)
+		mockCreditRepo.On("CreateScheduleVersion", ctx, mock.Anything).Return(nil)
+		mockTransferRepo.On("Create", ctx, mock.Anything).Return(nil)
+		mockLedger.On("Post", ctx, mock.Anything).Return(nil)
 
-		userID := int64(1)
-		accountID := int64(999)
-		amount := 100000.0
-		term := 12
+		// Действие
+		credit, err := service.Create(ctx, 1, 1, model.CreditTerms{Amount: money.Units(100000), Term: 12})
 
-		mockAccountRepo.On("GetByID", ctx, accountID).Return(nil, errors.New("account not found"))
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, money.Units(100000), credit.Amount)
+	})
+
+	t.Run("счет не найден", func(t *testing.T) {
+		// Подготовка
+		service, _, mockAccountRepo, _, _ := newService()
+		accountID := int64(999)
+		mockAccountRepo.On("GetByIDForUpdate", ctx, accountID).Return(nil, errors.New("account not found"))
 
 		// Действие
-		err := service.Create(ctx, userID, accountID, amount, term)
+		_, err := service.Create(ctx, 1, accountID, model.CreditTerms{Amount: money.Units(100000), Term: 12})
 
 		// Проверка
 		assert.Error(t, err)
 		mockAccountRepo.AssertExpectations(t)
 	})
+
+	t.Run("чужой счет", func(t *testing.T) {
+		// Подготовка
+		service, _, mockAccountRepo, _, _ := newService()
+		mockAccountRepo.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Account{ID: 1, UserID: 2}, nil)
+
+		// Действие
+		_, err := service.Create(ctx, 1, 1, model.CreditTerms{Amount: money.Units(100000), Term: 12})
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("счет в другой валюте", func(t *testing.T) {
+		// Подготовка
+		service, mockCreditRepo, mockAccountRepo, _, _ := newService()
+		mockAccountRepo.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Account{ID: 1, UserID: 1, Currency: money.USD}, nil)
+
+		// Действие
+		_, err := service.Create(ctx, 1, 1, model.CreditTerms{Amount: money.Units(100000), Term: 12})
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCreditCurrency)
+		mockCreditRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("ошибка зачисления откатывает кредит", func(t *testing.T) {
+		// Подготовка
+		service, mockCreditRepo, mockAccountRepo, mockTransferRepo, mockLedger := newService()
+		mockAccountRepo.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Account{ID: 1, UserID: 1, Currency: money.RUB}, nil)
+		mockCreditRepo.On("GetByUserID", ctx, int64(1)).Return(nil, nil)
+		mockCreditRepo.On("Create", ctx, mock.Anything).Return(nil)
+		mockCreditRepo.On("CreateSchedule", ctx, mock.Anything).Return(nil)
+		mockCreditRepo.On("CreateScheduleVersion", ctx, mock.Anything).Return(nil)
+		mockTransferRepo.On("Create", ctx, mock.Anything).Return(nil)
+		mockLedger.On("Post", ctx, mock.Anything).Return(ErrAccountFrozen)
+
+		// Действие
+		credit, err := service.Create(ctx, 1, 1, model.CreditTerms{Amount: money.Units(100000), Term: 12})
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrAccountFrozen)
+		assert.Nil(t, credit)
+	})
+
+	t.Run("некорректные параметры", func(t *testing.T) {
+		service, _, _, _, _ := newService()
+
+		_, err := service.Create(ctx, 1, 1, model.CreditTerms{Amount: 0, Term: 12})
+		assert.ErrorIs(t, err, ErrInvalidCreditAmount)
+
+		_, err = service.Create(ctx, 1, 1, model.CreditTerms{Amount: money.Units(1000), Term: 0})
+		assert.ErrorIs(t, err, ErrInvalidCreditTerm)
+	})
 }
 
-func TestCreditService_calculateMonthlyPayment(t *testing.T) {
-	mockCreditRepo := new(MockCreditRepository)
-	mockAccountRepo := new(MockAccountRepository)
-	cfg := &config.Config{}
-	service := &CreditSvc{
-		repo:     mockCreditRepo,
-		accounts: mockAccountRepo,
-		cfg:      cfg,
-	}
+func TestCreditService_Quote(t *testing.T) {
+	ctx := context.Background()
+	service := NewCreditService(nil, nil, nil, nil, fixedKeyRate(), &MockTransactor{}, creditTestConfig()).(*CreditSvc)
+	service.now = func() time.Time { return time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC) }
 
-	tests := []struct {
-		name     string
-		amount   float64
-		term     int
-		rate     float64
-		expected float64
-	}{
-		{
-			name:     "кредит 100000 на 12 месяцев под 12%",
-			amount:   100000,
-			term:     12,
-			rate:     12,
-			expected: 8884.88,
-		},
-		{
-			name:     "кредит 500000 на 24 месяца под 12%",
-			amount:   500000,
-			term:     24,
-			rate:     12,
-			expected: 23536.74,
-		},
-	}
+	t.Run("аннуитет по умолчанию", func(t *testing.T) {
+		// Действие
+		quote, err := service.Quote(ctx, model.CreditTerms{Amount: money.Units(100000), Term: 12})
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			payment := service.calculateMonthlyPayment(tt.amount, tt.term, tt.rate)
-			assert.InDelta(t, tt.expected, payment, 0.01)
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, model.ScheduleAnnuity, quote.ScheduleType)
+		assert.Equal(t, "30/360", quote.DayCount)
+		assert.Equal(t, money.MustParse("8884.88"), quote.MonthlyPayment)
+		require.Len(t, quote.Payments, 12)
+		assert.Equal(t, quote.Amount+quote.Overpayment, quote.TotalPayment)
+	})
+
+	t.Run("дифференцированный по ACT/365", func(t *testing.T) {
+		// Действие
+		quote, err := service.Quote(ctx, model.CreditTerms{
+			Amount:       money.Units(120000),
+			Term:         12,
+			ScheduleType: model.ScheduleDifferentiated,
+			DayCount:     "ACT/365",
 		})
-	}
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, "ACT/365", quote.DayCount)
+
+		// Первый платеж наибольший: 10000 долга и проценты за 31 день марта-апреля
+		assert.Equal(t, money.Units(10000), quote.Payments[0].Principal)
+		assert.Equal(t, money.MustParse("1223.01"), quote.Payments[0].Interest)
+		assert.Equal(t, quote.Payments[0].Amount, quote.MonthlyPayment)
+		assert.Equal(t, quote.Amount+quote.Overpayment, quote.TotalPayment)
+	})
+
+	t.Run("неизвестные тип графика и соглашение о днях", func(t *testing.T) {
+		_, err := service.Quote(ctx, model.CreditTerms{Amount: money.Units(1000), Term: 12, ScheduleType: "balloon"})
+		assert.ErrorIs(t, err, ErrUnknownScheduleType)
+
+		_, err = service.Quote(ctx, model.CreditTerms{Amount: money.Units(1000), Term: 12, DayCount: "ACT/360"})
+		assert.ErrorIs(t, err, ErrUnknownDayCount)
+	})
+
+	t.Run("ставка продукта - ключевая ставка плюс надбавка", func(t *testing.T) {
+		// Действие
+		quote, err := service.Quote(ctx, model.CreditTerms{
+			Product: model.CreditMortgage,
+			Amount:  money.Units(100000),
+			Term:    12,
+		})
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, 7.0, quote.KeyRate)
+		assert.Equal(t, 2.25, quote.Margin)
+		assert.Equal(t, 9.25, quote.InterestRate)
+		assert.Less(t, quote.MonthlyPayment, money.MustParse("8884.88"))
+	})
+
+	t.Run("неизвестный продукт", func(t *testing.T) {
+		_, err := service.Quote(ctx, model.CreditTerms{Product: "payday", Amount: money.Units(1000), Term: 12})
+		assert.ErrorIs(t, err, ErrUnknownCreditProduct)
+	})
+
+	t.Run("ключевая ставка недоступна", func(t *testing.T) {
+		// Подготовка
+		keyRates := new(MockKeyRateService)
+		keyRates.On("Current", ctx).Return(nil, ErrKeyRateUnavailable)
+		service := NewCreditService(nil, nil, nil, nil, keyRates, &MockTransactor{}, creditTestConfig())
+
+		// Действие
+		_, err := service.Quote(ctx, model.CreditTerms{Amount: money.Units(1000), Term: 12})
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrKeyRateUnavailable)
+	})
 }
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"bank-app/internal/model"
+	"bank-app/internal/repository"
+)
+
+// idempotencyKeyTTL - срок, в течение которого повтор запроса возвращает сохраненный ответ
+const idempotencyKeyTTL = 24 * time.Hour
+
+var (
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is still in progress")
+)
+
+type IdempotencySvc struct {
+	repo repository.IdempotencyRepository
+}
+
+func NewIdempotencyService(repo repository.IdempotencyRepository) IdempotencyService {
+	return &IdempotencySvc{repo: repo}
+}
+
+// Begin резервирует ключ за запросом. Если ключ уже использовался тем же
+// запросом и ответ сохранен, возвращается запись для повтора ответа
+// (второе значение false).
+func (s *IdempotencySvc) Begin(ctx context.Context, record *model.IdempotencyRecord) (*model.IdempotencyRecord, bool, error) {
+	created, err := s.repo.Create(ctx, record)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if created {
+		return record, true, nil
+	}
+
+	existing, err := s.repo.Get(ctx, record.UserID, record.Key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Истекший ключ освобождаем и резервируем заново
+	if time.Since(existing.CreatedAt) > idempotencyKeyTTL {
+		if err := s.repo.Delete(ctx, existing.ID); err != nil {
+			return nil, false, err
+		}
+		return s.Begin(ctx, record)
+	}
+
+	if existing.RequestHash != record.RequestHash || existing.Method != record.Method || existing.Path != record.Path {
+		return nil, false, ErrIdempotencyKeyReused
+	}
+
+	if !existing.Completed() {
+		return nil, false, ErrIdempotencyInProgress
+	}
+
+	return existing, false, nil
+}
+
+// Complete сохраняет ответ для последующих повторов
+func (s *IdempotencySvc) Complete(ctx context.Context, record *model.IdempotencyRecord) error {
+	return s.repo.Complete(ctx, record)
+}
+
+// Release освобождает ключ, если запрос завершился ошибкой сервера и может быть повторен
+func (s *IdempotencySvc) Release(ctx context.Context, record *model.IdempotencyRecord) error {
+	return s.repo.Delete(ctx, record.ID)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"bank-app/internal/model"
+)
+
+type MockIdempotencyRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyRepository) Create(ctx context.Context, record *model.IdempotencyRecord) (bool, error) {
+	args := m.Called(ctx, record)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) Get(ctx context.Context, userID int64, key string) (*model.IdempotencyRecord, error) {
+	args := m.Called(ctx, userID, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdempotencyRecord), args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) Complete(ctx context.Context, record *model.IdempotencyRecord) error {
+	args := m.Called(ctx, record)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) Delete(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func TestIdempotencyService_Begin(t *testing.T) {
+	ctx := context.Background()
+
+	newRecord := func() *model.IdempotencyRecord {
+		return &model.IdempotencyRecord{
+			UserID:      1,
+			Key:         "key-1",
+			Method:      "POST",
+			Path:        "/api/v1/transfers",
+			RequestHash: "hash-1",
+		}
+	}
+
+	t.Run("новый ключ", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockIdempotencyRepository)
+		service := NewIdempotencyService(mockRepo)
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.IdempotencyRecord")).Return(true, nil)
+
+		// Действие
+		record, isNew, err := service.Begin(ctx, newRecord())
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.True(t, isNew)
+		assert.Equal(t, "key-1", record.Key)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("повтор возвращает сохраненный ответ", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockIdempotencyRepository)
+		service := NewIdempotencyService(mockRepo)
+
+		stored := newRecord()
+		stored.StatusCode = 201
+		stored.ResponseBody = []byte(`{"success":true}`)
+		stored.CreatedAt = time.Now()
+
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.IdempotencyRecord")).Return(false, nil)
+		mockRepo.On("Get", ctx, int64(1), "key-1").Return(stored, nil)
+
+		// Действие
+		record, isNew, err := service.Begin(ctx, newRecord())
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.False(t, isNew)
+		assert.Equal(t, 201, record.StatusCode)
+		assert.Equal(t, stored.ResponseBody, record.ResponseBody)
+	})
+
+	t.Run("тот же ключ с другим телом", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockIdempotencyRepository)
+		service := NewIdempotencyService(mockRepo)
+
+		stored := newRecord()
+		stored.RequestHash = "hash-2"
+		stored.StatusCode = 201
+		stored.CreatedAt = time.Now()
+
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.IdempotencyRecord")).Return(false, nil)
+		mockRepo.On("Get", ctx, int64(1), "key-1").Return(stored, nil)
+
+		// Действие
+		_, _, err := service.Begin(ctx, newRecord())
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+	})
+
+	t.Run("запрос еще выполняется", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockIdempotencyRepository)
+		service := NewIdempotencyService(mockRepo)
+
+		stored := newRecord()
+		stored.CreatedAt = time.Now()
+
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.IdempotencyRecord")).Return(false, nil)
+		mockRepo.On("Get", ctx, int64(1), "key-1").Return(stored, nil)
+
+		// Действие
+		_, _, err := service.Begin(ctx, newRecord())
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrIdempotencyInProgress)
+	})
+
+	t.Run("истекший ключ резервируется заново", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockIdempotencyRepository)
+		service := NewIdempotencyService(mockRepo)
+
+		stored := newRecord()
+		stored.ID = 7
+		stored.RequestHash = "hash-2"
+		stored.CreatedAt = time.Now().Add(-2 * idempotencyKeyTTL)
+
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.IdempotencyRecord")).Return(false, nil).Once()
+		mockRepo.On("Get", ctx, int64(1), "key-1").Return(stored, nil).Once()
+		mockRepo.On("Delete", ctx, int64(7)).Return(nil).Once()
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.IdempotencyRecord")).Return(true, nil).Once()
+
+		// Действие
+		_, isNew, err := service.Begin(ctx, newRecord())
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.True(t, isNew)
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -2,12 +2,25 @@ package service
 
 import (
 	"bank-app/internal/model"
+	"bank-app/internal/money"
 	"context"
 )
 
 type UserService interface {
 	Register(ctx context.Context, username, email, password string) error
-	Login(ctx context.Context, email, password string) (string, error)
+	Login(ctx context.Context, email, password string, client model.ClientInfo) (*model.LoginResult, error)
+	CompleteMFALogin(ctx context.Context, mfaToken, code string, client model.ClientInfo) (*model.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string, client model.ClientInfo) (*model.TokenPair, error)
+	Logout(ctx context.Context, userID, sessionID int64) error
+	LogoutAll(ctx context.Context, userID int64) error
+	GetSessions(ctx context.Context, userID int64) ([]*model.Session, error)
+	Authenticate(ctx context.Context, token string) (*Claims, error)
+	SendVerificationEmail(ctx context.Context, userID int64) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequireVerifiedEmail(ctx context.Context, userID int64) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	UnlockAccount(ctx context.Context, token string) error
 	GetByID(ctx context.Context, id int64) (*model.User, error)
 }
 
@@ -15,31 +28,121 @@ type AccountService interface {
 	Create(ctx context.Context, userID int64) error
 	GetByID(ctx context.Context, id int64) (*model.Account, error)
 	GetByUserID(ctx context.Context, userID int64) ([]*model.Account, error)
-	UpdateBalance(ctx context.Context, id int64, amount float64) error
+	UpdateBalance(ctx context.Context, id int64, amount money.Amount) error
 }
 
 type CardService interface {
-	Create(ctx context.Context, accountID int64) error
+	Create(ctx context.Context, accountID int64, product string) error
+	CreateVirtual(ctx context.Context, accountID int64, singleUse, merchantLocked bool) (*model.Card, error)
 	GetByID(ctx context.Context, id int64) (*model.Card, error)
+	GetDetails(ctx context.Context, id int64) (*model.Card, error)
+	RevealDetails(ctx context.Context, id int64) (*model.Card, error)
 	GetByAccountID(ctx context.Context, accountID int64) ([]*model.Card, error)
-	ValidateCard(ctx context.Context, number, cvv string) error
+	Block(ctx context.Context, id int64, actor model.Actor, lost bool, reason string) error
+	Unblock(ctx context.Context, id int64, actor model.Actor, reason string) error
+	Close(ctx context.Context, id int64, actor model.Actor, reason string) error
+	Reissue(ctx context.Context, id int64, actor model.Actor, reason string) (*model.Card, error)
+	Activate(ctx context.Context, id int64, actor model.Actor, pin string) error
+	GetStatusHistory(ctx context.Context, id int64) ([]*model.CardStatusChange, error)
+	RenewExpiring(ctx context.Context) error
+	FindByNumber(ctx context.Context, number string) (*model.Card, error)
+	ValidateCard(ctx context.Context, number, cvv string) (*model.Card, error)
+	RecordAuthorization(ctx context.Context, card *model.Card, merchant string) error
+	SetPIN(ctx context.Context, id int64, pin string) error
+	ChangePIN(ctx context.Context, id int64, current, pin string) error
+	ResetPIN(ctx context.Context, id int64, actor model.Actor, pin string) error
+	VerifyPIN(ctx context.Context, id int64, pin string) error
+	ValidateCardPIN(ctx context.Context, number, pin string) (*model.Card, error)
+}
+
+type CardLimitService interface {
+	GetByCardID(ctx context.Context, cardID int64) ([]*model.CardLimit, error)
+	Create(ctx context.Context, limit *model.CardLimit) error
+	Update(ctx context.Context, limit *model.CardLimit) error
+	Delete(ctx context.Context, cardID, id int64) error
+	Authorize(ctx context.Context, spend *model.CardSpend) error
+	Release(ctx context.Context, spend *model.CardSpend) error
+}
+
+type ProcessingService interface {
+	AuthenticateAcquirer(apiKey string) (string, error)
+	Authorize(ctx context.Context, acquirer string, req *model.AuthorizationRequest) (*model.CardAuthorization, error)
+	Purchase(ctx context.Context, acquirer string, req *model.AuthorizationRequest) (*model.CardAuthorization, error)
+	Get(ctx context.Context, acquirer string, id int64) (*model.CardAuthorization, error)
+	GetByReference(ctx context.Context, acquirer, reference string) (*model.CardAuthorization, error)
+	Capture(ctx context.Context, acquirer string, id int64, amount money.Amount) (*model.CardAuthorization, error)
+	Reverse(ctx context.Context, acquirer string, id int64) (*model.CardAuthorization, error)
+	Refund(ctx context.Context, acquirer string, id int64, amount money.Amount) (*model.CardAuthorization, error)
+	Settle(ctx context.Context) error
 }
 
 type TransferService interface {
-	Transfer(ctx context.Context, fromID, toID int64, amount float64) error
+	Transfer(ctx context.Context, fromID, toID int64, amount money.Amount) (*model.Transaction, error)
 	GetByID(ctx context.Context, id int64) (*model.Transaction, error)
-	GetByAccountID(ctx context.Context, accountID int64) ([]*model.Transaction, error)
+	GetByAccountID(ctx context.Context, accountID int64, filter model.TransactionFilter) (*model.TransactionPage, error)
 }
 
 type CreditService interface {
-	Create(ctx context.Context, userID, accountID int64, amount float64, term int) error
+	Create(ctx context.Context, userID, accountID int64, terms model.CreditTerms) (*model.Credit, error)
+	Quote(ctx context.Context, terms model.CreditTerms) (*model.CreditQuote, error)
 	GetByID(ctx context.Context, id int64) (*model.Credit, error)
 	GetSchedule(ctx context.Context, creditID int64) ([]*model.PaymentSchedule, error)
+	Prepay(ctx context.Context, creditID int64, prepayment model.Prepayment) (*model.ScheduleVersion, error)
+	GetScheduleVersions(ctx context.Context, creditID int64) ([]*model.ScheduleVersion, error)
 	ProcessPayments(ctx context.Context) error
 }
 
+type KeyRateService interface {
+	Current(ctx context.Context) (*model.KeyRate, error)
+}
+
 type AnalyticsService interface {
-	GetTransactionAnalytics(ctx context.Context, userID int64, period string) (map[string]float64, error)
-	GetCreditLoad(ctx context.Context, userID int64) (float64, error)
-	PredictBalance(ctx context.Context, accountID int64, days int) (float64, error)
+	GetTransactionAnalytics(ctx context.Context, userID int64, period string) (map[string]money.Amount, error)
+	GetCreditLoad(ctx context.Context, userID int64) (money.Amount, error)
+	PredictBalance(ctx context.Context, accountID int64, days int) (money.Amount, error)
+}
+
+type LedgerService interface {
+	Post(ctx context.Context, entry *model.LedgerEntry) error
+	GetAccountLedger(ctx context.Context, accountID int64) (*model.AccountLedger, error)
+}
+
+type IdempotencyService interface {
+	Begin(ctx context.Context, record *model.IdempotencyRecord) (*model.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, record *model.IdempotencyRecord) error
+	Release(ctx context.Context, record *model.IdempotencyRecord) error
+}
+
+type AccessService interface {
+	Account(ctx context.Context, userID, accountID int64) (*model.Account, error)
+	Card(ctx context.Context, userID, cardID int64) (*model.Card, error)
+	Credit(ctx context.Context, userID, creditID int64) (*model.Credit, error)
+	Transaction(ctx context.Context, userID, transactionID int64) (*model.Transaction, error)
+}
+
+type AdminService interface {
+	SearchUsers(ctx context.Context, actor model.Actor, query string) ([]*model.User, error)
+	SetRole(ctx context.Context, actor model.Actor, userID int64, role string) error
+	GetUserAccounts(ctx context.Context, actor model.Actor, userID int64) ([]*model.Account, error)
+	GetAccount(ctx context.Context, actor model.Actor, accountID int64) (*model.Account, error)
+	FreezeAccount(ctx context.Context, actor model.Actor, accountID int64, reason string) error
+	UnfreezeAccount(ctx context.Context, actor model.Actor, accountID int64, reason string) error
+	BlockCard(ctx context.Context, actor model.Actor, cardID int64, reason string) error
+	UnblockCard(ctx context.Context, actor model.Actor, cardID int64, reason string) error
+	AddCaseNote(ctx context.Context, actor model.Actor, note *model.CaseNote) error
+	GetCaseNotes(ctx context.Context, actor model.Actor, userID int64) ([]*model.CaseNote, error)
+	GetAuditLog(ctx context.Context, actor model.Actor, limit int) ([]*model.AuditRecord, error)
+}
+
+type MFAService interface {
+	Enroll(ctx context.Context, userID int64) (*model.MFAEnrollment, error)
+	Confirm(ctx context.Context, userID int64, code string) ([]string, error)
+	Disable(ctx context.Context, userID int64, code string) error
+	Verify(ctx context.Context, userID int64, code string) error
+	IsEnabled(ctx context.Context, userID int64) (bool, error)
+	StepUp(ctx context.Context, userID, sessionID int64, code string) error
+	RequireStepUp(ctx context.Context, userID, sessionID int64) error
+	RequireStepUpForTransfer(ctx context.Context, userID, sessionID int64, amount money.Amount) error
+	CreateChallenge(ctx context.Context, userID int64, client model.ClientInfo) (string, error)
+	VerifyChallenge(ctx context.Context, token, code string) (int64, error)
 }
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"bank-app/internal/cbr"
+	"bank-app/internal/config"
+	"bank-app/internal/model"
+	"bank-app/internal/repository"
+)
+
+var ErrKeyRateUnavailable = errors.New("key rate is unavailable")
+
+// keyRateLookback - за сколько дней запрашивается ставка: ЦБ публикует ее
+// только за рабочие дни, а в длинные праздники ответ за последние дни пуст
+const keyRateLookback = 14
+
+type KeyRateSvc struct {
+	repo   repository.KeyRateRepository
+	source cbr.Source
+	logger *logrus.Logger
+	cfg    *config.Config
+	now    func() time.Time
+}
+
+func NewKeyRateService(repo repository.KeyRateRepository, source cbr.Source, logger *logrus.Logger, cfg *config.Config) KeyRateService {
+	return &KeyRateSvc{
+		repo:   repo,
+		source: source,
+		logger: logger,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+// Current возвращает действующую ключевую ставку. Ставка из базы
+// используется, пока не истек CBR_CACHE_TTL; затем она запрашивается у ЦБ и
+// сохраняется. Если ЦБ недоступен, возвращается последняя сохраненная, но
+// не старше CBR_MAX_STALENESS.
+func (s *KeyRateSvc) Current(ctx context.Context) (*model.KeyRate, error) {
+	now := s.now()
+
+	cached, err := s.repo.GetLatest(ctx, now)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if cached != nil && now.Sub(cached.FetchedAt) < s.cfg.CBRConfig.CacheTTL {
+		return cached, nil
+	}
+
+	rate, err := s.fetch(ctx, now)
+	if err != nil {
+		if cached != nil && now.Sub(cached.FetchedAt) < s.cfg.CBRConfig.MaxStaleness {
+			s.logger.WithError(err).WithFields(logrus.Fields{
+				"effective_date": cached.EffectiveDate.Format(time.DateOnly),
+				"age":            now.Sub(cached.FetchedAt).Round(time.Minute).String(),
+			}).Warn("failed to fetch key rate, using cached rate")
+			return cached, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrKeyRateUnavailable, err)
+	}
+
+	return rate, nil
+}
+
+// fetch запрашивает ставки у ЦБ за последние дни, сохраняет их и возвращает
+// действующую на now
+func (s *KeyRateSvc) fetch(ctx context.Context, now time.Time) (*model.KeyRate, error) {
+	rates, err := s.source.KeyRates(ctx, now.AddDate(0, 0, -keyRateLookback), now)
+	if err != nil {
+		return nil, err
+	}
+
+	var current *model.KeyRate
+	for _, rate := range rates {
+		rate.FetchedAt = now
+		if rate.EffectiveDate.After(now) {
+			continue
+		}
+		if current == nil || rate.EffectiveDate.After(current.EffectiveDate) {
+			current = rate
+		}
+	}
+	if current == nil {
+		return nil, errors.New("cbr returned no key rate")
+	}
+
+	if err := s.repo.Save(ctx, rates); err != nil {
+		return nil, err
+	}
+
+	return current, nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"bank-app/internal/cbr"
+	"bank-app/internal/cbr/cbrtest"
+	"bank-app/internal/config"
+	"bank-app/internal/model"
+)
+
+type MockKeyRateRepository struct {
+	mock.Mock
+}
+
+func (m *MockKeyRateRepository) GetLatest(ctx context.Context, at time.Time) (*model.KeyRate, error) {
+	args := m.Called(ctx, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.KeyRate), args.Error(1)
+}
+
+func (m *MockKeyRateRepository) Save(ctx context.Context, rates []*model.KeyRate) error {
+	args := m.Called(ctx, rates)
+	return args.Error(0)
+}
+
+func TestKeyRateService_Current(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 23, 9, 0, 0, 0, time.UTC)
+
+	// Заглушка ЦБ: ставка снижена с 16.5% до 16% с 20 марта
+	server := cbrtest.NewServer(
+		model.KeyRate{EffectiveDate: time.Date(2026, 3, 19, 0, 0, 0, 0, time.UTC), Rate: 16.5},
+		model.KeyRate{EffectiveDate: time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC), Rate: 16},
+		model.KeyRate{EffectiveDate: time.Date(2026, 3, 23, 0, 0, 0, 0, time.UTC), Rate: 16},
+	)
+	defer server.Close()
+
+	logger, logs := test.NewNullLogger()
+	newService := func(repo *MockKeyRateRepository) *KeyRateSvc {
+		cfg := &config.Config{CBRConfig: server.Config()}
+		cfg.CBRConfig.CacheTTL = 12 * time.Hour
+		cfg.CBRConfig.MaxStaleness = 7 * 24 * time.Hour
+		service := NewKeyRateService(repo, cbr.New(cfg.CBRConfig), logger, cfg).(*KeyRateSvc)
+		service.now = func() time.Time { return now }
+		return service
+	}
+
+	t.Run("свежая ставка из базы", func(t *testing.T) {
+		// Подготовка
+		repo := new(MockKeyRateRepository)
+		cached := &model.KeyRate{EffectiveDate: time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC), Rate: 16, FetchedAt: now.Add(-time.Hour)}
+		repo.On("GetLatest", ctx, now).Return(cached, nil)
+		requests := len(server.Requests())
+
+		// Действие
+		rate, err := newService(repo).Current(ctx)
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, cached, rate)
+		assert.Len(t, server.Requests(), requests)
+	})
+
+	t.Run("устаревшая ставка запрашивается у ЦБ и сохраняется", func(t *testing.T) {
+		// Подготовка
+		repo := new(MockKeyRateRepository)
+		stale := &model.KeyRate{EffectiveDate: time.Date(2026, 3, 19, 0, 0, 0, 0, time.UTC), Rate: 16.5, FetchedAt: now.Add(-24 * time.Hour)}
+		repo.On("GetLatest", ctx, now).Return(stale, nil)
+
+		var saved []*model.KeyRate
+		repo.On("Save", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			saved = args.Get(1).([]*model.KeyRate)
+		})
+
+		// Действие
+		rate, err := newService(repo).Current(ctx)
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2026, 3, 23, 0, 0, 0, 0, time.UTC), rate.EffectiveDate)
+		assert.Equal(t, 16.0, rate.Rate)
+		assert.Equal(t, now, rate.FetchedAt)
+		assert.Len(t, saved, 3)
+
+		requests := server.Requests()
+		assert.Equal(t, "2026-03-09T00:00:00", requests[len(requests)-1].From)
+		assert.Equal(t, "2026-03-23T00:00:00", requests[len(requests)-1].To)
+	})
+
+	t.Run("ЦБ недоступен - последняя сохраненная ставка", func(t *testing.T) {
+		// Подготовка
+		server.SetFault("Service temporarily unavailable")
+		defer server.SetFault("")
+
+		repo := new(MockKeyRateRepository)
+		stale := &model.KeyRate{EffectiveDate: time.Date(2026, 3, 19, 0, 0, 0, 0, time.UTC), Rate: 16.5, FetchedAt: now.Add(-24 * time.Hour)}
+		repo.On("GetLatest", ctx, now).Return(stale, nil)
+		logs.Reset()
+
+		// Действие
+		rate, err := newService(repo).Current(ctx)
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, stale, rate)
+		repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+
+		// Использование сохраненной ставки журналируется с ее возрастом
+		entry := logs.LastEntry()
+		require.NotNil(t, entry)
+		assert.Equal(t, logrus.WarnLevel, entry.Level)
+		assert.Equal(t, "24h0m0s", entry.Data["age"])
+		assert.Contains(t, entry.Data, logrus.ErrorKey)
+	})
+
+	t.Run("ЦБ недоступен и сохраненная ставка слишком старая", func(t *testing.T) {
+		// Подготовка
+		server.SetFault("Service temporarily unavailable")
+		defer server.SetFault("")
+
+		repo := new(MockKeyRateRepository)
+		stale := &model.KeyRate{EffectiveDate: time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC), Rate: 16.5, FetchedAt: now.Add(-8 * 24 * time.Hour)}
+		repo.On("GetLatest", ctx, now).Return(stale, nil)
+
+		// Действие
+		_, err := newService(repo).Current(ctx)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrKeyRateUnavailable)
+	})
+
+	t.Run("ЦБ недоступен и ставки в базе нет", func(t *testing.T) {
+		// Подготовка
+		server.SetFault("Service temporarily unavailable")
+		defer server.SetFault("")
+
+		repo := new(MockKeyRateRepository)
+		repo.On("GetLatest", ctx, now).Return(nil, ErrNotFound)
+
+		// Действие
+		_, err := newService(repo).Current(ctx)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrKeyRateUnavailable)
+	})
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+	"bank-app/internal/repository"
+)
+
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrAccountFrozen     = errors.New("account is frozen")
+	ErrCurrencyMismatch  = errors.New("currency mismatch")
+)
+
+type LedgerSvc struct {
+	repo     repository.LedgerRepository
+	accounts repository.AccountRepository
+	tx       repository.Transactor
+}
+
+func NewLedgerService(repo repository.LedgerRepository, accounts repository.AccountRepository, tx repository.Transactor) LedgerService {
+	return &LedgerSvc{
+		repo:     repo,
+		accounts: accounts,
+		tx:       tx,
+	}
+}
+
+// Post проводит сбалансированную запись и применяет ее к остаткам клиентских счетов.
+// Запись и изменение остатков фиксируются в одной транзакции.
+func (s *LedgerSvc) Post(ctx context.Context, entry *model.LedgerEntry) error {
+	deltas, err := validateEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Блокируем затронутые счета в порядке возрастания ID
+		ids := make([]int64, 0, len(deltas))
+		for id := range deltas {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		accounts := make([]*model.Account, 0, len(ids))
+		for _, id := range ids {
+			account, err := s.accounts.GetByIDForUpdate(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			// Все клиентские счета одной записи должны быть в одной валюте
+			if len(accounts) > 0 && accounts[0].Currency != account.Currency {
+				return ErrCurrencyMismatch
+			}
+
+			// С замороженного счета нельзя списывать средства, кроме сумм,
+			// заблокированных под авторизации до заморозки
+			if account.Status == model.AccountFrozen && deltas[id].IsNegative() && !entry.Preauthorized {
+				return ErrAccountFrozen
+			}
+
+			// Средства, заблокированные под авторизации по картам, списать нельзя
+			account.Balance += deltas[id]
+			if account.Balance.IsNegative() || deltas[id].IsNegative() && account.Available().IsNegative() {
+				return ErrInsufficientFunds
+			}
+			accounts = append(accounts, account)
+		}
+
+		if err := s.repo.CreateEntry(ctx, entry); err != nil {
+			return err
+		}
+
+		for _, account := range accounts {
+			if err := s.accounts.Update(ctx, account); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetAccountLedger возвращает проводки по счету и сверяет их сумму с хранимым остатком
+func (s *LedgerSvc) GetAccountLedger(ctx context.Context, accountID int64) (*model.AccountLedger, error) {
+	account, err := s.accounts.GetByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	postings, err := s.repo.GetPostingsByAccountID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	ledgerBalance, err := s.repo.GetAccountBalance(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if postings == nil {
+		postings = []*model.Posting{}
+	}
+
+	return &model.AccountLedger{
+		AccountID:     account.ID,
+		Balance:       account.Balance,
+		LedgerBalance: ledgerBalance,
+		Reconciled:    account.Balance == ledgerBalance,
+		Postings:      postings,
+	}, nil
+}
+
+// validateEntry проверяет, что сумма дебета равна сумме кредита, и возвращает
+// изменения остатков клиентских счетов
+func validateEntry(entry *model.LedgerEntry) (map[int64]money.Amount, error) {
+	if len(entry.Postings) < 2 {
+		return nil, errors.New("ledger entry must have at least two postings")
+	}
+
+	var debit, credit money.Amount
+	deltas := make(map[int64]money.Amount)
+	for _, posting := range entry.Postings {
+		if !posting.Amount.IsPositive() {
+			return nil, errors.New("posting amount must be positive")
+		}
+
+		if posting.LedgerAccount == model.LedgerCustomer && posting.AccountID == 0 {
+			return nil, errors.New("customer posting must reference an account")
+		}
+
+		switch posting.Direction {
+		case model.PostingDebit:
+			debit += posting.Amount
+			if posting.LedgerAccount == model.LedgerCustomer {
+				deltas[posting.AccountID] -= posting.Amount
+			}
+		case model.PostingCredit:
+			credit += posting.Amount
+			if posting.LedgerAccount == model.LedgerCustomer {
+				deltas[posting.AccountID] += posting.Amount
+			}
+		default:
+			return nil, errors.New("invalid posting direction")
+		}
+	}
+
+	if debit != credit {
+		return nil, errors.New("ledger entry is not balanced")
+	}
+
+	return deltas, nil
+}
+
+// transferEntry - перевод между клиентскими счетами
+func transferEntry(transactionID, fromID, toID int64, amount money.Amount) *model.LedgerEntry {
+	return &model.LedgerEntry{
+		TransactionID: transactionID,
+		Description:   "transfer",
+		Postings: []*model.Posting{
+			{LedgerAccount: model.LedgerCustomer, AccountID: fromID, Direction: model.PostingDebit, Amount: amount},
+			{LedgerAccount: model.LedgerCustomer, AccountID: toID, Direction: model.PostingCredit, Amount: amount},
+		},
+	}
+}
+
+// disbursementEntry - выдача кредита: ссудная задолженность против счета клиента
+func disbursementEntry(transactionID, accountID int64, amount money.Amount) *model.LedgerEntry {
+	return &model.LedgerEntry{
+		TransactionID: transactionID,
+		Description:   "credit disbursement",
+		Postings: []*model.Posting{
+			{LedgerAccount: model.LedgerLoans, Direction: model.PostingDebit, Amount: amount},
+			{LedgerAccount: model.LedgerCustomer, AccountID: accountID, Direction: model.PostingCredit, Amount: amount},
+		},
+	}
+}
+
+// repaymentEntry - погашение кредита: списание со счета клиента в погашение
+// основного долга, процентный доход и доход от неустойки
+func repaymentEntry(transactionID, accountID int64, principal, interest, penalty money.Amount) *model.LedgerEntry {
+	entry := &model.LedgerEntry{
+		TransactionID: transactionID,
+		Description:   "credit repayment",
+		Postings: []*model.Posting{
+			{LedgerAccount: model.LedgerCustomer, AccountID: accountID, Direction: model.PostingDebit, Amount: principal + interest + penalty},
+		},
+	}
+
+	if principal > 0 {
+		entry.Postings = append(entry.Postings, &model.Posting{
+			LedgerAccount: model.LedgerLoans, Direction: model.PostingCredit, Amount: principal,
+		})
+	}
+
+	if interest > 0 {
+		entry.Postings = append(entry.Postings, &model.Posting{
+			LedgerAccount: model.LedgerInterestIncome, Direction: model.PostingCredit, Amount: interest,
+		})
+	}
+
+	if penalty > 0 {
+		entry.Postings = append(entry.Postings, &model.Posting{
+			LedgerAccount: model.LedgerPenaltyIncome, Direction: model.PostingCredit, Amount: penalty,
+		})
+	}
+
+	return entry
+}
+
+// cashEntry - внесение (amount > 0) или снятие (amount < 0) наличных
+func cashEntry(transactionID, accountID int64, amount money.Amount) *model.LedgerEntry {
+	customer, cash := model.PostingCredit, model.PostingDebit
+	if amount.IsNegative() {
+		customer, cash = cash, customer
+		amount = -amount
+	}
+
+	return &model.LedgerEntry{
+		TransactionID: transactionID,
+		Description:   "cash",
+		Postings: []*model.Posting{
+			{LedgerAccount: model.LedgerCash, Direction: cash, Amount: amount},
+			{LedgerAccount: model.LedgerCustomer, AccountID: accountID, Direction: customer, Amount: amount},
+		},
+	}
+}
+
+// cardPurchaseEntry - расчет по покупке картой: списание со счета клиента
+// в пользу эквайрера. Сумма была заблокирована при авторизации, поэтому
+// расчет проходит и по счету, замороженному после нее.
+func cardPurchaseEntry(transactionID, accountID int64, amount money.Amount) *model.LedgerEntry {
+	return &model.LedgerEntry{
+		TransactionID: transactionID,
+		Description:   "card purchase",
+		Preauthorized: true,
+		Postings: []*model.Posting{
+			{LedgerAccount: model.LedgerCustomer, AccountID: accountID, Direction: model.PostingDebit, Amount: amount},
+			{LedgerAccount: model.LedgerCardSettlement, Direction: model.PostingCredit, Amount: amount},
+		},
+	}
+}
+
+// cardRefundEntry - возврат покупки по карте на счет клиента
+func cardRefundEntry(transactionID, accountID int64, amount money.Amount) *model.LedgerEntry {
+	return &model.LedgerEntry{
+		TransactionID: transactionID,
+		Description:   "card refund",
+		Postings: []*model.Posting{
+			{LedgerAccount: model.LedgerCardSettlement, Direction: model.PostingDebit, Amount: amount},
+			{LedgerAccount: model.LedgerCustomer, AccountID: accountID, Direction: model.PostingCredit, Amount: amount},
+		},
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+)
+
+type MockLedgerRepository struct {
+	mock.Mock
+}
+
+func (m *MockLedgerRepository) CreateEntry(ctx context.Context, entry *model.LedgerEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockLedgerRepository) GetPostingsByAccountID(ctx context.Context, accountID int64) ([]*model.Posting, error) {
+	args := m.Called(ctx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Posting), args.Error(1)
+}
+
+func (m *MockLedgerRepository) GetAccountBalance(ctx context.Context, accountID int64) (money.Amount, error) {
+	args := m.Called(ctx, accountID)
+	return args.Get(0).(money.Amount), args.Error(1)
+}
+
+type MockLedgerService struct {
+	mock.Mock
+}
+
+func (m *MockLedgerService) Post(ctx context.Context, entry *model.LedgerEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockLedgerService) GetAccountLedger(ctx context.Context, accountID int64) (*model.AccountLedger, error) {
+	args := m.Called(ctx, accountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.AccountLedger), args.Error(1)
+}
+
+func TestLedgerService_Post(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("перевод обновляет остатки", func(t *testing.T) {
+		// Подготовка
+		mockLedgerRepo := new(MockLedgerRepository)
+		mockAccountRepo := new(MockAccountRepository)
+		tx := &MockTransactor{}
+		service := NewLedgerService(mockLedgerRepo, mockAccountRepo, tx)
+
+		fromAcc := &model.Account{ID: 2, Balance: money.Units(1000)}
+		toAcc := &model.Account{ID: 1, Balance: 0}
+
+		// Счета блокируются в порядке возрастания ID
+		first := mockAccountRepo.On("GetByIDForUpdate", ctx, int64(1)).Return(toAcc, nil).Once()
+		mockAccountRepo.On("GetByIDForUpdate", ctx, int64(2)).Return(fromAcc, nil).Once().NotBefore(first)
+		mockLedgerRepo.On("CreateEntry", ctx, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+		mockAccountRepo.On("Update", ctx, mock.AnythingOfType("*model.Account")).Return(nil).Twice()
+
+		// Действие
+		err := service.Post(ctx, transferEntry(10, 2, 1, money.Units(300)))
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.True(t, tx.committed)
+		assert.Equal(t, money.Units(700), fromAcc.Balance)
+		assert.Equal(t, money.Units(300), toAcc.Balance)
+		mockAccountRepo.AssertExpectations(t)
+		mockLedgerRepo.AssertExpectations(t)
+	})
+
+	t.Run("недостаточно средств", func(t *testing.T) {
+		// Подготовка
+		mockLedgerRepo := new(MockLedgerRepository)
+		mockAccountRepo := new(MockAccountRepository)
+		tx := &MockTransactor{}
+		service := NewLedgerService(mockLedgerRepo, mockAccountRepo, tx)
+
+		mockAccountRepo.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Account{ID: 1, Balance: money.Units(100)}, nil)
+		mockAccountRepo.On("GetByIDForUpdate", ctx, int64(2)).Return(&model.Account{ID: 2}, nil)
+
+		// Действие
+		err := service.Post(ctx, transferEntry(10, 1, 2, money.Units(300)))
+
+		// Проверка
+		assert.Error(t, err)
+		assert.Equal(t, "insufficient funds", err.Error())
+		assert.True(t, tx.rolledBack)
+		mockLedgerRepo.AssertNotCalled(t, "CreateEntry", mock.Anything, mock.Anything)
+		mockAccountRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("заблокированные под авторизации средства не списываются", func(t *testing.T) {
+		// Подготовка
+		mockLedgerRepo := new(MockLedgerRepository)
+		mockAccountRepo := new(MockAccountRepository)
+		tx := &MockTransactor{}
+		service := NewLedgerService(mockLedgerRepo, mockAccountRepo, tx)
+
+		mockAccountRepo.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Account{ID: 1, Balance: money.Units(500), Held: money.Units(300)}, nil)
+		mockAccountRepo.On("GetByIDForUpdate", ctx, int64(2)).Return(&model.Account{ID: 2}, nil)
+
+		// Действие
+		err := service.Post(ctx, transferEntry(10, 1, 2, money.Units(300)))
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		mockLedgerRepo.AssertNotCalled(t, "CreateEntry", mock.Anything, mock.Anything)
+	})
+
+	t.Run("списание с замороженного счета", func(t *testing.T) {
+		// Подготовка
+		mockLedgerRepo := new(MockLedgerRepository)
+		mockAccountRepo := new(MockAccountRepository)
+		tx := &MockTransactor{}
+		service := NewLedgerService(mockLedgerRepo, mockAccountRepo, tx)
+
+		mockAccountRepo.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Account{ID: 1, Balance: money.Units(1000), Status: model.AccountFrozen}, nil)
+		mockAccountRepo.On("GetByIDForUpdate", ctx, int64(2)).Return(&model.Account{ID: 2, Status: model.AccountActive}, nil)
+
+		// Действие
+		err := service.Post(ctx, transferEntry(10, 1, 2, money.Units(300)))
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrAccountFrozen)
+		assert.True(t, tx.rolledBack)
+		mockLedgerRepo.AssertNotCalled(t, "CreateEntry", mock.Anything, mock.Anything)
+	})
+
+	t.Run("расчет по карте с замороженного счета", func(t *testing.T) {
+		// Подготовка
+		mockLedgerRepo := new(MockLedgerRepository)
+		mockAccountRepo := new(MockAccountRepository)
+		tx := &MockTransactor{}
+		service := NewLedgerService(mockLedgerRepo, mockAccountRepo, tx)
+
+		mockAccountRepo.On("GetByIDForUpdate", ctx, int64(1)).Return(&model.Account{ID: 1, Balance: money.Units(1000), Status: model.AccountFrozen}, nil)
+		mockLedgerRepo.On("CreateEntry", ctx, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+		mockAccountRepo.On("Update", ctx, mock.AnythingOfType("*model.Account")).Return(nil)
+
+		// Действие
+		err := service.Post(ctx, cardPurchaseEntry(10, 1, money.Units(300)))
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.True(t, tx.committed)
+		mockAccountRepo.AssertCalled(t, "Update", ctx, mock.MatchedBy(func(a *model.Account) bool {
+			return a.ID == 1 && a.Balance == money.Units(700)
+		}))
+	})
+
+	t.Run("несбалансированная запись", func(t *testing.T) {
+		// Подготовка
+		mockLedgerRepo := new(MockLedgerRepository)
+		mockAccountRepo := new(MockAccountRepository)
+		tx := &MockTransactor{}
+		service := NewLedgerService(mockLedgerRepo, mockAccountRepo, tx)
+
+		entry := &model.LedgerEntry{
+			Postings: []*model.Posting{
+				{LedgerAccount: model.LedgerLoans, Direction: model.PostingDebit, Amount: money.Units(100)},
+				{LedgerAccount: model.LedgerCustomer, AccountID: 1, Direction: model.PostingCredit, Amount: money.Units(90)},
+			},
+		}
+
+		// Действие
+		err := service.Post(ctx, entry)
+
+		// Проверка
+		assert.Error(t, err)
+		assert.Equal(t, "ledger entry is not balanced", err.Error())
+		mockAccountRepo.AssertNotCalled(t, "GetByIDForUpdate", mock.Anything, mock.Anything)
+	})
+
+	t.Run("погашение кредита сбалансировано", func(t *testing.T) {
+		// Подготовка
+		entry := repaymentEntry(10, 1, money.Units(8000), money.MustParse("884.88"), 0)
+
+		// Действие
+		deltas, err := validateEntry(entry)
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.Equal(t, money.MustParse("-8884.88"), deltas[1])
+	})
+}
+
+func TestLedgerService_GetAccountLedger(t *testing.T) {
+	ctx := context.Background()
+
+	mockLedgerRepo := new(MockLedgerRepository)
+	mockAccountRepo := new(MockAccountRepository)
+	service := NewLedgerService(mockLedgerRepo, mockAccountRepo, &MockTransactor{})
+
+	postings := []*model.Posting{
+		{ID: 1, AccountID: 1, LedgerAccount: model.LedgerCustomer, Direction: model.PostingCredit, Amount: money.Units(500)},
+		{ID: 2, AccountID: 1, LedgerAccount: model.LedgerCustomer, Direction: model.PostingDebit, Amount: money.Units(200)},
+	}
+
+	mockAccountRepo.On("GetByID", ctx, int64(1)).Return(&model.Account{ID: 1, Balance: money.Units(300)}, nil)
+	mockLedgerRepo.On("GetPostingsByAccountID", ctx, int64(1)).Return(postings, nil)
+	mockLedgerRepo.On("GetAccountBalance", ctx, int64(1)).Return(money.Units(300), nil)
+
+	ledger, err := service.GetAccountLedger(ctx, 1)
+
+	assert.NoError(t, err)
+	assert.True(t, ledger.Reconciled)
+	assert.Len(t, ledger.Postings, 2)
+}
+
+func TestLedgerService_GetAccountLedger_OpeningBalance(t *testing.T) {
+	ctx := context.Background()
+
+	// Подготовка: счет пополнен до ведения журнала, остаток перенесен
+	// начальной записью миграции
+	mockLedgerRepo := new(MockLedgerRepository)
+	mockAccountRepo := new(MockAccountRepository)
+	service := NewLedgerService(mockLedgerRepo, mockAccountRepo, &MockTransactor{})
+
+	opening := &model.LedgerEntry{
+		Description: "opening balance",
+		Postings: []*model.Posting{
+			{LedgerAccount: model.LedgerOpeningBalance, Direction: model.PostingDebit, Amount: money.Units(1500)},
+			{LedgerAccount: model.LedgerCustomer, AccountID: 1, Direction: model.PostingCredit, Amount: money.Units(1500)},
+		},
+	}
+	deltas, err := validateEntry(opening)
+	require.NoError(t, err)
+
+	mockAccountRepo.On("GetByID", ctx, int64(1)).Return(&model.Account{ID: 1, Balance: money.Units(1500)}, nil)
+	mockLedgerRepo.On("GetPostingsByAccountID", ctx, int64(1)).Return(opening.Postings[1:], nil)
+	mockLedgerRepo.On("GetAccountBalance", ctx, int64(1)).Return(deltas[1], nil)
+
+	// Действие
+	ledger, err := service.GetAccountLedger(ctx, 1)
+
+	// Проверка
+	require.NoError(t, err)
+	assert.True(t, ledger.Reconciled)
+	assert.Equal(t, money.Units(1500), ledger.LedgerBalance)
+}
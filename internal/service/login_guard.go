@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"bank-app/internal/mailer"
+	"bank-app/internal/model"
+	"bank-app/internal/repository"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrTooManyAttempts    = errors.New("too many failed login attempts")
+	ErrAccountLocked      = errors.New("account is temporarily locked")
+	ErrInvalidUnlockToken = errors.New("invalid or expired unlock link")
+)
+
+const accountUnlockAudience = "account_unlock"
+
+// RetryAfterError - отказ во входе с указанием, когда можно повторить попытку
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// accountUnlockClaims - утверждения ссылки разблокировки. LockedUntil привязывает
+// ссылку к конкретной блокировке: после разблокировки или новой блокировки она не действует.
+type accountUnlockClaims struct {
+	LockedUntil int64 `json:"lu"`
+	jwt.RegisteredClaims
+}
+
+// checkIPThrottle ограничивает число неудачных попыток с одного IP за окно
+func (s *UserSvc) checkIPThrottle(ctx context.Context, ip string, now time.Time) error {
+	if s.login.MaxFailuresPerIP <= 0 || ip == "" {
+		return nil
+	}
+
+	count, first, err := s.logins.CountFailuresByIP(ctx, ip, now.Add(-s.login.Window))
+	if err != nil {
+		return err
+	}
+
+	if count < s.login.MaxFailuresPerIP {
+		return nil
+	}
+
+	return &RetryAfterError{Err: ErrTooManyAttempts, RetryAfter: first.Add(s.login.Window).Sub(now)}
+}
+
+// checkAccountThrottle проверяет блокировку учетной записи и прогрессивную
+// задержку после серии неудач. Проверка идет до сравнения пароля, чтобы
+// перебор не нагружал сервер вычислением bcrypt.
+func (s *UserSvc) checkAccountThrottle(user *model.User, now time.Time) error {
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		return &RetryAfterError{Err: ErrAccountLocked, RetryAfter: user.LockedUntil.Sub(now)}
+	}
+
+	if user.LastFailedLoginAt == nil || !now.Before(user.LastFailedLoginAt.Add(s.login.Window)) {
+		return nil
+	}
+
+	delay := s.loginDelay(user.FailedLogins)
+	if next := user.LastFailedLoginAt.Add(delay); now.Before(next) {
+		return &RetryAfterError{Err: ErrTooManyAttempts, RetryAfter: next.Sub(now)}
+	}
+
+	return nil
+}
+
+// loginDelay возвращает паузу после failures неудач подряд: BaseDelay,
+// удваиваемая с каждой неудачей сверх DelayAfter, но не больше MaxDelay
+func (s *UserSvc) loginDelay(failures int) time.Duration {
+	if failures < s.login.DelayAfter || s.login.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := s.login.BaseDelay
+	for i := s.login.DelayAfter; i < failures && delay < s.login.MaxDelay; i++ {
+		delay *= 2
+	}
+	if s.login.MaxDelay > 0 && delay > s.login.MaxDelay {
+		delay = s.login.MaxDelay
+	}
+
+	return delay
+}
+
+// loginFailed учитывает неверный пароль и при достижении порога блокирует
+// учетную запись. Письмо со ссылкой разблокировки - удобство для клиента:
+// блокировка снимается и сама, поэтому ошибка отправки не мешает ответу.
+func (s *UserSvc) loginFailed(ctx context.Context, user *model.User, now time.Time) error {
+	failures, err := s.repo.IncrementFailedLogins(ctx, user.ID, s.login.Window)
+	if err != nil {
+		return err
+	}
+
+	if s.login.LockoutThreshold <= 0 || failures < s.login.LockoutThreshold {
+		if delay := s.loginDelay(failures); delay > 0 {
+			return &RetryAfterError{Err: ErrInvalidCredentials, RetryAfter: delay}
+		}
+		return ErrInvalidCredentials
+	}
+
+	until := now.Add(s.login.LockoutDuration)
+	if err := s.repo.Lock(ctx, user.ID, until); err != nil {
+		return err
+	}
+
+	_ = s.sendUnlock(ctx, user, until)
+
+	return &RetryAfterError{Err: ErrAccountLocked, RetryAfter: s.login.LockoutDuration}
+}
+
+// recordLogin записывает попытку входа в журнал
+func (s *UserSvc) recordLogin(ctx context.Context, attempt *model.LoginAttempt, reason string) error {
+	attempt.Success = reason == ""
+	attempt.Reason = reason
+	return s.logins.Create(ctx, attempt)
+}
+
+func (s *UserSvc) sendUnlock(ctx context.Context, user *model.User, until time.Time) error {
+	token, err := s.keys.Sign(accountUnlockClaims{
+		LockedUntil: until.Unix(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(user.ID, 10),
+			Audience:  jwt.ClaimStrings{accountUnlockAudience},
+			ExpiresAt: jwt.NewNumericDate(until),
+			IssuedAt:  jwt.NewNumericDate(s.now()),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(ctx, user.Email, mailer.AccountLocked, mailData{
+		Username:  user.Username,
+		Link:      s.publicURL + "/api/v1/account/unlock?token=" + url.QueryEscape(token),
+		ExpiresAt: until,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMailDelivery, err)
+	}
+
+	return nil
+}
+
+// UnlockAccount снимает блокировку входа по ссылке из письма
+func (s *UserSvc) UnlockAccount(ctx context.Context, token string) error {
+	claims := &accountUnlockClaims{}
+	_, err := s.keys.Parse(token, claims, jwt.WithAudience(accountUnlockAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return ErrInvalidUnlockToken
+	}
+
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return ErrInvalidUnlockToken
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrInvalidUnlockToken
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.LockedUntil == nil || user.LockedUntil.Unix() != claims.LockedUntil {
+		return ErrInvalidUnlockToken
+	}
+
+	return s.repo.ResetFailedLogins(ctx, user.ID)
+}
+
+// rejectLogin записывает неудачную попытку и возвращает причину отказа
+func (s *UserSvc) rejectLogin(ctx context.Context, attempt *model.LoginAttempt, reason string, cause error) error {
+	if err := s.recordLogin(ctx, attempt, reason); err != nil {
+		return err
+	}
+	return cause
+}
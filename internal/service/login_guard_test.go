@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"bank-app/internal/mailer"
+	"bank-app/internal/model"
+)
+
+func TestUserService_LoginProtection(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+
+	newService := func() (*UserSvc, *MockUserRepository, *MockLoginAttemptRepository) {
+		mockRepo := new(MockUserRepository)
+		service := newTestUserService(mockRepo, new(MockSessionRepository), new(MockRevokedTokenRepository))
+		logins := new(MockLoginAttemptRepository)
+		logins.On("CountFailuresByIP", ctx, "10.0.0.1", now.Add(-15*time.Minute)).Return(0, time.Time{}, nil).Maybe()
+		service.logins = logins
+		service.now = func() time.Time { return now }
+		return service, mockRepo, logins
+	}
+
+	client := model.ClientInfo{IP: "10.0.0.1", UserAgent: "test"}
+
+	t.Run("превышен лимит неудач с одного IP", func(t *testing.T) {
+		// Подготовка
+		service, mockRepo, _ := newService()
+		logins := new(MockLoginAttemptRepository)
+		service.logins = logins
+		logins.On("CountFailuresByIP", ctx, "10.0.0.1", now.Add(-15*time.Minute)).Return(50, now.Add(-10*time.Minute), nil)
+		logins.On("Create", ctx, mock.MatchedBy(func(a *model.LoginAttempt) bool {
+			return !a.Success && a.Reason == model.LoginIPThrottled && a.IP == "10.0.0.1"
+		})).Return(nil)
+
+		// Действие
+		_, err := service.Login(ctx, "user@example.com", "password123", client)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrTooManyAttempts)
+		var retry *RetryAfterError
+		require.True(t, errors.As(err, &retry))
+		assert.Equal(t, 5*time.Minute, retry.RetryAfter)
+		mockRepo.AssertNotCalled(t, "GetByEmail", mock.Anything, mock.Anything)
+		logins.AssertExpectations(t)
+	})
+
+	t.Run("неизвестный email записывается в журнал", func(t *testing.T) {
+		// Подготовка
+		service, mockRepo, logins := newService()
+		mockRepo.On("GetByEmail", ctx, "ghost@example.com").Return(nil, ErrNotFound)
+		logins.On("Create", ctx, mock.MatchedBy(func(a *model.LoginAttempt) bool {
+			return a.UserID == 0 && a.Email == "ghost@example.com" && a.Reason == model.LoginUnknownEmail
+		})).Return(nil)
+
+		// Действие
+		_, err := service.Login(ctx, "ghost@example.com", "password123", client)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		logins.AssertExpectations(t)
+	})
+
+	t.Run("заблокированная учетная запись", func(t *testing.T) {
+		// Подготовка
+		service, mockRepo, logins := newService()
+		lockedUntil := now.Add(20 * time.Minute)
+		mockRepo.On("GetByEmail", ctx, "user@example.com").Return(&model.User{
+			ID: 1, PasswordHash: string(hashedPassword), FailedLogins: 10, LockedUntil: &lockedUntil,
+		}, nil)
+		logins.On("Create", ctx, mock.MatchedBy(func(a *model.LoginAttempt) bool {
+			return a.UserID == 1 && a.Reason == model.LoginLocked
+		})).Return(nil)
+
+		// Действие: даже верный пароль не принимается
+		_, err := service.Login(ctx, "user@example.com", "password123", client)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrAccountLocked)
+		var retry *RetryAfterError
+		require.True(t, errors.As(err, &retry))
+		assert.Equal(t, 20*time.Minute, retry.RetryAfter)
+		mockRepo.AssertNotCalled(t, "IncrementFailedLogins", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("прогрессивная задержка после серии неудач", func(t *testing.T) {
+		// Подготовка
+		service, mockRepo, logins := newService()
+		lastFailure := now.Add(-time.Second)
+		mockRepo.On("GetByEmail", ctx, "user@example.com").Return(&model.User{
+			ID: 1, PasswordHash: string(hashedPassword), FailedLogins: 5, LastFailedLoginAt: &lastFailure,
+		}, nil)
+		logins.On("Create", ctx, mock.MatchedBy(func(a *model.LoginAttempt) bool {
+			return a.Reason == model.LoginThrottled
+		})).Return(nil)
+
+		// Действие
+		_, err := service.Login(ctx, "user@example.com", "password123", client)
+
+		// Проверка: после 5 неудач пауза 1s * 2^2 = 4s, прошла 1s
+		assert.ErrorIs(t, err, ErrTooManyAttempts)
+		var retry *RetryAfterError
+		require.True(t, errors.As(err, &retry))
+		assert.Equal(t, 3*time.Second, retry.RetryAfter)
+	})
+
+	t.Run("блокировка при достижении порога", func(t *testing.T) {
+		// Подготовка
+		service, mockRepo, logins := newService()
+		mail := new(MockMailer)
+		service.mailer = mail
+		user := &model.User{ID: 1, Username: "ivan", Email: "user@example.com", PasswordHash: string(hashedPassword)}
+		mockRepo.On("GetByEmail", ctx, "user@example.com").Return(user, nil)
+		mockRepo.On("IncrementFailedLogins", ctx, int64(1), 15*time.Minute).Return(10, nil)
+		mockRepo.On("Lock", ctx, int64(1), now.Add(30*time.Minute)).Return(nil)
+		mail.On("Send", ctx, "user@example.com", mailer.AccountLocked, mock.Anything).Return(nil)
+		logins.On("Create", ctx, mock.MatchedBy(func(a *model.LoginAttempt) bool {
+			return a.Reason == model.LoginInvalidPassword
+		})).Return(nil)
+
+		// Действие
+		_, err := service.Login(ctx, "user@example.com", "wrongpassword", client)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrAccountLocked)
+		mockRepo.AssertExpectations(t)
+		mail.AssertExpectations(t)
+	})
+
+	t.Run("ошибка отправки письма не мешает блокировке", func(t *testing.T) {
+		// Подготовка
+		service, mockRepo, logins := newService()
+		mail := new(MockMailer)
+		service.mailer = mail
+		mockRepo.On("GetByEmail", ctx, "user@example.com").Return(&model.User{ID: 1, PasswordHash: string(hashedPassword)}, nil)
+		mockRepo.On("IncrementFailedLogins", ctx, int64(1), 15*time.Minute).Return(12, nil)
+		mockRepo.On("Lock", ctx, int64(1), now.Add(30*time.Minute)).Return(nil)
+		mail.On("Send", ctx, mock.Anything, mailer.AccountLocked, mock.Anything).Return(errors.New("smtp down"))
+		logins.On("Create", ctx, mock.Anything).Return(nil)
+
+		// Действие
+		_, err := service.Login(ctx, "user@example.com", "wrongpassword", client)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrAccountLocked)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("успешный вход сбрасывает счетчик неудач", func(t *testing.T) {
+		// Подготовка
+		service, mockRepo, logins := newService()
+		sessions := new(MockSessionRepository)
+		service.sessions = sessions
+		lastFailure := now.Add(-time.Hour)
+		mockRepo.On("GetByEmail", ctx, "user@example.com").Return(&model.User{
+			ID: 1, PasswordHash: string(hashedPassword), FailedLogins: 7, LastFailedLoginAt: &lastFailure,
+		}, nil)
+		mockRepo.On("ResetFailedLogins", ctx, int64(1)).Return(nil)
+		sessions.On("Create", ctx, mock.Anything).Return(nil)
+		logins.On("Create", ctx, mock.MatchedBy(func(a *model.LoginAttempt) bool {
+			return a.Success && a.Reason == ""
+		})).Return(nil)
+
+		// Действие
+		result, err := service.Login(ctx, "user@example.com", "password123", client)
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.NotNil(t, result.TokenPair)
+		mockRepo.AssertExpectations(t)
+		logins.AssertExpectations(t)
+	})
+}
+
+func TestUserService_LoginDelay(t *testing.T) {
+	service := newTestUserService(new(MockUserRepository), new(MockSessionRepository), new(MockRevokedTokenRepository))
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 2, want: 0},
+		{failures: 3, want: time.Second},
+		{failures: 4, want: 2 * time.Second},
+		{failures: 6, want: 8 * time.Second},
+		{failures: 100, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, service.loginDelay(tt.failures), "failures=%d", tt.failures)
+	}
+}
+
+func TestUserService_UnlockAccount(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	lockedUntil := now.Add(30 * time.Minute)
+	user := &model.User{ID: 1, Username: "ivan", Email: "user@example.com", LockedUntil: &lockedUntil}
+
+	// unlockToken получает ссылку из письма о блокировке
+	unlockToken := func(t *testing.T, service *UserSvc) string {
+		mail := new(MockMailer)
+		service.mailer = mail
+		var link string
+		mail.On("Send", ctx, user.Email, mailer.AccountLocked, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			link = args.Get(3).(mailData).Link
+		})
+		require.NoError(t, service.sendUnlock(ctx, user, lockedUntil))
+
+		require.True(t, strings.HasPrefix(link, "https://bank.example/api/v1/account/unlock?token="))
+		parsed, err := url.Parse(link)
+		require.NoError(t, err)
+		return parsed.Query().Get("token")
+	}
+
+	t.Run("разблокировка по ссылке", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockUserRepository)
+		service := newTestUserService(mockRepo, new(MockSessionRepository), new(MockRevokedTokenRepository))
+		token := unlockToken(t, service)
+		mockRepo.On("GetByID", ctx, int64(1)).Return(user, nil)
+		mockRepo.On("ResetFailedLogins", ctx, int64(1)).Return(nil)
+
+		// Действие
+		err := service.UnlockAccount(ctx, token)
+
+		// Проверка
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("ссылка от прежней блокировки", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockUserRepository)
+		service := newTestUserService(mockRepo, new(MockSessionRepository), new(MockRevokedTokenRepository))
+		token := unlockToken(t, service)
+		mockRepo.On("GetByID", ctx, int64(1)).Return(&model.User{ID: 1}, nil)
+
+		// Действие
+		err := service.UnlockAccount(ctx, token)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidUnlockToken)
+		mockRepo.AssertNotCalled(t, "ResetFailedLogins", mock.Anything, mock.Anything)
+	})
+
+	t.Run("чужая подписанная ссылка", func(t *testing.T) {
+		// Подготовка
+		service := newTestUserService(new(MockUserRepository), new(MockSessionRepository), new(MockRevokedTokenRepository))
+
+		// Действие: ссылка подтверждения email не подходит для разблокировки
+		token, _ := service.keys.Sign(emailVerificationClaims{Email: user.Email})
+		err := service.UnlockAccount(ctx, token)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidUnlockToken)
+	})
+}
@@ -0,0 +1,394 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bank-app/internal/config"
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+	"bank-app/internal/repository"
+	"bank-app/internal/totp"
+)
+
+var (
+	ErrInvalidMFACode        = errors.New("invalid verification code")
+	ErrMFAAlreadyEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled         = errors.New("two-factor authentication is not enabled")
+	ErrMFAEnrollmentRequired = errors.New("two-factor authentication must be enabled for this operation")
+	ErrStepUpRequired        = errors.New("step-up verification required")
+	ErrInvalidMFAChallenge   = errors.New("invalid or expired mfa token")
+	ErrMFALocked             = errors.New("too many invalid verification codes, try again later")
+)
+
+const (
+	recoveryCodeCount = 10
+	mfaChallengeTTL   = 5 * time.Minute
+	maxMFAAttempts    = 5
+	// mfaLockout - на сколько блокируется проверка кодов пользователя после
+	// maxMFAAttempts неверных кодов подряд
+	mfaLockout = 15 * time.Minute
+	// totpSkew - допустимое расхождение часов клиента в шагах TOTP
+	totpSkew = 1
+)
+
+type MFASvc struct {
+	users    repository.UserRepository
+	repo     repository.MFARepository
+	sessions repository.SessionRepository
+	tx       repository.Transactor
+	cfg      config.MFAConfig
+	now      func() time.Time
+}
+
+func NewMFAService(users repository.UserRepository, repo repository.MFARepository, sessions repository.SessionRepository, tx repository.Transactor, cfg *config.Config) MFAService {
+	return &MFASvc{
+		users:    users,
+		repo:     repo,
+		sessions: sessions,
+		tx:       tx,
+		cfg:      cfg.MFA,
+		now:      time.Now,
+	}
+}
+
+// Enroll создает новый секрет. Двухфакторная аутентификация включается
+// только после подтверждения кодом из приложения.
+func (s *MFASvc) Enroll(ctx context.Context, userID int64) (*model.MFAEnrollment, error) {
+	var enrollment *model.MFAEnrollment
+
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		existing, err := s.repo.Get(ctx, userID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		if existing != nil && existing.Enabled {
+			return ErrMFAAlreadyEnabled
+		}
+
+		user, err := s.users.GetByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			return err
+		}
+
+		if err := s.repo.Save(ctx, &model.MFA{UserID: userID, Secret: secret}); err != nil {
+			return err
+		}
+
+		enrollment = &model.MFAEnrollment{
+			Secret: secret,
+			URI:    totp.URI(s.cfg.Issuer, user.Email, secret),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+// Confirm включает двухфакторную аутентификацию и возвращает коды восстановления.
+// Коды показываются один раз, в базе хранятся только их хеши.
+func (s *MFASvc) Confirm(ctx context.Context, userID int64, code string) ([]string, error) {
+	var codes []string
+
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		mfa, err := s.repo.Get(ctx, userID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrMFANotEnabled
+		}
+		if err != nil {
+			return err
+		}
+		if mfa.Enabled {
+			return ErrMFAAlreadyEnabled
+		}
+
+		now := s.now()
+		step, ok := totp.Validate(mfa.Secret, code, now, totpSkew)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+
+		mfa.Enabled = true
+		mfa.LastUsedStep = step
+		mfa.ConfirmedAt = &now
+		if err := s.repo.Save(ctx, mfa); err != nil {
+			return err
+		}
+
+		hashes := make([]string, recoveryCodeCount)
+		codes = make([]string, recoveryCodeCount)
+		for i := range codes {
+			if codes[i], err = newRecoveryCode(); err != nil {
+				return err
+			}
+			hashes[i] = hashRecoveryCode(codes[i])
+		}
+
+		return s.repo.ReplaceRecoveryCodes(ctx, userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable отключает двухфакторную аутентификацию после проверки кода
+func (s *MFASvc) Disable(ctx context.Context, userID int64, code string) error {
+	var codeErr error
+
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if codeErr, err = s.verify(ctx, userID, code); err != nil || codeErr != nil {
+			return err
+		}
+
+		return s.repo.Delete(ctx, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	return codeErr
+}
+
+// Verify проверяет код TOTP или одноразовый код восстановления. Неверные коды
+// подсчитываются, после maxMFAAttempts подряд проверка блокируется на mfaLockout.
+func (s *MFASvc) Verify(ctx context.Context, userID int64, code string) error {
+	var codeErr error
+
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		codeErr, err = s.verify(ctx, userID, code)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return codeErr
+}
+
+// verify проверяет код в транзакции вызывающего. Ошибка кода возвращается
+// отдельно от err, чтобы вызывающий зафиксировал счетчик неудач, а не откатил его.
+func (s *MFASvc) verify(ctx context.Context, userID int64, code string) (codeErr error, err error) {
+	mfa, err := s.repo.Get(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrMFANotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.Enabled {
+		return nil, ErrMFANotEnabled
+	}
+
+	now := s.now()
+	if mfa.LockedUntil != nil && now.Before(*mfa.LockedUntil) {
+		return nil, &RetryAfterError{Err: ErrMFALocked, RetryAfter: mfa.LockedUntil.Sub(now)}
+	}
+
+	step, ok := totp.Validate(mfa.Secret, code, now, totpSkew)
+	// Код уже принятого шага (или более раннего) повторно не принимается
+	if ok && step > mfa.LastUsedStep {
+		mfa.LastUsedStep = step
+		mfa.FailedAttempts = 0
+		mfa.LockedUntil = nil
+		return nil, s.repo.Save(ctx, mfa)
+	}
+
+	if !ok {
+		used, err := s.repo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+		if err != nil {
+			return nil, err
+		}
+		if used {
+			if mfa.FailedAttempts == 0 && mfa.LockedUntil == nil {
+				return nil, nil
+			}
+			mfa.FailedAttempts = 0
+			mfa.LockedUntil = nil
+			return nil, s.repo.Save(ctx, mfa)
+		}
+	}
+
+	mfa.FailedAttempts++
+	if mfa.FailedAttempts >= maxMFAAttempts {
+		until := now.Add(mfaLockout)
+		mfa.FailedAttempts = 0
+		mfa.LockedUntil = &until
+	}
+
+	return ErrInvalidMFACode, s.repo.Save(ctx, mfa)
+}
+
+// IsEnabled сообщает, включена ли у пользователя двухфакторная аутентификация
+func (s *MFASvc) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	mfa, err := s.repo.Get(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return mfa.Enabled, nil
+}
+
+// StepUp подтверждает второй фактор в рамках текущей сессии
+func (s *MFASvc) StepUp(ctx context.Context, userID, sessionID int64, code string) error {
+	var codeErr error
+
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		session, err := s.sessions.GetByID(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+		if session.UserID != userID {
+			return fmt.Errorf("session %w", ErrNotFound)
+		}
+
+		if codeErr, err = s.verify(ctx, userID, code); err != nil || codeErr != nil {
+			return err
+		}
+
+		now := s.now()
+		session.MFAVerifiedAt = &now
+		return s.sessions.Update(ctx, session)
+	})
+	if err != nil {
+		return err
+	}
+
+	return codeErr
+}
+
+// RequireStepUp проверяет, что второй фактор подтвержден в сессии недавно
+func (s *MFASvc) RequireStepUp(ctx context.Context, userID, sessionID int64) error {
+	enabled, err := s.IsEnabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrMFAEnrollmentRequired
+	}
+
+	session, err := s.sessions.GetByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if session.UserID != userID || session.MFAVerifiedAt == nil ||
+		s.now().Sub(*session.MFAVerifiedAt) > s.cfg.StepUpTTL {
+		return ErrStepUpRequired
+	}
+
+	return nil
+}
+
+// RequireStepUpForTransfer требует подтверждения для переводов больше порога
+func (s *MFASvc) RequireStepUpForTransfer(ctx context.Context, userID, sessionID int64, amount money.Amount) error {
+	if amount <= s.cfg.TransferThreshold {
+		return nil
+	}
+
+	return s.RequireStepUp(ctx, userID, sessionID)
+}
+
+// CreateChallenge начинает второй шаг входа и возвращает токен для него
+func (s *MFASvc) CreateChallenge(ctx context.Context, userID int64, client model.ClientInfo) (string, error) {
+	token, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	challenge := &model.MFAChallenge{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+		ExpiresAt: s.now().Add(mfaChallengeTTL),
+	}
+	if err := s.repo.CreateChallenge(ctx, challenge); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// VerifyChallenge проверяет код второго шага входа и возвращает пользователя.
+// Неудачные попытки сохраняются, после maxMFAAttempts вход нужно начинать заново.
+func (s *MFASvc) VerifyChallenge(ctx context.Context, token, code string) (int64, error) {
+	var (
+		userID  int64
+		codeErr error
+	)
+
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		challenge, err := s.repo.GetChallengeByTokenHash(ctx, hashToken(token))
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidMFAChallenge
+		}
+		if err != nil {
+			return err
+		}
+
+		now := s.now()
+		if challenge.ConsumedAt != nil || !now.Before(challenge.ExpiresAt) || challenge.Attempts >= maxMFAAttempts {
+			return ErrInvalidMFAChallenge
+		}
+
+		// Ошибку кода возвращаем после фиксации счетчиков попыток
+		if codeErr, err = s.verify(ctx, challenge.UserID, code); err != nil {
+			return err
+		}
+		if codeErr != nil {
+			challenge.Attempts++
+			return s.repo.UpdateChallenge(ctx, challenge)
+		}
+
+		challenge.ConsumedAt = &now
+		userID = challenge.UserID
+		return s.repo.UpdateChallenge(ctx, challenge)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if codeErr != nil {
+		return 0, codeErr
+	}
+
+	return userID, nil
+}
+
+// newRecoveryCode генерирует код вида "abcd-efgh"
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+	return code[:4] + "-" + code[4:], nil
+}
+
+// hashRecoveryCode нормализует код перед хешированием, чтобы регистр
+// и дефис при вводе не имели значения
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return hashToken(code)
+}
@@ -0,0 +1,386 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"bank-app/internal/config"
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+	"bank-app/internal/totp"
+)
+
+type MockMFARepository struct {
+	mock.Mock
+}
+
+func (m *MockMFARepository) Get(ctx context.Context, userID int64) (*model.MFA, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.MFA), args.Error(1)
+}
+
+func (m *MockMFARepository) Save(ctx context.Context, mfa *model.MFA) error {
+	args := m.Called(ctx, mfa)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) Delete(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	args := m.Called(ctx, userID, hashes)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) UseRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error) {
+	args := m.Called(ctx, userID, hash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) CreateChallenge(ctx context.Context, challenge *model.MFAChallenge) error {
+	args := m.Called(ctx, challenge)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) GetChallengeByTokenHash(ctx context.Context, hash string) (*model.MFAChallenge, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.MFAChallenge), args.Error(1)
+}
+
+func (m *MockMFARepository) UpdateChallenge(ctx context.Context, challenge *model.MFAChallenge) error {
+	args := m.Called(ctx, challenge)
+	return args.Error(0)
+}
+
+type MockMFAService struct {
+	mock.Mock
+}
+
+func (m *MockMFAService) Enroll(ctx context.Context, userID int64) (*model.MFAEnrollment, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.MFAEnrollment), args.Error(1)
+}
+
+func (m *MockMFAService) Confirm(ctx context.Context, userID int64, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockMFAService) Disable(ctx context.Context, userID int64, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) Verify(ctx context.Context, userID int64, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFAService) StepUp(ctx context.Context, userID, sessionID int64, code string) error {
+	args := m.Called(ctx, userID, sessionID, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) RequireStepUp(ctx context.Context, userID, sessionID int64) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) RequireStepUpForTransfer(ctx context.Context, userID, sessionID int64, amount money.Amount) error {
+	args := m.Called(ctx, userID, sessionID, amount)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) CreateChallenge(ctx context.Context, userID int64, client model.ClientInfo) (string, error) {
+	args := m.Called(ctx, userID, client)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockMFAService) VerifyChallenge(ctx context.Context, token, code string) (int64, error) {
+	args := m.Called(ctx, token, code)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+var testNow = time.Unix(1700000000, 0)
+
+func newTestMFAService(repo *MockMFARepository, sessions *MockSessionRepository) *MFASvc {
+	cfg := &config.Config{
+		MFA: config.MFAConfig{
+			Issuer:            "Bank App",
+			StepUpTTL:         5 * time.Minute,
+			TransferThreshold: money.Units(100000),
+		},
+	}
+	service := NewMFAService(new(MockUserRepository), repo, sessions, &MockTransactor{}, cfg).(*MFASvc)
+	service.now = func() time.Time { return testNow }
+	return service
+}
+
+func TestMFAService_Confirm(t *testing.T) {
+	ctx := context.Background()
+
+	// Подготовка
+	mockRepo := new(MockMFARepository)
+	service := newTestMFAService(mockRepo, new(MockSessionRepository))
+
+	mfa := &model.MFA{UserID: 1, Secret: testTOTPSecret}
+	code, _ := totp.Code(testTOTPSecret, totp.Step(testNow))
+
+	mockRepo.On("Get", ctx, int64(1)).Return(mfa, nil)
+	mockRepo.On("Save", ctx, mfa).Return(nil)
+	mockRepo.On("ReplaceRecoveryCodes", ctx, int64(1), mock.AnythingOfType("[]string")).Return(nil)
+
+	// Действие
+	codes, err := service.Confirm(ctx, 1, code)
+
+	// Проверка
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.True(t, mfa.Enabled)
+	assert.Equal(t, totp.Step(testNow), mfa.LastUsedStep)
+}
+
+func TestMFAService_Verify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("повторное использование кода", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockMFARepository)
+		service := newTestMFAService(mockRepo, new(MockSessionRepository))
+
+		code, _ := totp.Code(testTOTPSecret, totp.Step(testNow))
+		mfa := &model.MFA{UserID: 1, Secret: testTOTPSecret, Enabled: true, LastUsedStep: totp.Step(testNow)}
+		mockRepo.On("Get", ctx, int64(1)).Return(mfa, nil)
+		mockRepo.On("Save", ctx, mfa).Return(nil)
+
+		// Действие
+		err := service.Verify(ctx, 1, code)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidMFACode)
+		assert.Equal(t, 1, mfa.FailedAttempts)
+		assert.Equal(t, totp.Step(testNow), mfa.LastUsedStep)
+	})
+
+	t.Run("неверный код фиксирует счетчик неудач", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockMFARepository)
+		tx := &MockTransactor{}
+		service := newTestMFAService(mockRepo, new(MockSessionRepository))
+		service.tx = tx
+
+		mfa := &model.MFA{UserID: 1, Secret: testTOTPSecret, Enabled: true, FailedAttempts: maxMFAAttempts - 2}
+		mockRepo.On("Get", ctx, int64(1)).Return(mfa, nil)
+		mockRepo.On("UseRecoveryCode", ctx, int64(1), mock.Anything).Return(false, nil)
+		mockRepo.On("Save", ctx, mfa).Return(nil)
+
+		// Действие
+		err := service.Verify(ctx, 1, "000000")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidMFACode)
+		assert.Equal(t, maxMFAAttempts-1, mfa.FailedAttempts)
+		assert.Nil(t, mfa.LockedUntil)
+		assert.True(t, tx.committed)
+	})
+
+	t.Run("серия неверных кодов блокирует проверку", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockMFARepository)
+		service := newTestMFAService(mockRepo, new(MockSessionRepository))
+
+		mfa := &model.MFA{UserID: 1, Secret: testTOTPSecret, Enabled: true, FailedAttempts: maxMFAAttempts - 1}
+		mockRepo.On("Get", ctx, int64(1)).Return(mfa, nil)
+		mockRepo.On("UseRecoveryCode", ctx, int64(1), mock.Anything).Return(false, nil)
+		mockRepo.On("Save", ctx, mfa).Return(nil)
+
+		// Действие
+		err := service.Verify(ctx, 1, "000000")
+		code, _ := totp.Code(testTOTPSecret, totp.Step(testNow))
+		lockedErr := service.Verify(ctx, 1, code)
+
+		// Проверка: во время блокировки не принимается и верный код
+		assert.ErrorIs(t, err, ErrInvalidMFACode)
+		require.NotNil(t, mfa.LockedUntil)
+		assert.Equal(t, testNow.Add(mfaLockout), *mfa.LockedUntil)
+
+		var retry *RetryAfterError
+		require.ErrorAs(t, lockedErr, &retry)
+		assert.ErrorIs(t, lockedErr, ErrMFALocked)
+		assert.Equal(t, mfaLockout, retry.RetryAfter)
+		assert.Equal(t, int64(0), mfa.LastUsedStep)
+	})
+
+	t.Run("верный код сбрасывает счетчик", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockMFARepository)
+		service := newTestMFAService(mockRepo, new(MockSessionRepository))
+
+		lockedUntil := testNow.Add(-time.Minute)
+		code, _ := totp.Code(testTOTPSecret, totp.Step(testNow))
+		mfa := &model.MFA{UserID: 1, Secret: testTOTPSecret, Enabled: true, FailedAttempts: 3, LockedUntil: &lockedUntil}
+		mockRepo.On("Get", ctx, int64(1)).Return(mfa, nil)
+		mockRepo.On("Save", ctx, mfa).Return(nil)
+
+		// Действие
+		err := service.Verify(ctx, 1, code)
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.Zero(t, mfa.FailedAttempts)
+		assert.Nil(t, mfa.LockedUntil)
+	})
+
+	t.Run("код восстановления", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockMFARepository)
+		service := newTestMFAService(mockRepo, new(MockSessionRepository))
+
+		mockRepo.On("Get", ctx, int64(1)).Return(&model.MFA{UserID: 1, Secret: testTOTPSecret, Enabled: true}, nil)
+		mockRepo.On("UseRecoveryCode", ctx, int64(1), hashRecoveryCode("abcdefgh")).Return(true, nil)
+
+		// Действие
+		err := service.Verify(ctx, 1, "ABCD-EFGH")
+
+		// Проверка
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestMFAService_RequireStepUp(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("подтверждение устарело", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockMFARepository)
+		mockSessions := new(MockSessionRepository)
+		service := newTestMFAService(mockRepo, mockSessions)
+
+		verifiedAt := testNow.Add(-10 * time.Minute)
+		mockRepo.On("Get", ctx, int64(1)).Return(&model.MFA{UserID: 1, Enabled: true}, nil)
+		mockSessions.On("GetByID", ctx, int64(3)).Return(&model.Session{ID: 3, UserID: 1, MFAVerifiedAt: &verifiedAt}, nil)
+
+		// Действие
+		err := service.RequireStepUp(ctx, 1, 3)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrStepUpRequired)
+	})
+
+	t.Run("двухфакторная аутентификация не подключена", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockMFARepository)
+		service := newTestMFAService(mockRepo, new(MockSessionRepository))
+
+		mockRepo.On("Get", ctx, int64(1)).Return(nil, ErrNotFound)
+
+		// Действие
+		err := service.RequireStepUp(ctx, 1, 3)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrMFAEnrollmentRequired)
+	})
+
+	t.Run("перевод ниже порога", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockMFARepository)
+		service := newTestMFAService(mockRepo, new(MockSessionRepository))
+
+		// Действие
+		err := service.RequireStepUpForTransfer(ctx, 1, 3, money.Units(5000))
+
+		// Проверка
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	})
+}
+
+func TestMFAService_VerifyChallenge(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("неверный код увеличивает счетчик попыток", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockMFARepository)
+		tx := &MockTransactor{}
+		service := newTestMFAService(mockRepo, new(MockSessionRepository))
+		service.tx = tx
+
+		challenge := &model.MFAChallenge{ID: 1, UserID: 1, ExpiresAt: testNow.Add(time.Minute)}
+		mockRepo.On("GetChallengeByTokenHash", ctx, hashToken("token")).Return(challenge, nil)
+		mockRepo.On("Get", ctx, int64(1)).Return(&model.MFA{UserID: 1, Secret: testTOTPSecret, Enabled: true}, nil)
+		mockRepo.On("UseRecoveryCode", ctx, int64(1), mock.Anything).Return(false, nil)
+		mockRepo.On("Save", ctx, mock.AnythingOfType("*model.MFA")).Return(nil)
+		mockRepo.On("UpdateChallenge", ctx, challenge).Return(nil)
+
+		// Действие
+		_, err := service.VerifyChallenge(ctx, "token", "000000")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidMFACode)
+		assert.Equal(t, 1, challenge.Attempts)
+		assert.True(t, tx.committed)
+	})
+
+	t.Run("исчерпаны попытки", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockMFARepository)
+		service := newTestMFAService(mockRepo, new(MockSessionRepository))
+
+		challenge := &model.MFAChallenge{ID: 1, UserID: 1, Attempts: maxMFAAttempts, ExpiresAt: testNow.Add(time.Minute)}
+		mockRepo.On("GetChallengeByTokenHash", ctx, hashToken("token")).Return(challenge, nil)
+
+		// Действие
+		_, err := service.VerifyChallenge(ctx, "token", "000000")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+	})
+
+	t.Run("успешная проверка", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockMFARepository)
+		service := newTestMFAService(mockRepo, new(MockSessionRepository))
+
+		code, _ := totp.Code(testTOTPSecret, totp.Step(testNow))
+		challenge := &model.MFAChallenge{ID: 1, UserID: 7, ExpiresAt: testNow.Add(time.Minute)}
+		mockRepo.On("GetChallengeByTokenHash", ctx, hashToken("token")).Return(challenge, nil)
+		mockRepo.On("Get", ctx, int64(7)).Return(&model.MFA{UserID: 7, Secret: testTOTPSecret, Enabled: true}, nil)
+		mockRepo.On("Save", ctx, mock.AnythingOfType("*model.MFA")).Return(nil)
+		mockRepo.On("UpdateChallenge", ctx, challenge).Return(nil)
+
+		// Действие
+		userID, err := service.VerifyChallenge(ctx, "token", code)
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), userID)
+		assert.NotNil(t, challenge.ConsumedAt)
+	})
+}
@@ -0,0 +1,448 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bank-app/internal/config"
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+	"bank-app/internal/repository"
+)
+
+var (
+	ErrInvalidAPIKey               = errors.New("invalid API key")
+	ErrInvalidAuthorizationRequest = errors.New("invalid authorization request")
+	ErrDuplicateReference          = errors.New("reference has already been used for another operation")
+	ErrAuthorizationState          = errors.New("operation is not allowed in the current authorization status")
+	ErrInvalidCaptureAmount        = errors.New("capture amount must be positive and not exceed the authorized amount")
+	ErrInvalidRefundAmount         = errors.New("refund amount must be positive and not exceed the settled amount")
+)
+
+// Типы операций по счету, создаваемых при расчетах по картам
+const (
+	transactionCardPurchase = "card_purchase"
+	transactionCardRefund   = "card_refund"
+)
+
+type ProcessingSvc struct {
+	auths     repository.CardAuthorizationRepository
+	accounts  repository.AccountRepository
+	transfers repository.TransferRepository
+	cards     CardService
+	limits    CardLimitService
+	ledger    LedgerService
+	tx        repository.Transactor
+	apiKeys   map[string]string
+	holdTTL   time.Duration
+	now       func() time.Time
+}
+
+func NewProcessingService(auths repository.CardAuthorizationRepository, accounts repository.AccountRepository, transfers repository.TransferRepository, cards CardService, limits CardLimitService, ledger LedgerService, tx repository.Transactor, cfg *config.Config) ProcessingService {
+	return &ProcessingSvc{
+		auths:     auths,
+		accounts:  accounts,
+		transfers: transfers,
+		cards:     cards,
+		limits:    limits,
+		ledger:    ledger,
+		tx:        tx,
+		apiKeys:   cfg.Processing.APIKeys,
+		holdTTL:   cfg.Processing.HoldTTL,
+		now:       time.Now,
+	}
+}
+
+// AuthenticateAcquirer возвращает идентификатор эквайрера по ключу API
+func (s *ProcessingSvc) AuthenticateAcquirer(apiKey string) (string, error) {
+	if apiKey == "" {
+		return "", ErrInvalidAPIKey
+	}
+
+	// Сравниваются хеши одинаковой длины, чтобы время не зависело от ключа
+	presented := sha256.Sum256([]byte(apiKey))
+	for acquirer, key := range s.apiKeys {
+		expected := sha256.Sum256([]byte(key))
+		if subtle.ConstantTimeCompare(presented[:], expected[:]) == 1 {
+			return acquirer, nil
+		}
+	}
+
+	return "", ErrInvalidAPIKey
+}
+
+// Authorize проверяет реквизиты, статус и лимиты карты и блокирует сумму на
+// счете. Повторный запрос с тем же номером операции возвращает прежнюю
+// авторизацию, поэтому эквайрер может безопасно повторять запросы.
+func (s *ProcessingSvc) Authorize(ctx context.Context, acquirer string, req *model.AuthorizationRequest) (*model.CardAuthorization, error) {
+	return s.authorize(ctx, acquirer, req, false)
+}
+
+// Purchase авторизует покупку и сразу подтверждает ее на всю сумму. Блокировка
+// и подтверждение выполняются в одной транзакции, поэтому ошибка
+// подтверждения не оставляет сумму заблокированной на счете.
+func (s *ProcessingSvc) Purchase(ctx context.Context, acquirer string, req *model.AuthorizationRequest) (*model.CardAuthorization, error) {
+	return s.authorize(ctx, acquirer, req, true)
+}
+
+// authorize блокирует сумму по запросу req, при capture - с подтверждением
+// в той же транзакции
+func (s *ProcessingSvc) authorize(ctx context.Context, acquirer string, req *model.AuthorizationRequest, capture bool) (*model.CardAuthorization, error) {
+	if err := validateAuthorizationRequest(req); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.auths.GetByReference(ctx, acquirer, req.Reference)
+	if err == nil {
+		auth, err := s.repeated(ctx, existing, req)
+		if err != nil || !capture {
+			return auth, err
+		}
+		return s.Capture(ctx, acquirer, auth.ID, 0)
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	// Карта, предъявленная в банкомате или терминале, проверяется по PIN.
+	// Неверный PIN учитывается до транзакции авторизации и не откатывается.
+	var card *model.Card
+	if req.PIN != "" {
+		card, err = s.cards.ValidateCardPIN(ctx, req.CardNumber, req.PIN)
+	} else {
+		card, err = s.cards.ValidateCard(ctx, req.CardNumber, req.CVV)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &model.CardAuthorization{
+		CardID:       card.ID,
+		AccountID:    card.AccountID,
+		Acquirer:     acquirer,
+		Reference:    req.Reference,
+		MerchantName: req.MerchantName,
+		MCC:          req.MCC,
+		Channel:      req.Channel,
+		Amount:       req.Amount,
+		Status:       model.AuthorizationApproved,
+		ExpiresAt:    s.now().Add(s.holdTTL),
+	}
+
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.auths.Create(ctx, auth); err != nil {
+			return err
+		}
+
+		// Лимиты блокируют строку карты и учитывают расход
+		if err := s.limits.Authorize(ctx, authorizationSpend(auth, auth.Amount)); err != nil {
+			return err
+		}
+
+		// Ограничения виртуальных карт: торговец и однократное использование
+		if err := s.cards.RecordAuthorization(ctx, card, auth.MerchantName); err != nil {
+			return err
+		}
+
+		account, err := s.accounts.GetByIDForUpdate(ctx, auth.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if account.Status == model.AccountFrozen {
+			return ErrAccountFrozen
+		}
+
+		if account.Available() < auth.Amount {
+			return ErrInsufficientFunds
+		}
+
+		if err := s.accounts.AdjustHeld(ctx, auth.AccountID, auth.Amount); err != nil {
+			return err
+		}
+
+		if !capture {
+			return nil
+		}
+
+		auth.CapturedAmount = auth.Amount
+		auth.Status = model.AuthorizationCaptured
+		return s.auths.Update(ctx, auth)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+func (s *ProcessingSvc) Get(ctx context.Context, acquirer string, id int64) (*model.CardAuthorization, error) {
+	auth, err := s.auths.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if auth.Acquirer != acquirer {
+		return nil, fmt.Errorf("card authorization %w", ErrNotFound)
+	}
+
+	return auth, nil
+}
+
+// repeated возвращает прежнюю авторизацию на повторный запрос. Номер
+// операции, использованный для другой карты или суммы, отклоняется.
+func (s *ProcessingSvc) repeated(ctx context.Context, existing *model.CardAuthorization, req *model.AuthorizationRequest) (*model.CardAuthorization, error) {
+	if existing.Amount != req.Amount {
+		return nil, ErrDuplicateReference
+	}
+
+	card, err := s.cards.FindByNumber(ctx, req.CardNumber)
+	if errors.Is(err, ErrInvalidCard) {
+		return nil, ErrDuplicateReference
+	}
+	if err != nil {
+		return nil, err
+	}
+	if card.ID != existing.CardID {
+		return nil, ErrDuplicateReference
+	}
+
+	return existing, nil
+}
+
+// GetByReference ищет авторизацию эквайрера по номеру операции
+func (s *ProcessingSvc) GetByReference(ctx context.Context, acquirer, reference string) (*model.CardAuthorization, error) {
+	return s.auths.GetByReference(ctx, acquirer, reference)
+}
+
+// Capture подтверждает покупку на сумму amount (нулевая - вся авторизованная
+// сумма). Остаток блокировки снимается и возвращается в лимиты карты.
+func (s *ProcessingSvc) Capture(ctx context.Context, acquirer string, id int64, amount money.Amount) (*model.CardAuthorization, error) {
+	return s.update(ctx, acquirer, id, func(ctx context.Context, auth *model.CardAuthorization) error {
+		if amount == 0 {
+			amount = auth.Amount
+		}
+
+		// Повтор того же подтверждения
+		if auth.Status == model.AuthorizationCaptured && auth.CapturedAmount == amount {
+			return nil
+		}
+
+		if auth.Status != model.AuthorizationApproved {
+			return ErrAuthorizationState
+		}
+
+		if !amount.IsPositive() || amount > auth.Amount {
+			return ErrInvalidCaptureAmount
+		}
+
+		if rest := auth.Amount - amount; rest > 0 {
+			if err := s.release(ctx, auth, rest); err != nil {
+				return err
+			}
+		}
+
+		auth.CapturedAmount = amount
+		auth.Status = model.AuthorizationCaptured
+		return s.auths.Update(ctx, auth)
+	})
+}
+
+// Reverse отменяет нерассчитанную авторизацию и снимает блокировку
+func (s *ProcessingSvc) Reverse(ctx context.Context, acquirer string, id int64) (*model.CardAuthorization, error) {
+	return s.update(ctx, acquirer, id, func(ctx context.Context, auth *model.CardAuthorization) error {
+		if auth.Status == model.AuthorizationReversed {
+			return nil
+		}
+
+		if auth.Status != model.AuthorizationApproved && auth.Status != model.AuthorizationCaptured {
+			return ErrAuthorizationState
+		}
+
+		if err := s.release(ctx, auth, auth.HeldAmount()); err != nil {
+			return err
+		}
+
+		auth.Status = model.AuthorizationReversed
+		return s.auths.Update(ctx, auth)
+	})
+}
+
+// Refund возвращает на счет часть или всю сумму рассчитанной покупки
+func (s *ProcessingSvc) Refund(ctx context.Context, acquirer string, id int64, amount money.Amount) (*model.CardAuthorization, error) {
+	return s.update(ctx, acquirer, id, func(ctx context.Context, auth *model.CardAuthorization) error {
+		if auth.Status != model.AuthorizationSettled {
+			return ErrAuthorizationState
+		}
+
+		if !amount.IsPositive() || auth.RefundedAmount+amount > auth.CapturedAmount {
+			return ErrInvalidRefundAmount
+		}
+
+		transaction := &model.Transaction{
+			ToAccountID: auth.AccountID,
+			Amount:      amount,
+			Type:        transactionCardRefund,
+			Status:      "completed",
+		}
+		if err := s.transfers.Create(ctx, transaction); err != nil {
+			return err
+		}
+
+		if err := s.ledger.Post(ctx, cardRefundEntry(transaction.ID, auth.AccountID, amount)); err != nil {
+			return err
+		}
+
+		auth.RefundedAmount += amount
+		return s.auths.Update(ctx, auth)
+	})
+}
+
+// Settle списывает со счетов подтвержденные покупки и снимает блокировки
+// авторизаций, которые не были подтверждены до истечения срока. Ошибка по
+// одной авторизации не останавливает обработку остальных.
+func (s *ProcessingSvc) Settle(ctx context.Context) error {
+	var errs []error
+
+	captured, err := s.auths.GetCaptured(ctx, cardJobBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, auth := range captured {
+		if err := s.settle(ctx, auth.ID); err != nil {
+			errs = append(errs, fmt.Errorf("settle authorization %d: %w", auth.ID, err))
+		}
+	}
+
+	expired, err := s.auths.GetExpired(ctx, s.now(), cardJobBatchSize)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, auth := range expired {
+		if err := s.expire(ctx, auth.ID); err != nil {
+			errs = append(errs, fmt.Errorf("expire authorization %d: %w", auth.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *ProcessingSvc) settle(ctx context.Context, id int64) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		auth, err := s.auths.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// Авторизацию успели отменить
+		if auth.Status != model.AuthorizationCaptured {
+			return nil
+		}
+
+		// Блокировка снимается до проводки, иначе сумма не пройдет проверку доступного остатка
+		if err := s.accounts.AdjustHeld(ctx, auth.AccountID, -auth.CapturedAmount); err != nil {
+			return err
+		}
+
+		transaction := &model.Transaction{
+			FromAccountID: auth.AccountID,
+			Amount:        auth.CapturedAmount,
+			Type:          transactionCardPurchase,
+			Status:        "completed",
+		}
+		if err := s.transfers.Create(ctx, transaction); err != nil {
+			return err
+		}
+
+		if err := s.ledger.Post(ctx, cardPurchaseEntry(transaction.ID, auth.AccountID, auth.CapturedAmount)); err != nil {
+			return err
+		}
+
+		auth.TransactionID = transaction.ID
+		auth.Status = model.AuthorizationSettled
+		return s.auths.Update(ctx, auth)
+	})
+}
+
+func (s *ProcessingSvc) expire(ctx context.Context, id int64) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		auth, err := s.auths.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if auth.Status != model.AuthorizationApproved || auth.ExpiresAt.After(s.now()) {
+			return nil
+		}
+
+		if err := s.release(ctx, auth, auth.Amount); err != nil {
+			return err
+		}
+
+		auth.Status = model.AuthorizationExpired
+		return s.auths.Update(ctx, auth)
+	})
+}
+
+// update блокирует авторизацию эквайрера и применяет к ней apply в транзакции
+func (s *ProcessingSvc) update(ctx context.Context, acquirer string, id int64, apply func(ctx context.Context, auth *model.CardAuthorization) error) (*model.CardAuthorization, error) {
+	var auth *model.CardAuthorization
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		auth, err = s.auths.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if auth.Acquirer != acquirer {
+			return fmt.Errorf("card authorization %w", ErrNotFound)
+		}
+
+		return apply(ctx, auth)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+// release снимает блокировку amount со счета и возвращает сумму в лимиты карты
+func (s *ProcessingSvc) release(ctx context.Context, auth *model.CardAuthorization, amount money.Amount) error {
+	if err := s.accounts.AdjustHeld(ctx, auth.AccountID, -amount); err != nil {
+		return err
+	}
+
+	return s.limits.Release(ctx, authorizationSpend(auth, amount))
+}
+
+// authorizationSpend - расход по карте, учитываемый в лимитах на дату авторизации
+func authorizationSpend(auth *model.CardAuthorization, amount money.Amount) *model.CardSpend {
+	return &model.CardSpend{
+		CardID:  auth.CardID,
+		Channel: auth.Channel,
+		MCC:     auth.MCC,
+		Amount:  amount,
+		At:      auth.CreatedAt,
+	}
+}
+
+func validateAuthorizationRequest(req *model.AuthorizationRequest) error {
+	switch {
+	case strings.TrimSpace(req.Reference) == "":
+		return fmt.Errorf("%w: reference is required", ErrInvalidAuthorizationRequest)
+	case !req.Amount.IsPositive():
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidAuthorizationRequest)
+	case !cardChannels[req.Channel]:
+		return fmt.Errorf("%w: %v", ErrInvalidAuthorizationRequest, ErrUnknownChannel)
+	case req.MCC != "" && !isMCC(req.MCC):
+		return fmt.Errorf("%w: %v", ErrInvalidAuthorizationRequest, ErrInvalidMCC)
+	}
+
+	return nil
+}
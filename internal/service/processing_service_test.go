@@ -0,0 +1,530 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"bank-app/internal/config"
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+)
+
+type MockCardAuthorizationRepository struct {
+	mock.Mock
+}
+
+func (m *MockCardAuthorizationRepository) Create(ctx context.Context, auth *model.CardAuthorization) error {
+	args := m.Called(ctx, auth)
+	return args.Error(0)
+}
+
+func (m *MockCardAuthorizationRepository) GetByID(ctx context.Context, id int64) (*model.CardAuthorization, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CardAuthorization), args.Error(1)
+}
+
+func (m *MockCardAuthorizationRepository) GetByIDForUpdate(ctx context.Context, id int64) (*model.CardAuthorization, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CardAuthorization), args.Error(1)
+}
+
+func (m *MockCardAuthorizationRepository) GetByReference(ctx context.Context, acquirer, reference string) (*model.CardAuthorization, error) {
+	args := m.Called(ctx, acquirer, reference)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CardAuthorization), args.Error(1)
+}
+
+func (m *MockCardAuthorizationRepository) GetCaptured(ctx context.Context, limit int) ([]*model.CardAuthorization, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.CardAuthorization), args.Error(1)
+}
+
+func (m *MockCardAuthorizationRepository) GetExpired(ctx context.Context, now time.Time, limit int) ([]*model.CardAuthorization, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.CardAuthorization), args.Error(1)
+}
+
+func (m *MockCardAuthorizationRepository) Update(ctx context.Context, auth *model.CardAuthorization) error {
+	args := m.Called(ctx, auth)
+	return args.Error(0)
+}
+
+type MockCardLimitService struct {
+	mock.Mock
+}
+
+func (m *MockCardLimitService) GetByCardID(ctx context.Context, cardID int64) ([]*model.CardLimit, error) {
+	args := m.Called(ctx, cardID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.CardLimit), args.Error(1)
+}
+
+func (m *MockCardLimitService) Create(ctx context.Context, limit *model.CardLimit) error {
+	args := m.Called(ctx, limit)
+	return args.Error(0)
+}
+
+func (m *MockCardLimitService) Update(ctx context.Context, limit *model.CardLimit) error {
+	args := m.Called(ctx, limit)
+	return args.Error(0)
+}
+
+func (m *MockCardLimitService) Delete(ctx context.Context, cardID, id int64) error {
+	args := m.Called(ctx, cardID, id)
+	return args.Error(0)
+}
+
+func (m *MockCardLimitService) Authorize(ctx context.Context, spend *model.CardSpend) error {
+	args := m.Called(ctx, spend)
+	return args.Error(0)
+}
+
+func (m *MockCardLimitService) Release(ctx context.Context, spend *model.CardSpend) error {
+	args := m.Called(ctx, spend)
+	return args.Error(0)
+}
+
+// processingMocks - зависимости сервиса приема операций по картам
+type processingMocks struct {
+	auths     *MockCardAuthorizationRepository
+	accounts  *MockAccountRepository
+	transfers *MockTransferRepository
+	cards     *MockCardRepository
+	limits    *MockCardLimitService
+	ledger    *MockLedgerService
+}
+
+const testCardNumber = "4276000000000009"
+
+func newTestProcessingService(t *testing.T) (*ProcessingSvc, *processingMocks) {
+	m := &processingMocks{
+		auths:     new(MockCardAuthorizationRepository),
+		accounts:  new(MockAccountRepository),
+		transfers: new(MockTransferRepository),
+		cards:     new(MockCardRepository),
+		limits:    new(MockCardLimitService),
+		ledger:    new(MockLedgerService),
+	}
+
+	cards := newTestCardService(t, m.cards)
+	cards.now = func() time.Time { return time.Date(2026, time.March, 15, 12, 0, 0, 0, time.UTC) }
+
+	cvvHash, err := bcrypt.GenerateFromPassword([]byte("123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	m.cards.On("GetByNumberHMAC", mock.Anything, cards.vault.MAC(testCardNumber)).Return(&model.Card{
+		ID:         3,
+		AccountID:  10,
+		Status:     model.CardActive,
+		ExpiryDate: model.CardExpiryDate(2029, time.March),
+		CVVHash:    string(cvvHash),
+	}, nil).Maybe()
+
+	cfg := &config.Config{
+		Processing: config.ProcessingConfig{
+			APIKeys: map[string]string{"shop": "secret-key"},
+			HoldTTL: 7 * 24 * time.Hour,
+		},
+	}
+	service := NewProcessingService(m.auths, m.accounts, m.transfers, cards, m.limits, m.ledger, &MockTransactor{}, cfg).(*ProcessingSvc)
+	service.now = cards.now
+	return service, m
+}
+
+func purchaseRequest(amount string) *model.AuthorizationRequest {
+	return &model.AuthorizationRequest{
+		Reference:  "order-1",
+		CardNumber: testCardNumber,
+		CVV:        "123",
+		Amount:     money.MustParse(amount),
+		Channel:    model.ChannelECommerce,
+		MCC:        "5411",
+	}
+}
+
+func TestProcessingService_AuthenticateAcquirer(t *testing.T) {
+	service, _ := newTestProcessingService(t)
+
+	acquirer, err := service.AuthenticateAcquirer("secret-key")
+	require.NoError(t, err)
+	assert.Equal(t, "shop", acquirer)
+
+	_, err = service.AuthenticateAcquirer("wrong")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	_, err = service.AuthenticateAcquirer("")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestProcessingService_Authorize(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("блокировка суммы на счете", func(t *testing.T) {
+		// Подготовка
+		service, m := newTestProcessingService(t)
+		m.auths.On("GetByReference", ctx, "shop", "order-1").Return(nil, ErrNotFound)
+		m.auths.On("Create", ctx, mock.AnythingOfType("*model.CardAuthorization")).Return(nil)
+		m.limits.On("Authorize", ctx, mock.MatchedBy(func(s *model.CardSpend) bool {
+			return s.CardID == 3 && s.Channel == model.ChannelECommerce && s.MCC == "5411" && s.Amount == money.Units(700)
+		})).Return(nil)
+		m.accounts.On("GetByIDForUpdate", ctx, int64(10)).Return(&model.Account{ID: 10, Balance: money.Units(1000), Held: money.Units(300)}, nil)
+		m.accounts.On("AdjustHeld", ctx, int64(10), money.Units(700)).Return(nil)
+
+		// Действие
+		auth, err := service.Authorize(ctx, "shop", purchaseRequest("700"))
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, model.AuthorizationApproved, auth.Status)
+		assert.Equal(t, int64(3), auth.CardID)
+		assert.Equal(t, int64(10), auth.AccountID)
+		assert.Equal(t, service.now().Add(7*24*time.Hour), auth.ExpiresAt)
+		m.accounts.AssertExpectations(t)
+		m.limits.AssertExpectations(t)
+	})
+
+	t.Run("недостаточно доступных средств", func(t *testing.T) {
+		// Подготовка
+		service, m := newTestProcessingService(t)
+		m.auths.On("GetByReference", ctx, "shop", "order-1").Return(nil, ErrNotFound)
+		m.auths.On("Create", ctx, mock.Anything).Return(nil)
+		m.limits.On("Authorize", ctx, mock.Anything).Return(nil)
+		m.accounts.On("GetByIDForUpdate", ctx, int64(10)).Return(&model.Account{ID: 10, Balance: money.Units(1000), Held: money.Units(400)}, nil)
+
+		// Действие
+		_, err := service.Authorize(ctx, "shop", purchaseRequest("700"))
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		m.accounts.AssertNotCalled(t, "AdjustHeld", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("превышен лимит карты", func(t *testing.T) {
+		// Подготовка
+		service, m := newTestProcessingService(t)
+		m.auths.On("GetByReference", ctx, "shop", "order-1").Return(nil, ErrNotFound)
+		m.auths.On("Create", ctx, mock.Anything).Return(nil)
+		m.limits.On("Authorize", ctx, mock.Anything).Return(ErrCardLimitExceeded)
+
+		// Действие
+		_, err := service.Authorize(ctx, "shop", purchaseRequest("700"))
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCardLimitExceeded)
+		m.accounts.AssertNotCalled(t, "AdjustHeld", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("неверный CVV", func(t *testing.T) {
+		// Подготовка
+		service, m := newTestProcessingService(t)
+		m.auths.On("GetByReference", ctx, "shop", "order-1").Return(nil, ErrNotFound)
+		req := purchaseRequest("700")
+		req.CVV = "999"
+
+		// Действие
+		_, err := service.Authorize(ctx, "shop", req)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidCVV)
+		m.auths.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("повтор запроса возвращает прежнюю авторизацию", func(t *testing.T) {
+		// Подготовка
+		service, m := newTestProcessingService(t)
+		existing := &model.CardAuthorization{ID: 5, CardID: 3, Amount: money.Units(700), Status: model.AuthorizationApproved}
+		m.auths.On("GetByReference", ctx, "shop", "order-1").Return(existing, nil)
+		otherCard := purchaseRequest("700")
+		otherCard.CardNumber = "4000000000000002"
+		m.cards.On("GetByNumberHMAC", mock.Anything, service.cards.(*CardSvc).vault.MAC(otherCard.CardNumber)).Return(&model.Card{ID: 4}, nil)
+
+		// Действие
+		auth, err := service.Authorize(ctx, "shop", purchaseRequest("700"))
+		_, conflict := service.Authorize(ctx, "shop", purchaseRequest("800"))
+		_, cardConflict := service.Authorize(ctx, "shop", otherCard)
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Same(t, existing, auth)
+		assert.ErrorIs(t, conflict, ErrDuplicateReference)
+		assert.ErrorIs(t, cardConflict, ErrDuplicateReference)
+		m.auths.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("некорректный запрос", func(t *testing.T) {
+		service, _ := newTestProcessingService(t)
+		req := purchaseRequest("700")
+		req.Channel = "phone"
+
+		_, err := service.Authorize(ctx, "shop", req)
+
+		assert.ErrorIs(t, err, ErrInvalidAuthorizationRequest)
+	})
+}
+
+func TestProcessingService_Purchase(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("покупка блокируется и подтверждается одной транзакцией", func(t *testing.T) {
+		// Подготовка
+		service, m := newTestProcessingService(t)
+		tx := service.tx.(*MockTransactor)
+		m.auths.On("GetByReference", ctx, "shop", "order-1").Return(nil, ErrNotFound)
+		m.auths.On("Create", ctx, mock.Anything).Return(nil)
+		m.limits.On("Authorize", ctx, mock.Anything).Return(nil)
+		m.accounts.On("GetByIDForUpdate", ctx, int64(10)).Return(&model.Account{ID: 10, Balance: money.Units(1000)}, nil)
+		m.accounts.On("AdjustHeld", ctx, int64(10), money.Units(700)).Return(nil)
+		m.auths.On("Update", ctx, mock.MatchedBy(func(auth *model.CardAuthorization) bool {
+			return auth.Status == model.AuthorizationCaptured && auth.CapturedAmount == money.Units(700)
+		})).Return(nil)
+
+		// Действие
+		auth, err := service.Purchase(ctx, "shop", purchaseRequest("700"))
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, model.AuthorizationCaptured, auth.Status)
+		assert.Equal(t, money.Units(700), auth.HeldAmount())
+		assert.True(t, tx.committed)
+		m.auths.AssertExpectations(t)
+		m.auths.AssertNotCalled(t, "GetByIDForUpdate", mock.Anything, mock.Anything)
+	})
+
+	t.Run("ошибка подтверждения откатывает блокировку", func(t *testing.T) {
+		// Подготовка
+		service, m := newTestProcessingService(t)
+		tx := service.tx.(*MockTransactor)
+		m.auths.On("GetByReference", ctx, "shop", "order-1").Return(nil, ErrNotFound)
+		m.auths.On("Create", ctx, mock.Anything).Return(nil)
+		m.limits.On("Authorize", ctx, mock.Anything).Return(nil)
+		m.accounts.On("GetByIDForUpdate", ctx, int64(10)).Return(&model.Account{ID: 10, Balance: money.Units(1000)}, nil)
+		m.accounts.On("AdjustHeld", ctx, int64(10), money.Units(700)).Return(nil)
+		m.auths.On("Update", ctx, mock.Anything).Return(errors.New("connection reset"))
+
+		// Действие
+		auth, err := service.Purchase(ctx, "shop", purchaseRequest("700"))
+
+		// Проверка: блокировка и подтверждение были в одной транзакции,
+		// которая откатилась целиком
+		require.Error(t, err)
+		assert.Nil(t, auth)
+		assert.True(t, tx.rolledBack)
+		assert.False(t, tx.committed)
+	})
+
+	t.Run("повтор покупки возвращает подтвержденную авторизацию", func(t *testing.T) {
+		// Подготовка
+		service, m := newTestProcessingService(t)
+		existing := &model.CardAuthorization{ID: 5, CardID: 3, AccountID: 10, Acquirer: "shop", Amount: money.Units(700),
+			Status: model.AuthorizationCaptured, CapturedAmount: money.Units(700)}
+		m.auths.On("GetByReference", ctx, "shop", "order-1").Return(existing, nil)
+		m.auths.On("GetByIDForUpdate", ctx, int64(5)).Return(existing, nil)
+
+		// Действие
+		auth, err := service.Purchase(ctx, "shop", purchaseRequest("700"))
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Same(t, existing, auth)
+		m.auths.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		m.accounts.AssertNotCalled(t, "AdjustHeld", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestProcessingService_Capture(t *testing.T) {
+	ctx := context.Background()
+
+	newAuth := func() *model.CardAuthorization {
+		return &model.CardAuthorization{
+			ID:        5,
+			CardID:    3,
+			AccountID: 10,
+			Acquirer:  "shop",
+			Channel:   model.ChannelPOS,
+			Amount:    money.Units(700),
+			Status:    model.AuthorizationApproved,
+		}
+	}
+
+	t.Run("частичное подтверждение снимает остаток блокировки", func(t *testing.T) {
+		// Подготовка
+		service, m := newTestProcessingService(t)
+		m.auths.On("GetByIDForUpdate", ctx, int64(5)).Return(newAuth(), nil)
+		m.accounts.On("AdjustHeld", ctx, int64(10), money.Units(-200)).Return(nil)
+		m.limits.On("Release", ctx, mock.MatchedBy(func(s *model.CardSpend) bool {
+			return s.CardID == 3 && s.Amount == money.Units(200)
+		})).Return(nil)
+		m.auths.On("Update", ctx, mock.Anything).Return(nil)
+
+		// Действие
+		auth, err := service.Capture(ctx, "shop", 5, money.Units(500))
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, model.AuthorizationCaptured, auth.Status)
+		assert.Equal(t, money.Units(500), auth.CapturedAmount)
+		assert.Equal(t, money.Units(500), auth.HeldAmount())
+		m.accounts.AssertExpectations(t)
+		m.limits.AssertExpectations(t)
+	})
+
+	t.Run("сумма больше авторизованной", func(t *testing.T) {
+		service, m := newTestProcessingService(t)
+		m.auths.On("GetByIDForUpdate", ctx, int64(5)).Return(newAuth(), nil)
+
+		_, err := service.Capture(ctx, "shop", 5, money.Units(701))
+
+		assert.ErrorIs(t, err, ErrInvalidCaptureAmount)
+	})
+
+	t.Run("авторизация другого эквайрера", func(t *testing.T) {
+		service, m := newTestProcessingService(t)
+		m.auths.On("GetByIDForUpdate", ctx, int64(5)).Return(newAuth(), nil)
+
+		_, err := service.Capture(ctx, "other", 5, 0)
+
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("отмена подтвержденной покупки", func(t *testing.T) {
+		// Подготовка
+		service, m := newTestProcessingService(t)
+		auth := newAuth()
+		auth.Status = model.AuthorizationCaptured
+		auth.CapturedAmount = money.Units(500)
+		m.auths.On("GetByIDForUpdate", ctx, int64(5)).Return(auth, nil)
+		m.accounts.On("AdjustHeld", ctx, int64(10), money.Units(-500)).Return(nil)
+		m.limits.On("Release", ctx, mock.Anything).Return(nil)
+		m.auths.On("Update", ctx, auth).Return(nil)
+
+		// Действие
+		_, err := service.Reverse(ctx, "shop", 5)
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, model.AuthorizationReversed, auth.Status)
+		m.accounts.AssertExpectations(t)
+	})
+
+	t.Run("рассчитанную покупку нельзя отменить", func(t *testing.T) {
+		service, m := newTestProcessingService(t)
+		auth := newAuth()
+		auth.Status = model.AuthorizationSettled
+		m.auths.On("GetByIDForUpdate", ctx, int64(5)).Return(auth, nil)
+
+		_, err := service.Reverse(ctx, "shop", 5)
+
+		assert.ErrorIs(t, err, ErrAuthorizationState)
+	})
+}
+
+func TestProcessingService_Settle(t *testing.T) {
+	ctx := context.Background()
+
+	// Подготовка
+	service, m := newTestProcessingService(t)
+	captured := &model.CardAuthorization{
+		ID: 5, CardID: 3, AccountID: 10, Amount: money.Units(700), CapturedAmount: money.Units(500),
+		Status: model.AuthorizationCaptured,
+	}
+	expired := &model.CardAuthorization{
+		ID: 6, CardID: 3, AccountID: 10, Amount: money.Units(100),
+		Status: model.AuthorizationApproved, ExpiresAt: service.now().Add(-time.Minute),
+	}
+	m.auths.On("GetCaptured", ctx, cardJobBatchSize).Return([]*model.CardAuthorization{captured}, nil)
+	m.auths.On("GetExpired", ctx, service.now(), cardJobBatchSize).Return([]*model.CardAuthorization{expired}, nil)
+	m.auths.On("GetByIDForUpdate", ctx, int64(5)).Return(captured, nil)
+	m.auths.On("GetByIDForUpdate", ctx, int64(6)).Return(expired, nil)
+	m.auths.On("Update", ctx, mock.Anything).Return(nil)
+
+	first := m.accounts.On("AdjustHeld", ctx, int64(10), money.Units(-500)).Return(nil).Once()
+	m.transfers.On("Create", ctx, mock.MatchedBy(func(tr *model.Transaction) bool {
+		return tr.FromAccountID == 10 && tr.Amount == money.Units(500) && tr.Type == transactionCardPurchase
+	})).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*model.Transaction).ID = 77
+	})
+	m.ledger.On("Post", ctx, mock.MatchedBy(func(e *model.LedgerEntry) bool {
+		return e.TransactionID == 77 && e.Description == "card purchase"
+	})).Return(nil).NotBefore(first)
+
+	m.accounts.On("AdjustHeld", ctx, int64(10), money.Units(-100)).Return(nil).Once()
+	m.limits.On("Release", ctx, mock.MatchedBy(func(s *model.CardSpend) bool {
+		return s.Amount == money.Units(100)
+	})).Return(nil)
+
+	// Действие
+	err := service.Settle(ctx)
+
+	// Проверка
+	require.NoError(t, err)
+	assert.Equal(t, model.AuthorizationSettled, captured.Status)
+	assert.Equal(t, int64(77), captured.TransactionID)
+	assert.Equal(t, model.AuthorizationExpired, expired.Status)
+	m.accounts.AssertExpectations(t)
+	m.ledger.AssertExpectations(t)
+	m.limits.AssertExpectations(t)
+}
+
+func TestProcessingService_Refund(t *testing.T) {
+	ctx := context.Background()
+
+	newSettled := func() *model.CardAuthorization {
+		return &model.CardAuthorization{
+			ID: 5, AccountID: 10, Acquirer: "shop", Amount: money.Units(700), CapturedAmount: money.Units(500),
+			RefundedAmount: money.Units(300), Status: model.AuthorizationSettled,
+		}
+	}
+
+	t.Run("частичный возврат", func(t *testing.T) {
+		// Подготовка
+		service, m := newTestProcessingService(t)
+		m.auths.On("GetByIDForUpdate", ctx, int64(5)).Return(newSettled(), nil)
+		m.transfers.On("Create", ctx, mock.MatchedBy(func(tr *model.Transaction) bool {
+			return tr.ToAccountID == 10 && tr.Type == transactionCardRefund
+		})).Return(nil)
+		m.ledger.On("Post", ctx, mock.MatchedBy(func(e *model.LedgerEntry) bool {
+			return e.Description == "card refund"
+		})).Return(nil)
+		m.auths.On("Update", ctx, mock.Anything).Return(nil)
+
+		// Действие
+		auth, err := service.Refund(ctx, "shop", 5, money.Units(200))
+
+		// Проверка
+		require.NoError(t, err)
+		assert.Equal(t, money.Units(500), auth.RefundedAmount)
+		m.ledger.AssertExpectations(t)
+	})
+
+	t.Run("возврат больше рассчитанной суммы", func(t *testing.T) {
+		service, m := newTestProcessingService(t)
+		m.auths.On("GetByIDForUpdate", ctx, int64(5)).Return(newSettled(), nil)
+
+		_, err := service.Refund(ctx, "shop", 5, money.Units(201))
+
+		assert.ErrorIs(t, err, ErrInvalidRefundAmount)
+		m.transfers.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
@@ -1,27 +1,57 @@
 package service
 
 import (
+	"github.com/sirupsen/logrus"
+
+	"bank-app/internal/cardvault"
+	"bank-app/internal/cbr"
 	"bank-app/internal/config"
+	"bank-app/internal/jwtkeys"
+	"bank-app/internal/mailer"
 	"bank-app/internal/repository"
 )
 
 type Services struct {
-	Users     UserService
-	Accounts  AccountService
-	Cards     CardService
-	Credits   CreditService
-	Transfers TransferService
-	Analytics AnalyticsService
+	Users       UserService
+	Accounts    AccountService
+	Cards       CardService
+	CardLimits  CardLimitService
+	Processing  ProcessingService
+	Credits     CreditService
+	KeyRates    KeyRateService
+	Transfers   TransferService
+	Analytics   AnalyticsService
+	Ledger      LedgerService
+	Idempotency IdempotencyService
+	Access      AccessService
+	Admin       AdminService
+	MFA         MFAService
+	Keys        *jwtkeys.KeySet
 }
 
-func NewServices(repos *repository.Repositories, cfg *config.Config) *Services {
+func NewServices(repos *repository.Repositories, keys *jwtkeys.KeySet, vault *cardvault.Vault, mail mailer.Mailer, rates cbr.Source, logger *logrus.Logger, cfg *config.Config) *Services {
+	ledger := NewLedgerService(repos.Ledger, repos.Accounts, repos.Transactor)
+	cards := NewCardService(repos.Cards, repos.CardPINs, repos.Transactor, vault, cfg)
+	limits := NewCardLimitService(repos.CardLimits, repos.Cards, repos.Accounts, repos.Transactor)
+	mfa := NewMFAService(repos.Users, repos.MFA, repos.Sessions, repos.Transactor, cfg)
+	keyRates := NewKeyRateService(repos.KeyRates, rates, logger, cfg)
+
 	return &Services{
-		Users:     NewUserService(repos.Users),
-		Accounts:  NewAccountService(repos.Accounts),
-		Cards:     NewCardService(repos.Cards),
-		Credits:   NewCreditService(repos.Credits, repos.Accounts, cfg),
-		Transfers: NewTransferService(repos.Transfers, repos.Accounts),
-		Analytics: NewAnalyticsService(repos.Analytics),
+		Users:       NewUserService(repos.Users, repos.Sessions, repos.Revoked, repos.Resets, repos.Logins, repos.Transactor, mfa, mail, keys, cfg),
+		Accounts:    NewAccountService(repos.Accounts, repos.Transfers, ledger, repos.Transactor),
+		Cards:       cards,
+		CardLimits:  limits,
+		Processing:  NewProcessingService(repos.CardAuths, repos.Accounts, repos.Transfers, cards, limits, ledger, repos.Transactor, cfg),
+		Credits:     NewCreditService(repos.Credits, repos.Accounts, repos.Transfers, ledger, keyRates, repos.Transactor, cfg),
+		KeyRates:    keyRates,
+		Transfers:   NewTransferService(repos.Transfers, repos.Accounts, ledger, repos.Transactor),
+		Analytics:   NewAnalyticsService(repos.Analytics),
+		Ledger:      ledger,
+		Idempotency: NewIdempotencyService(repos.Idempotency),
+		Access:      NewAccessService(repos.Accounts, repos.Cards, repos.Credits, repos.Transfers),
+		Admin:       NewAdminService(repos.Users, repos.Sessions, repos.Revoked, repos.Accounts, repos.CaseNotes, repos.Audit, cards, repos.Transactor),
+		MFA:         mfa,
+		Keys:        keys,
 	}
 }
 
@@ -3,75 +3,137 @@ package service
 import (
 	"context"
 	"errors"
+	"strconv"
 
 	"bank-app/internal/model"
+	"bank-app/internal/money"
 	"bank-app/internal/repository"
 )
 
+var ErrDestinationNotFound = errors.New("destination account not found")
+
 type TransferSvc struct {
 	repo     repository.TransferRepository
 	accounts repository.AccountRepository
+	ledger   LedgerService
+	tx       repository.Transactor
 }
 
-func NewTransferService(repo repository.TransferRepository, accounts repository.AccountRepository) TransferService {
+func NewTransferService(repo repository.TransferRepository, accounts repository.AccountRepository, ledger LedgerService, tx repository.Transactor) TransferService {
 	return &TransferSvc{
 		repo:     repo,
 		accounts: accounts,
+		ledger:   ledger,
+		tx:       tx,
 	}
 }
 
-func (s *TransferSvc) Transfer(ctx context.Context, fromID, toID int64, amount float64) error {
-	// Проверяем существование счетов
-	fromAcc, err := s.accounts.GetByID(ctx, fromID)
-	if err != nil {
-		return err
-	}
+const (
+	defaultTransactionPageSize = 50
+	maxTransactionPageSize     = 100
+)
 
-	toAcc, err := s.accounts.GetByID(ctx, toID)
-	if err != nil {
-		return err
+func (s *TransferSvc) Transfer(ctx context.Context, fromID, toID int64, amount money.Amount) (*model.Transaction, error) {
+	if fromID == toID {
+		return nil, errors.New("cannot transfer to the same account")
 	}
 
-	// Проверяем достаточность средств
-	if fromAcc.Balance < amount {
-		return errors.New("insufficient funds")
+	if !amount.IsPositive() {
+		return nil, errors.New("amount must be positive")
 	}
 
-	// Создаем транзакцию
 	transaction := &model.Transaction{
 		FromAccountID: fromID,
 		ToAccountID:   toID,
 		Amount:        amount,
+		Type:          "transfer",
 		Status:        "pending",
 	}
 
-	// Сохраняем транзакцию
-	if err := s.repo.Create(ctx, transaction); err != nil {
-		return err
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.checkDestination(ctx, fromID, toID); err != nil {
+			return err
+		}
+
+		if err := s.repo.Create(ctx, transaction); err != nil {
+			return err
+		}
+
+		// Проводим перевод по главной книге: счета блокируются
+		// и обновляются в той же транзакции
+		if err := s.ledger.Post(ctx, transferEntry(transaction.ID, fromID, toID, amount)); err != nil {
+			return err
+		}
+
+		// Обновляем статус транзакции
+		transaction.Status = "completed"
+		return s.repo.Update(ctx, transaction)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Обновляем балансы счетов
-	fromAcc.Balance -= amount
-	toAcc.Balance += amount
+	return transaction, nil
+}
 
-	if err := s.accounts.Update(ctx, fromAcc); err != nil {
+// checkDestination проверяет, что счет получателя существует и открыт в
+// валюте счета списания
+func (s *TransferSvc) checkDestination(ctx context.Context, fromID, toID int64) error {
+	from, err := s.accounts.GetByID(ctx, fromID)
+	if err != nil {
 		return err
 	}
 
-	if err := s.accounts.Update(ctx, toAcc); err != nil {
-		// В реальном приложении здесь нужно откатить изменения первого счета
+	to, err := s.accounts.GetByID(ctx, toID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrDestinationNotFound
+	}
+	if err != nil {
 		return err
 	}
 
-	// Обновляем статус транзакции
-	transaction.Status = "completed"
-	return s.repo.Update(ctx, transaction)
+	if from.Currency != to.Currency {
+		return ErrCurrencyMismatch
+	}
+
+	return nil
 }
 
 func (s *TransferSvc) GetByID(ctx context.Context, id int64) (*model.Transaction, error) {
 	return s.repo.GetByID(ctx, id)
 }
 
-func (s *TransferSvc) GetByAccountID(ctx context.Context, accountID int64) ([]*model.Transaction, error) {
-	return s.repo.GetByAccountID(ctx, accountID)
+// GetByAccountID возвращает страницу истории операций по счету
+func (s *TransferSvc) GetByAccountID(ctx context.Context, accountID int64, filter model.TransactionFilter) (*model.TransactionPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultTransactionPageSize
+	}
+	if filter.Limit > maxTransactionPageSize {
+		filter.Limit = maxTransactionPageSize
+	}
+
+	if filter.MinAmount > 0 && filter.MaxAmount > 0 && filter.MinAmount > filter.MaxAmount {
+		return nil, errors.New("min_amount must not exceed max_amount")
+	}
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+
+	transactions, err := s.repo.GetByAccountID(ctx, accountID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.TransactionPage{Transactions: transactions}
+	if len(transactions) > limit {
+		page.Transactions = transactions[:limit]
+		page.NextCursor = strconv.FormatInt(page.Transactions[limit-1].ID, 10)
+	}
+
+	if page.Transactions == nil {
+		page.Transactions = []*model.Transaction{}
+	}
+
+	return page, nil
 }
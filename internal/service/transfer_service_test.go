@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"bank-app/internal/model"
+	"bank-app/internal/money"
+)
+
+type MockTransferRepository struct {
+	mock.Mock
+}
+
+func (m *MockTransferRepository) Create(ctx context.Context, transaction *model.Transaction) error {
+	args := m.Called(ctx, transaction)
+	return args.Error(0)
+}
+
+func (m *MockTransferRepository) GetByID(ctx context.Context, id int64) (*model.Transaction, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Transaction), args.Error(1)
+}
+
+func (m *MockTransferRepository) GetByAccountID(ctx context.Context, accountID int64, filter model.TransactionFilter) ([]*model.Transaction, error) {
+	args := m.Called(ctx, accountID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Transaction), args.Error(1)
+}
+
+func (m *MockTransferRepository) Update(ctx context.Context, transaction *model.Transaction) error {
+	args := m.Called(ctx, transaction)
+	return args.Error(0)
+}
+
+// MockTransactor - мок unit of work, запоминающий результат транзакции
+type MockTransactor struct {
+	committed  bool
+	rolledBack bool
+}
+
+func (m *MockTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(ctx); err != nil {
+		m.rolledBack = true
+		return err
+	}
+	m.committed = true
+	return nil
+}
+
+// transferAccounts возвращает мок счетов с рублевыми счетами ids
+func transferAccounts(ctx context.Context, ids ...int64) *MockAccountRepository {
+	accounts := new(MockAccountRepository)
+	for _, id := range ids {
+		accounts.On("GetByID", ctx, id).Return(&model.Account{ID: id, Currency: "RUB"}, nil)
+	}
+	return accounts
+}
+
+func TestTransferService_Transfer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("успешный перевод", func(t *testing.T) {
+		// Подготовка
+		mockTransferRepo := new(MockTransferRepository)
+		mockLedger := new(MockLedgerService)
+		tx := &MockTransactor{}
+		service := NewTransferService(mockTransferRepo, transferAccounts(ctx, 1, 2), mockLedger, tx)
+
+		mockTransferRepo.On("Create", ctx, mock.AnythingOfType("*model.Transaction")).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.Transaction).ID = 10
+		})
+		mockLedger.On("Post", ctx, mock.AnythingOfType("*model.LedgerEntry")).Return(nil)
+		mockTransferRepo.On("Update", ctx, mock.AnythingOfType("*model.Transaction")).Return(nil)
+
+		// Действие
+		transaction, err := service.Transfer(ctx, 2, 1, money.Units(300))
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), transaction.ID)
+		assert.Equal(t, "completed", transaction.Status)
+		assert.True(t, tx.committed)
+		mockLedger.AssertExpectations(t)
+		mockTransferRepo.AssertExpectations(t)
+
+		entry := mockLedger.Calls[0].Arguments[1].(*model.LedgerEntry)
+		assert.Equal(t, int64(10), entry.TransactionID)
+		assert.Equal(t, int64(2), entry.Postings[0].AccountID)
+		assert.Equal(t, model.PostingDebit, entry.Postings[0].Direction)
+		assert.Equal(t, int64(1), entry.Postings[1].AccountID)
+		assert.Equal(t, model.PostingCredit, entry.Postings[1].Direction)
+	})
+
+	t.Run("ошибка проводки откатывает транзакцию", func(t *testing.T) {
+		// Подготовка
+		mockTransferRepo := new(MockTransferRepository)
+		mockLedger := new(MockLedgerService)
+		tx := &MockTransactor{}
+		service := NewTransferService(mockTransferRepo, transferAccounts(ctx, 1, 2), mockLedger, tx)
+
+		mockTransferRepo.On("Create", ctx, mock.AnythingOfType("*model.Transaction")).Return(nil)
+		mockLedger.On("Post", ctx, mock.AnythingOfType("*model.LedgerEntry")).Return(errors.New("insufficient funds"))
+
+		// Действие
+		_, err := service.Transfer(ctx, 1, 2, money.Units(300))
+
+		// Проверка
+		assert.Error(t, err)
+		assert.Equal(t, "insufficient funds", err.Error())
+		assert.True(t, tx.rolledBack)
+		assert.False(t, tx.committed)
+		mockTransferRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("перевод на тот же счет", func(t *testing.T) {
+		// Подготовка
+		mockTransferRepo := new(MockTransferRepository)
+		mockLedger := new(MockLedgerService)
+		tx := &MockTransactor{}
+		service := NewTransferService(mockTransferRepo, new(MockAccountRepository), mockLedger, tx)
+
+		// Действие
+		_, err := service.Transfer(ctx, 1, 1, money.Units(300))
+
+		// Проверка
+		assert.Error(t, err)
+		assert.False(t, tx.committed)
+		mockTransferRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("счет получателя не найден", func(t *testing.T) {
+		// Подготовка
+		mockTransferRepo := new(MockTransferRepository)
+		mockLedger := new(MockLedgerService)
+		accounts := transferAccounts(ctx, 1)
+		accounts.On("GetByID", ctx, int64(99)).Return(nil, ErrNotFound)
+		service := NewTransferService(mockTransferRepo, accounts, mockLedger, &MockTransactor{})
+
+		// Действие
+		_, err := service.Transfer(ctx, 1, 99, money.Units(300))
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrDestinationNotFound)
+		mockTransferRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		mockLedger.AssertNotCalled(t, "Post", mock.Anything, mock.Anything)
+	})
+
+	t.Run("счет получателя в другой валюте", func(t *testing.T) {
+		// Подготовка
+		mockTransferRepo := new(MockTransferRepository)
+		mockLedger := new(MockLedgerService)
+		accounts := transferAccounts(ctx, 1)
+		accounts.On("GetByID", ctx, int64(2)).Return(&model.Account{ID: 2, Currency: "USD"}, nil)
+		service := NewTransferService(mockTransferRepo, accounts, mockLedger, &MockTransactor{})
+
+		// Действие
+		_, err := service.Transfer(ctx, 1, 2, money.Units(300))
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrCurrencyMismatch)
+		mockTransferRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestTransferService_GetByAccountID(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("есть следующая страница", func(t *testing.T) {
+		// Подготовка
+		mockTransferRepo := new(MockTransferRepository)
+		service := NewTransferService(mockTransferRepo, new(MockAccountRepository), new(MockLedgerService), &MockTransactor{})
+
+		transactions := []*model.Transaction{{ID: 30}, {ID: 20}, {ID: 10}}
+		expectedFilter := model.TransactionFilter{Status: "completed", Cursor: 40, Limit: 3}
+		mockTransferRepo.On("GetByAccountID", ctx, int64(1), expectedFilter).Return(transactions, nil)
+
+		// Действие
+		page, err := service.GetByAccountID(ctx, 1, model.TransactionFilter{Status: "completed", Cursor: 40, Limit: 2})
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.Len(t, page.Transactions, 2)
+		assert.Equal(t, "20", page.NextCursor)
+		mockTransferRepo.AssertExpectations(t)
+	})
+
+	t.Run("последняя страница", func(t *testing.T) {
+		// Подготовка
+		mockTransferRepo := new(MockTransferRepository)
+		service := NewTransferService(mockTransferRepo, new(MockAccountRepository), new(MockLedgerService), &MockTransactor{})
+
+		expectedFilter := model.TransactionFilter{Limit: defaultTransactionPageSize + 1}
+		mockTransferRepo.On("GetByAccountID", ctx, int64(1), expectedFilter).Return(nil, nil)
+
+		// Действие
+		page, err := service.GetByAccountID(ctx, 1, model.TransactionFilter{})
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.Empty(t, page.Transactions)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("некорректный диапазон сумм", func(t *testing.T) {
+		// Подготовка
+		mockTransferRepo := new(MockTransferRepository)
+		service := NewTransferService(mockTransferRepo, new(MockAccountRepository), new(MockLedgerService), &MockTransactor{})
+
+		// Действие
+		_, err := service.GetByAccountID(ctx, 1, model.TransactionFilter{MinAmount: money.Units(100), MaxAmount: money.Units(10)})
+
+		// Проверка
+		assert.Error(t, err)
+		mockTransferRepo.AssertNotCalled(t, "GetByAccountID", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -2,30 +2,115 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 
+	"bank-app/internal/config"
+	"bank-app/internal/jwtkeys"
+	"bank-app/internal/mailer"
 	"bank-app/internal/model"
 	"bank-app/internal/repository"
 )
 
+var (
+	ErrInvalidToken             = errors.New("invalid token")
+	ErrTokenRevoked             = errors.New("token has been revoked")
+	ErrInvalidRefreshToken      = errors.New("invalid refresh token")
+	ErrMailDelivery             = errors.New("failed to send email")
+	ErrEmailNotVerified         = errors.New("email address is not verified")
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification link")
+	ErrInvalidResetToken        = errors.New("invalid or expired password reset token")
+	ErrWeakPassword             = errors.New("password must be at least 8 characters")
+)
+
+const (
+	minPasswordLength         = 8
+	emailVerificationTTL      = 48 * time.Hour
+	passwordResetTTL          = time.Hour
+	emailVerificationAudience = "email_verification"
+)
+
+// emailVerificationClaims - утверждения подписанной ссылки подтверждения email.
+// Токен не содержит jti, поэтому не принимается как access-токен.
+type emailVerificationClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// Claims - утверждения access-токена. ID (jti) используется для отзыва токена,
+// SessionID связывает токен с сессией для выхода.
+type Claims struct {
+	Role      string `json:"role"`
+	SessionID int64  `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// UserID возвращает идентификатор пользователя из subject
+func (c *Claims) UserID() (int64, error) {
+	id, err := strconv.ParseInt(c.Subject, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return id, nil
+}
+
 type UserSvc struct {
-	repo      repository.UserRepository
-	jwtSecret string
+	repo       repository.UserRepository
+	sessions   repository.SessionRepository
+	revoked    repository.RevokedTokenRepository
+	resets     repository.PasswordResetRepository
+	logins     repository.LoginAttemptRepository
+	tx         repository.Transactor
+	mfa        MFAService
+	mailer     mailer.Mailer
+	keys       *jwtkeys.KeySet
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	publicURL  string
+	resetURL   string
+	login      config.LoginProtectionConfig
+	now        func() time.Time
 }
 
-func NewUserService(repo repository.UserRepository) UserService {
+func NewUserService(repo repository.UserRepository, sessions repository.SessionRepository, revoked repository.RevokedTokenRepository, resets repository.PasswordResetRepository, logins repository.LoginAttemptRepository, tx repository.Transactor, mfa MFAService, mail mailer.Mailer, keys *jwtkeys.KeySet, cfg *config.Config) UserService {
 	return &UserSvc{
-		repo:      repo,
-		jwtSecret: "your-secret-key", // В реальном приложении брать из конфига
+		repo:       repo,
+		sessions:   sessions,
+		revoked:    revoked,
+		resets:     resets,
+		logins:     logins,
+		tx:         tx,
+		mfa:        mfa,
+		mailer:     mail,
+		keys:       keys,
+		accessTTL:  cfg.Auth.AccessTokenTTL,
+		refreshTTL: cfg.Auth.RefreshTokenTTL,
+		publicURL:  strings.TrimRight(cfg.PublicURL, "/"),
+		resetURL:   cfg.PasswordResetURL,
+		login:      cfg.Login,
+		now:        time.Now,
 	}
 }
 
+// Register создает пользователя и отправляет ссылку для подтверждения email.
+// Если письмо не отправлено, пользователь все равно создан: возвращается
+// ErrMailDelivery, ссылку можно запросить повторно.
 func (s *UserSvc) Register(ctx context.Context, username, email, password string) error {
+	if len(password) < minPasswordLength {
+		return ErrWeakPassword
+	}
+
 	// Проверяем, что пользователь с таким email не существует
 	if _, err := s.repo.GetByEmail(ctx, email); err == nil {
 		return errors.New("user with this email already exists")
@@ -47,40 +132,537 @@ func (s *UserSvc) Register(ctx context.Context, username, email, password string
 		Username:     username,
 		Email:        email,
 		PasswordHash: string(hashedPassword),
+		Role:         model.RoleCustomer,
+	}
+
+	if err := s.repo.Create(ctx, user); err != nil {
+		return err
+	}
+
+	return s.sendVerification(ctx, user)
+}
+
+// SendVerificationEmail повторно отправляет ссылку подтверждения email
+func (s *UserSvc) SendVerificationEmail(ctx context.Context, userID int64) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	return s.sendVerification(ctx, user)
+}
+
+// VerifyEmail подтверждает email по подписанной ссылке. Ссылка действует,
+// только пока адрес пользователя не изменился.
+func (s *UserSvc) VerifyEmail(ctx context.Context, token string) error {
+	claims := &emailVerificationClaims{}
+	_, err := s.keys.Parse(token, claims, jwt.WithAudience(emailVerificationAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(user.Email, claims.Email) {
+		return ErrInvalidVerificationToken
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	now := s.now()
+	user.EmailVerifiedAt = &now
+	return s.repo.Update(ctx, user)
+}
+
+// RequireVerifiedEmail возвращает ErrEmailNotVerified, если email не подтвержден
+func (s *UserSvc) RequireVerifiedEmail(ctx context.Context, userID int64) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
+
+// ForgotPassword отправляет одноразовую ссылку для смены пароля. Для неизвестного
+// email ошибка не возвращается, чтобы нельзя было проверить наличие учетной записи.
+func (s *UserSvc) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.repo.GetByEmail(ctx, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := newRefreshToken()
+	if err != nil {
+		return err
 	}
 
-	return s.repo.Create(ctx, user)
+	reset := &model.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: s.now().Add(passwordResetTTL),
+	}
+	if err := s.resets.Create(ctx, reset); err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(ctx, user.Email, mailer.PasswordReset, mailData{
+		Username:  user.Username,
+		Link:      s.resetLink(token),
+		ExpiresAt: reset.ExpiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMailDelivery, err)
+	}
+
+	return nil
 }
 
-func (s *UserSvc) Login(ctx context.Context, email, password string) (string, error) {
+// resetLink - ссылка на страницу смены пароля с токеном из письма
+func (s *UserSvc) resetLink(token string) string {
+	separator := "?"
+	if strings.Contains(s.resetURL, "?") {
+		separator = "&"
+	}
+	return s.resetURL + separator + "token=" + url.QueryEscape(token)
+}
+
+// ResetPassword меняет пароль по токену из письма. Токен погашается вместе
+// с остальными неиспользованными токенами пользователя, все сессии завершаются.
+func (s *UserSvc) ResetPassword(ctx context.Context, token, password string) error {
+	if len(password) < minPasswordLength {
+		return ErrWeakPassword
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		reset, err := s.resets.GetByTokenHash(ctx, hashToken(token))
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+
+		now := s.now()
+		if reset.UsedAt != nil || !now.Before(reset.ExpiresAt) {
+			return ErrInvalidResetToken
+		}
+
+		user, err := s.repo.GetByID(ctx, reset.UserID)
+		if err != nil {
+			return err
+		}
+
+		user.PasswordHash = string(hashedPassword)
+		// Письмо получено, значит адрес принадлежит пользователю
+		if user.EmailVerifiedAt == nil {
+			user.EmailVerifiedAt = &now
+		}
+		if err := s.repo.Update(ctx, user); err != nil {
+			return err
+		}
+
+		if err := s.resets.InvalidateByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return s.LogoutAll(ctx, user.ID)
+	})
+}
+
+// mailData - данные для шаблонов писем со ссылкой
+type mailData struct {
+	Username  string
+	Link      string
+	ExpiresAt time.Time
+}
+
+func (s *UserSvc) sendVerification(ctx context.Context, user *model.User) error {
+	expiresAt := s.now().Add(emailVerificationTTL)
+	token, err := s.keys.Sign(emailVerificationClaims{
+		Email: user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(user.ID, 10),
+			Audience:  jwt.ClaimStrings{emailVerificationAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(s.now()),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(ctx, user.Email, mailer.VerifyEmail, mailData{
+		Username:  user.Username,
+		Link:      s.publicURL + "/api/v1/email/verify?token=" + url.QueryEscape(token),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMailDelivery, err)
+	}
+
+	return nil
+}
+
+// Login проверяет пароль и открывает новую сессию. Если включена двухфакторная
+// аутентификация, вместо токенов возвращается токен второго шага. Каждая
+// попытка записывается в журнал; частые неудачи замедляют вход
+// и временно блокируют учетную запись.
+func (s *UserSvc) Login(ctx context.Context, email, password string, client model.ClientInfo) (*model.LoginResult, error) {
+	now := s.now()
+	attempt := &model.LoginAttempt{Email: email, IP: client.IP, UserAgent: client.UserAgent}
+
+	if err := s.checkIPThrottle(ctx, client.IP, now); err != nil {
+		return nil, s.rejectLogin(ctx, attempt, model.LoginIPThrottled, err)
+	}
+
 	// Получаем пользователя по email
 	user, err := s.repo.GetByEmail(ctx, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, s.rejectLogin(ctx, attempt, model.LoginUnknownEmail, ErrInvalidCredentials)
+	}
 	if err != nil {
-		return "", errors.New("invalid email or password")
+		return nil, err
+	}
+	attempt.UserID = user.ID
+
+	if err := s.checkAccountThrottle(user, now); err != nil {
+		reason := model.LoginThrottled
+		if errors.Is(err, ErrAccountLocked) {
+			reason = model.LoginLocked
+		}
+		return nil, s.rejectLogin(ctx, attempt, reason, err)
 	}
 
 	// Проверяем пароль
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
-		return "", errors.New("invalid email or password")
+		return nil, s.rejectLogin(ctx, attempt, model.LoginInvalidPassword, s.loginFailed(ctx, user, now))
+	}
+
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		if err := s.repo.ResetFailedLogins(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.recordLogin(ctx, attempt, ""); err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.mfa.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled {
+		token, err := s.mfa.CreateChallenge(ctx, user.ID, client)
+		if err != nil {
+			return nil, err
+		}
+		return &model.LoginResult{MFARequired: true, MFAToken: token}, nil
 	}
 
-	// Создаем JWT токен
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Subject:   strconv.FormatInt(user.ID, 10),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	tokens, err := s.startSession(ctx, user, client, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LoginResult{TokenPair: tokens}, nil
+}
+
+// CompleteMFALogin завершает вход кодом второго фактора. Сессия сразу
+// считается подтвержденной для операций, требующих step-up.
+func (s *UserSvc) CompleteMFALogin(ctx context.Context, mfaToken, code string, client model.ClientInfo) (*model.TokenPair, error) {
+	userID, err := s.mfa.VerifyChallenge(ctx, mfaToken, code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startSession(ctx, user, client, true)
+}
+
+func (s *UserSvc) startSession(ctx context.Context, user *model.User, client model.ClientInfo, mfaVerified bool) (*model.TokenPair, error) {
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	session := &model.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: hashToken(refreshToken),
+		UserAgent:        client.UserAgent,
+		IP:               client.IP,
+		ExpiresAt:        now.Add(s.refreshTTL),
+	}
+	if mfaVerified {
+		session.MFAVerifiedAt = &now
+	}
+	if session.AccessJTI, err = newTokenID(); err != nil {
+		return nil, err
+	}
+	session.AccessExpiresAt = now.Add(s.accessTTL)
+
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, session, refreshToken, now)
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов. Старый refresh-токен
+// перестает действовать, а его повторное предъявление считается кражей
+// и завершает всю сессию.
+func (s *UserSvc) Refresh(ctx context.Context, refreshToken string, client model.ClientInfo) (*model.TokenPair, error) {
+	var (
+		pair  *model.TokenPair
+		reuse bool
+	)
+
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		hash := hashToken(refreshToken)
+		session, err := s.sessions.GetByRefreshTokenHash(ctx, hash)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		now := s.now()
+		if !session.Active(now) {
+			return ErrInvalidRefreshToken
+		}
+
+		// Отзыв должен сохраниться, поэтому транзакция завершается без ошибки
+		if session.RefreshTokenHash != hash {
+			reuse = true
+			return s.revokeSession(ctx, session, now)
+		}
+
+		user, err := s.repo.GetByID(ctx, session.UserID)
+		if err != nil {
+			return err
+		}
+
+		// Access-токен, выданный вместе со старым refresh-токеном, больше не нужен
+		if err := s.revokeAccessToken(ctx, session, now); err != nil {
+			return err
+		}
+
+		newToken, err := newRefreshToken()
+		if err != nil {
+			return err
+		}
+
+		session.PreviousTokenHash = session.RefreshTokenHash
+		session.RefreshTokenHash = hashToken(newToken)
+		if session.AccessJTI, err = newTokenID(); err != nil {
+			return err
+		}
+		session.AccessExpiresAt = now.Add(s.accessTTL)
+		session.UserAgent = client.UserAgent
+		session.IP = client.IP
+		session.LastUsedAt = now
+
+		if err := s.sessions.Update(ctx, session); err != nil {
+			return err
+		}
+
+		pair, err = s.issueTokens(user, session, newToken, now)
+		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reuse {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return pair, nil
+}
+
+// Logout завершает сессию, к которой относится access-токен
+func (s *UserSvc) Logout(ctx context.Context, userID, sessionID int64) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		session, err := s.sessions.GetByID(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+
+		if session.UserID != userID {
+			return fmt.Errorf("session %w", ErrNotFound)
+		}
+
+		if session.RevokedAt != nil {
+			return nil
+		}
 
-	// Подписываем токен
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+		return s.revokeSession(ctx, session, s.now())
+	})
+}
+
+// LogoutAll завершает все активные сессии пользователя
+func (s *UserSvc) LogoutAll(ctx context.Context, userID int64) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		return revokeUserSessions(ctx, s.sessions, s.revoked, userID, s.now())
+	})
+}
+
+// GetSessions возвращает активные сессии пользователя
+func (s *UserSvc) GetSessions(ctx context.Context, userID int64) ([]*model.Session, error) {
+	return s.sessions.GetActiveByUserID(ctx, userID)
+}
+
+// Authenticate проверяет подпись и срок access-токена и сверяет jti со списком отзыва
+func (s *UserSvc) Authenticate(ctx context.Context, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := s.keys.Parse(tokenString, claims, jwt.WithExpirationRequired())
 	if err != nil {
-		return "", err
+		return nil, ErrInvalidToken
+	}
+
+	if claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := s.revoked.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, ErrTokenRevoked
 	}
 
-	return tokenString, nil
+	return claims, nil
 }
 
 func (s *UserSvc) GetByID(ctx context.Context, id int64) (*model.User, error) {
 	return s.repo.GetByID(ctx, id)
 }
+
+func (s *UserSvc) issueTokens(user *model.User, session *model.Session, refreshToken string, now time.Time) (*model.TokenPair, error) {
+	// Подписываем токен активным ключом
+	accessToken, err := s.keys.Sign(Claims{
+		Role:      user.Role,
+		SessionID: session.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        session.AccessJTI,
+			Subject:   strconv.FormatInt(user.ID, 10),
+			ExpiresAt: jwt.NewNumericDate(session.AccessExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(session.AccessExpiresAt.Sub(now).Seconds()),
+	}, nil
+}
+
+func (s *UserSvc) revokeSession(ctx context.Context, session *model.Session, now time.Time) error {
+	return revokeSession(ctx, s.sessions, s.revoked, session, now)
+}
+
+func (s *UserSvc) revokeAccessToken(ctx context.Context, session *model.Session, now time.Time) error {
+	return revokeAccessToken(ctx, s.revoked, session, now)
+}
+
+// revokeUserSessions завершает все активные сессии пользователя. Вызывается
+// в транзакции вызывающего.
+func revokeUserSessions(ctx context.Context, sessions repository.SessionRepository, revoked repository.RevokedTokenRepository, userID int64, now time.Time) error {
+	active, err := sessions.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range active {
+		if err := revokeSession(ctx, sessions, revoked, session, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func revokeSession(ctx context.Context, sessions repository.SessionRepository, revoked repository.RevokedTokenRepository, session *model.Session, now time.Time) error {
+	if err := revokeAccessToken(ctx, revoked, session, now); err != nil {
+		return err
+	}
+
+	session.RevokedAt = &now
+	return sessions.Update(ctx, session)
+}
+
+// revokeAccessToken вносит текущий access-токен сессии в список отзыва,
+// если срок его действия еще не истек
+func revokeAccessToken(ctx context.Context, revoked repository.RevokedTokenRepository, session *model.Session, now time.Time) error {
+	if !session.AccessExpiresAt.After(now) {
+		return nil
+	}
+	return revoked.Create(ctx, session.AccessJTI, session.AccessExpiresAt)
+}
+
+// newRefreshToken генерирует непрозрачный refresh-токен. В базе хранится только его хеш.
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
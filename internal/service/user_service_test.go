@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"errors"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,6 +12,9 @@ import (
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/crypto/bcrypt"
 
+	"bank-app/internal/config"
+	"bank-app/internal/jwtkeys"
+	"bank-app/internal/mailer"
 	"bank-app/internal/model"
 )
 
@@ -52,9 +57,166 @@ func (m *MockUserRepository) Update(ctx context.Context, user *model.User) error
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) Search(ctx context.Context, query string, limit int) ([]*model.User, error) {
+	args := m.Called(ctx, query, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.User), args.Error(1)
+}
+
+func (m *MockUserRepository) IncrementFailedLogins(ctx context.Context, id int64, window time.Duration) (int, error) {
+	args := m.Called(ctx, id, window)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) ResetFailedLogins(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Lock(ctx context.Context, id int64, until time.Time) error {
+	args := m.Called(ctx, id, until)
+	return args.Error(0)
+}
+
+// MockLoginAttemptRepository - мок для журнала попыток входа
+type MockLoginAttemptRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptRepository) Create(ctx context.Context, attempt *model.LoginAttempt) error {
+	args := m.Called(ctx, attempt)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptRepository) CountFailuresByIP(ctx context.Context, ip string, since time.Time) (int, time.Time, error) {
+	args := m.Called(ctx, ip, since)
+	return args.Int(0), args.Get(1).(time.Time), args.Error(2)
+}
+
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) Create(ctx context.Context, session *model.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) GetByID(ctx context.Context, id int64) (*model.Session, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) GetByRefreshTokenHash(ctx context.Context, hash string) (*model.Session, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) GetActiveByUserID(ctx context.Context, userID int64) ([]*model.Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) Update(ctx context.Context, session *model.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+type MockRevokedTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRevokedTokenRepository) Create(ctx context.Context, jti string, expiresAt time.Time) error {
+	args := m.Called(ctx, jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockRevokedTokenRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	args := m.Called(ctx, jti)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockPasswordResetRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetRepository) Create(ctx context.Context, token *model.PasswordResetToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockPasswordResetRepository) GetByTokenHash(ctx context.Context, hash string) (*model.PasswordResetToken, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PasswordResetToken), args.Error(1)
+}
+
+func (m *MockPasswordResetRepository) InvalidateByUserID(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+// MockMailer - мок отправки писем
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(ctx context.Context, to string, tmpl mailer.Template, data interface{}) error {
+	args := m.Called(ctx, to, tmpl, data)
+	return args.Error(0)
+}
+
+func newTestUserService(repo *MockUserRepository, sessions *MockSessionRepository, revoked *MockRevokedTokenRepository) *UserSvc {
+	cfg := &config.Config{
+		PublicURL:        "https://bank.example",
+		PasswordResetURL: "https://app.bank.example/reset",
+		Auth: config.AuthConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 24 * time.Hour,
+		},
+		Login: config.LoginProtectionConfig{
+			Window:           15 * time.Minute,
+			DelayAfter:       3,
+			BaseDelay:        time.Second,
+			MaxDelay:         time.Minute,
+			LockoutThreshold: 10,
+			LockoutDuration:  30 * time.Minute,
+			MaxFailuresPerIP: 50,
+		},
+	}
+	keys, _ := jwtkeys.NewKeySet(jwtkeys.DefaultKeyID, jwtkeys.NewHMACKey(jwtkeys.DefaultKeyID, []byte("test-secret")))
+
+	// По умолчанию двухфакторная аутентификация выключена
+	mfa := new(MockMFAService)
+	mfa.On("IsEnabled", mock.Anything, mock.Anything).Return(false, nil).Maybe()
+
+	mail := new(MockMailer)
+	mail.On("Send", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	// По умолчанию попытки входа только записываются в журнал
+	logins := new(MockLoginAttemptRepository)
+	logins.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+	logins.On("CountFailuresByIP", mock.Anything, mock.Anything, mock.Anything).Return(0, time.Time{}, nil).Maybe()
+
+	return NewUserService(repo, sessions, revoked, new(MockPasswordResetRepository), logins, &MockTransactor{}, mfa, mail, keys, cfg).(*UserSvc)
+}
+
 func TestUserService_Register(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := newTestUserService(mockRepo, new(MockSessionRepository), new(MockRevokedTokenRepository))
 	ctx := context.Background()
 
 	t.Run("успешная регистрация", func(t *testing.T) {
@@ -103,7 +265,8 @@ func TestUserService_Register(t *testing.T) {
 
 func TestUserService_Login(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	mockSessions := new(MockSessionRepository)
+	service := newTestUserService(mockRepo, mockSessions, new(MockRevokedTokenRepository))
 	ctx := context.Background()
 
 	t.Run("успешный вход", func(t *testing.T) {
@@ -119,14 +282,21 @@ func TestUserService_Login(t *testing.T) {
 		}
 
 		mockRepo.On("GetByEmail", ctx, email).Return(user, nil)
+		mockSessions.On("Create", ctx, mock.AnythingOfType("*model.Session")).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.Session).ID = 3
+		})
 
 		// Действие
-		token, err := service.Login(ctx, email, password)
+		result, err := service.Login(ctx, email, password, model.ClientInfo{UserAgent: "test", IP: "127.0.0.1"})
 
 		// Проверка
 		assert.NoError(t, err)
-		assert.NotEmpty(t, token)
+		assert.False(t, result.MFARequired)
+		assert.NotEmpty(t, result.AccessToken)
+		assert.NotEmpty(t, result.RefreshToken)
+		assert.Equal(t, int64(15*60), result.ExpiresIn)
 		mockRepo.AssertExpectations(t)
+		mockSessions.AssertExpectations(t)
 	})
 
 	t.Run("неверный пароль", func(t *testing.T) {
@@ -142,21 +312,68 @@ func TestUserService_Login(t *testing.T) {
 		}
 
 		mockRepo.On("GetByEmail", ctx, email).Return(user, nil)
+		mockRepo.On("IncrementFailedLogins", ctx, int64(1), 15*time.Minute).Return(1, nil)
 
 		// Действие
-		token, err := service.Login(ctx, email, password)
+		result, err := service.Login(ctx, email, password, model.ClientInfo{})
 
 		// Проверка
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
 		assert.Equal(t, "invalid email or password", err.Error())
-		assert.Empty(t, token)
+		assert.Nil(t, result)
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("включена двухфакторная аутентификация", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockUserRepository)
+		mockSessions := new(MockSessionRepository)
+		mockMFA := new(MockMFAService)
+		service := newTestUserService(mockRepo, mockSessions, new(MockRevokedTokenRepository))
+		service.mfa = mockMFA
+
+		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+		mockRepo.On("GetByEmail", ctx, "mfa@example.com").Return(&model.User{ID: 2, PasswordHash: string(hashedPassword)}, nil)
+		mockMFA.On("IsEnabled", ctx, int64(2)).Return(true, nil)
+		mockMFA.On("CreateChallenge", ctx, int64(2), mock.Anything).Return("challenge-token", nil)
+
+		// Действие
+		result, err := service.Login(ctx, "mfa@example.com", "password123", model.ClientInfo{})
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.True(t, result.MFARequired)
+		assert.Equal(t, "challenge-token", result.MFAToken)
+		assert.Nil(t, result.TokenPair)
+		mockSessions.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserService_CompleteMFALogin(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := new(MockUserRepository)
+	mockSessions := new(MockSessionRepository)
+	mockMFA := new(MockMFAService)
+	service := newTestUserService(mockRepo, mockSessions, new(MockRevokedTokenRepository))
+	service.mfa = mockMFA
+
+	mockMFA.On("VerifyChallenge", ctx, "challenge-token", "123456").Return(int64(2), nil)
+	mockRepo.On("GetByID", ctx, int64(2)).Return(&model.User{ID: 2}, nil)
+	mockSessions.On("Create", ctx, mock.MatchedBy(func(session *model.Session) bool {
+		return session.MFAVerifiedAt != nil
+	})).Return(nil)
+
+	tokens, err := service.CompleteMFALogin(ctx, "challenge-token", "123456", model.ClientInfo{})
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	mockSessions.AssertExpectations(t)
 }
 
 func TestUserService_GetByID(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := newTestUserService(mockRepo, new(MockSessionRepository), new(MockRevokedTokenRepository))
 	ctx := context.Background()
 
 	t.Run("пользователь найден", func(t *testing.T) {
@@ -195,3 +412,351 @@ func TestUserService_GetByID(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestUserService_Refresh(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ротация refresh-токена", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockUserRepository)
+		mockSessions := new(MockSessionRepository)
+		mockRevoked := new(MockRevokedTokenRepository)
+		service := newTestUserService(mockRepo, mockSessions, mockRevoked)
+
+		now := time.Now()
+		session := &model.Session{
+			ID:               3,
+			UserID:           1,
+			RefreshTokenHash: hashToken("old-token"),
+			AccessJTI:        "old-jti",
+			AccessExpiresAt:  now.Add(10 * time.Minute),
+			ExpiresAt:        now.Add(time.Hour),
+		}
+
+		mockSessions.On("GetByRefreshTokenHash", ctx, hashToken("old-token")).Return(session, nil)
+		mockRepo.On("GetByID", ctx, int64(1)).Return(&model.User{ID: 1, Role: model.RoleCustomer}, nil)
+		mockRevoked.On("Create", ctx, "old-jti", session.AccessExpiresAt).Return(nil)
+		mockSessions.On("Update", ctx, session).Return(nil)
+
+		// Действие
+		tokens, err := service.Refresh(ctx, "old-token", model.ClientInfo{UserAgent: "test"})
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.NotEqual(t, "old-token", tokens.RefreshToken)
+		assert.Equal(t, hashToken(tokens.RefreshToken), session.RefreshTokenHash)
+		assert.Equal(t, hashToken("old-token"), session.PreviousTokenHash)
+		assert.NotEqual(t, "old-jti", session.AccessJTI)
+		mockRevoked.AssertExpectations(t)
+	})
+
+	t.Run("повторное использование старого токена завершает сессию", func(t *testing.T) {
+		// Подготовка
+		mockSessions := new(MockSessionRepository)
+		mockRevoked := new(MockRevokedTokenRepository)
+		service := newTestUserService(new(MockUserRepository), mockSessions, mockRevoked)
+
+		now := time.Now()
+		session := &model.Session{
+			ID:                3,
+			UserID:            1,
+			RefreshTokenHash:  hashToken("new-token"),
+			PreviousTokenHash: hashToken("old-token"),
+			AccessJTI:         "current-jti",
+			AccessExpiresAt:   now.Add(10 * time.Minute),
+			ExpiresAt:         now.Add(time.Hour),
+		}
+
+		mockSessions.On("GetByRefreshTokenHash", ctx, hashToken("old-token")).Return(session, nil)
+		mockRevoked.On("Create", ctx, "current-jti", session.AccessExpiresAt).Return(nil)
+		mockSessions.On("Update", ctx, session).Return(nil)
+
+		// Действие
+		tokens, err := service.Refresh(ctx, "old-token", model.ClientInfo{})
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		assert.Nil(t, tokens)
+		assert.NotNil(t, session.RevokedAt)
+		mockRevoked.AssertExpectations(t)
+	})
+
+	t.Run("неизвестный токен", func(t *testing.T) {
+		// Подготовка
+		mockSessions := new(MockSessionRepository)
+		service := newTestUserService(new(MockUserRepository), mockSessions, new(MockRevokedTokenRepository))
+
+		mockSessions.On("GetByRefreshTokenHash", ctx, mock.Anything).Return(nil, ErrNotFound)
+
+		// Действие
+		_, err := service.Refresh(ctx, "unknown", model.ClientInfo{})
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+}
+
+func TestUserService_LogoutAll(t *testing.T) {
+	ctx := context.Background()
+
+	mockSessions := new(MockSessionRepository)
+	mockRevoked := new(MockRevokedTokenRepository)
+	service := newTestUserService(new(MockUserRepository), mockSessions, mockRevoked)
+
+	expires := time.Now().Add(10 * time.Minute)
+	sessions := []*model.Session{
+		{ID: 1, UserID: 1, AccessJTI: "jti-1", AccessExpiresAt: expires},
+		{ID: 2, UserID: 1, AccessJTI: "jti-2", AccessExpiresAt: expires},
+	}
+
+	mockSessions.On("GetActiveByUserID", ctx, int64(1)).Return(sessions, nil)
+	mockSessions.On("Update", ctx, mock.AnythingOfType("*model.Session")).Return(nil).Twice()
+	mockRevoked.On("Create", ctx, "jti-1", expires).Return(nil)
+	mockRevoked.On("Create", ctx, "jti-2", expires).Return(nil)
+
+	err := service.LogoutAll(ctx, 1)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, sessions[0].RevokedAt)
+	assert.NotNil(t, sessions[1].RevokedAt)
+	mockRevoked.AssertExpectations(t)
+}
+
+func TestUserService_Authenticate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("отозванный токен", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockUserRepository)
+		mockSessions := new(MockSessionRepository)
+		mockRevoked := new(MockRevokedTokenRepository)
+		service := newTestUserService(mockRepo, mockSessions, mockRevoked)
+
+		session := &model.Session{ID: 3, AccessJTI: "jti-1", AccessExpiresAt: time.Now().Add(time.Minute)}
+		tokens, err := service.issueTokens(&model.User{ID: 1, Role: model.RoleCustomer}, session, "refresh", time.Now())
+		assert.NoError(t, err)
+
+		mockRevoked.On("IsRevoked", ctx, "jti-1").Return(true, nil)
+
+		// Действие
+		claims, err := service.Authenticate(ctx, tokens.AccessToken)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+		assert.Nil(t, claims)
+	})
+
+	t.Run("действующий токен", func(t *testing.T) {
+		// Подготовка
+		mockRevoked := new(MockRevokedTokenRepository)
+		service := newTestUserService(new(MockUserRepository), new(MockSessionRepository), mockRevoked)
+
+		session := &model.Session{ID: 3, AccessJTI: "jti-2", AccessExpiresAt: time.Now().Add(time.Minute)}
+		tokens, err := service.issueTokens(&model.User{ID: 1, Role: model.RoleOperator}, session, "refresh", time.Now())
+		assert.NoError(t, err)
+
+		mockRevoked.On("IsRevoked", ctx, "jti-2").Return(false, nil)
+
+		// Действие
+		claims, err := service.Authenticate(ctx, tokens.AccessToken)
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), claims.SessionID)
+		assert.Equal(t, model.RoleOperator, claims.Role)
+	})
+}
+
+func TestUserService_EmailVerification(t *testing.T) {
+	ctx := context.Background()
+
+	// Подготовка: регистрация отправляет письмо со ссылкой
+	mockRepo := new(MockUserRepository)
+	mockMailer := new(MockMailer)
+	service := newTestUserService(mockRepo, new(MockSessionRepository), new(MockRevokedTokenRepository))
+	service.mailer = mockMailer
+
+	mockRepo.On("GetByEmail", ctx, "new@example.com").Return(nil, ErrNotFound)
+	mockRepo.On("GetByUsername", ctx, "new").Return(nil, ErrNotFound)
+	mockRepo.On("Create", ctx, mock.AnythingOfType("*model.User")).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*model.User).ID = 5
+	})
+
+	var link string
+	mockMailer.On("Send", ctx, "new@example.com", mailer.VerifyEmail, mock.AnythingOfType("service.mailData")).Return(nil).Run(func(args mock.Arguments) {
+		link = args.Get(3).(mailData).Link
+	})
+
+	err := service.Register(ctx, "new", "new@example.com", "password123")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(link, "https://bank.example/api/v1/email/verify?token="))
+
+	token, err := url.QueryUnescape(strings.TrimPrefix(link, "https://bank.example/api/v1/email/verify?token="))
+	assert.NoError(t, err)
+
+	t.Run("ссылка подтверждает email", func(t *testing.T) {
+		// Подготовка
+		user := &model.User{ID: 5, Email: "new@example.com"}
+		mockRepo.On("GetByID", ctx, int64(5)).Return(user, nil).Once()
+		mockRepo.On("Update", ctx, user).Return(nil).Once()
+
+		// Действие
+		err := service.VerifyEmail(ctx, token)
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.NotNil(t, user.EmailVerifiedAt)
+	})
+
+	t.Run("адрес изменился после отправки ссылки", func(t *testing.T) {
+		// Подготовка
+		mockRepo.On("GetByID", ctx, int64(5)).Return(&model.User{ID: 5, Email: "other@example.com"}, nil).Once()
+
+		// Действие
+		err := service.VerifyEmail(ctx, token)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+	})
+
+	t.Run("access-токен не подходит для подтверждения", func(t *testing.T) {
+		// Подготовка
+		session := &model.Session{ID: 1, AccessJTI: "jti", AccessExpiresAt: time.Now().Add(time.Minute)}
+		tokens, _ := service.issueTokens(&model.User{ID: 5}, session, "refresh", time.Now())
+
+		// Действие
+		err := service.VerifyEmail(ctx, tokens.AccessToken)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+	})
+
+	t.Run("ссылка подтверждения не принимается как access-токен", func(t *testing.T) {
+		// Действие
+		claims, err := service.Authenticate(ctx, token)
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		assert.Nil(t, claims)
+	})
+}
+
+func TestUserService_Register_MailFailure(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := new(MockUserRepository)
+	mockMailer := new(MockMailer)
+	service := newTestUserService(mockRepo, new(MockSessionRepository), new(MockRevokedTokenRepository))
+	service.mailer = mockMailer
+
+	mockRepo.On("GetByEmail", ctx, "new@example.com").Return(nil, ErrNotFound)
+	mockRepo.On("GetByUsername", ctx, "new").Return(nil, ErrNotFound)
+	mockRepo.On("Create", ctx, mock.AnythingOfType("*model.User")).Return(nil)
+	mockMailer.On("Send", ctx, "new@example.com", mailer.VerifyEmail, mock.Anything).Return(errors.New("connection refused"))
+
+	err := service.Register(ctx, "new", "new@example.com", "password123")
+
+	// Пользователь создан, ошибка отправки сообщается отдельно
+	assert.ErrorIs(t, err, ErrMailDelivery)
+	mockRepo.AssertCalled(t, "Create", ctx, mock.AnythingOfType("*model.User"))
+}
+
+func TestUserService_ResetPassword(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("успешная смена пароля", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockUserRepository)
+		mockSessions := new(MockSessionRepository)
+		mockResets := new(MockPasswordResetRepository)
+		service := newTestUserService(mockRepo, mockSessions, new(MockRevokedTokenRepository))
+		service.resets = mockResets
+
+		user := &model.User{ID: 5, PasswordHash: "old"}
+		mockResets.On("GetByTokenHash", ctx, hashToken("reset-token")).Return(&model.PasswordResetToken{
+			ID: 1, UserID: 5, ExpiresAt: time.Now().Add(time.Hour),
+		}, nil)
+		mockRepo.On("GetByID", ctx, int64(5)).Return(user, nil)
+		mockRepo.On("Update", ctx, user).Return(nil)
+		mockResets.On("InvalidateByUserID", ctx, int64(5)).Return(nil)
+		mockSessions.On("GetActiveByUserID", ctx, int64(5)).Return([]*model.Session{}, nil)
+
+		// Действие
+		err := service.ResetPassword(ctx, "reset-token", "new-password")
+
+		// Проверка
+		assert.NoError(t, err)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("new-password")))
+		assert.NotNil(t, user.EmailVerifiedAt)
+		mockResets.AssertExpectations(t)
+		mockSessions.AssertExpectations(t)
+	})
+
+	t.Run("токен уже использован", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockUserRepository)
+		mockResets := new(MockPasswordResetRepository)
+		service := newTestUserService(mockRepo, new(MockSessionRepository), new(MockRevokedTokenRepository))
+		service.resets = mockResets
+
+		usedAt := time.Now().Add(-time.Minute)
+		mockResets.On("GetByTokenHash", ctx, hashToken("reset-token")).Return(&model.PasswordResetToken{
+			ID: 1, UserID: 5, ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt,
+		}, nil)
+
+		// Действие
+		err := service.ResetPassword(ctx, "reset-token", "new-password")
+
+		// Проверка
+		assert.ErrorIs(t, err, ErrInvalidResetToken)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserService_ForgotPassword(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("неизвестный email", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockUserRepository)
+		mockMailer := new(MockMailer)
+		service := newTestUserService(mockRepo, new(MockSessionRepository), new(MockRevokedTokenRepository))
+		service.mailer = mockMailer
+
+		mockRepo.On("GetByEmail", ctx, "ghost@example.com").Return(nil, ErrNotFound)
+
+		// Действие
+		err := service.ForgotPassword(ctx, "ghost@example.com")
+
+		// Проверка
+		assert.NoError(t, err)
+		mockMailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("ссылка отправлена", func(t *testing.T) {
+		// Подготовка
+		mockRepo := new(MockUserRepository)
+		mockMailer := new(MockMailer)
+		mockResets := new(MockPasswordResetRepository)
+		service := newTestUserService(mockRepo, new(MockSessionRepository), new(MockRevokedTokenRepository))
+		service.mailer = mockMailer
+		service.resets = mockResets
+
+		var stored *model.PasswordResetToken
+		mockRepo.On("GetByEmail", ctx, "anna@example.com").Return(&model.User{ID: 7, Email: "anna@example.com"}, nil)
+		mockResets.On("Create", ctx, mock.AnythingOfType("*model.PasswordResetToken")).Return(nil).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*model.PasswordResetToken)
+		})
+		mockMailer.On("Send", ctx, "anna@example.com", mailer.PasswordReset, mock.MatchedBy(func(data mailData) bool {
+			token := strings.TrimPrefix(data.Link, "https://app.bank.example/reset?token=")
+			return hashToken(token) == stored.TokenHash
+		})).Return(nil)
+
+		// Действие
+		err := service.ForgotPassword(ctx, "anna@example.com")
+
+		// Проверка
+		assert.NoError(t, err)
+		mockMailer.AssertExpectations(t)
+	})
+}
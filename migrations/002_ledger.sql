@@ -0,0 +1,74 @@
+-- Журнал проводок (двойная запись)
+CREATE TABLE ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT REFERENCES transactions(id),
+    description VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Проводки по дебету и кредиту
+CREATE TABLE ledger_postings (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id BIGINT NOT NULL REFERENCES ledger_entries(id),
+    ledger_account VARCHAR(50) NOT NULL,
+    account_id BIGINT REFERENCES accounts(id),
+    direction VARCHAR(6) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT valid_direction CHECK (direction IN ('debit', 'credit')),
+    CONSTRAINT positive_posting_amount CHECK (amount > 0)
+);
+
+CREATE INDEX idx_ledger_entries_transaction_id ON ledger_entries(transaction_id);
+CREATE INDEX idx_ledger_postings_entry_id ON ledger_postings(entry_id);
+CREATE INDEX idx_ledger_postings_account_id ON ledger_postings(account_id);
+
+-- Проверка сбалансированности записи при фиксации транзакции
+CREATE OR REPLACE FUNCTION check_ledger_entry_balanced()
+RETURNS TRIGGER AS $$
+DECLARE
+    diff DECIMAL(15,2);
+BEGIN
+    SELECT COALESCE(SUM(CASE WHEN direction = 'debit' THEN amount ELSE -amount END), 0)
+    INTO diff
+    FROM ledger_postings
+    WHERE entry_id = NEW.entry_id;
+
+    IF diff <> 0 THEN
+        RAISE EXCEPTION 'ledger entry % is not balanced', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE CONSTRAINT TRIGGER ledger_postings_balanced
+    AFTER INSERT ON ledger_postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE FUNCTION check_ledger_entry_balanced();
+
+-- Остатки счетов, открытых до ведения журнала, переносятся начальной
+-- записью с капитала банка (opening_balance), чтобы счета сверялись с
+-- журналом. Повторное применение пропускает счета, у которых уже есть проводки.
+DO $$
+DECLARE
+    opening RECORD;
+    opening_entry_id BIGINT;
+BEGIN
+    FOR opening IN
+        SELECT a.id, a.balance
+        FROM accounts a
+        WHERE a.balance > 0
+            AND NOT EXISTS (SELECT 1 FROM ledger_postings p WHERE p.account_id = a.id)
+        ORDER BY a.id
+    LOOP
+        INSERT INTO ledger_entries (description)
+        VALUES ('opening balance')
+        RETURNING id INTO opening_entry_id;
+
+        INSERT INTO ledger_postings (entry_id, ledger_account, account_id, direction, amount)
+        VALUES
+            (opening_entry_id, 'opening_balance', NULL, 'debit', opening.balance),
+            (opening_entry_id, 'customer', opening.id, 'credit', opening.balance);
+    END LOOP;
+END $$;
@@ -0,0 +1,38 @@
+-- Роли пользователей
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'customer';
+ALTER TABLE users ADD CONSTRAINT valid_user_role CHECK (role IN ('customer', 'operator', 'admin'));
+
+-- Статус счета (заморозка операторами)
+ALTER TABLE accounts ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE accounts ADD CONSTRAINT valid_account_status CHECK (status IN ('active', 'frozen'));
+
+-- Статус карты (блокировка операторами)
+ALTER TABLE cards ADD COLUMN status VARCHAR(50) NOT NULL DEFAULT 'active';
+
+-- Заметки сотрудников по обращениям клиентов
+CREATE TABLE case_notes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    author_id BIGINT NOT NULL REFERENCES users(id),
+    account_id BIGINT REFERENCES accounts(id),
+    card_id BIGINT REFERENCES cards(id),
+    note TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Журнал действий сотрудников
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id BIGINT NOT NULL REFERENCES users(id),
+    actor_role VARCHAR(20) NOT NULL,
+    action VARCHAR(100) NOT NULL,
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id BIGINT,
+    allowed BOOLEAN NOT NULL,
+    details TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_case_notes_user_id ON case_notes(user_id);
+CREATE INDEX idx_audit_log_actor_id ON audit_log(actor_id);
+CREATE INDEX idx_audit_log_resource ON audit_log(resource_type, resource_id);
@@ -0,0 +1,4 @@
+-- Номер карты хранится в зашифрованном виде (encrypted_data), колонка number
+-- содержит только маскированный номер. Поиск по номеру выполняется через HMAC.
+COMMENT ON COLUMN cards.number IS 'masked PAN, e.g. 427600******1234';
+CREATE UNIQUE INDEX idx_cards_number_hmac ON cards(number_hmac);
@@ -0,0 +1,3 @@
+-- Продукт карты определяет диапазон BIN при выпуске
+ALTER TABLE cards ADD COLUMN product VARCHAR(20) NOT NULL DEFAULT 'debit';
+ALTER TABLE cards ADD CONSTRAINT valid_card_product CHECK (product IN ('debit', 'credit', 'virtual'));
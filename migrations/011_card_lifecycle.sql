@@ -0,0 +1,21 @@
+-- Жизненный цикл карты
+UPDATE cards SET status = 'blocked_temporarily' WHERE status = 'blocked';
+ALTER TABLE cards ADD CONSTRAINT valid_card_status
+    CHECK (status IN ('issued', 'active', 'blocked_temporarily', 'blocked_lost', 'closed', 'expired'));
+
+-- Перевыпущенная карта ссылается на предыдущую
+ALTER TABLE cards ADD COLUMN replaces_card_id BIGINT REFERENCES cards(id);
+CREATE UNIQUE INDEX idx_cards_replaces_card_id ON cards(replaces_card_id) WHERE replaces_card_id IS NOT NULL;
+
+-- История статусов карты с причинами
+CREATE TABLE card_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    card_id BIGINT NOT NULL REFERENCES cards(id),
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL,
+    reason TEXT NOT NULL,
+    changed_by BIGINT REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_card_status_history_card_id ON card_status_history(card_id, created_at);
@@ -0,0 +1,30 @@
+-- Часовой пояс счета для календарных лимитов карт
+ALTER TABLE accounts ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT 'Europe/Moscow';
+
+-- Лимиты расходов по карте. Пустые channel и mcc означают любой канал и любую
+-- категорию, нулевая сумма - отсутствие лимита.
+CREATE TABLE card_limits (
+    id BIGSERIAL PRIMARY KEY,
+    card_id BIGINT NOT NULL REFERENCES cards(id),
+    channel VARCHAR(20) NOT NULL DEFAULT '',
+    mcc VARCHAR(4) NOT NULL DEFAULT '',
+    per_transaction DECIMAL(15,2) NOT NULL DEFAULT 0,
+    daily DECIMAL(15,2) NOT NULL DEFAULT 0,
+    monthly DECIMAL(15,2) NOT NULL DEFAULT 0,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT valid_card_limit_channel CHECK (channel IN ('', 'pos', 'ecommerce', 'atm')),
+    CONSTRAINT non_negative_card_limits CHECK (per_transaction >= 0 AND daily >= 0 AND monthly >= 0),
+    CONSTRAINT unique_card_limit UNIQUE (card_id, channel, mcc)
+);
+
+-- Расходы по карте за календарный день счета в разрезе канала и категории
+CREATE TABLE card_spend_counters (
+    card_id BIGINT NOT NULL REFERENCES cards(id),
+    channel VARCHAR(20) NOT NULL,
+    mcc VARCHAR(4) NOT NULL,
+    day DATE NOT NULL,
+    amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (card_id, day, channel, mcc)
+);
@@ -0,0 +1,33 @@
+-- Средства, заблокированные под авторизации по картам
+ALTER TABLE accounts ADD COLUMN held DECIMAL(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD CONSTRAINT non_negative_held CHECK (held >= 0);
+
+-- Авторизации покупок по картам
+CREATE TABLE card_authorizations (
+    id BIGSERIAL PRIMARY KEY,
+    card_id BIGINT NOT NULL REFERENCES cards(id),
+    account_id BIGINT NOT NULL REFERENCES accounts(id),
+    acquirer VARCHAR(100) NOT NULL,
+    reference VARCHAR(100) NOT NULL,
+    merchant_name VARCHAR(255) NOT NULL DEFAULT '',
+    mcc VARCHAR(4) NOT NULL DEFAULT '',
+    channel VARCHAR(20) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    captured_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    refunded_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL,
+    transaction_id BIGINT REFERENCES transactions(id),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT positive_authorization_amount CHECK (amount > 0),
+    CONSTRAINT valid_captured_amount CHECK (captured_amount >= 0 AND captured_amount <= amount),
+    CONSTRAINT valid_refunded_amount CHECK (refunded_amount >= 0 AND refunded_amount <= captured_amount),
+    CONSTRAINT valid_authorization_status
+        CHECK (status IN ('authorized', 'captured', 'settled', 'reversed', 'expired')),
+    CONSTRAINT unique_authorization_reference UNIQUE (acquirer, reference)
+);
+
+CREATE INDEX idx_card_authorizations_card_id ON card_authorizations(card_id);
+CREATE INDEX idx_card_authorizations_pending ON card_authorizations(status, expires_at)
+    WHERE status IN ('authorized', 'captured');
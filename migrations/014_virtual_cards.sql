@@ -0,0 +1,9 @@
+-- Виртуальные карты: одноразовые и закрепляемые за первым торговцем
+ALTER TABLE cards ADD COLUMN single_use BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE cards ADD COLUMN merchant_locked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE cards ADD COLUMN locked_merchant VARCHAR(255);
+ALTER TABLE cards ADD CONSTRAINT virtual_card_options
+    CHECK (product = 'virtual' OR (NOT single_use AND NOT merchant_locked));
+
+-- CVV виртуальной карты хранится зашифрованным до единственного раскрытия реквизитов
+ALTER TABLE cards ADD COLUMN details_revealed_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,9 @@
+-- PIN карты хранится отдельно от карты в виде bcrypt-хеша вместе со счетчиком
+-- неверных попыток
+CREATE TABLE card_pins (
+    card_id BIGINT PRIMARY KEY REFERENCES cards(id),
+    pin_hash VARCHAR(255) NOT NULL,
+    failed_attempts INTEGER NOT NULL DEFAULT 0 CHECK (failed_attempts >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,5 @@
+-- Ежемесячный платеж хранится вместе с кредитом, график платежей - построчно
+-- в payment_schedules
+ALTER TABLE credits ADD COLUMN monthly_payment DECIMAL(15,2) NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX idx_payment_schedules_credit_date ON payment_schedules(credit_id, date);
@@ -0,0 +1,6 @@
+-- Тип графика погашения и соглашение о расчете дней, по которому начисляются
+-- проценты
+ALTER TABLE credits ADD COLUMN schedule_type VARCHAR(20) NOT NULL DEFAULT 'annuity';
+ALTER TABLE credits ADD COLUMN day_count VARCHAR(10) NOT NULL DEFAULT '30/360';
+ALTER TABLE credits ADD CONSTRAINT valid_schedule_type CHECK (schedule_type IN ('annuity', 'differentiated'));
+ALTER TABLE credits ADD CONSTRAINT valid_day_count CHECK (day_count IN ('30/360', 'ACT/365', 'ACT/ACT'));
@@ -0,0 +1,15 @@
+-- Погашение кредитов по графику: внесенная часть платежа, неустойка за
+-- просрочку и дата, по которую она начислена
+ALTER TABLE payment_schedules ADD COLUMN paid_amount DECIMAL(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE payment_schedules ADD COLUMN penalty DECIMAL(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE payment_schedules ADD COLUMN penalty_paid DECIMAL(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE payment_schedules ADD COLUMN penalty_accrued_to TIMESTAMP WITH TIME ZONE;
+ALTER TABLE payment_schedules ADD COLUMN paid_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE payment_schedules ADD CONSTRAINT valid_payment_paid
+    CHECK (paid_amount >= 0 AND paid_amount <= amount AND penalty_paid >= 0 AND penalty_paid <= penalty);
+ALTER TABLE payment_schedules ADD CONSTRAINT valid_payment_status
+    CHECK (status IN ('pending', 'partially_paid', 'paid', 'overdue'));
+
+ALTER TABLE credits ADD CONSTRAINT valid_credit_status CHECK (status IN ('active', 'overdue', 'closed'));
+
+CREATE INDEX idx_payment_schedules_unpaid ON payment_schedules(date) WHERE status <> 'paid';
@@ -0,0 +1,39 @@
+-- Досрочное погашение: номер действующей редакции графика и дата последнего
+-- досрочного погашения, с которой начисляются проценты по следующему платежу
+ALTER TABLE credits ADD COLUMN schedule_version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE credits ADD COLUMN prepaid_at TIMESTAMP WITH TIME ZONE;
+
+-- Редакции графика платежей: первая составляется при выдаче кредита,
+-- следующие - при каждом досрочном погашении
+CREATE TABLE credit_schedule_versions (
+    id BIGSERIAL PRIMARY KEY,
+    credit_id BIGINT NOT NULL REFERENCES credits(id),
+    version INTEGER NOT NULL,
+    mode VARCHAR(50) NOT NULL,
+    prepayment DECIMAL(15,2) NOT NULL DEFAULT 0,
+    principal DECIMAL(15,2) NOT NULL DEFAULT 0,
+    interest DECIMAL(15,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_schedule_version UNIQUE (credit_id, version),
+    CONSTRAINT valid_schedule_version_mode
+        CHECK (mode IN ('initial', 'shorten_term', 'reduce_payment', 'full'))
+);
+
+-- Платежи редакции графика, включая уже погашенные к ее составлению
+CREATE TABLE credit_schedule_version_payments (
+    version_id BIGINT NOT NULL REFERENCES credit_schedule_versions(id),
+    date TIMESTAMP WITH TIME ZONE NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    principal DECIMAL(15,2) NOT NULL,
+    interest DECIMAL(15,2) NOT NULL,
+    PRIMARY KEY (version_id, date)
+);
+
+-- Графики выданных ранее кредитов становятся их первой редакцией
+INSERT INTO credit_schedule_versions (credit_id, version, mode, created_at)
+SELECT id, 1, 'initial', created_at FROM credits;
+
+INSERT INTO credit_schedule_version_payments (version_id, date, amount, principal, interest)
+SELECT v.id, p.date, p.amount, p.principal, p.interest
+FROM payment_schedules p
+JOIN credit_schedule_versions v ON v.credit_id = p.credit_id AND v.version = 1;
@@ -0,0 +1,12 @@
+-- Ключевая ставка ЦБ РФ по датам начала действия. fetched_at - время
+-- последнего запроса к ЦБ, по нему определяется срок хранения в кэше.
+CREATE TABLE key_rates (
+    effective_date DATE PRIMARY KEY,
+    rate DECIMAL(5,2) NOT NULL,
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT non_negative_key_rate CHECK (rate >= 0)
+);
+
+-- Продукт кредита определяет надбавку к ключевой ставке
+ALTER TABLE credits ADD COLUMN product VARCHAR(20) NOT NULL DEFAULT 'consumer';
+ALTER TABLE credits ADD CONSTRAINT valid_credit_product CHECK (product IN ('consumer', 'mortgage', 'auto'));
@@ -0,0 +1,4 @@
+-- Проверка года окончания срока относительно CURRENT_DATE выполнялась при
+-- каждом UPDATE, поэтому после смены года карты с истекшим сроком нельзя было
+-- ни перевести в expired, ни закрыть. Срок действия проверяет сервис карт.
+ALTER TABLE cards DROP CONSTRAINT valid_expiry_year;
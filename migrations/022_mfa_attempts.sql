@@ -0,0 +1,4 @@
+-- Счетчик неверных кодов второго фактора подряд и время, до которого
+-- проверка кодов пользователя заблокирована после maxMFAAttempts неудач
+ALTER TABLE user_mfa ADD COLUMN failed_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE user_mfa ADD COLUMN locked_until TIMESTAMP WITH TIME ZONE;